@@ -29,7 +29,7 @@ func NewEnvironmentValidator(infrastructureManager infrastructureManager, boshCl
 }
 
 func (e EnvironmentValidator) Validate(state storage.State) error {
-	if state.Stack.Name == "" && state.TFState == "" {
+	if state.Stack.Name == "" && !state.HasTerraformState() {
 		return application.BBLNotFound
 	}
 
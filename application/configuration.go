@@ -1,10 +1,24 @@
 package application
 
-import "github.com/cloudfoundry/bosh-bootloader/storage"
+import (
+	"time"
+
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+)
+
+type StateLock interface {
+	Release() error
+}
 
 type GlobalConfiguration struct {
-	StateDir string
-	Debug    bool
+	StateDir              string
+	StateBackend          storage.StateBackend
+	StateLock             StateLock
+	Debug                 bool
+	TerraformRetries      int
+	TerraformRetryBackoff time.Duration
+	BOSHDeploymentDir     string
+	JumpboxDeploymentDir  string
 }
 
 type StringSlice []string
@@ -23,7 +23,7 @@ func NewEnvironmentValidator(boshClientProvider boshClientProvider) EnvironmentV
 }
 
 func (e EnvironmentValidator) Validate(state storage.State) error {
-	if state.TFState == "" {
+	if !state.HasTerraformState() {
 		return application.BBLNotFound
 	}
 
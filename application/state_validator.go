@@ -2,22 +2,22 @@ package application
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
+
+	"github.com/cloudfoundry/bosh-bootloader/storage"
 )
 
 type StateValidator struct {
-	stateDir string
+	stateBackend storage.StateBackend
 }
 
-func NewStateValidator(stateDir string) StateValidator {
-	return StateValidator{stateDir: stateDir}
+func NewStateValidator(stateBackend storage.StateBackend) StateValidator {
+	return StateValidator{stateBackend: stateBackend}
 }
 
 func (s StateValidator) Validate() error {
-	_, err := os.Stat(filepath.Join(s.stateDir, "bbl-state.json"))
-	if os.IsNotExist(err) {
-		return fmt.Errorf("bbl-state.json not found in %q, ensure you're running this command in the proper state directory or create a new environment with bbl up", s.stateDir)
+	_, err := s.stateBackend.Read(storage.StateFileName)
+	if err == storage.StateFileNotFound {
+		return fmt.Errorf("bbl-state.json not found in %q, ensure you're running this command in the proper state directory or create a new environment with bbl up", s.stateBackend.Location())
 	}
 	if err != nil {
 		return err
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 
 	"github.com/cloudfoundry/bosh-bootloader/application"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -23,7 +24,7 @@ var _ = Describe("StateValidator", func() {
 		tempDirectory, err = ioutil.TempDir("", "")
 		Expect(err).NotTo(HaveOccurred())
 
-		stateValidator = application.NewStateValidator(tempDirectory)
+		stateValidator = application.NewStateValidator(storage.NewLocalBackend(tempDirectory))
 	})
 
 	It("returns no error when state file exists", func() {
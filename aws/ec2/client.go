@@ -99,14 +99,46 @@ func (c Client) CheckExists(networkName string) (bool, error) {
 }
 
 func (c Client) ValidateSafeToDelete(vpcID, envID string) error {
+	vms, err := c.remainingVMs("vpc-id", []string{vpcID}, envID)
+	if err != nil {
+		return err
+	}
+
+	if len(vms) > 0 {
+		return fmt.Errorf("vpc %s is not safe to delete; vms still exist: [%s]", vpcID, strings.Join(vms, ", "))
+	}
+
+	return nil
+}
+
+// ValidateSafeToDeleteSubnets only considers the VMs in the given subnets,
+// for environments that live in a VPC shared with other workloads.
+func (c Client) ValidateSafeToDeleteSubnets(subnetIDs []string, envID string) error {
+	if len(subnetIDs) == 0 {
+		return nil
+	}
+
+	vms, err := c.remainingVMs("subnet-id", subnetIDs, envID)
+	if err != nil {
+		return err
+	}
+
+	if len(vms) > 0 {
+		return fmt.Errorf("subnets %s are not safe to delete; vms still exist: [%s]", strings.Join(subnetIDs, ", "), strings.Join(vms, ", "))
+	}
+
+	return nil
+}
+
+func (c Client) remainingVMs(filterName string, filterValues []string, envID string) ([]string, error) {
 	output, err := c.ec2Client.DescribeInstances(&awsec2.DescribeInstancesInput{
 		Filters: []*awsec2.Filter{{
-			Name:   awslib.String("vpc-id"),
-			Values: []*string{awslib.String(vpcID)},
+			Name:   awslib.String(filterName),
+			Values: awslib.StringSlice(filterValues),
 		}},
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	vms := c.flattenVMs(output.Reservations)
@@ -115,11 +147,7 @@ func (c Client) ValidateSafeToDelete(vpcID, envID string) error {
 	vms = c.removeOneVM(vms, "bosh/0")
 	vms = c.removeOneVM(vms, "jumpbox/0")
 
-	if len(vms) > 0 {
-		return fmt.Errorf("vpc %s is not safe to delete; vms still exist: [%s]", vpcID, strings.Join(vms, ", "))
-	}
-
-	return nil
+	return vms, nil
 }
 
 func (c Client) flattenVMs(reservations []*awsec2.Reservation) []string {
@@ -181,6 +181,46 @@ var _ = Describe("Client", func() {
 			})
 		})
 
+		Describe("ValidateSafeToDeleteSubnets", func() {
+			It("only looks for vms in the given subnets", func() {
+				ec2Client.DescribeInstancesCall.Returns.Output = &awsec2.DescribeInstancesOutput{
+					Reservations: []*awsec2.Reservation{
+						reservationContainingInstance("example-env-id-nat"),
+						reservationContainingInstance("bosh/0"),
+					},
+				}
+
+				err := client.ValidateSafeToDeleteSubnets([]string{"some-subnet-id", "some-other-subnet-id"}, "example-env-id")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(ec2Client.DescribeInstancesCall.Receives.Input).To(Equal(&awsec2.DescribeInstancesInput{
+					Filters: []*awsec2.Filter{{
+						Name:   awslib.String("subnet-id"),
+						Values: []*string{awslib.String("some-subnet-id"), awslib.String("some-other-subnet-id")},
+					}},
+				}))
+			})
+
+			It("returns an error when there are bosh-deployed VMs in the subnets", func() {
+				ec2Client.DescribeInstancesCall.Returns.Output = &awsec2.DescribeInstancesOutput{
+					Reservations: []*awsec2.Reservation{
+						reservationContainingInstance("bosh/0"),
+						reservationContainingInstance("some-bosh-deployed-vm"),
+					},
+				}
+
+				err := client.ValidateSafeToDeleteSubnets([]string{"some-subnet-id"}, "")
+				Expect(err).To(MatchError("subnets some-subnet-id are not safe to delete; vms still exist: [some-bosh-deployed-vm]"))
+			})
+
+			It("does not look for vms when there are no subnets", func() {
+				err := client.ValidateSafeToDeleteSubnets([]string{}, "")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(ec2Client.DescribeInstancesCall.Receives.Input).To(BeNil())
+			})
+		})
+
 		It("returns nil when there are no instances at all", func() {
 			ec2Client.DescribeInstancesCall.Returns.Output = &awsec2.DescribeInstancesOutput{
 				Reservations: []*awsec2.Reservation{},
@@ -4,9 +4,12 @@ import (
 	"bytes"
 	"crypto/rand"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/cloudfoundry/bosh-bootloader/application"
 	"github.com/cloudfoundry/bosh-bootloader/aws"
@@ -21,9 +24,11 @@ import (
 	"github.com/cloudfoundry/bosh-bootloader/cloudconfig"
 	"github.com/cloudfoundry/bosh-bootloader/commands"
 	"github.com/cloudfoundry/bosh-bootloader/config"
+	"github.com/cloudfoundry/bosh-bootloader/fsck"
 	"github.com/cloudfoundry/bosh-bootloader/gcp"
 	"github.com/cloudfoundry/bosh-bootloader/helpers"
 	"github.com/cloudfoundry/bosh-bootloader/proxy"
+	"github.com/cloudfoundry/bosh-bootloader/runtimeconfig"
 	"github.com/cloudfoundry/bosh-bootloader/stack"
 	"github.com/cloudfoundry/bosh-bootloader/storage"
 	"github.com/cloudfoundry/bosh-bootloader/terraform"
@@ -44,7 +49,14 @@ var (
 )
 
 func main() {
-	newConfig := config.NewConfig(storage.GetState)
+	var loadedState storage.State
+	loadState := func(backend storage.StateBackend) (storage.State, error) {
+		state, err := storage.LoadState(backend)
+		loadedState = state
+		return state, err
+	}
+
+	newConfig := config.NewConfig(loadState, acquireStateLock)
 	appConfig, err := newConfig.Bootstrap(os.Args)
 	log.SetFlags(0)
 	if err != nil {
@@ -55,7 +67,7 @@ func main() {
 	if needsIAASConfig {
 		err = config.ValidateIAAS(appConfig.State, appConfig.Command)
 		if err != nil {
-			log.Fatalf("\n\n%s\n", err)
+			exitWithError(appConfig, err)
 		}
 	}
 
@@ -69,13 +81,15 @@ func main() {
 
 	storage.GetStateLogger = stderrLogger
 
-	stateStore := storage.NewStore(appConfig.Global.StateDir)
-	stateValidator := application.NewStateValidator(appConfig.Global.StateDir)
+	stateStore := storage.NewStoreWithBackend(appConfig.Global.StateBackend, appConfig.Command)
+	stateStore.Loaded(loadedState)
+	stateValidator := application.NewStateValidator(appConfig.Global.StateBackend)
 
 	// Terraform
 	terraformOutputBuffer := bytes.NewBuffer([]byte{})
 	terraformCmd := terraform.NewCmd(os.Stderr, terraformOutputBuffer)
-	terraformExecutor := terraform.NewExecutor(terraformCmd, appConfig.Global.Debug)
+	terraformExecutor := terraform.NewExecutor(terraformCmd, filepath.Join(appConfig.Global.StateDir, terraform.OverridesDirName),
+		terraform.WorkingDir(appConfig.Global.StateDir), appConfig.Global.Debug)
 
 	var (
 		stackMigrator             stack.Migrator
@@ -114,7 +128,7 @@ func main() {
 		stackManager = cloudformation.NewStackManager(cloudFormationClient, logger)
 		infrastructureManager = cloudformation.NewInfrastructureManager(templateBuilder, stackManager)
 
-		stackMigrator = stack.NewMigrator(terraformExecutor, infrastructureManager, certificateDescriber, userPolicyDeleter, availabilityZoneRetriever, awsKeyPairDeleter)
+		stackMigrator = stack.NewMigrator(terraformExecutor, awsterraform.NewTemplateGenerator(), awsterraform.NewInputGenerator(awsClient), infrastructureManager, certificateDescriber, userPolicyDeleter, availabilityZoneRetriever, awsKeyPairDeleter)
 		networkClient = awsClient
 	}
 
@@ -122,7 +136,7 @@ func main() {
 		gcpClientProvider := gcp.NewClientProvider(gcpBasePath)
 		err = gcpClientProvider.SetConfig(appConfig.State.GCP.ServiceAccountKey, appConfig.State.GCP.ProjectID, appConfig.State.GCP.Region, appConfig.State.GCP.Zone)
 		if err != nil {
-			log.Fatalf("\n\n%s\n", err)
+			exitWithError(appConfig, err)
 		}
 		gcpClient = gcpClientProvider.Client()
 		networkClient = gcpClient
@@ -162,14 +176,21 @@ func main() {
 		TerraformOutputBuffer: terraformOutputBuffer,
 		Logger:                logger,
 		StackMigrator:         stackMigrator,
+		RetryPolicy: terraform.RetryPolicy{
+			Retries: appConfig.Global.TerraformRetries,
+			Backoff: appConfig.Global.TerraformRetryBackoff,
+		},
 	})
 
 	// BOSH
 	hostKeyGetter := proxy.NewHostKeyGetter()
 	socks5Proxy := proxy.NewSocks5Proxy(logger, hostKeyGetter, 0)
 	boshCommand := bosh.NewCmd(os.Stderr)
-	boshExecutor := bosh.NewExecutor(boshCommand, ioutil.TempDir, ioutil.ReadFile, json.Unmarshal,
-		json.Marshal, ioutil.WriteFile)
+	boshExecutor := bosh.NewExecutor(boshCommand, helpers.TempDir, ioutil.ReadFile, json.Unmarshal,
+		json.Marshal, ioutil.WriteFile, bosh.DeploymentDirs{
+			BOSH:    appConfig.Global.BOSHDeploymentDir,
+			Jumpbox: appConfig.Global.JumpboxDeploymentDir,
+		})
 	boshManager := bosh.NewManager(boshExecutor, logger, socks5Proxy)
 	boshClientProvider := bosh.NewClientProvider(socks5Proxy)
 
@@ -197,6 +218,7 @@ func main() {
 		cloudConfigOpsGenerator = azurecloudconfig.NewOpsGenerator(terraformManager)
 	}
 	cloudConfigManager := cloudconfig.NewManager(logger, boshCommand, cloudConfigOpsGenerator, boshClientProvider, socks5Proxy, terraformManager, sshKeyGetter)
+	runtimeConfigManager := runtimeconfig.NewManager(logger, boshCommand, boshClientProvider, boshExecutor)
 
 	// Subcommands
 	var (
@@ -206,30 +228,33 @@ func main() {
 		deleteLBsCmd commands.DeleteLBsCmd
 	)
 	if appConfig.State.IAAS == "aws" {
-		upCmd = commands.NewAWSUp(boshManager, cloudConfigManager, stateStore, envIDManager, terraformManager)
+		upCmd = commands.NewAWSUp(boshManager, cloudConfigManager, runtimeConfigManager, stateStore, envIDManager, terraformManager)
 		createLBsCmd = commands.NewAWSCreateLBs(cloudConfigManager, stateStore, terraformManager, environmentValidator)
 		lbsCmd = commands.NewAWSLBs(terraformManager, logger)
 		deleteLBsCmd = commands.NewAWSDeleteLBs(cloudConfigManager, stateStore, environmentValidator, terraformManager)
 	} else if appConfig.State.IAAS == "gcp" {
-		upCmd = commands.NewGCPUp(stateStore, terraformManager, boshManager, cloudConfigManager, envIDManager, gcpClient)
+		upCmd = commands.NewGCPUp(stateStore, terraformManager, boshManager, cloudConfigManager, runtimeConfigManager, envIDManager, gcpClient)
 		createLBsCmd = commands.NewGCPCreateLBs(terraformManager, cloudConfigManager, stateStore, environmentValidator, gcpClient)
 		lbsCmd = commands.NewGCPLBs(terraformManager, logger)
 		deleteLBsCmd = commands.NewGCPDeleteLBs(stateStore, environmentValidator, terraformManager, cloudConfigManager)
 	} else if appConfig.State.IAAS == "azure" {
 		azureClient := azure.NewClient()
-		upCmd = commands.NewAzureUp(azureClient, boshManager, cloudConfigManager, envIDManager, logger, stateStore, terraformManager)
+		upCmd = commands.NewAzureUp(azureClient, boshManager, cloudConfigManager, runtimeConfigManager, envIDManager, logger, stateStore, terraformManager)
 		deleteLBsCmd = commands.NewAzureDeleteLBs(cloudConfigManager, stateStore, terraformManager)
 	}
 
-	up := commands.NewUp(upCmd, boshManager)
+	upPlan := commands.NewUpPlan(logger, terraformManager, boshManager)
+	up := commands.NewUp(logger, upCmd, upPlan, boshManager)
 
 	// Commands
 	commandSet := application.CommandSet{}
 	commandSet["help"] = usage
 	commandSet["version"] = commands.NewVersion(Version, logger)
 	commandSet["up"] = up
+	commandSet["plan"] = commands.NewPlan(up)
 	sshKeyDeleter := bosh.NewSSHKeyDeleter()
 	commandSet["rotate"] = commands.NewRotate(stateValidator, sshKeyDeleter, up)
+	commandSet["upgrade-director"] = commands.NewUpgradeDirector(stateValidator, terraformManager, boshManager, stateStore)
 	commandSet["destroy"] = commands.NewDestroy(logger, os.Stdin, boshManager, stackManager, infrastructureManager, certificateDeleter, stateStore, stateValidator, terraformManager, networkDeletionValidator)
 	commandSet["down"] = commandSet["destroy"]
 	commandSet["create-lbs"] = commands.NewCreateLBs(createLBsCmd, logger, stateValidator, certificateValidator, boshManager)
@@ -246,12 +271,75 @@ func main() {
 	commandSet["latest-error"] = commands.NewLatestError(logger, stateValidator)
 	commandSet["print-env"] = commands.NewPrintEnv(logger, stateValidator, terraformManager)
 	commandSet["cloud-config"] = commands.NewCloudConfig(logger, stateValidator, cloudConfigManager)
+	commandSet["runtime-config"] = commands.NewRuntimeConfig(logger, stateValidator, runtimeConfigManager)
+	commandSet["state"] = commands.NewState(logger, stateValidator, stateStore, stateStore, stateStore)
+	commandSet["export"] = commands.NewExport(logger, stateValidator, terraformManager, Version, bosh.DeploymentVersions)
+	commandSet["import"] = commands.NewImport(logger, stateStore)
+	commandSet["drift"] = commands.NewDrift(logger, stateValidator, terraformManager)
+	commandSet["terraform"] = commands.NewTerraform(logger, stateValidator, terraformManager, stateStore, os.Stdout)
+	commandSet["fsck"] = commands.NewFsck(logger, stateValidator, terraformManager, fsck.NewChecker(), stateStore)
+	commandSet["force-unlock"] = commands.NewForceUnlock(logger, storage.NewLock(appConfig.Global.StateDir))
 	commandSet["bosh-deployment-vars"] = commands.NewBOSHDeploymentVars(logger, boshManager, stateValidator, terraformManager)
 
 	app := application.New(commandSet, appConfig, usage)
 
 	err = app.Run()
+	if err != nil {
+		exitWithError(appConfig, err)
+	}
+
+	err = helpers.RemoveTempDirs()
+	if err != nil {
+		releaseStateLock(appConfig)
+		log.Fatalf("\n\nfailed to remove temp dirs: %s\n", err)
+	}
+
+	err = releaseStateLock(appConfig)
 	if err != nil {
 		log.Fatalf("\n\n%s\n", err)
 	}
 }
+
+func acquireStateLock(dir, command string, timeout time.Duration) (application.StateLock, error) {
+	lock := storage.NewLock(dir)
+	err := lock.Acquire(command, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return lock, nil
+}
+
+func releaseStateLock(appConfig application.Configuration) error {
+	if appConfig.Global.StateLock == nil {
+		return nil
+	}
+
+	err := appConfig.Global.StateLock.Release()
+	if err != nil {
+		return fmt.Errorf("failed to release state lock: %s", err)
+	}
+
+	return nil
+}
+
+// exitCoder is an error that sets the exit status of bbl.
+type exitCoder interface {
+	ExitCode() int
+}
+
+func exitWithError(appConfig application.Configuration, err error) {
+	helpers.RemoveTempDirs()
+
+	releaseErr := releaseStateLock(appConfig)
+	if releaseErr != nil {
+		log.Fatalf("\n\n%s\n%s\n", err, releaseErr)
+	}
+
+	if exitErr, ok := err.(exitCoder); ok {
+		log.Printf("\n\n%s\n", err)
+		os.Exit(exitErr.ExitCode())
+	}
+
+	log.Fatalf("\n\n%s\n", err)
+}
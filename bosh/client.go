@@ -17,6 +17,7 @@ import (
 
 type Client interface {
 	UpdateCloudConfig(yaml []byte) error
+	UpdateRuntimeConfig(name string, yaml []byte) error
 	Info() (Info, error)
 }
 
@@ -75,7 +76,17 @@ func (c client) Info() (Info, error) {
 }
 
 func (c client) UpdateCloudConfig(yaml []byte) error {
-	request, err := http.NewRequest("POST", fmt.Sprintf("%s/cloud_configs", c.directorAddress), bytes.NewBuffer(yaml))
+	return c.postYAML(fmt.Sprintf("%s/cloud_configs", c.directorAddress), yaml)
+}
+
+func (c client) UpdateRuntimeConfig(name string, yaml []byte) error {
+	return c.postYAML(fmt.Sprintf("%s/runtime_configs?name=%s", c.directorAddress, url.QueryEscape(name)), yaml)
+}
+
+// postYAML authenticates against UAA on the director when there is a
+// jumpbox, and with basic auth otherwise.
+func (c client) postYAML(address string, yaml []byte) error {
+	request, err := http.NewRequest("POST", address, bytes.NewBuffer(yaml))
 	if err != nil {
 		return err
 	}
@@ -29,6 +29,8 @@ var _ = Describe("Client", func() {
 		username               string
 		password               string
 		cloudConfigContentType string
+		runtimeConfig          []byte
+		runtimeConfigName      string
 		httpClient             *http.Client
 		failStatus             int
 	)
@@ -94,6 +96,22 @@ var _ = Describe("Client", func() {
 				var err error
 				cloudConfig, err = ioutil.ReadAll(req.Body)
 				Expect(err).NotTo(HaveOccurred())
+			case "/runtime_configs":
+				if failStatus != 0 {
+					w.WriteHeader(failStatus)
+					return
+				}
+
+				username, password, _ = req.BasicAuth()
+
+				token = req.Header.Get("Authorization")
+				runtimeConfigName = req.URL.Query().Get("name")
+
+				w.WriteHeader(http.StatusCreated)
+
+				var err error
+				runtimeConfig, err = ioutil.ReadAll(req.Body)
+				Expect(err).NotTo(HaveOccurred())
 			default:
 				dump, err := httputil.DumpRequest(req, true)
 				Expect(err).NotTo(HaveOccurred())
@@ -255,4 +273,60 @@ var _ = Describe("Client", func() {
 			})
 		})
 	})
+
+	Describe("UpdateRuntimeConfig", func() {
+		Context("when a jumpbox is enabled", func() {
+			It("uses UAA to get a token when it uploads the runtime-config", func() {
+				dialer := &fakes.Socks5Client{}
+				dialer.DialCall.Stub = func(network, addr string) (net.Conn, error) {
+					u, _ := url.Parse(fakeBOSH.URL)
+					return net.Dial(network, u.Host)
+				}
+
+				httpClient = &http.Client{
+					Transport: &http.Transport{
+						Dial:            dialer.Dial,
+						TLSClientConfig: tlsConfig,
+					},
+				}
+
+				fakeBOSH.StartTLS()
+
+				client := bosh.NewClient(httpClient, true, fakeBOSH.URL, "some-username", "some-password", string(ca))
+
+				err := client.UpdateRuntimeConfig("bbl", []byte("runtime: config"))
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(token).To(Equal("Bearer some-uaa-token"))
+				Expect(runtimeConfigName).To(Equal("bbl"))
+				Expect(runtimeConfig).To(Equal([]byte("runtime: config")))
+			})
+		})
+
+		Context("when a jumpbox is not enabled", func() {
+			It("uploads the runtime-config with the given name", func() {
+				fakeBOSH.StartTLS()
+
+				client := bosh.NewClient(httpClient, false, fakeBOSH.URL, "some-username", "some-password", string(ca))
+
+				err := client.UpdateRuntimeConfig("bbl", []byte("runtime: config"))
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(runtimeConfigName).To(Equal("bbl"))
+				Expect(runtimeConfig).To(Equal([]byte("runtime: config")))
+				Expect(username).To(Equal("some-username"))
+				Expect(password).To(Equal("some-password"))
+			})
+
+			It("returns an error when a non-201 occurs", func() {
+				failStatus = http.StatusInternalServerError
+				fakeBOSH.StartTLS()
+
+				client := bosh.NewClient(httpClient, false, fakeBOSH.URL, "", "", string(ca))
+
+				err := client.UpdateRuntimeConfig("bbl", []byte("runtime: config"))
+				Expect(err).To(MatchError("unexpected http response 500 Internal Server Error"))
+			})
+		})
+	})
 })
@@ -0,0 +1,83 @@
+package bosh
+
+import (
+	"fmt"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+)
+
+const (
+	boshDeploymentAssets    = "vendor/github.com/cloudfoundry/bosh-deployment"
+	jumpboxDeploymentAssets = "vendor/github.com/cppforlife/jumpbox-deployment"
+)
+
+var gitRevParse = defaultGitRevParse
+
+func defaultGitRevParse(dir string) (string, error) {
+	output, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// DeploymentDirs are local checkouts of bosh-deployment and
+// jumpbox-deployment. The executor reads manifests and ops files from them
+// instead of the copies compiled into bbl when they are set.
+type DeploymentDirs struct {
+	BOSH    string
+	Jumpbox string
+}
+
+// deploymentFiles reads the given files of a deployment, keyed by the name
+// they are written to in the interpolation directory. A checkout must hold
+// every file; all missing files are reported at once.
+func (e Executor) deploymentFiles(dir, assets, name string, paths map[string]string) (map[string][]byte, error) {
+	files := map[string][]byte{}
+
+	if dir == "" {
+		for file, assetPath := range paths {
+			files[file] = MustAsset(path.Join(assets, assetPath))
+		}
+		return files, nil
+	}
+
+	var missing []string
+	for file, checkoutPath := range paths {
+		contents, err := e.readFile(filepath.Join(dir, checkoutPath))
+		if err != nil {
+			missing = append(missing, checkoutPath)
+			continue
+		}
+		files[file] = contents
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("%s checkout %s is missing %s", name, dir, strings.Join(missing, ", "))
+	}
+
+	return files, nil
+}
+
+// deploymentSource describes where the files of a deployment came from: the
+// commit compiled into bbl, or the checkout and its HEAD commit. The commit
+// is left empty when the checkout is not a git repository.
+func deploymentSource(dir, name string) storage.DeploymentSource {
+	if dir == "" {
+		version := strings.SplitN(DeploymentVersions[name], "@", 2)
+		return storage.DeploymentSource{Source: version[0], SHA: version[1]}
+	}
+
+	sha, err := gitRevParse(dir)
+	if err != nil {
+		sha = ""
+	}
+
+	return storage.DeploymentSource{Source: dir, SHA: sha}
+}
@@ -0,0 +1,9 @@
+package bosh
+
+// DeploymentVersions lists the jumpbox-deployment and bosh-deployment commits
+// compiled into deployment_files.go. Keep it in sync with
+// deployment-versions.txt when the deployments are bumped.
+var DeploymentVersions = map[string]string{
+	"jumpbox-deployment": "cppforlife/jumpbox-deployment@8c7c9495c5f8f81f1a48ba662900a98e6c9a2453",
+	"bosh-deployment":    "cloudfoundry/bosh-deployment@b1bd02133d45023c10ecab8d13fc3b9d9cc1b187",
+}
@@ -0,0 +1,25 @@
+package bosh_test
+
+import (
+	"io/ioutil"
+	"regexp"
+
+	"github.com/cloudfoundry/bosh-bootloader/bosh"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DeploymentVersions", func() {
+	It("matches deployment-versions.txt", func() {
+		contents, err := ioutil.ReadFile("../deployment-versions.txt")
+		Expect(err).NotTo(HaveOccurred())
+
+		versions := map[string]string{}
+		for _, match := range regexp.MustCompile(`Current ([a-z-]+): ([^*\s]+)`).FindAllStringSubmatch(string(contents), -1) {
+			versions[match[1]] = match[2]
+		}
+
+		Expect(bosh.DeploymentVersions).To(Equal(versions))
+	})
+})
@@ -8,8 +8,11 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 
 	"github.com/cloudfoundry/bosh-bootloader/helpers"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+	yaml "gopkg.in/yaml.v2"
 )
 
 const gcpBoshDirectorEphemeralIPOps = `
@@ -33,6 +36,14 @@ const awsEncryptDiskOps = `---
     kms_key_arn: ((kms_key_arn))
 `
 
+const awsEncryptDiskDefaultKeyOps = `---
+- type: replace
+  path: /disk_pools/name=disks/cloud_properties?
+  value:
+    type: gp2
+    encrypted: true
+`
+
 const azureSSHStaticIP = `
 - type: replace
   path: /cloud_provider/ssh_tunnel/host
@@ -40,12 +51,13 @@ const azureSSHStaticIP = `
 `
 
 type Executor struct {
-	command       command
-	tempDir       func(string, string) (string, error)
-	readFile      func(string) ([]byte, error)
-	unmarshalJSON func([]byte, interface{}) error
-	marshalJSON   func(interface{}) ([]byte, error)
-	writeFile     func(string, []byte, os.FileMode) error
+	command        command
+	tempDir        func(string, string) (string, error)
+	readFile       func(string) ([]byte, error)
+	unmarshalJSON  func([]byte, interface{}) error
+	marshalJSON    func(interface{}) ([]byte, error)
+	writeFile      func(string, []byte, os.FileMode) error
+	deploymentDirs DeploymentDirs
 }
 
 type InterpolateInput struct {
@@ -54,17 +66,30 @@ type InterpolateInput struct {
 	JumpboxDeploymentVars string
 	BOSHState             map[string]interface{}
 	Variables             string
-	OpsFile               string
+	// OpsFiles, VarsFiles and Vars are the contents of the --ops-file and
+	// --vars-file flags and the values of --var, applied in order after the
+	// ops files of bbl.
+	OpsFiles  []string
+	VarsFiles []string
+	Vars      []string
+	// JumpboxOpsFiles are the contents of the --jumpbox-ops-file flags,
+	// applied in order after the cpi ops file of the jumpbox.
+	JumpboxOpsFiles []string
+	// DefaultKMSKey encrypts the director disks with the AWS managed key
+	// when the environment has no KMS key of its own.
+	DefaultKMSKey bool
 }
 
 type InterpolateOutput struct {
-	Variables string
-	Manifest  string
+	Variables        string
+	Manifest         string
+	DeploymentSource storage.DeploymentSource
 }
 
 type JumpboxInterpolateOutput struct {
-	Variables string
-	Manifest  string
+	Variables        string
+	Manifest         string
+	DeploymentSource storage.DeploymentSource
 }
 
 type CreateEnvInput struct {
@@ -91,14 +116,16 @@ const VERSION_DEV_BUILD = "[DEV BUILD]"
 
 func NewExecutor(cmd command, tempDir func(string, string) (string, error), readFile func(string) ([]byte, error),
 	unmarshalJSON func([]byte, interface{}) error,
-	marshalJSON func(interface{}) ([]byte, error), writeFile func(string, []byte, os.FileMode) error) Executor {
+	marshalJSON func(interface{}) ([]byte, error), writeFile func(string, []byte, os.FileMode) error,
+	deploymentDirs DeploymentDirs) Executor {
 	return Executor{
-		command:       cmd,
-		tempDir:       tempDir,
-		readFile:      readFile,
-		unmarshalJSON: unmarshalJSON,
-		marshalJSON:   marshalJSON,
-		writeFile:     writeFile,
+		command:        cmd,
+		tempDir:        tempDir,
+		readFile:       readFile,
+		unmarshalJSON:  unmarshalJSON,
+		marshalJSON:    marshalJSON,
+		writeFile:      writeFile,
+		deploymentDirs: deploymentDirs,
 	}
 }
 
@@ -108,18 +135,26 @@ func (e Executor) JumpboxInterpolate(interpolateInput InterpolateInput) (Jumpbox
 		return JumpboxInterpolateOutput{}, fmt.Errorf("create temp dir: %s", err)
 	}
 
-	var jumpboxSetupFiles = map[string][]byte{
-		"jumpbox-deployment-vars.yml": []byte(interpolateInput.JumpboxDeploymentVars),
-		"jumpbox.yml":                 MustAsset("vendor/github.com/cppforlife/jumpbox-deployment/jumpbox.yml"),
-		"cpi.yml":                     MustAsset(fmt.Sprintf("vendor/github.com/cppforlife/jumpbox-deployment/%s/cpi.yml", interpolateInput.IAAS)),
+	jumpboxSetupFiles, err := e.deploymentFiles(e.deploymentDirs.Jumpbox, jumpboxDeploymentAssets, "jumpbox-deployment", map[string]string{
+		"jumpbox.yml": "jumpbox.yml",
+		"cpi.yml":     fmt.Sprintf("%s/cpi.yml", interpolateInput.IAAS),
+	})
+	if err != nil {
+		return JumpboxInterpolateOutput{}, err
 	}
 
+	jumpboxSetupFiles["jumpbox-deployment-vars.yml"] = []byte(interpolateInput.JumpboxDeploymentVars)
+
 	if interpolateInput.Variables != "" {
 		jumpboxSetupFiles["variables.yml"] = []byte(interpolateInput.Variables)
 	}
 
+	for i, opsFile := range interpolateInput.JumpboxOpsFiles {
+		jumpboxSetupFiles[userOpsFileName(i)] = []byte(opsFile)
+	}
+
 	for path, contents := range jumpboxSetupFiles {
-		err = e.writeFile(filepath.Join(tempDir, path), contents, os.ModePerm)
+		err = e.writeFile(filepath.Join(tempDir, path), contents, 0600)
 		if err != nil {
 			//not tested
 			return JumpboxInterpolateOutput{}, fmt.Errorf("write file: %s", err)
@@ -134,6 +169,10 @@ func (e Executor) JumpboxInterpolate(interpolateInput InterpolateInput) (Jumpbox
 		"-o", filepath.Join(tempDir, "cpi.yml"),
 	}
 
+	for i := range interpolateInput.JumpboxOpsFiles {
+		args = append(args, "-o", filepath.Join(tempDir, userOpsFileName(i)))
+	}
+
 	buffer := bytes.NewBuffer([]byte{})
 	err = e.command.Run(buffer, tempDir, args)
 	if err != nil {
@@ -146,8 +185,9 @@ func (e Executor) JumpboxInterpolate(interpolateInput InterpolateInput) (Jumpbox
 	}
 
 	return JumpboxInterpolateOutput{
-		Variables: string(varsStore),
-		Manifest:  buffer.String(),
+		Variables:        string(varsStore),
+		Manifest:         buffer.String(),
+		DeploymentSource: deploymentSource(e.deploymentDirs.Jumpbox, "jumpbox-deployment"),
 	}, nil
 }
 
@@ -158,30 +198,49 @@ func (e Executor) DirectorInterpolate(interpolateInput InterpolateInput) (Interp
 		return InterpolateOutput{}, err
 	}
 
-	var directorSetupFiles = map[string][]byte{
-		"deployment-vars.yml":                    []byte(interpolateInput.DeploymentVars),
-		"user-ops-file.yml":                      []byte(interpolateInput.OpsFile),
-		"bosh.yml":                               MustAsset("vendor/github.com/cloudfoundry/bosh-deployment/bosh.yml"),
-		"cpi.yml":                                MustAsset(fmt.Sprintf("vendor/github.com/cloudfoundry/bosh-deployment/%s/cpi.yml", interpolateInput.IAAS)),
-		"iam-instance-profile.yml":               MustAsset("vendor/github.com/cloudfoundry/bosh-deployment/aws/iam-instance-profile.yml"),
-		"gcp-bosh-director-ephemeral-ip-ops.yml": []byte(gcpBoshDirectorEphemeralIPOps),
-		"aws-bosh-director-ephemeral-ip-ops.yml": []byte(awsBoshDirectorEphemeralIPOps),
-		"aws-bosh-director-encrypt-disk-ops.yml": []byte(awsEncryptDiskOps),
-		"azure-ssh-static-ip.yml":                []byte(azureSSHStaticIP),
-		"jumpbox-user.yml":                       MustAsset("vendor/github.com/cloudfoundry/bosh-deployment/jumpbox-user.yml"),
-		"gcp-external-ip-not-recommended.yml":    MustAsset("vendor/github.com/cloudfoundry/bosh-deployment/external-ip-not-recommended.yml"),
-		"azure-external-ip-not-recommended.yml":  MustAsset("vendor/github.com/cloudfoundry/bosh-deployment/external-ip-not-recommended.yml"),
-		"aws-external-ip-not-recommended.yml":    MustAsset("vendor/github.com/cloudfoundry/bosh-deployment/external-ip-with-registry-not-recommended.yml"),
-		"uaa.yml":     MustAsset("vendor/github.com/cloudfoundry/bosh-deployment/uaa.yml"),
-		"credhub.yml": MustAsset("vendor/github.com/cloudfoundry/bosh-deployment/credhub.yml"),
+	boshDeploymentFiles := map[string]string{
+		"bosh.yml":         "bosh.yml",
+		"cpi.yml":          fmt.Sprintf("%s/cpi.yml", interpolateInput.IAAS),
+		"jumpbox-user.yml": "jumpbox-user.yml",
+		"uaa.yml":          "uaa.yml",
+		"credhub.yml":      "credhub.yml",
 	}
 
+	switch interpolateInput.IAAS {
+	case "aws":
+		boshDeploymentFiles["iam-instance-profile.yml"] = "aws/iam-instance-profile.yml"
+		boshDeploymentFiles["aws-external-ip-not-recommended.yml"] = "external-ip-with-registry-not-recommended.yml"
+	case "gcp":
+		boshDeploymentFiles["gcp-external-ip-not-recommended.yml"] = "external-ip-not-recommended.yml"
+	case "azure":
+		boshDeploymentFiles["azure-external-ip-not-recommended.yml"] = "external-ip-not-recommended.yml"
+	}
+
+	directorSetupFiles, err := e.deploymentFiles(e.deploymentDirs.BOSH, boshDeploymentAssets, "bosh-deployment", boshDeploymentFiles)
+	if err != nil {
+		return InterpolateOutput{}, err
+	}
+
+	directorSetupFiles["deployment-vars.yml"] = []byte(interpolateInput.DeploymentVars)
+	directorSetupFiles["gcp-bosh-director-ephemeral-ip-ops.yml"] = []byte(gcpBoshDirectorEphemeralIPOps)
+	directorSetupFiles["aws-bosh-director-ephemeral-ip-ops.yml"] = []byte(awsBoshDirectorEphemeralIPOps)
+	directorSetupFiles["aws-bosh-director-encrypt-disk-ops.yml"] = []byte(awsEncryptDiskOps)
+	directorSetupFiles["azure-ssh-static-ip.yml"] = []byte(azureSSHStaticIP)
+
 	if interpolateInput.Variables != "" {
 		directorSetupFiles["variables.yml"] = []byte(interpolateInput.Variables)
 	}
 
+	if interpolateInput.DefaultKMSKey {
+		directorSetupFiles["aws-bosh-director-encrypt-disk-ops.yml"] = []byte(awsEncryptDiskDefaultKeyOps)
+	}
+
+	for i, opsFile := range interpolateInput.OpsFiles {
+		directorSetupFiles[userOpsFileName(i)] = []byte(opsFile)
+	}
+
 	for path, contents := range directorSetupFiles {
-		err = e.writeFile(filepath.Join(tempDir, path), contents, os.ModePerm)
+		err = e.writeFile(filepath.Join(tempDir, path), contents, 0600)
 		if err != nil {
 			//not tested
 			return InterpolateOutput{}, err
@@ -194,9 +253,18 @@ func (e Executor) DirectorInterpolate(interpolateInput InterpolateInput) (Interp
 		"--var-errs-unused",
 		"--vars-store", filepath.Join(tempDir, "variables.yml"),
 		"--vars-file", filepath.Join(tempDir, "deployment-vars.yml"),
-		"-o", filepath.Join(tempDir, "cpi.yml"),
 	}
 
+	for i := range interpolateInput.VarsFiles {
+		args = append(args, "--vars-file", filepath.Join(tempDir, userVarsFileName(i)))
+	}
+
+	if len(interpolateInput.Vars) > 0 {
+		args = append(args, "--vars-file", filepath.Join(tempDir, "user-vars.yml"))
+	}
+
+	args = append(args, "-o", filepath.Join(tempDir, "cpi.yml"))
+
 	if interpolateInput.JumpboxDeploymentVars == "" {
 		args = append(args,
 			"-o", filepath.Join(tempDir, "jumpbox-user.yml"),
@@ -231,45 +299,142 @@ func (e Executor) DirectorInterpolate(interpolateInput InterpolateInput) (Interp
 		)
 	}
 
-	buffer := bytes.NewBuffer([]byte{})
-	err = e.command.Run(buffer, tempDir, args)
-	if err != nil {
-		return InterpolateOutput{}, err
+	for i := range interpolateInput.OpsFiles {
+		args = append(args, "-o", filepath.Join(tempDir, userOpsFileName(i)))
 	}
 
-	if interpolateInput.OpsFile != "" {
-		err = e.writeFile(filepath.Join(tempDir, "bosh.yml"), buffer.Bytes(), os.ModePerm)
+	// Vars files shared between deployments usually hold variables that the
+	// director does not use, so only the vars the manifest and ops files
+	// refer to are passed on and --var-errs-unused keeps checking the rest.
+	referenced := referencedVars(directorSetupFiles, args)
+
+	for i, varsFile := range interpolateInput.VarsFiles {
+		contents, err := usedVars([]byte(varsFile), referenced)
+		if err != nil {
+			return InterpolateOutput{}, fmt.Errorf("invalid vars file %d: %s", i+1, err)
+		}
+
+		err = e.writeFile(filepath.Join(tempDir, userVarsFileName(i)), contents, 0600)
 		if err != nil {
 			//not tested
 			return InterpolateOutput{}, err
 		}
+	}
 
-		args = []string{
-			"interpolate", filepath.Join(tempDir, "bosh.yml"),
-			"--var-errs",
-			"--vars-store", filepath.Join(tempDir, "variables.yml"),
-			"--vars-file", filepath.Join(tempDir, "deployment-vars.yml"),
-			"-o", filepath.Join(tempDir, "user-ops-file.yml"),
+	// The vars are written to a file rather than passed with --var, so
+	// that their values do not show up in the process list.
+	if len(interpolateInput.Vars) > 0 {
+		userVars, err := userVarsFile(interpolateInput.Vars, referenced)
+		if err != nil {
+			return InterpolateOutput{}, err
 		}
 
-		buffer = bytes.NewBuffer([]byte{})
-		err = e.command.Run(buffer, tempDir, args)
+		err = e.writeFile(filepath.Join(tempDir, "user-vars.yml"), userVars, 0600)
 		if err != nil {
+			//not tested
 			return InterpolateOutput{}, err
 		}
 	}
 
+	buffer := bytes.NewBuffer([]byte{})
+	err = e.command.Run(buffer, tempDir, args)
+	if err != nil {
+		return InterpolateOutput{}, err
+	}
+
 	varsStore, err := e.readFile(filepath.Join(tempDir, "variables.yml"))
 	if err != nil {
 		return InterpolateOutput{}, err
 	}
 
 	return InterpolateOutput{
-		Variables: string(varsStore),
-		Manifest:  buffer.String(),
+		Variables:        string(varsStore),
+		Manifest:         buffer.String(),
+		DeploymentSource: deploymentSource(e.deploymentDirs.BOSH, "bosh-deployment"),
 	}, nil
 }
 
+func userOpsFileName(index int) string {
+	return fmt.Sprintf("user-ops-file-%d.yml", index)
+}
+
+func userVarsFileName(index int) string {
+	return fmt.Sprintf("user-vars-file-%d.yml", index)
+}
+
+// userVarsFile turns the referenced name=value vars into a vars file. It is
+// passed after the user vars files, so the vars take precedence over them as
+// --var would.
+func userVarsFile(vars []string, referenced map[string]bool) ([]byte, error) {
+	values := map[string]string{}
+	for _, v := range vars {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid var %q, expected name=value", v)
+		}
+
+		if referenced[parts[0]] {
+			values[parts[0]] = parts[1]
+		}
+	}
+
+	return yaml.Marshal(values)
+}
+
+var varReferencePattern = regexp.MustCompile(`\(\((!?[-/\.\w\pL]+)\)\)`)
+
+// referencedVars returns the names of the variables referred to in the
+// manifest and the ops files args applies to it.
+func referencedVars(files map[string][]byte, args []string) map[string]bool {
+	templates := [][]byte{files["bosh.yml"]}
+	for i, arg := range args {
+		if arg == "-o" && i+1 < len(args) {
+			templates = append(templates, files[filepath.Base(args[i+1])])
+		}
+	}
+
+	names := map[string]bool{}
+	for _, template := range templates {
+		for _, match := range varReferencePattern.FindAllSubmatch(template, -1) {
+			name := strings.TrimPrefix(string(match[1]), "!")
+			names[strings.SplitN(name, ".", 2)[0]] = true
+		}
+	}
+
+	return names
+}
+
+// usedVars returns the vars file with only the referenced variables.
+func usedVars(varsFile []byte, referenced map[string]bool) ([]byte, error) {
+	var vars map[string]interface{}
+	err := yaml.Unmarshal(varsFile, &vars)
+	if err != nil {
+		return nil, err
+	}
+
+	used := map[string]interface{}{}
+	for name, value := range vars {
+		if referenced[name] {
+			used[name] = value
+		}
+	}
+
+	return yaml.Marshal(used)
+}
+
+// DNSRuntimeConfig returns the BOSH DNS runtime config of the bosh-deployment
+// the director is deployed from.
+func (e Executor) DNSRuntimeConfig() ([]byte, error) {
+	files, err := e.deploymentFiles(e.deploymentDirs.BOSH, boshDeploymentAssets, "bosh-deployment", map[string]string{
+		"dns.yml": "runtime-configs/dns.yml",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files["dns.yml"], nil
+}
+
 func (e Executor) CreateEnv(createEnvInput CreateEnvInput) (CreateEnvOutput, error) {
 	tempDir, err := e.writePreviousFiles(createEnvInput.State, createEnvInput.Variables, createEnvInput.Manifest)
 	if err != nil {
@@ -395,19 +560,19 @@ func (e Executor) writePreviousFiles(state map[string]interface{}, variables, ma
 		if err != nil {
 			return "", err
 		}
-		err = e.writeFile(statePath, boshStateContents, os.ModePerm)
+		err = e.writeFile(statePath, boshStateContents, 0600)
 		if err != nil {
 			return "", err
 		}
 	}
 
-	err = e.writeFile(variablesPath, []byte(variables), os.ModePerm)
+	err = e.writeFile(variablesPath, []byte(variables), 0600)
 	if err != nil {
 		// not tested
 		return "", err
 	}
 
-	err = e.writeFile(boshManifestPath, []byte(manifest), os.ModePerm)
+	err = e.writeFile(boshManifestPath, []byte(manifest), 0600)
 	if err != nil {
 		// not tested
 		return "", err
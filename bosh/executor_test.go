@@ -7,9 +7,11 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	"github.com/cloudfoundry/bosh-bootloader/bosh"
 	"github.com/cloudfoundry/bosh-bootloader/fakes"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
 	"github.com/pivotal-cf-experimental/gomegamatchers"
 
 	. "github.com/onsi/ginkgo"
@@ -48,10 +50,10 @@ var _ = Describe("Executor", func() {
 					"key": "value",
 				},
 				Variables: "key: value",
-				OpsFile:   "some-ops-file",
+				OpsFiles:  []string{"some-ops-file"},
 			}
 
-			executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile)
+			executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile, bosh.DeploymentDirs{})
 		})
 
 		AfterEach(func() {
@@ -75,7 +77,7 @@ var _ = Describe("Executor", func() {
 				interpolateOutput, err := executor.DirectorInterpolate(azureInterpolateInput)
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(cmd.RunCallCount()).To(Equal(2))
+				Expect(cmd.RunCallCount()).To(Equal(1))
 				Expect(tempDirCallCount).To(Equal(1))
 
 				expectedArgs := append([]string{
@@ -88,22 +90,12 @@ var _ = Describe("Executor", func() {
 					"-o", fmt.Sprintf("%s/jumpbox-user.yml", tempDir),
 					"-o", fmt.Sprintf("%s/azure-external-ip-not-recommended.yml", tempDir),
 					"-o", fmt.Sprintf("%s/azure-ssh-static-ip.yml", tempDir),
+					"-o", fmt.Sprintf("%s/user-ops-file-0.yml", tempDir),
 				})
 
 				_, _, args := cmd.RunArgsForCall(0)
 				Expect(args).To(Equal(expectedArgs))
 
-				expectedArgs = append([]string{
-					"interpolate", fmt.Sprintf("%s/bosh.yml", tempDir),
-					"--var-errs",
-					"--vars-store", fmt.Sprintf("%s/variables.yml", tempDir),
-					"--vars-file", fmt.Sprintf("%s/deployment-vars.yml", tempDir),
-					"-o", fmt.Sprintf("%s/user-ops-file.yml", tempDir),
-				})
-
-				_, _, args = cmd.RunArgsForCall(1)
-				Expect(args).To(Equal(expectedArgs))
-
 				Expect(interpolateOutput.Manifest).To(Equal("some-manifest"))
 				Expect(interpolateOutput.Variables).To(Equal("key: value"))
 			})
@@ -126,7 +118,7 @@ var _ = Describe("Executor", func() {
 				interpolateOutput, err := executor.DirectorInterpolate(awsInterpolateInput)
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(cmd.RunCallCount()).To(Equal(2))
+				Expect(cmd.RunCallCount()).To(Equal(1))
 				Expect(tempDirCallCount).To(Equal(1))
 
 				expectedArgs := append([]string{
@@ -140,30 +132,41 @@ var _ = Describe("Executor", func() {
 					"-o", fmt.Sprintf("%s/aws-external-ip-not-recommended.yml", tempDir),
 					"-o", fmt.Sprintf("%s/iam-instance-profile.yml", tempDir),
 					"-o", fmt.Sprintf("%s/aws-bosh-director-encrypt-disk-ops.yml", tempDir),
+					"-o", fmt.Sprintf("%s/user-ops-file-0.yml", tempDir),
 				})
 
 				_, _, args := cmd.RunArgsForCall(0)
 				Expect(args).To(Equal(expectedArgs))
 
-				expectedArgs = append([]string{
-					"interpolate", fmt.Sprintf("%s/bosh.yml", tempDir),
-					"--var-errs",
-					"--vars-store", fmt.Sprintf("%s/variables.yml", tempDir),
-					"--vars-file", fmt.Sprintf("%s/deployment-vars.yml", tempDir),
-					"-o", fmt.Sprintf("%s/user-ops-file.yml", tempDir),
-				})
-
-				_, _, args = cmd.RunArgsForCall(1)
-				Expect(args).To(Equal(expectedArgs))
-
 				Expect(interpolateOutput.Manifest).To(Equal("some-manifest"))
 				Expect(interpolateOutput.Variables).To(Equal("key: value"))
 			})
 
+			It("encrypts the director disks with the kms key of the environment", func() {
+				_, err := executor.DirectorInterpolate(awsInterpolateInput)
+				Expect(err).NotTo(HaveOccurred())
+
+				ops, err := ioutil.ReadFile(filepath.Join(tempDir, "aws-bosh-director-encrypt-disk-ops.yml"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(ops)).To(ContainSubstring("kms_key_arn: ((kms_key_arn))"))
+			})
+
+			It("encrypts the director disks with the aws managed key when there is no kms key", func() {
+				awsInterpolateInput.DefaultKMSKey = true
+
+				_, err := executor.DirectorInterpolate(awsInterpolateInput)
+				Expect(err).NotTo(HaveOccurred())
+
+				ops, err := ioutil.ReadFile(filepath.Join(tempDir, "aws-bosh-director-encrypt-disk-ops.yml"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(ops)).To(ContainSubstring("encrypted: true"))
+				Expect(string(ops)).NotTo(ContainSubstring("kms_key_arn"))
+			})
+
 			Context("when there are jumpbox deployment vars", func() {
 				It("interpolates the jumpbox and bosh manifests", func() {
 					awsInterpolateInput.JumpboxDeploymentVars = "internal_cidr: 10.0.0.0/24"
-					awsInterpolateInput.OpsFile = ""
+					awsInterpolateInput.OpsFiles = nil
 
 					jumpboxInterpolateOutput, err := executor.JumpboxInterpolate(awsInterpolateInput)
 					Expect(err).NotTo(HaveOccurred())
@@ -231,7 +234,7 @@ var _ = Describe("Executor", func() {
 				interpolateOutput, err := executor.DirectorInterpolate(gcpInterpolateInput)
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(cmd.RunCallCount()).To(Equal(2))
+				Expect(cmd.RunCallCount()).To(Equal(1))
 				Expect(tempDirCallCount).To(Equal(1))
 
 				expectedArgs := append([]string{
@@ -242,21 +245,13 @@ var _ = Describe("Executor", func() {
 					"--vars-file", fmt.Sprintf("%s/deployment-vars.yml", tempDir),
 					"-o", fmt.Sprintf("%s/cpi.yml", tempDir),
 					"-o", fmt.Sprintf("%s/jumpbox-user.yml", tempDir),
-					"-o", fmt.Sprintf("%s/gcp-external-ip-not-recommended.yml", tempDir)})
+					"-o", fmt.Sprintf("%s/gcp-external-ip-not-recommended.yml", tempDir),
+					"-o", fmt.Sprintf("%s/user-ops-file-0.yml", tempDir),
+				})
 
 				_, _, args := cmd.RunArgsForCall(0)
 				Expect(args).To(Equal(expectedArgs))
 
-				expectedArgs = append([]string{
-					"interpolate", fmt.Sprintf("%s/bosh.yml", tempDir),
-					"--var-errs",
-					"--vars-store", fmt.Sprintf("%s/variables.yml", tempDir),
-					"--vars-file", fmt.Sprintf("%s/deployment-vars.yml", tempDir),
-					"-o", fmt.Sprintf("%s/user-ops-file.yml", tempDir)})
-
-				_, _, args = cmd.RunArgsForCall(1)
-				Expect(args).To(Equal(expectedArgs))
-
 				Expect(interpolateOutput.Manifest).To(Equal("some-manifest"))
 				Expect(interpolateOutput.Variables).To(Equal("key: value"))
 			})
@@ -264,7 +259,7 @@ var _ = Describe("Executor", func() {
 			Context("when there are jumpbox deployment vars", func() {
 				It("interpolates the jumpbox and bosh manifests", func() {
 					gcpInterpolateInput.JumpboxDeploymentVars = "internal_cidr: 10.0.0.0/24"
-					gcpInterpolateInput.OpsFile = ""
+					gcpInterpolateInput.OpsFiles = nil
 
 					jumpboxInterpolateOutput, err := executor.JumpboxInterpolate(gcpInterpolateInput)
 					Expect(err).NotTo(HaveOccurred())
@@ -312,56 +307,41 @@ var _ = Describe("Executor", func() {
 				})
 			})
 
-			Context("when a user opsfile is provided", func() {
-				It("re-interpolates the bosh manifest", func() {
-					opsfile := `
----
-- type: replace
-path: /networks/name=default/subnets/0/cloud_properties/tags/-
-value: sabeti-bosh-isolation
-`
-					gcpInterpolateInput.OpsFile = opsfile
-
-					manifest := `
----
-networks
-- name: default
-  cloud_properties:
-    tags:
-      - some-tag
-`
-					manifestWithUserOpsFile := `
----
-networks
-- name: default
-  cloud_properties:
-    tags:
-      - some-tag
-      - sabeti-bosh-isolation
-`
-
-					writtenManifest := []byte{}
-					cmd.RunStub = func(stdout io.Writer, workingDirectory string, args []string) error {
-						for _, arg := range args {
-							if arg == fmt.Sprintf("%s/user-ops-file.yml", tempDir) {
-								var err error
-								writtenManifest, err = ioutil.ReadFile(fmt.Sprintf("%s/bosh.yml", tempDir))
-								if err != nil {
-									return err
-								}
-								stdout.Write([]byte(manifestWithUserOpsFile))
-								return nil
-							}
-						}
-						stdout.Write([]byte(manifest))
-						return nil
-					}
+			Context("when jumpbox ops files are provided", func() {
+				It("applies them in order after the cpi ops file", func() {
+					gcpInterpolateInput.JumpboxDeploymentVars = "internal_cidr: 10.0.0.0/24"
+					gcpInterpolateInput.JumpboxOpsFiles = []string{"some-jumpbox-ops-file", "some-other-jumpbox-ops-file"}
 
-					interpolateOutput, err := executor.DirectorInterpolate(gcpInterpolateInput)
+					_, err := executor.JumpboxInterpolate(gcpInterpolateInput)
 					Expect(err).NotTo(HaveOccurred())
 
-					Expect(cmd.RunCallCount()).To(Equal(2))
-					Expect(tempDirCallCount).To(Equal(1))
+					_, _, args := cmd.RunArgsForCall(0)
+					Expect(args).To(Equal([]string{
+						"interpolate", fmt.Sprintf("%s/jumpbox.yml", tempDir),
+						"--var-errs",
+						"--vars-store", fmt.Sprintf("%s/variables.yml", tempDir),
+						"--vars-file", fmt.Sprintf("%s/jumpbox-deployment-vars.yml", tempDir),
+						"-o", fmt.Sprintf("%s/cpi.yml", tempDir),
+						"-o", fmt.Sprintf("%s/user-ops-file-0.yml", tempDir),
+						"-o", fmt.Sprintf("%s/user-ops-file-1.yml", tempDir),
+					}))
+
+					opsFile, err := ioutil.ReadFile(filepath.Join(tempDir, "user-ops-file-1.yml"))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(opsFile)).To(Equal("some-other-jumpbox-ops-file"))
+				})
+			})
+
+			Context("when user ops files and vars are provided", func() {
+				It("applies them in order after the ops files of bbl", func() {
+					gcpInterpolateInput.OpsFiles = []string{"some-ops-file", "some-other-ops-file"}
+					gcpInterpolateInput.VarsFiles = []string{"some-vars-file: some-value", "some-other-vars-file: some-value"}
+					gcpInterpolateInput.Vars = []string{"some-var=some-value"}
+
+					_, err := executor.DirectorInterpolate(gcpInterpolateInput)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(cmd.RunCallCount()).To(Equal(1))
 
 					expectedArgs := append([]string{
 						"interpolate", fmt.Sprintf("%s/bosh.yml", tempDir),
@@ -369,31 +349,169 @@ networks
 						"--var-errs-unused",
 						"--vars-store", fmt.Sprintf("%s/variables.yml", tempDir),
 						"--vars-file", fmt.Sprintf("%s/deployment-vars.yml", tempDir),
+						"--vars-file", fmt.Sprintf("%s/user-vars-file-0.yml", tempDir),
+						"--vars-file", fmt.Sprintf("%s/user-vars-file-1.yml", tempDir),
+						"--vars-file", fmt.Sprintf("%s/user-vars.yml", tempDir),
 						"-o", fmt.Sprintf("%s/cpi.yml", tempDir),
 						"-o", fmt.Sprintf("%s/jumpbox-user.yml", tempDir),
-						"-o", fmt.Sprintf("%s/gcp-external-ip-not-recommended.yml", tempDir)})
+						"-o", fmt.Sprintf("%s/gcp-external-ip-not-recommended.yml", tempDir),
+						"-o", fmt.Sprintf("%s/user-ops-file-0.yml", tempDir),
+						"-o", fmt.Sprintf("%s/user-ops-file-1.yml", tempDir),
+					})
 
 					_, _, args := cmd.RunArgsForCall(0)
 					Expect(args).To(Equal(expectedArgs))
 
-					expectedArgsWithUserOpsfile := append([]string{
-						"interpolate", fmt.Sprintf("%s/bosh.yml", tempDir),
-						"--var-errs",
-						"--vars-store", fmt.Sprintf("%s/variables.yml", tempDir),
-						"--vars-file", fmt.Sprintf("%s/deployment-vars.yml", tempDir),
-						"-o", fmt.Sprintf("%s/user-ops-file.yml", tempDir)})
+					for path, contents := range map[string]string{
+						"user-ops-file-0.yml":  "some-ops-file",
+						"user-ops-file-1.yml":  "some-other-ops-file",
+						"user-vars-file-0.yml": "{}\n",
+						"user-vars-file-1.yml": "{}\n",
+						"user-vars.yml":        "{}\n",
+					} {
+						written, err := ioutil.ReadFile(filepath.Join(tempDir, path))
+						Expect(err).NotTo(HaveOccurred())
+						Expect(string(written)).To(Equal(contents))
+
+						info, err := os.Stat(filepath.Join(tempDir, path))
+						Expect(err).NotTo(HaveOccurred())
+						Expect(info.Mode()).To(Equal(os.FileMode(0600)))
+					}
+				})
 
-					_, _, args = cmd.RunArgsForCall(1)
-					Expect(args).To(Equal(expectedArgsWithUserOpsfile))
+				It("passes on only the vars the manifest and ops files refer to", func() {
+					gcpInterpolateInput.OpsFiles = []string{"- type: replace\n  path: /name\n  value: ((some-name))\n", "- type: replace\n  path: /tags?\n  value: ((!some-tags.director))\n"}
+					gcpInterpolateInput.VarsFiles = []string{"some-name: some-value\nsome-other-deployment-var: some-value\n", "some-tags:\n  director: some-tag\n"}
+					gcpInterpolateInput.Vars = []string{"director_name=some-director", "some-unused-var=some-value"}
+
+					_, err := executor.DirectorInterpolate(gcpInterpolateInput)
+					Expect(err).NotTo(HaveOccurred())
+
+					_, _, args := cmd.RunArgsForCall(0)
+					Expect(args).To(ContainElement("--var-errs-unused"))
+
+					for path, contents := range map[string]string{
+						"user-vars-file-0.yml": "some-name: some-value\n",
+						"user-vars-file-1.yml": "some-tags:\n  director: some-tag\n",
+						"user-vars.yml":        "director_name: some-director\n",
+					} {
+						written, err := ioutil.ReadFile(filepath.Join(tempDir, path))
+						Expect(err).NotTo(HaveOccurred())
+						Expect(string(written)).To(Equal(contents))
+					}
+				})
+
+				It("returns an error when a vars file is not a map of variables", func() {
+					gcpInterpolateInput.VarsFiles = []string{"some-vars-file"}
+
+					_, err := executor.DirectorInterpolate(gcpInterpolateInput)
+					Expect(err).To(MatchError(ContainSubstring("invalid vars file 1: yaml: unmarshal errors")))
+					Expect(cmd.RunCallCount()).To(Equal(0))
+				})
+
+				It("returns an error when a var is not of the form name=value", func() {
+					gcpInterpolateInput.Vars = []string{"some-var"}
+
+					_, err := executor.DirectorInterpolate(gcpInterpolateInput)
+					Expect(err).To(MatchError(`invalid var "some-var", expected name=value`))
+					Expect(cmd.RunCallCount()).To(Equal(0))
+				})
+			})
+		})
+
+		Context("deployment sources", func() {
+			var checkoutDir string
+
+			writeCheckout := func(files ...string) {
+				for _, file := range files {
+					path := filepath.Join(checkoutDir, file)
+					Expect(os.MkdirAll(filepath.Dir(path), os.ModePerm)).To(Succeed())
+					Expect(ioutil.WriteFile(path, []byte("checkout "+file), os.ModePerm)).To(Succeed())
+				}
+			}
 
-					opsFileContents, err := ioutil.ReadFile(fmt.Sprintf("%s/user-ops-file.yml", tempDir))
+			BeforeEach(func() {
+				var err error
+				checkoutDir, err = ioutil.TempDir("", "")
+				Expect(err).NotTo(HaveOccurred())
+
+				bosh.SetGitRevParse(func(dir string) (string, error) {
+					return "some-sha", nil
+				})
+			})
+
+			AfterEach(func() {
+				bosh.ResetGitRevParse()
+			})
+
+			It("records the bosh-deployment compiled into bbl", func() {
+				interpolateOutput, err := executor.DirectorInterpolate(bosh.InterpolateInput{IAAS: "gcp", Variables: "key: value"})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(interpolateOutput.DeploymentSource).To(Equal(storage.DeploymentSource{
+					Source: "cloudfoundry/bosh-deployment",
+					SHA:    "b1bd02133d45023c10ecab8d13fc3b9d9cc1b187",
+				}))
+			})
+
+			It("reads the director files from a bosh-deployment checkout", func() {
+				writeCheckout("bosh.yml", "gcp/cpi.yml", "jumpbox-user.yml", "uaa.yml", "credhub.yml", "external-ip-not-recommended.yml")
+				executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile,
+					bosh.DeploymentDirs{BOSH: checkoutDir})
+
+				interpolateOutput, err := executor.DirectorInterpolate(bosh.InterpolateInput{IAAS: "gcp", Variables: "key: value"})
+				Expect(err).NotTo(HaveOccurred())
+
+				for file, contents := range map[string]string{
+					"bosh.yml":                            "checkout bosh.yml",
+					"cpi.yml":                             "checkout gcp/cpi.yml",
+					"gcp-external-ip-not-recommended.yml": "checkout external-ip-not-recommended.yml",
+				} {
+					written, err := ioutil.ReadFile(filepath.Join(tempDir, file))
 					Expect(err).NotTo(HaveOccurred())
-					Expect(string(opsFileContents)).To(Equal(opsfile))
-					Expect(string(writtenManifest)).To(Equal(manifest))
+					Expect(string(written)).To(Equal(contents))
+				}
+
+				Expect(interpolateOutput.DeploymentSource).To(Equal(storage.DeploymentSource{Source: checkoutDir, SHA: "some-sha"}))
+			})
 
-					Expect(interpolateOutput.Manifest).To(Equal(manifestWithUserOpsFile))
-					Expect(interpolateOutput.Variables).To(Equal("key: value"))
+			It("reads the jumpbox files from a jumpbox-deployment checkout", func() {
+				writeCheckout("jumpbox.yml", "gcp/cpi.yml")
+				executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile,
+					bosh.DeploymentDirs{Jumpbox: checkoutDir})
+
+				interpolateOutput, err := executor.JumpboxInterpolate(bosh.InterpolateInput{IAAS: "gcp", Variables: "key: value"})
+				Expect(err).NotTo(HaveOccurred())
+
+				written, err := ioutil.ReadFile(filepath.Join(tempDir, "jumpbox.yml"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(written)).To(Equal("checkout jumpbox.yml"))
+
+				Expect(interpolateOutput.DeploymentSource).To(Equal(storage.DeploymentSource{Source: checkoutDir, SHA: "some-sha"}))
+			})
+
+			It("leaves the sha empty when the checkout is not a git repository", func() {
+				writeCheckout("jumpbox.yml", "aws/cpi.yml")
+				bosh.SetGitRevParse(func(dir string) (string, error) {
+					return "", errors.New("not a git repository")
 				})
+				executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile,
+					bosh.DeploymentDirs{Jumpbox: checkoutDir})
+
+				interpolateOutput, err := executor.JumpboxInterpolate(bosh.InterpolateInput{IAAS: "aws", Variables: "key: value"})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(interpolateOutput.DeploymentSource).To(Equal(storage.DeploymentSource{Source: checkoutDir}))
+			})
+
+			It("returns an error listing the files missing from a checkout", func() {
+				writeCheckout("bosh.yml", "jumpbox-user.yml", "uaa.yml")
+				executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile,
+					bosh.DeploymentDirs{BOSH: checkoutDir})
+
+				_, err := executor.DirectorInterpolate(bosh.InterpolateInput{IAAS: "aws", Variables: "key: value"})
+				Expect(err).To(MatchError(fmt.Sprintf("bosh-deployment checkout %s is missing aws/cpi.yml, aws/iam-instance-profile.yml, credhub.yml, external-ip-with-registry-not-recommended.yml", checkoutDir)))
+				Expect(cmd.RunCallCount()).To(Equal(0))
 			})
 		})
 
@@ -401,30 +519,19 @@ networks
 			It("fails when trying to run command", func() {
 				cmd.RunReturnsOnCall(0, errors.New("failed to run command"))
 
-				executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile)
+				executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile, bosh.DeploymentDirs{})
 				_, err := executor.DirectorInterpolate(bosh.InterpolateInput{
 					IAAS: "aws",
 				})
 				Expect(err).To(MatchError("failed to run command"))
 			})
 
-			It("fails when trying to run the command to interpolate with the user opsfile", func() {
-				cmd.RunReturnsOnCall(1, errors.New("failed to run command"))
-
-				executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile)
-				_, err := executor.DirectorInterpolate(bosh.InterpolateInput{
-					IAAS:    "aws",
-					OpsFile: "some-ops-file",
-				})
-				Expect(err).To(MatchError("failed to run command"))
-			})
-
 			It("fails when the variables file fails to be read", func() {
 				readFileFunc := func(path string) ([]byte, error) {
 					return []byte{}, errors.New("failed to read variables file")
 				}
 
-				executor = bosh.NewExecutor(cmd, tempDirFunc, readFileFunc, json.Unmarshal, json.Marshal, ioutil.WriteFile)
+				executor = bosh.NewExecutor(cmd, tempDirFunc, readFileFunc, json.Unmarshal, json.Marshal, ioutil.WriteFile, bosh.DeploymentDirs{})
 				_, err := executor.DirectorInterpolate(bosh.InterpolateInput{
 					IAAS: "aws",
 				})
@@ -456,7 +563,7 @@ networks
 				return tempDir, nil
 			}
 
-			executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile)
+			executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile, bosh.DeploymentDirs{})
 		})
 
 		It("fails when the temporary directory cannot be created", func() {
@@ -464,7 +571,7 @@ networks
 				return "", errors.New("failed to create temp dir")
 			}
 
-			executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile)
+			executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile, bosh.DeploymentDirs{})
 			err := callback(executor)
 			Expect(err).To(MatchError("failed to create temp dir"))
 		})
@@ -474,7 +581,7 @@ networks
 				return []byte{}, errors.New("failed to marshal state")
 			}
 
-			executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, marshalFunc, ioutil.WriteFile)
+			executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, marshalFunc, ioutil.WriteFile, bosh.DeploymentDirs{})
 			err := callback(executor)
 			Expect(err).To(MatchError("failed to marshal state"))
 		})
@@ -484,7 +591,7 @@ networks
 				return errors.New("failed to write file")
 			}
 
-			executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, json.Marshal, writeFile)
+			executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, json.Marshal, writeFile, bosh.DeploymentDirs{})
 			err := callback(executor)
 			Expect(err).To(MatchError("failed to write file"))
 		})
@@ -524,7 +631,7 @@ networks
 				return tempDir, nil
 			}
 
-			executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile)
+			executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile, bosh.DeploymentDirs{})
 
 			createEnvInput = bosh.CreateEnvInput{
 				Manifest:  "some-manifest",
@@ -573,6 +680,24 @@ networks
 			}))
 		})
 
+		It("writes the manifest, vars store and state only the user can read", func() {
+			createEnvInput.State = map[string]interface{}{"some-key": "some-value"}
+
+			var modes []os.FileMode
+			cmd.RunStub = func(stdout io.Writer, workingDirectory string, args []string) error {
+				for _, path := range []string{manifestPath, variablesPath, statePath} {
+					info, err := os.Stat(path)
+					Expect(err).NotTo(HaveOccurred())
+					modes = append(modes, info.Mode())
+				}
+				return nil
+			}
+
+			_, err := executor.CreateEnv(createEnvInput)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(modes).To(Equal([]os.FileMode{0600, 0600, 0600}))
+		})
+
 		Context("failure cases", func() {
 			createEnvDeleteEnvFailureCases(func(executor bosh.Executor) error {
 				createEnvInput := bosh.CreateEnvInput{
@@ -587,7 +712,7 @@ networks
 			Context("when command run fails", func() {
 				BeforeEach(func() {
 					cmd.RunReturns(errors.New("failed to run"))
-					executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile)
+					executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile, bosh.DeploymentDirs{})
 
 					cmd.RunStub = func(stdout io.Writer, workingDirectory string, args []string) error {
 						ioutil.WriteFile(statePath, []byte(`{"key": "value"}`), os.ModePerm)
@@ -609,7 +734,7 @@ networks
 							return []byte{}, errors.New("failed to read file")
 						}
 
-						executor = bosh.NewExecutor(cmd, tempDirFunc, readFile, json.Unmarshal, json.Marshal, ioutil.WriteFile)
+						executor = bosh.NewExecutor(cmd, tempDirFunc, readFile, json.Unmarshal, json.Marshal, ioutil.WriteFile, bosh.DeploymentDirs{})
 					})
 
 					It("returns an error", func() {
@@ -624,7 +749,7 @@ networks
 							return errors.New("failed to unmarshal")
 						}
 
-						executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, unmarshalFunc, json.Marshal, ioutil.WriteFile)
+						executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, unmarshalFunc, json.Marshal, ioutil.WriteFile, bosh.DeploymentDirs{})
 					})
 
 					It("returns an error", func() {
@@ -639,7 +764,7 @@ networks
 					return []byte{}, errors.New("failed to read file")
 				}
 
-				executor = bosh.NewExecutor(cmd, tempDirFunc, readFile, json.Unmarshal, json.Marshal, ioutil.WriteFile)
+				executor = bosh.NewExecutor(cmd, tempDirFunc, readFile, json.Unmarshal, json.Marshal, ioutil.WriteFile, bosh.DeploymentDirs{})
 				_, err := executor.CreateEnv(createEnvInput)
 				Expect(err).To(MatchError("failed to read file"))
 			})
@@ -649,7 +774,7 @@ networks
 					return errors.New("failed to unmarshal")
 				}
 
-				executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, unmarshalFunc, json.Marshal, ioutil.WriteFile)
+				executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, unmarshalFunc, json.Marshal, ioutil.WriteFile, bosh.DeploymentDirs{})
 				_, err := executor.CreateEnv(createEnvInput)
 				Expect(err).To(MatchError("failed to unmarshal"))
 			})
@@ -684,7 +809,7 @@ networks
 				return tempDir, nil
 			}
 
-			executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile)
+			executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile, bosh.DeploymentDirs{})
 
 			deleteEnvInput = bosh.DeleteEnvInput{
 				Manifest:  "some-manifest",
@@ -742,7 +867,7 @@ networks
 			Context("when command run fails", func() {
 				BeforeEach(func() {
 					cmd.RunReturnsOnCall(0, errors.New("failed to run"))
-					executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile)
+					executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile, bosh.DeploymentDirs{})
 
 					cmd.RunStub = func(stdout io.Writer, workingDirectory string, args []string) error {
 						ioutil.WriteFile(statePath, []byte(`{"partial": "state"}`), os.ModePerm)
@@ -764,7 +889,7 @@ networks
 							return []byte{}, errors.New("failed to read file")
 						}
 
-						executor = bosh.NewExecutor(cmd, tempDirFunc, readFile, json.Unmarshal, json.Marshal, ioutil.WriteFile)
+						executor = bosh.NewExecutor(cmd, tempDirFunc, readFile, json.Unmarshal, json.Marshal, ioutil.WriteFile, bosh.DeploymentDirs{})
 					})
 
 					It("returns an error", func() {
@@ -776,6 +901,41 @@ networks
 		})
 	})
 
+	Describe("DNSRuntimeConfig", func() {
+		It("returns the bosh dns runtime config compiled into bbl", func() {
+			executor := bosh.NewExecutor(&fakes.BOSHCommand{}, ioutil.TempDir, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile, bosh.DeploymentDirs{})
+
+			runtimeConfig, err := executor.DNSRuntimeConfig()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(runtimeConfig).To(Equal(bosh.MustAsset("vendor/github.com/cloudfoundry/bosh-deployment/runtime-configs/dns.yml")))
+		})
+
+		It("reads the bosh dns runtime config from a bosh-deployment checkout", func() {
+			checkoutDir, err := ioutil.TempDir("", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.MkdirAll(filepath.Join(checkoutDir, "runtime-configs"), os.ModePerm)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(checkoutDir, "runtime-configs", "dns.yml"), []byte("checkout dns.yml"), os.ModePerm)).To(Succeed())
+
+			executor := bosh.NewExecutor(&fakes.BOSHCommand{}, ioutil.TempDir, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile,
+				bosh.DeploymentDirs{BOSH: checkoutDir})
+
+			runtimeConfig, err := executor.DNSRuntimeConfig()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(runtimeConfig)).To(Equal("checkout dns.yml"))
+		})
+
+		It("returns an error when the checkout has no bosh dns runtime config", func() {
+			checkoutDir, err := ioutil.TempDir("", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			executor := bosh.NewExecutor(&fakes.BOSHCommand{}, ioutil.TempDir, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile,
+				bosh.DeploymentDirs{BOSH: checkoutDir})
+
+			_, err = executor.DNSRuntimeConfig()
+			Expect(err).To(MatchError(fmt.Sprintf("bosh-deployment checkout %s is missing runtime-configs/dns.yml", checkoutDir)))
+		})
+	})
+
 	Describe("Version", func() {
 		var (
 			cmd              *fakes.BOSHCommand
@@ -801,7 +961,7 @@ networks
 				return tempDir, nil
 			}
 
-			executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile)
+			executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile, bosh.DeploymentDirs{})
 		})
 
 		It("passes the correct args and dir to run command", func() {
@@ -824,7 +984,7 @@ networks
 					return "", errors.New("failed to create temp dir")
 				}
 
-				executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile)
+				executor = bosh.NewExecutor(cmd, tempDirFunc, ioutil.ReadFile, json.Unmarshal, json.Marshal, ioutil.WriteFile, bosh.DeploymentDirs{})
 				_, err := executor.Version()
 				Expect(err).To(MatchError("failed to create temp dir"))
 			})
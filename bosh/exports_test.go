@@ -29,3 +29,11 @@ func SetProxySOCKS5(f func(string, string, *proxy.Auth, proxy.Dialer) (proxy.Dia
 func ResetProxySOCKS5() {
 	proxySOCKS5 = proxy.SOCKS5
 }
+
+func SetGitRevParse(f func(string) (string, error)) {
+	gitRevParse = f
+}
+
+func ResetGitRevParse() {
+	gitRevParse = defaultGitRevParse
+}
@@ -15,8 +15,7 @@ var (
 )
 
 const (
-	DIRECTOR_USERNAME    = "admin"
-	DIRECTOR_INTERNAL_IP = "10.0.0.6"
+	DIRECTOR_USERNAME = "admin"
 )
 
 type Manager struct {
@@ -140,6 +139,7 @@ func (m *Manager) CreateJumpbox(state storage.State, terraformOutputs map[string
 		JumpboxDeploymentVars: m.GetJumpboxDeploymentVars(state, terraformOutputs),
 		DeploymentVars:        m.GetDirectorDeploymentVars(state, terraformOutputs),
 		Variables:             state.Jumpbox.Variables,
+		JumpboxOpsFiles:       userFileContents(state.Jumpbox.OpsFiles),
 	}
 
 	interpolateOutputs, err := m.executor.JumpboxInterpolate(iaasInputs)
@@ -166,8 +166,9 @@ func (m *Manager) CreateJumpbox(state storage.State, terraformOutputs map[string
 			Variables: interpolateOutputs.Variables,
 			State:     ceErr.BOSHState(),
 			Manifest:  interpolateOutputs.Manifest,
+			OpsFiles:  state.Jumpbox.OpsFiles,
 		}
-		return storage.State{}, fmt.Errorf("create env error: %s", NewManagerCreateError(state, err))
+		return storage.State{}, NewManagerCreateError(state, fmt.Errorf("create env error: %s", err))
 	case error:
 		return storage.State{}, fmt.Errorf("create env: %s", err)
 	}
@@ -178,6 +179,9 @@ func (m *Manager) CreateJumpbox(state storage.State, terraformOutputs map[string
 		State:     createEnvOutputs.State,
 		Manifest:  interpolateOutputs.Manifest,
 		URL:       terraformOutputs["jumpbox_url"].(string),
+		OpsFiles:  state.Jumpbox.OpsFiles,
+
+		DeploymentSource: &interpolateOutputs.DeploymentSource,
 	}
 
 	m.logger.Step("created jumpbox")
@@ -206,7 +210,10 @@ func (m *Manager) CreateDirector(state storage.State, terraformOutputs map[strin
 		DeploymentVars:        m.GetDirectorDeploymentVars(state, terraformOutputs),
 		JumpboxDeploymentVars: m.GetJumpboxDeploymentVars(state, terraformOutputs),
 		Variables:             state.BOSH.Variables,
-		OpsFile:               state.BOSH.UserOpsFile,
+		OpsFiles:              userFileContents(state.BOSH.OpsFiles),
+		VarsFiles:             userFileContents(state.BOSH.VarsFiles),
+		Vars:                  state.BOSH.Vars,
+		DefaultKMSKey:         !state.ComponentEnabled(storage.KMSComponent),
 	}
 
 	interpolateOutputs, err := m.executor.DirectorInterpolate(iaasInputs)
@@ -224,9 +231,14 @@ func (m *Manager) CreateDirector(state storage.State, terraformOutputs map[strin
 	case CreateEnvError:
 		ceErr := err.(CreateEnvError)
 		state.BOSH = storage.BOSH{
-			Variables: interpolateOutputs.Variables,
-			State:     ceErr.BOSHState(),
-			Manifest:  interpolateOutputs.Manifest,
+			Variables:             interpolateOutputs.Variables,
+			State:                 ceErr.BOSHState(),
+			Manifest:              interpolateOutputs.Manifest,
+			OpsFiles:              state.BOSH.OpsFiles,
+			VarsFiles:             state.BOSH.VarsFiles,
+			Vars:                  state.BOSH.Vars,
+			RuntimeConfigOpsFiles: state.BOSH.RuntimeConfigOpsFiles,
+			DeploymentSource:      &interpolateOutputs.DeploymentSource,
 		}
 		return storage.State{}, NewManagerCreateError(state, err)
 	case error:
@@ -241,7 +253,7 @@ func (m *Manager) CreateDirector(state storage.State, terraformOutputs map[strin
 	var directorAddress string
 	directorAddress = terraformOutputs["director_address"].(string)
 	if state.Jumpbox.Enabled {
-		directorAddress = fmt.Sprintf("https://%s:25555", DIRECTOR_INTERNAL_IP)
+		directorAddress = fmt.Sprintf("https://%s:25555", mustNetworkLayout(state).DirectorIP())
 	}
 
 	state.BOSH = storage.BOSH{
@@ -255,34 +267,93 @@ func (m *Manager) CreateDirector(state storage.State, terraformOutputs map[strin
 		Variables:              interpolateOutputs.Variables,
 		State:                  createEnvOutputs.State,
 		Manifest:               interpolateOutputs.Manifest,
-		UserOpsFile:            state.BOSH.UserOpsFile,
+		OpsFiles:               state.BOSH.OpsFiles,
+		VarsFiles:              state.BOSH.VarsFiles,
+		Vars:                   state.BOSH.Vars,
+		RuntimeConfigOpsFiles:  state.BOSH.RuntimeConfigOpsFiles,
+		DeploymentSource:       &interpolateOutputs.DeploymentSource,
 	}
 
 	m.logger.Step("created bosh director")
 	return state, nil
 }
 
+// PlanManifests interpolates the director and jumpbox manifests that the
+// next create-env would deploy and diffs them against the manifests in state.
+// The interpolated variables are discarded.
+func (m *Manager) PlanManifests(state storage.State, terraformOutputs map[string]interface{}) (ManifestPlan, error) {
+	var plan ManifestPlan
+
+	if state.Jumpbox.Enabled {
+		jumpboxOutputs, err := m.executor.JumpboxInterpolate(InterpolateInput{
+			IAAS:                  state.IAAS,
+			JumpboxDeploymentVars: m.GetJumpboxDeploymentVars(state, terraformOutputs),
+			DeploymentVars:        m.GetDirectorDeploymentVars(state, terraformOutputs),
+			Variables:             state.Jumpbox.Variables,
+			JumpboxOpsFiles:       userFileContents(state.Jumpbox.OpsFiles),
+		})
+		if err != nil {
+			return ManifestPlan{}, fmt.Errorf("jumpbox interpolate: %s", err)
+		}
+
+		plan.JumpboxDiff = DiffManifests(state.Jumpbox.Manifest, jumpboxOutputs.Manifest)
+	}
+
+	if !state.NoDirector {
+		directorOutputs, err := m.executor.DirectorInterpolate(InterpolateInput{
+			IAAS:                  state.IAAS,
+			DeploymentVars:        m.GetDirectorDeploymentVars(state, terraformOutputs),
+			JumpboxDeploymentVars: m.GetJumpboxDeploymentVars(state, terraformOutputs),
+			Variables:             state.BOSH.Variables,
+			OpsFiles:              userFileContents(state.BOSH.OpsFiles),
+			VarsFiles:             userFileContents(state.BOSH.VarsFiles),
+			Vars:                  state.BOSH.Vars,
+			DefaultKMSKey:         !state.ComponentEnabled(storage.KMSComponent),
+		})
+		if err != nil {
+			return ManifestPlan{}, fmt.Errorf("director interpolate: %s", err)
+		}
+
+		plan.DirectorDiff = DiffManifests(state.BOSH.Manifest, directorOutputs.Manifest)
+	}
+
+	return plan, nil
+}
+
+// StartJumpboxProxy starts the socks5 proxy to the jumpbox in the state, for
+// commands that reach the director without deploying the jumpbox first.
+func (m *Manager) StartJumpboxProxy(state storage.State) error {
+	jumpboxPrivateKey, err := getJumpboxPrivateKey(state.Jumpbox.Variables)
+	if err != nil {
+		return err
+	}
+
+	err = m.socks5Proxy.Start(jumpboxPrivateKey, state.Jumpbox.URL)
+	if err != nil {
+		return err
+	}
+
+	osSetenv("BOSH_ALL_PROXY", fmt.Sprintf("socks5://%s", m.socks5Proxy.Addr()))
+
+	return nil
+}
+
 func (m *Manager) Delete(state storage.State, terraformOutputs map[string]interface{}) error {
 	iaasInputs := InterpolateInput{
 		IAAS:      state.IAAS,
 		BOSHState: state.BOSH.State,
 		Variables: state.BOSH.Variables,
-		OpsFile:   state.BOSH.UserOpsFile,
+		OpsFiles:  userFileContents(state.BOSH.OpsFiles),
+		VarsFiles: userFileContents(state.BOSH.VarsFiles),
+		Vars:      state.BOSH.Vars,
 	}
 
 	if state.Jumpbox.Enabled {
-		jumpboxPrivateKey, err := getJumpboxPrivateKey(state.Jumpbox.Variables)
-		if err != nil {
-			return err
-		}
-
-		err = m.socks5Proxy.Start(jumpboxPrivateKey, state.Jumpbox.URL)
+		err := m.StartJumpboxProxy(state)
 		if err != nil {
 			return err
 		}
 
-		osSetenv("BOSH_ALL_PROXY", fmt.Sprintf("socks5://%s", m.socks5Proxy.Addr()))
-
 		iaasInputs.JumpboxDeploymentVars = m.GetJumpboxDeploymentVars(state, terraformOutputs)
 	}
 
@@ -321,6 +392,7 @@ func (m *Manager) DeleteJumpbox(state storage.State, terraformOutputs map[string
 		IAAS:                  state.IAAS,
 		Variables:             state.BOSH.Variables,
 		JumpboxDeploymentVars: m.GetJumpboxDeploymentVars(state, terraformOutputs),
+		JumpboxOpsFiles:       userFileContents(state.Jumpbox.OpsFiles),
 	}
 
 	interpolateOutputs, err := m.executor.JumpboxInterpolate(iaasInputs)
@@ -350,10 +422,11 @@ func (m *Manager) GetJumpboxDeploymentVars(state storage.State, terraformOutputs
 		return ""
 	}
 
+	network := mustNetworkLayout(state)
 	vars := sharedDeploymentVarsYAML{
-		InternalCIDR: "10.0.0.0/24",
-		InternalGW:   "10.0.0.1",
-		InternalIP:   "10.0.0.5",
+		InternalCIDR: network.DirectorSubnetCIDR(),
+		InternalGW:   network.DirectorSubnetGateway(),
+		InternalIP:   network.JumpboxIP(),
 		DirectorName: fmt.Sprintf("bosh-%s", state.EnvID),
 		ExternalIP:   getTerraformOutput("external_ip", terraformOutputs),
 	}
@@ -394,6 +467,24 @@ func mustMarshal(yamlStruct interface{}) []byte {
 	return yamlBytes
 }
 
+func mustNetworkLayout(state storage.State) storage.NetworkLayout {
+	network, err := state.NetworkLayout()
+	if err != nil {
+		// this should never happen since bbl up validates the network before
+		// storing it, and loading or importing a state validates it again
+		panic(fmt.Sprintf("bosh manager: %s", err))
+	}
+	return network
+}
+
+func userFileContents(files []storage.UserFile) []string {
+	var contents []string
+	for _, file := range files {
+		contents = append(contents, file.Contents)
+	}
+	return contents
+}
+
 func getTerraformOutput(key string, outputs map[string]interface{}) string {
 	if value, ok := outputs[key]; ok {
 		return fmt.Sprintf("%s", value)
@@ -402,10 +493,11 @@ func getTerraformOutput(key string, outputs map[string]interface{}) string {
 }
 
 func (m *Manager) GetDirectorDeploymentVars(state storage.State, terraformOutputs map[string]interface{}) string {
+	network := mustNetworkLayout(state)
 	vars := sharedDeploymentVarsYAML{
-		InternalCIDR: "10.0.0.0/24",
-		InternalGW:   "10.0.0.1",
-		InternalIP:   DIRECTOR_INTERNAL_IP,
+		InternalCIDR: network.DirectorSubnetCIDR(),
+		InternalGW:   network.DirectorSubnetGateway(),
+		InternalIP:   network.DirectorIP(),
 		DirectorName: fmt.Sprintf("bosh-%s", state.EnvID),
 		ExternalIP:   getTerraformOutput("external_ip", terraformOutputs),
 	}
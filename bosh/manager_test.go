@@ -60,8 +60,9 @@ var _ = Describe("Manager", func() {
 	Describe("CreateDirector", func() {
 		BeforeEach(func() {
 			boshExecutor.DirectorInterpolateCall.Returns.Output = bosh.InterpolateOutput{
-				Manifest:  "some-manifest",
-				Variables: variablesYAML,
+				Manifest:         "some-manifest",
+				Variables:        variablesYAML,
+				DeploymentSource: storage.DeploymentSource{Source: "some-bosh-deployment-dir", SHA: "some-bosh-deployment-sha"},
 			}
 			boshExecutor.CreateEnvCall.Returns.Output = bosh.CreateEnvOutput{
 				State: map[string]interface{}{"some-new-key": "some-new-value"}}
@@ -93,7 +94,11 @@ var _ = Describe("Manager", func() {
 						State: map[string]interface{}{
 							"some-key": "some-value",
 						},
-						UserOpsFile: "some-ops-file",
+						OpsFiles:  []storage.UserFile{{Path: "some-ops-file-path", Contents: "some-ops-file"}},
+						VarsFiles: []storage.UserFile{{Path: "some-vars-file-path", Contents: "some-vars-file"}},
+						Vars:      []string{"some-var=some-value"},
+
+						RuntimeConfigOpsFiles: []storage.UserFile{{Path: "some-runtime-config-ops-file-path", Contents: "some-runtime-config-ops-file"}},
 					},
 				}
 			})
@@ -120,6 +125,10 @@ project_id: some-project-id
 gcp_credentials_json: some-credential-json
 `))
 
+				Expect(boshExecutor.DirectorInterpolateCall.Receives.InterpolateInput.OpsFiles).To(Equal([]string{"some-ops-file"}))
+				Expect(boshExecutor.DirectorInterpolateCall.Receives.InterpolateInput.VarsFiles).To(Equal([]string{"some-vars-file"}))
+				Expect(boshExecutor.DirectorInterpolateCall.Receives.InterpolateInput.Vars).To(Equal([]string{"some-var=some-value"}))
+
 				Expect(socks5Proxy.StartCall.CallCount).To(Equal(0))
 				Expect(boshExecutor.JumpboxInterpolateCall.CallCount).To(Equal(0))
 				Expect(stateWithDirector.BOSH).To(Equal(storage.BOSH{
@@ -135,7 +144,11 @@ gcp_credentials_json: some-credential-json
 					DirectorSSLCA:          "some-ca",
 					DirectorSSLCertificate: "some-certificate",
 					DirectorSSLPrivateKey:  "some-private-key",
-					UserOpsFile:            "some-ops-file",
+					OpsFiles:               []storage.UserFile{{Path: "some-ops-file-path", Contents: "some-ops-file"}},
+					VarsFiles:              []storage.UserFile{{Path: "some-vars-file-path", Contents: "some-vars-file"}},
+					Vars:                   []string{"some-var=some-value"},
+					RuntimeConfigOpsFiles:  []storage.UserFile{{Path: "some-runtime-config-ops-file-path", Contents: "some-runtime-config-ops-file"}},
+					DeploymentSource:       &storage.DeploymentSource{Source: "some-bosh-deployment-dir", SHA: "some-bosh-deployment-sha"},
 				}))
 			})
 		})
@@ -153,8 +166,8 @@ gcp_credentials_json: some-credential-json
 							Region:          "some-region",
 						},
 						BOSH: storage.BOSH{
-							State:       map[string]interface{}{"some-key": "some-value"},
-							UserOpsFile: "some-yaml",
+							State:    map[string]interface{}{"some-key": "some-value"},
+							OpsFiles: []storage.UserFile{{Path: "some-ops-file-path", Contents: "some-yaml"}},
 						},
 					}
 					terraformOutputs = map[string]interface{}{
@@ -204,32 +217,71 @@ kms_key_arn: some-kms-arn
 						DirectorSSLCA:          "some-ca",
 						DirectorSSLCertificate: "some-certificate",
 						DirectorSSLPrivateKey:  "some-private-key",
-						UserOpsFile:            "some-yaml",
+						OpsFiles:               []storage.UserFile{{Path: "some-ops-file-path", Contents: "some-yaml"}},
+						DeploymentSource:       &storage.DeploymentSource{Source: "some-bosh-deployment-dir", SHA: "some-bosh-deployment-sha"},
 					}))
 				})
+				It("encrypts the director disks with the aws managed key when kms is disabled", func() {
+					incomingAWSState.Components = storage.Components{"kms": false}
+					delete(terraformOutputs, "kms_key_arn")
+
+					_, err := boshManager.CreateDirector(incomingAWSState, terraformOutputs)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(boshExecutor.DirectorInterpolateCall.Receives.InterpolateInput.DefaultKMSKey).To(BeTrue())
+					Expect(boshExecutor.DirectorInterpolateCall.Receives.InterpolateInput.DeploymentVars).NotTo(ContainSubstring("kms_key_arn"))
+				})
 			})
 
 			Context("when the executor's create env call fails with create env error", func() {
-				var expectedError bosh.ManagerCreateError
+				var (
+					incomingState storage.State
+					expectedError bosh.ManagerCreateError
+				)
 
 				BeforeEach(func() {
 					boshState := map[string]interface{}{"partial": "bosh-state"}
 					createEnvError := bosh.NewCreateEnvError(boshState, errors.New("failed to create env"))
 					boshExecutor.CreateEnvCall.Returns.Error = createEnvError
 
+					incomingState = storage.State{
+						BOSH: storage.BOSH{
+							OpsFiles:              []storage.UserFile{{Path: "some-ops-file-path", Contents: "some-ops-file"}},
+							VarsFiles:             []storage.UserFile{{Path: "some-vars-file-path", Contents: "some-vars-file"}},
+							Vars:                  []string{"some-var=some-value"},
+							RuntimeConfigOpsFiles: []storage.UserFile{{Path: "some-runtime-config-ops-file-path", Contents: "some-runtime-config-ops-file"}},
+						},
+					}
+
 					expectedState := storage.State{}
 					expectedState.BOSH = storage.BOSH{
-						Manifest:  "some-manifest",
-						State:     boshState,
-						Variables: variablesYAML,
+						Manifest:              "some-manifest",
+						State:                 boshState,
+						Variables:             variablesYAML,
+						OpsFiles:              incomingState.BOSH.OpsFiles,
+						VarsFiles:             incomingState.BOSH.VarsFiles,
+						Vars:                  incomingState.BOSH.Vars,
+						RuntimeConfigOpsFiles: incomingState.BOSH.RuntimeConfigOpsFiles,
+						DeploymentSource:      &storage.DeploymentSource{Source: "some-bosh-deployment-dir", SHA: "some-bosh-deployment-sha"},
 					}
 					expectedError = bosh.NewManagerCreateError(expectedState, createEnvError)
 				})
 
 				It("returns a bosh manager create error with a valid state", func() {
-					_, err := boshManager.CreateDirector(storage.State{}, terraformOutputs)
+					_, err := boshManager.CreateDirector(incomingState, terraformOutputs)
 					Expect(err).To(MatchError(expectedError))
 				})
+
+				It("keeps the user files in the state so that the next bbl up applies them", func() {
+					_, err := boshManager.CreateDirector(incomingState, terraformOutputs)
+
+					managerCreateError, ok := err.(bosh.ManagerCreateError)
+					Expect(ok).To(BeTrue())
+					Expect(managerCreateError.State().BOSH.OpsFiles).To(Equal(incomingState.BOSH.OpsFiles))
+					Expect(managerCreateError.State().BOSH.VarsFiles).To(Equal(incomingState.BOSH.VarsFiles))
+					Expect(managerCreateError.State().BOSH.Vars).To(Equal(incomingState.BOSH.Vars))
+					Expect(managerCreateError.State().BOSH.RuntimeConfigOpsFiles).To(Equal(incomingState.BOSH.RuntimeConfigOpsFiles))
+				})
 			})
 		})
 
@@ -327,13 +379,15 @@ gcp_credentials_json: some-credential-json
 `
 
 			boshExecutor.JumpboxInterpolateCall.Returns.Output = bosh.JumpboxInterpolateOutput{
-				Manifest:  "name: jumpbox",
-				Variables: "jumpbox_ssh:\n  private_key: some-jumpbox-private-key",
+				Manifest:         "name: jumpbox",
+				Variables:        "jumpbox_ssh:\n  private_key: some-jumpbox-private-key",
+				DeploymentSource: storage.DeploymentSource{Source: "cppforlife/jumpbox-deployment", SHA: "some-jumpbox-deployment-sha"},
 			}
 
 			boshExecutor.DirectorInterpolateCall.Returns.Output = bosh.InterpolateOutput{
-				Manifest:  "some-manifest",
-				Variables: variablesYAML,
+				Manifest:         "some-manifest",
+				Variables:        variablesYAML,
+				DeploymentSource: storage.DeploymentSource{Source: "cloudfoundry/bosh-deployment", SHA: "some-bosh-deployment-sha"},
 			}
 		})
 
@@ -362,6 +416,17 @@ gcp_credentials_json: some-credential-json
 			}))
 		})
 
+		It("applies the jumpbox ops files and keeps them in state", func() {
+			opsFiles := []storage.UserFile{{Path: "some-jumpbox-ops-file-path", Contents: "some-jumpbox-ops-file"}}
+			incomingGCPState.Jumpbox.OpsFiles = opsFiles
+
+			state, err := boshManager.CreateJumpbox(incomingGCPState, terraformOutputs)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(boshExecutor.JumpboxInterpolateCall.Receives.InterpolateInput.JumpboxOpsFiles).To(Equal([]string{"some-jumpbox-ops-file"}))
+			Expect(state.Jumpbox.OpsFiles).To(Equal(opsFiles))
+		})
+
 		Context("when bosh director is created after jumpbox", func() {
 			It("returns a bbl state with bosh and jumpbox deployment values", func() {
 				boshExecutor.CreateEnvCall.Returns.Output = bosh.CreateEnvOutput{
@@ -395,6 +460,7 @@ gcp_credentials_json: some-credential-json
 						State: map[string]interface{}{
 							"some-new-key": "some-new-value",
 						},
+						DeploymentSource: &storage.DeploymentSource{Source: "cppforlife/jumpbox-deployment", SHA: "some-jumpbox-deployment-sha"},
 					},
 					BOSH: storage.BOSH{
 						State: map[string]interface{}{
@@ -409,6 +475,7 @@ gcp_credentials_json: some-credential-json
 						DirectorSSLCA:          "some-ca",
 						DirectorSSLCertificate: "some-certificate",
 						DirectorSSLPrivateKey:  "some-private-key",
+						DeploymentSource:       &storage.DeploymentSource{Source: "cloudfoundry/bosh-deployment", SHA: "some-bosh-deployment-sha"},
 					},
 				}))
 			})
@@ -430,6 +497,12 @@ gcp_credentials_json: some-credential-json
 
 					_, err := boshManager.CreateJumpbox(incomingGCPState, terraformOutputs)
 					Expect(err).To(MatchError("create env error: apple"))
+
+					managerCreateError, ok := err.(bosh.ManagerCreateError)
+					Expect(ok).To(BeTrue())
+					Expect(managerCreateError.State().Jumpbox.Enabled).To(BeTrue())
+					Expect(managerCreateError.State().Jumpbox.State).To(Equal(map[string]interface{}{"foo": "bar"}))
+					Expect(managerCreateError.State().Jumpbox.Variables).To(Equal("jumpbox_ssh:\n  private_key: some-jumpbox-private-key"))
 				})
 			})
 
@@ -471,6 +544,7 @@ gcp_credentials_json: some-credential-json
 						"key": "value",
 					},
 					Variables: vars,
+					OpsFiles:  []storage.UserFile{{Path: "some-jumpbox-ops-file-path", Contents: "some-jumpbox-ops-file"}},
 				},
 			}
 		})
@@ -483,6 +557,7 @@ gcp_credentials_json: some-credential-json
 
 			err := boshManager.DeleteJumpbox(incomingState, map[string]interface{}{"jumpbox_ssh": "nick-da-quick"})
 			Expect(err).NotTo(HaveOccurred())
+			Expect(boshExecutor.JumpboxInterpolateCall.Receives.InterpolateInput.JumpboxOpsFiles).To(Equal([]string{"some-jumpbox-ops-file"}))
 			Expect(boshExecutor.DeleteEnvCall.Receives.Input.Variables).To(Equal(vars))
 		})
 
@@ -526,6 +601,113 @@ gcp_credentials_json: some-credential-json
 		})
 	})
 
+	Describe("PlanManifests", func() {
+		var state storage.State
+
+		BeforeEach(func() {
+			terraformOutputs = map[string]interface{}{"external_ip": "some-external-ip"}
+			state = storage.State{
+				IAAS:  "gcp",
+				EnvID: "some-env-id",
+				BOSH: storage.BOSH{
+					Manifest:  "name: bosh\n",
+					Variables: variablesYAML,
+					OpsFiles:  []storage.UserFile{{Path: "some-ops-file-path", Contents: "some-ops-file"}},
+				},
+				Jumpbox: storage.Jumpbox{
+					Enabled:   true,
+					Manifest:  "name: jumpbox\n",
+					Variables: "some-jumpbox-vars",
+					OpsFiles:  []storage.UserFile{{Path: "some-jumpbox-ops-file-path", Contents: "some-jumpbox-ops-file"}},
+				},
+			}
+
+			boshExecutor.DirectorInterpolateCall.Returns.Output = bosh.InterpolateOutput{Manifest: "name: new-bosh\n"}
+			boshExecutor.JumpboxInterpolateCall.Returns.Output = bosh.JumpboxInterpolateOutput{Manifest: "name: jumpbox\n"}
+		})
+
+		It("diffs the interpolated manifests against the manifests in state", func() {
+			plan, err := boshManager.PlanManifests(state, terraformOutputs)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(plan).To(Equal(bosh.ManifestPlan{
+				DirectorDiff: "- name: bosh\n+ name: new-bosh\n",
+				JumpboxDiff:  "",
+			}))
+
+			Expect(boshExecutor.DirectorInterpolateCall.Receives.InterpolateInput.Variables).To(Equal(variablesYAML))
+			Expect(boshExecutor.DirectorInterpolateCall.Receives.InterpolateInput.OpsFiles).To(Equal([]string{"some-ops-file"}))
+			Expect(boshExecutor.JumpboxInterpolateCall.Receives.InterpolateInput.Variables).To(Equal("some-jumpbox-vars"))
+			Expect(boshExecutor.JumpboxInterpolateCall.Receives.InterpolateInput.JumpboxOpsFiles).To(Equal([]string{"some-jumpbox-ops-file"}))
+			Expect(boshExecutor.CreateEnvCall.CallCount).To(Equal(0))
+		})
+
+		It("skips the jumpbox when it is not enabled", func() {
+			state.Jumpbox = storage.Jumpbox{}
+
+			_, err := boshManager.PlanManifests(state, terraformOutputs)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(boshExecutor.JumpboxInterpolateCall.CallCount).To(Equal(0))
+		})
+
+		It("skips the director for --no-director environments", func() {
+			state.NoDirector = true
+
+			_, err := boshManager.PlanManifests(state, terraformOutputs)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(boshExecutor.DirectorInterpolateCall.CallCount).To(Equal(0))
+		})
+
+		It("returns an error when the director manifest cannot be interpolated", func() {
+			boshExecutor.DirectorInterpolateCall.Returns.Error = errors.New("failed to interpolate")
+
+			_, err := boshManager.PlanManifests(state, terraformOutputs)
+			Expect(err).To(MatchError("director interpolate: failed to interpolate"))
+		})
+
+		It("returns an error when the jumpbox manifest cannot be interpolated", func() {
+			boshExecutor.JumpboxInterpolateCall.Returns.Error = errors.New("failed to interpolate")
+
+			_, err := boshManager.PlanManifests(state, terraformOutputs)
+			Expect(err).To(MatchError("jumpbox interpolate: failed to interpolate"))
+		})
+	})
+
+	Describe("StartJumpboxProxy", func() {
+		It("starts a socks5 proxy with the jumpbox key and url in the state", func() {
+			socks5Proxy.AddrCall.Returns.Addr = "localhost:1234"
+
+			err := boshManager.StartJumpboxProxy(storage.State{
+				Jumpbox: storage.Jumpbox{
+					Enabled:   true,
+					Variables: "jumpbox_ssh:\n  private_key: some-jumpbox-private-key",
+					URL:       "some-jumpbox-url",
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(socks5Proxy.StartCall.CallCount).To(Equal(1))
+			Expect(socks5Proxy.StartCall.Receives.JumpboxPrivateKey).To(Equal("some-jumpbox-private-key"))
+			Expect(socks5Proxy.StartCall.Receives.JumpboxExternalURL).To(Equal("some-jumpbox-url"))
+			Expect(osSetenvKey).To(Equal("BOSH_ALL_PROXY"))
+			Expect(osSetenvValue).To(Equal("socks5://localhost:1234"))
+		})
+
+		It("returns an error when the socks5 proxy fails to start", func() {
+			socks5Proxy.StartCall.Returns.Error = errors.New("failed to start socks5Proxy")
+
+			err := boshManager.StartJumpboxProxy(storage.State{
+				Jumpbox: storage.Jumpbox{
+					Enabled:   true,
+					Variables: "jumpbox_ssh:\n  private_key: some-jumpbox-private-key",
+				},
+			})
+			Expect(err).To(MatchError("failed to start socks5Proxy"))
+		})
+	})
+
 	Describe("Delete", func() {
 		BeforeEach(func() {
 			boshExecutor.DirectorInterpolateCall.Returns.Output = bosh.InterpolateOutput{
@@ -783,6 +965,27 @@ gcp_credentials_json: some-credential-json
 `))
 			})
 
+			Context("when a network cidr is configured", func() {
+				It("places the director in the director subnet of the network", func() {
+					incomingState.Network = &storage.Network{
+						CIDR: "172.16.0.0/16",
+					}
+
+					vars := boshManager.GetDirectorDeploymentVars(incomingState, map[string]interface{}{})
+					Expect(vars).To(ContainSubstring(`internal_cidr: 172.16.0.0/24
+internal_gw: 172.16.0.1
+internal_ip: 172.16.0.6
+`))
+
+					incomingState.Jumpbox.Enabled = true
+					vars = boshManager.GetJumpboxDeploymentVars(incomingState, map[string]interface{}{})
+					Expect(vars).To(ContainSubstring(`internal_cidr: 172.16.0.0/24
+internal_gw: 172.16.0.1
+internal_ip: 172.16.0.5
+`))
+				})
+			})
+
 			Context("when using a jumpbox", func() {
 				BeforeEach(func() {
 					incomingState.Jumpbox.Enabled = true
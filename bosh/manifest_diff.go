@@ -0,0 +1,96 @@
+package bosh
+
+import "strings"
+
+const manifestDiffContext = 3
+
+type ManifestPlan struct {
+	DirectorDiff string
+	JumpboxDiff  string
+}
+
+// DiffManifests returns a line diff from current to planned, showing removed
+// lines prefixed with "- ", added lines with "+ " and a few unchanged lines
+// around each change. It returns an empty string when the manifests match.
+func DiffManifests(current, planned string) string {
+	if current == planned {
+		return ""
+	}
+
+	a := splitLines(current)
+	b := splitLines(planned)
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []string
+	var changed []bool
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, "  "+a[i])
+			changed = append(changed, false)
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, "- "+a[i])
+			changed = append(changed, true)
+			i++
+		default:
+			lines = append(lines, "+ "+b[j])
+			changed = append(changed, true)
+			j++
+		}
+	}
+
+	visible := make([]bool, len(lines))
+	for n := range lines {
+		if !changed[n] {
+			continue
+		}
+		for c := n - manifestDiffContext; c <= n+manifestDiffContext; c++ {
+			if c >= 0 && c < len(lines) {
+				visible[c] = true
+			}
+		}
+	}
+
+	var diff []string
+	skipped := false
+	for n, line := range lines {
+		if !visible[n] {
+			skipped = true
+			continue
+		}
+		if skipped && len(diff) > 0 {
+			diff = append(diff, "...")
+		}
+		skipped = false
+		diff = append(diff, line)
+	}
+
+	return strings.Join(diff, "\n") + "\n"
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
@@ -0,0 +1,36 @@
+package bosh_test
+
+import (
+	"github.com/cloudfoundry/bosh-bootloader/bosh"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DiffManifests", func() {
+	It("returns an empty diff for identical manifests", func() {
+		Expect(bosh.DiffManifests("name: bosh\n", "name: bosh\n")).To(BeEmpty())
+	})
+
+	It("shows changed lines with surrounding context", func() {
+		current := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn\n"
+		planned := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn\no\n"
+
+		Expect(bosh.DiffManifests(current, planned)).To(Equal(`  a
+- b
++ B
+  c
+  d
+  e
+...
+  l
+  m
+  n
++ o
+`))
+	})
+
+	It("shows every line as added for a new manifest", func() {
+		Expect(bosh.DiffManifests("", "name: bosh\nversion: 1\n")).To(Equal("+ name: bosh\n+ version: 1\n"))
+	})
+})
@@ -18,7 +18,7 @@ func NewOpsGenerator(awsCloudFormationOpsGenerator cloudconfig.OpsGenerator, aws
 }
 
 func (o OpsGenerator) Generate(state storage.State) (string, error) {
-	if state.TFState != "" {
+	if state.HasTerraformState() {
 		return o.awsTerraformOpsGenerator.Generate(state)
 	} else {
 		return o.awsCloudFormationOpsGenerator.Generate(state)
@@ -59,13 +59,17 @@ func (o OpsGenerator) Generate(state storage.State) (string, error) {
 		return "", err
 	}
 
+	networkLayout, err := state.NetworkLayout()
+	if err != nil {
+		return "", err
+	}
+
 	zones := []string{"z1", "z2", "z3"}
 	var subnets []networkSubnet
 	for i, _ := range zones {
-		cidr := fmt.Sprintf("10.0.%d.0/20", 16*(i+1))
 		subnet, err := generateNetworkSubnet(
 			fmt.Sprintf("z%d", i+1),
-			cidr,
+			networkLayout.InternalSubnetCIDR(i),
 			terraformOutputs["bosh_network_name"].(string),
 			terraformOutputs["bosh_subnet_name"].(string),
 			terraformOutputs["bosh_default_security_group"].(string),
@@ -5,6 +5,8 @@ import (
 	"os"
 
 	"golang.org/x/net/proxy"
+
+	"github.com/cloudfoundry/bosh-bootloader/helpers"
 )
 
 func SetTempDir(f func(string, string) (string, error)) {
@@ -12,7 +14,7 @@ func SetTempDir(f func(string, string) (string, error)) {
 }
 
 func ResetTempDir() {
-	tempDir = ioutil.TempDir
+	tempDir = helpers.TempDir
 }
 
 func SetWriteFile(f func(string, []byte, os.FileMode) error) {
@@ -118,12 +118,16 @@ func (o *OpsGenerator) generateGCPOps(state storage.State) ([]op, error) {
 		}))
 	}
 
+	networkLayout, err := state.NetworkLayout()
+	if err != nil {
+		return []op{}, err
+	}
+
 	var subnets []networkSubnet
 	for i, _ := range state.GCP.Zones {
-		cidr := fmt.Sprintf("10.0.%d.0/20", 16*(i+1))
 		subnet, err := generateNetworkSubnet(
 			fmt.Sprintf("z%d", i+1),
-			cidr,
+			networkLayout.InternalSubnetCIDR(i),
 			terraformOutputs["network_name"].(string),
 			terraformOutputs["subnetwork_name"].(string),
 			terraformOutputs["internal_tag_name"].(string),
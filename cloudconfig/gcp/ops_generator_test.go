@@ -62,6 +62,21 @@ var _ = Describe("GCPOpsGenerator", func() {
 			Expect(opsYAML).To(gomegamatchers.MatchYAML(expectedOpsFile))
 		})
 
+		It("places the subnets in the configured network", func() {
+			incomingState.Network = &storage.Network{
+				CIDR:                "172.16.0.0/16",
+				InternalSubnetCIDRs: []string{"172.16.128.0/20"},
+			}
+
+			opsYAML, err := opsGenerator.Generate(incomingState)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(opsYAML).To(ContainSubstring("range: 172.16.128.0/20"))
+			Expect(opsYAML).To(ContainSubstring("range: 172.16.32.0/20"))
+			Expect(opsYAML).To(ContainSubstring("range: 172.16.48.0/20"))
+			Expect(opsYAML).NotTo(ContainSubstring("10.0."))
+		})
+
 		DescribeTable("returns an ops file with additional vm extensions to support lb",
 			func(lbType string, lbOutputs map[string]interface{}) {
 				incomingState.LB.Type = lbType
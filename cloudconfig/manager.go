@@ -11,11 +11,12 @@ import (
 	"golang.org/x/net/proxy"
 
 	"github.com/cloudfoundry/bosh-bootloader/bosh"
+	"github.com/cloudfoundry/bosh-bootloader/helpers"
 	"github.com/cloudfoundry/bosh-bootloader/storage"
 )
 
 var (
-	tempDir     func(string, string) (string, error)                                  = ioutil.TempDir
+	tempDir     func(string, string) (string, error)                                  = helpers.TempDir
 	writeFile   func(string, []byte, os.FileMode) error                               = ioutil.WriteFile
 	proxySOCKS5 func(string, string, *proxy.Auth, proxy.Dialer) (proxy.Dialer, error) = proxy.SOCKS5
 )
@@ -79,7 +80,7 @@ func (m Manager) Generate(state storage.State) (string, error) {
 		return "", err
 	}
 
-	err = writeFile(filepath.Join(workingDir, "cloud-config.yml"), []byte(BaseCloudConfig), os.ModePerm)
+	err = writeFile(filepath.Join(workingDir, "cloud-config.yml"), []byte(BaseCloudConfig), 0600)
 	if err != nil {
 		return "", err
 	}
@@ -89,7 +90,7 @@ func (m Manager) Generate(state storage.State) (string, error) {
 		return "", err
 	}
 
-	err = writeFile(filepath.Join(workingDir, "ops.yml"), []byte(ops), os.ModePerm)
+	err = writeFile(filepath.Join(workingDir, "ops.yml"), []byte(ops), 0600)
 	if err != nil {
 		return "", err
 	}
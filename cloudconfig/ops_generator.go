@@ -27,7 +27,7 @@ func (o OpsGeneratorWrapper) Generate(state storage.State) (string, error) {
 	case "gcp":
 		return o.gcpOpsGenerator.Generate(state)
 	case "aws":
-		if state.TFState != "" {
+		if state.HasTerraformState() {
 			return o.awsTerraformOpsGenerator.Generate(state)
 		} else {
 			return o.awsCloudFormationOpsGenerator.Generate(state)
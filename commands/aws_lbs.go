@@ -21,7 +21,7 @@ func NewAWSLBs(terraformManager terraformOutputter, logger logger) AWSLBs {
 }
 
 func (l AWSLBs) Execute(subcommandFlags []string, state storage.State) error {
-	if state.TFState != "" {
+	if state.HasTerraformState() {
 		terraformOutputs, err := l.terraformManager.GetOutputs(state)
 		if err != nil {
 			return err
@@ -2,8 +2,6 @@ package commands
 
 import (
 	"errors"
-	"fmt"
-	"io/ioutil"
 
 	"github.com/cloudfoundry/bosh-bootloader/bosh"
 	"github.com/cloudfoundry/bosh-bootloader/helpers"
@@ -11,21 +9,23 @@ import (
 )
 
 type AWSUp struct {
-	boshManager        boshManager
-	cloudConfigManager cloudConfigManager
-	stateStore         stateStore
-	envIDManager       envIDManager
-	terraformManager   terraformApplier
+	boshManager          boshManager
+	cloudConfigManager   cloudConfigManager
+	runtimeConfigManager runtimeConfigManager
+	stateStore           stateStore
+	envIDManager         envIDManager
+	terraformManager     terraformApplier
 }
 
-func NewAWSUp(boshManager boshManager, cloudConfigManager cloudConfigManager,
+func NewAWSUp(boshManager boshManager, cloudConfigManager cloudConfigManager, runtimeConfigManager runtimeConfigManager,
 	stateStore stateStore, envIDManager envIDManager, terraformManager terraformApplier) AWSUp {
 	return AWSUp{
-		boshManager:        boshManager,
-		cloudConfigManager: cloudConfigManager,
-		stateStore:         stateStore,
-		envIDManager:       envIDManager,
-		terraformManager:   terraformManager,
+		boshManager:          boshManager,
+		cloudConfigManager:   cloudConfigManager,
+		runtimeConfigManager: runtimeConfigManager,
+		stateStore:           stateStore,
+		envIDManager:         envIDManager,
+		terraformManager:     terraformManager,
 	}
 }
 
@@ -38,18 +38,7 @@ func (u AWSUp) Execute(config UpConfig, state storage.State) error {
 		state.NoDirector = true
 	}
 
-	var (
-		err             error
-		opsFileContents []byte
-	)
-	if config.OpsFile != "" {
-		opsFileContents, err = ioutil.ReadFile(config.OpsFile)
-		if err != nil {
-			return fmt.Errorf("error reading ops-file contents: %v", err)
-		}
-	}
-
-	state, err = u.envIDManager.Sync(state, config.Name)
+	state, err := u.envIDManager.Sync(state, config.Name)
 	if err != nil {
 		return err
 	}
@@ -77,6 +66,7 @@ func (u AWSUp) Execute(config UpConfig, state storage.State) error {
 	if !state.NoDirector {
 		if config.Jumpbox {
 			state.Jumpbox.Enabled = true
+			state.Jumpbox.OpsFiles = config.JumpboxOpsFiles
 			state, err = u.boshManager.CreateJumpbox(state, terraformOutputs)
 			if err != nil {
 				return err
@@ -88,7 +78,10 @@ func (u AWSUp) Execute(config UpConfig, state storage.State) error {
 			}
 		}
 
-		state.BOSH.UserOpsFile = string(opsFileContents)
+		state.BOSH.OpsFiles = config.OpsFiles
+		state.BOSH.VarsFiles = config.VarsFiles
+		state.BOSH.Vars = config.Vars
+		state.BOSH.RuntimeConfigOpsFiles = config.RuntimeConfigOpsFiles
 
 		state, err = u.boshManager.CreateDirector(state, terraformOutputs)
 		switch err.(type) {
@@ -114,6 +107,11 @@ func (u AWSUp) Execute(config UpConfig, state storage.State) error {
 		if err != nil {
 			return err
 		}
+
+		err = u.runtimeConfigManager.Update(state)
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
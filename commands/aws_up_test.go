@@ -2,8 +2,6 @@ package commands_test
 
 import (
 	"errors"
-	"io/ioutil"
-	"os"
 
 	"github.com/cloudfoundry/bosh-bootloader/bosh"
 	"github.com/cloudfoundry/bosh-bootloader/commands"
@@ -17,12 +15,13 @@ import (
 var _ = Describe("AWSUp", func() {
 	Describe("Execute", func() {
 		var (
-			command            commands.AWSUp
-			boshManager        *fakes.BOSHManager
-			terraformManager   *fakes.TerraformManager
-			cloudConfigManager *fakes.CloudConfigManager
-			stateStore         *fakes.StateStore
-			envIDManager       *fakes.EnvIDManager
+			command              commands.AWSUp
+			boshManager          *fakes.BOSHManager
+			terraformManager     *fakes.TerraformManager
+			cloudConfigManager   *fakes.CloudConfigManager
+			runtimeConfigManager *fakes.RuntimeConfigManager
+			stateStore           *fakes.StateStore
+			envIDManager         *fakes.EnvIDManager
 		)
 
 		BeforeEach(func() {
@@ -57,6 +56,7 @@ var _ = Describe("AWSUp", func() {
 			}
 
 			cloudConfigManager = &fakes.CloudConfigManager{}
+			runtimeConfigManager = &fakes.RuntimeConfigManager{}
 			stateStore = &fakes.StateStore{}
 
 			envIDManager = &fakes.EnvIDManager{}
@@ -65,7 +65,7 @@ var _ = Describe("AWSUp", func() {
 			}
 
 			command = commands.NewAWSUp(boshManager,
-				cloudConfigManager, stateStore,
+				cloudConfigManager, runtimeConfigManager, stateStore,
 				envIDManager, terraformManager)
 		})
 
@@ -154,6 +154,15 @@ var _ = Describe("AWSUp", func() {
 					Jumpbox: storage.Jumpbox{Enabled: true},
 				}))
 			})
+
+			It("passes the jumpbox ops files to the bosh manager", func() {
+				opsFiles := []storage.UserFile{{Path: "some-jumpbox-ops-file-path", Contents: "some-jumpbox-ops-file-contents"}}
+
+				err := command.Execute(commands.UpConfig{Jumpbox: true, JumpboxOpsFiles: opsFiles}, storage.State{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(boshManager.CreateJumpboxCall.Receives.State.Jumpbox.OpsFiles).To(Equal(opsFiles))
+			})
 		})
 
 		Context("failure cases", func() {
@@ -299,24 +308,52 @@ var _ = Describe("AWSUp", func() {
 			Expect(boshManager.CreateDirectorCall.Receives.State).To(Equal(incomingState))
 		})
 
-		Context("when ops file are passed in via --ops-file flag", func() {
-			It("passes the ops file contents to the bosh manager", func() {
-				opsFile, err := ioutil.TempFile("", "ops-file")
-				Expect(err).NotTo(HaveOccurred())
-
-				opsFilePath := opsFile.Name()
-				opsFileContents := "some-ops-file-contents"
-				err = ioutil.WriteFile(opsFilePath, []byte(opsFileContents), os.ModePerm)
-				Expect(err).NotTo(HaveOccurred())
+		Context("when ops files and vars are passed in", func() {
+			It("passes them to the bosh manager", func() {
+				opsFiles := []storage.UserFile{{Path: "some-ops-file-path", Contents: "some-ops-file-contents"}}
+				varsFiles := []storage.UserFile{{Path: "some-vars-file-path", Contents: "some-vars-file-contents"}}
+				runtimeConfigOpsFiles := []storage.UserFile{{Path: "some-runtime-config-ops-file-path", Contents: "some-runtime-config-ops-file-contents"}}
 
-				err = command.Execute(commands.UpConfig{
-					OpsFile: opsFilePath,
+				err := command.Execute(commands.UpConfig{
+					OpsFiles:              opsFiles,
+					VarsFiles:             varsFiles,
+					Vars:                  []string{"some-var=some-value"},
+					RuntimeConfigOpsFiles: runtimeConfigOpsFiles,
 				}, storage.State{
 					EnvID: "bbl-lake-time-stamp",
 				})
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(boshManager.CreateDirectorCall.Receives.State.BOSH.UserOpsFile).To(Equal("some-ops-file-contents"))
+				Expect(boshManager.CreateDirectorCall.Receives.State.BOSH.OpsFiles).To(Equal(opsFiles))
+				Expect(boshManager.CreateDirectorCall.Receives.State.BOSH.VarsFiles).To(Equal(varsFiles))
+				Expect(boshManager.CreateDirectorCall.Receives.State.BOSH.Vars).To(Equal([]string{"some-var=some-value"}))
+				Expect(boshManager.CreateDirectorCall.Receives.State.BOSH.RuntimeConfigOpsFiles).To(Equal(runtimeConfigOpsFiles))
+			})
+		})
+
+		Describe("runtime config", func() {
+			It("applies the runtime config with the state the cloud config was applied with", func() {
+				err := command.Execute(commands.UpConfig{}, storage.State{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(runtimeConfigManager.UpdateCall.CallCount).To(Equal(1))
+				Expect(runtimeConfigManager.UpdateCall.Receives.State).To(Equal(cloudConfigManager.UpdateCall.Receives.State))
+			})
+
+			It("does not apply the runtime config without a director", func() {
+				terraformManager.ApplyCall.Returns.BBLState.NoDirector = true
+
+				err := command.Execute(commands.UpConfig{NoDirector: true}, storage.State{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(runtimeConfigManager.UpdateCall.CallCount).To(Equal(0))
+			})
+
+			It("returns an error when the runtime config cannot be applied", func() {
+				runtimeConfigManager.UpdateCall.Returns.Error = errors.New("failed to update runtime config")
+
+				err := command.Execute(commands.UpConfig{}, storage.State{})
+				Expect(err).To(MatchError("failed to update runtime config"))
 			})
 		})
 
@@ -414,13 +451,6 @@ var _ = Describe("AWSUp", func() {
 				Expect(err).To(MatchError("cannot parse terraform output"))
 			})
 
-			It("returns an error when the ops file cannot be read", func() {
-				err := command.Execute(commands.UpConfig{
-					OpsFile: "some/fake/path",
-				}, storage.State{})
-				Expect(err).To(MatchError("error reading ops-file contents: open some/fake/path: no such file or directory"))
-			})
-
 			It("returns an error when bosh cannot be deployed", func() {
 				boshManager.CreateDirectorCall.Returns.Error = errors.New("cannot deploy bosh")
 
@@ -11,30 +11,33 @@ type azureClient interface {
 }
 
 type AzureUp struct {
-	azureClient        azureClient
-	boshManager        boshManager
-	cloudConfigManager cloudConfigManager
-	envIDManager       envIDManager
-	logger             logger
-	stateStore         stateStore
-	terraformManager   terraformApplier
+	azureClient          azureClient
+	boshManager          boshManager
+	cloudConfigManager   cloudConfigManager
+	runtimeConfigManager runtimeConfigManager
+	envIDManager         envIDManager
+	logger               logger
+	stateStore           stateStore
+	terraformManager     terraformApplier
 }
 
 func NewAzureUp(azureClient azureClient,
 	boshManager boshManager,
 	cloudConfigManager cloudConfigManager,
+	runtimeConfigManager runtimeConfigManager,
 	envIDManager envIDManager,
 	logger logger,
 	stateStore stateStore,
 	terraformManager terraformApplier) AzureUp {
 	return AzureUp{
-		azureClient:        azureClient,
-		boshManager:        boshManager,
-		cloudConfigManager: cloudConfigManager,
-		envIDManager:       envIDManager,
-		logger:             logger,
-		stateStore:         stateStore,
-		terraformManager:   terraformManager,
+		azureClient:          azureClient,
+		boshManager:          boshManager,
+		cloudConfigManager:   cloudConfigManager,
+		runtimeConfigManager: runtimeConfigManager,
+		envIDManager:         envIDManager,
+		logger:               logger,
+		stateStore:           stateStore,
+		terraformManager:     terraformManager,
 	}
 }
 
@@ -73,6 +76,12 @@ func (u AzureUp) Execute(upConfig UpConfig, state storage.State) error {
 	}
 
 	if !state.NoDirector {
+		state.Jumpbox.OpsFiles = upConfig.JumpboxOpsFiles
+		state.BOSH.OpsFiles = upConfig.OpsFiles
+		state.BOSH.VarsFiles = upConfig.VarsFiles
+		state.BOSH.Vars = upConfig.Vars
+		state.BOSH.RuntimeConfigOpsFiles = upConfig.RuntimeConfigOpsFiles
+
 		state, err = u.boshManager.CreateDirector(state, tfOutputs)
 		if err != nil {
 			return err
@@ -85,6 +94,10 @@ func (u AzureUp) Execute(upConfig UpConfig, state storage.State) error {
 		if err := u.cloudConfigManager.Update(state); err != nil {
 			return err
 		}
+
+		if err := u.runtimeConfigManager.Update(state); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -15,25 +15,27 @@ var _ = Describe("AzureUp", func() {
 	var (
 		azureUp commands.AzureUp
 
-		azureClient        *fakes.AzureClient
-		boshManager        *fakes.BOSHManager
-		cloudConfigManager *fakes.CloudConfigManager
-		envIDManager       *fakes.EnvIDManager
-		logger             *fakes.Logger
-		stateStore         *fakes.StateStore
-		terraformManager   *fakes.TerraformManager
+		azureClient          *fakes.AzureClient
+		boshManager          *fakes.BOSHManager
+		cloudConfigManager   *fakes.CloudConfigManager
+		runtimeConfigManager *fakes.RuntimeConfigManager
+		envIDManager         *fakes.EnvIDManager
+		logger               *fakes.Logger
+		stateStore           *fakes.StateStore
+		terraformManager     *fakes.TerraformManager
 	)
 
 	BeforeEach(func() {
 		azureClient = &fakes.AzureClient{}
 		boshManager = &fakes.BOSHManager{}
 		cloudConfigManager = &fakes.CloudConfigManager{}
+		runtimeConfigManager = &fakes.RuntimeConfigManager{}
 		envIDManager = &fakes.EnvIDManager{}
 		logger = &fakes.Logger{}
 		stateStore = &fakes.StateStore{}
 		terraformManager = &fakes.TerraformManager{}
 
-		azureUp = commands.NewAzureUp(azureClient, boshManager, cloudConfigManager, envIDManager, logger, stateStore, terraformManager)
+		azureUp = commands.NewAzureUp(azureClient, boshManager, cloudConfigManager, runtimeConfigManager, envIDManager, logger, stateStore, terraformManager)
 	})
 
 	Describe("Execute", func() {
@@ -130,6 +132,11 @@ var _ = Describe("AzureUp", func() {
 				Expect(cloudConfigManager.UpdateCall.CallCount).To(Equal(1))
 				Expect(cloudConfigManager.UpdateCall.Receives.State).To(Equal(stateWithBOSH))
 			})
+
+			By("updating the runtime config", func() {
+				Expect(runtimeConfigManager.UpdateCall.CallCount).To(Equal(1))
+				Expect(runtimeConfigManager.UpdateCall.Receives.State).To(Equal(stateWithBOSH))
+			})
 		})
 
 		Context("given invalid credentials", func() {
@@ -286,6 +293,36 @@ var _ = Describe("AzureUp", func() {
 			})
 		})
 
+		Context("when runtime config manager update fails", func() {
+			It("returns an error", func() {
+				runtimeConfigManager.UpdateCall.Returns.Error = errors.New("runtime config update failed")
+				err := azureUp.Execute(commands.UpConfig{}, storage.State{})
+
+				Expect(err).To(MatchError("runtime config update failed"))
+			})
+		})
+
+		Context("when ops files and vars are passed in", func() {
+			It("passes them to the bosh manager", func() {
+				opsFiles := []storage.UserFile{{Path: "some-ops-file-path", Contents: "some-ops-file-contents"}}
+				varsFiles := []storage.UserFile{{Path: "some-vars-file-path", Contents: "some-vars-file-contents"}}
+				jumpboxOpsFiles := []storage.UserFile{{Path: "some-jumpbox-ops-file-path", Contents: "some-jumpbox-ops-file-contents"}}
+
+				err := azureUp.Execute(commands.UpConfig{
+					OpsFiles:        opsFiles,
+					VarsFiles:       varsFiles,
+					Vars:            []string{"some-var=some-value"},
+					JumpboxOpsFiles: jumpboxOpsFiles,
+				}, state)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(boshManager.CreateDirectorCall.Receives.State.BOSH.OpsFiles).To(Equal(opsFiles))
+				Expect(boshManager.CreateDirectorCall.Receives.State.BOSH.VarsFiles).To(Equal(varsFiles))
+				Expect(boshManager.CreateDirectorCall.Receives.State.BOSH.Vars).To(Equal([]string{"some-var=some-value"}))
+				Expect(boshManager.CreateDirectorCall.Receives.State.Jumpbox.OpsFiles).To(Equal(jumpboxOpsFiles))
+			})
+		})
+
 		Context("when the no-director flag is provided", func() {
 			BeforeEach(func() {
 				terraformManager.ApplyCall.Returns.BBLState.NoDirector = true
@@ -5,8 +5,24 @@ const (
 
   --iaas                     IAAS to deploy your BOSH director onto. Valid options: "gcp", "aws" (Defaults to environment variable BBL_IAAS)
   [--name]                   Name to assign to your BOSH director (optional, will be randomly generated)
-  [--ops-file]               Path to BOSH ops file (optional)
+  [--ops-file]               Path to BOSH ops file, applied in the order given (repeatable)
+  [--vars-file]              Path to a YAML file with variables for the ops files (repeatable)
+  [--var]                    Variable for the ops files as name=value (repeatable)
+  [--jumpbox-ops-file]       Path to an ops file for the jumpbox, applied in the order given (repeatable)
+  [--runtime-config-ops-file] Path to an ops file for the runtime config, applied in the order given (repeatable)
   [--no-director]            Skips creating BOSH environment
+  [--dry-run]                Prints the infrastructure changes and manifest diffs without applying them
+  [--terraform-backend]      Keeps terraform state in a terraform backend. Valid options: "s3", "gcs", "azurerm", "local" (optional)
+  [--terraform-backend-config] Setting of the terraform backend as key=value, e.g. bucket=some-bucket (repeatable)
+  [--existing-vpc-id]        Uses an existing AWS VPC instead of creating one (optional)
+  [--existing-network]       Uses an existing GCP network instead of creating one (optional)
+  [--existing-vnet]          Uses an existing Azure virtual network, given as <resource-group>/<vnet> (optional)
+  [--network-cidr]           CIDR of the network, the subnets are carved out of it (optional, defaults to 10.0.0.0/16)
+  [--director-subnet-cidr]   CIDR of the subnet of the BOSH director and jumpbox (optional)
+  [--internal-subnet-cidr]   CIDR of an internal subnet, in availability zone order (repeatable)
+  [--enable]                 Adds an optional infrastructure component, e.g. "s3-endpoint" (repeatable, comma separated)
+  [--disable]                Leaves out an optional infrastructure component, e.g. "flow-logs,kms" (repeatable, comma separated)
+  [--components-file]        Path to a YAML file with the lists of components to "enable" and "disable" (optional)
 
   --aws-access-key-id        AWS Access Key ID to use (Defaults to environment variable BBL_AWS_ACCESS_KEY_ID)
   --aws-secret-access-key    AWS Secret Access Key to use (Defaults to environment variable BBL_AWS_SECRET_ACCESS_KEY)
@@ -87,10 +103,110 @@ const (
 	BOSHDeploymentVarsCommandUsage = "Prints required variables for BOSH deployment"
 
 	CloudConfigUsage = "Prints suggested cloud configuration for BOSH environment"
+
+	RuntimeConfigCommandUsage = `Prints the runtime config bbl applies to the BOSH director
+
+  [--ops-file]               Path to an ops file for the runtime config, applied in the order given (repeatable)
+
+  The runtime config is the BOSH DNS runtime config of bosh-deployment. Without --ops-file, the ops files
+  stored by "bbl up --runtime-config-ops-file" are applied. "bbl up" applies it with the name "bbl".`
+
+	StateCommandUsage = `Manages the bbl-state.json file
+
+  show                 Prints the state with secrets redacted
+    --format           Output format. Valid options: "json", "yaml" (Defaults to "json")
+    --reveal <path>    Shows the redacted field at the given path, e.g. bosh.variables.admin_password (Repeatable)
+  encrypt              Encrypts bbl-state.json with the key in BBL_STATE_KEY
+  decrypt              Decrypts bbl-state.json with the key in BBL_STATE_KEY
+  history              Lists the previous versions of bbl-state.json with the command that wrote them
+  rollback --to <id>   Restores bbl-state.json from the history entry with the given id
+  split                Moves terraform state, create-env state, manifests and vars stores into separate files
+  join                 Moves the separate files back into bbl-state.json
+
+  BBL_STATE_KEY may contain a passphrase or "file:" followed by the path to a key file. Once encrypted,
+  bbl-state.json stays encrypted and every bbl command requires BBL_STATE_KEY.
+
+  The last 50 versions of bbl-state.json are kept in .bbl-history in the state directory until bbl destroy removes them.
+
+  The split layout writes vars/director-vars-store.yml, vars/jumpbox-vars-store.yml,
+  terraform/terraform.tfstate, terraform/latest-output.log, create-env/director-state.json,
+  create-env/jumpbox-state.json, create-env/director-manifest.yml and create-env/jumpbox-manifest.yml
+  next to a slim bbl-state.json. Once split, the state stays split until it is joined.`
+
+	ExportCommandUsage = `Packages the environment into a bundle that can be imported elsewhere
+
+  --output                   Path of the bundle to write, e.g. env.tgz
+
+  The bundle contains bbl-state.json, the generated terraform template, the director and jumpbox
+  manifests and the bosh-deployment and jumpbox-deployment versions. bbl-state.json is encrypted
+  when BBL_STATE_KEY is set.`
+
+	ImportCommandUsage = `Imports an environment bundle written by "bbl export" into the state directory
+
+  bbl import env.tgz --state-dir <dir>
+
+  The bundle must have been exported with the same state version as this bbl.`
+
+	PlanCommandUsage = `Prints what "bbl up" would change without changing anything
+
+  [--ops-file]               Path to BOSH ops file, applied in the order given (repeatable)
+  [--vars-file]              Path to a YAML file with variables for the ops files (repeatable)
+  [--var]                    Variable for the ops files as name=value (repeatable)
+  [--jumpbox-ops-file]       Path to an ops file for the jumpbox, applied in the order given (repeatable)
+  [--no-director]            Plans without a BOSH director
+  [--credhub]                Plans with a jumpbox, credhub and uaa
+
+  Runs terraform plan with the generated template and prints the resources to add, change and destroy,
+  followed by diffs of the director and jumpbox manifests against the ones in bbl-state.json.
+  The manifest diffs use the current terraform outputs. Takes the same IAAS flags as "bbl up".`
+
+	UpgradeDirectorCommandUsage = `Redeploys the jumpbox and BOSH director without changing the infrastructure
+
+  [--jumpbox-only]           Redeploys only the jumpbox
+  [--director-only]          Redeploys only the BOSH director
+
+  Reads the current terraform outputs and runs create-env with the vars stores, ops files and vars
+  in bbl-state.json. Use it to roll out a new stemcell or bosh-deployment version. The cloud config
+  is not updated.`
+
+	ForceUnlockCommandUsage = `Removes the lock on the state directory
+
+  Commands that modify an environment lock the state directory while they run.
+  Only use force-unlock when the lock was left behind by a bbl process that is no longer running.`
+
+	FsckCommandUsage = `Checks bbl-state.json, the terraform outputs and the create-env states for inconsistencies
+
+  --repair                   Applies the safe fixes for problems marked as repairable
+
+  Errors make fsck exit with a non-zero status, warnings do not.`
+
+	DriftCommandUsage = `Checks the infrastructure for changes made outside of bbl
+
+  --json                     Prints the drifted resources as JSON
+
+  Refreshes a copy of the terraform state against the IAAS and prints the resources that were changed
+  or deleted since bbl last applied them. Nothing is changed. Exits with status 2 when drift is found.`
+
+	TerraformCommandUsage = `Runs terraform state commands against the terraform state of the environment
+
+  state list [<address>...]         Lists the resources in the terraform state
+  state show <address>              Prints the attributes of a resource
+  state rm <address>...             Removes resources from the terraform state
+  state mv <source> <destination>   Moves a resource to another address
+  state import <address> <id>       Imports an existing resource into the terraform state
+
+  Runs terraform with the generated template and the IAAS credentials of the environment.
+  rm, mv and import save the updated terraform state to bbl-state.json.`
 )
 
 func (Up) Usage() string { return UpCommandUsage }
 
+func (Plan) Usage() string { return PlanCommandUsage }
+
+func (Drift) Usage() string { return DriftCommandUsage }
+
+func (Terraform) Usage() string { return TerraformCommandUsage }
+
 func (Destroy) Usage() string { return DestroyCommandUsage }
 
 func (CreateLBs) Usage() string { return CreateLBsCommandUsage }
@@ -109,12 +225,26 @@ func (LatestError) Usage() string { return LatestErrorCommandUsage }
 
 func (CloudConfig) Usage() string { return CloudConfigUsage }
 
+func (RuntimeConfig) Usage() string { return RuntimeConfigCommandUsage }
+
 func (BOSHDeploymentVars) Usage() string { return BOSHDeploymentVarsCommandUsage }
 
 func (SSHKey) Usage() string { return SSHKeyCommandUsage }
 
+func (State) Usage() string { return StateCommandUsage }
+
+func (ForceUnlock) Usage() string { return ForceUnlockCommandUsage }
+
+func (Export) Usage() string { return ExportCommandUsage }
+
+func (Import) Usage() string { return ImportCommandUsage }
+
+func (Fsck) Usage() string { return FsckCommandUsage }
+
 func (Rotate) Usage() string { return RotateCommandUsage }
 
+func (UpgradeDirector) Usage() string { return UpgradeDirectorCommandUsage }
+
 func (s StateQuery) Usage() string {
 	switch s.propertyName {
 	case EnvIDPropertyName:
@@ -18,8 +18,24 @@ var _ = Describe("Commands Usage", func() {
 
   --iaas                     IAAS to deploy your BOSH director onto. Valid options: "gcp", "aws" (Defaults to environment variable BBL_IAAS)
   [--name]                   Name to assign to your BOSH director (optional, will be randomly generated)
-  [--ops-file]               Path to BOSH ops file (optional)
+  [--ops-file]               Path to BOSH ops file, applied in the order given (repeatable)
+  [--vars-file]              Path to a YAML file with variables for the ops files (repeatable)
+  [--var]                    Variable for the ops files as name=value (repeatable)
+  [--jumpbox-ops-file]       Path to an ops file for the jumpbox, applied in the order given (repeatable)
+  [--runtime-config-ops-file] Path to an ops file for the runtime config, applied in the order given (repeatable)
   [--no-director]            Skips creating BOSH environment
+  [--dry-run]                Prints the infrastructure changes and manifest diffs without applying them
+  [--terraform-backend]      Keeps terraform state in a terraform backend. Valid options: "s3", "gcs", "azurerm", "local" (optional)
+  [--terraform-backend-config] Setting of the terraform backend as key=value, e.g. bucket=some-bucket (repeatable)
+  [--existing-vpc-id]        Uses an existing AWS VPC instead of creating one (optional)
+  [--existing-network]       Uses an existing GCP network instead of creating one (optional)
+  [--existing-vnet]          Uses an existing Azure virtual network, given as <resource-group>/<vnet> (optional)
+  [--network-cidr]           CIDR of the network, the subnets are carved out of it (optional, defaults to 10.0.0.0/16)
+  [--director-subnet-cidr]   CIDR of the subnet of the BOSH director and jumpbox (optional)
+  [--internal-subnet-cidr]   CIDR of an internal subnet, in availability zone order (repeatable)
+  [--enable]                 Adds an optional infrastructure component, e.g. "s3-endpoint" (repeatable, comma separated)
+  [--disable]                Leaves out an optional infrastructure component, e.g. "flow-logs,kms" (repeatable, comma separated)
+  [--components-file]        Path to a YAML file with the lists of components to "enable" and "disable" (optional)
 
   --aws-access-key-id        AWS Access Key ID to use (Defaults to environment variable BBL_AWS_ACCESS_KEY_ID)
   --aws-secret-access-key    AWS Secret Access Key to use (Defaults to environment variable BBL_AWS_SECRET_ACCESS_KEY)
@@ -40,6 +56,25 @@ var _ = Describe("Commands Usage", func() {
 		})
 	})
 
+	Describe("Terraform", func() {
+		Describe("Usage", func() {
+			It("returns string describing usage", func() {
+				command := commands.Terraform{}
+				usageText := command.Usage()
+				Expect(usageText).To(Equal(`Runs terraform state commands against the terraform state of the environment
+
+  state list [<address>...]         Lists the resources in the terraform state
+  state show <address>              Prints the attributes of a resource
+  state rm <address>...             Removes resources from the terraform state
+  state mv <source> <destination>   Moves a resource to another address
+  state import <address> <id>       Imports an existing resource into the terraform state
+
+  Runs terraform with the generated template and the IAAS credentials of the environment.
+  rm, mv and import save the updated terraform state to bbl-state.json.`))
+			})
+		})
+	})
+
 	Describe("Create LBs", func() {
 		Describe("Usage", func() {
 			It("returns string describing usage", func() {
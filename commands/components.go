@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+	yaml "gopkg.in/yaml.v2"
+)
+
+type componentsFile struct {
+	Enable  []string `yaml:"enable"`
+	Disable []string `yaml:"disable"`
+}
+
+// withComponents records the components enabled or disabled with
+// --components-file, --enable and --disable in the state. The flags take
+// precedence over the file.
+func withComponents(config UpConfig, state storage.State) (storage.State, error) {
+	var file componentsFile
+	if config.ComponentsFile != "" {
+		contents, err := ioutil.ReadFile(config.ComponentsFile)
+		if err != nil {
+			return storage.State{}, fmt.Errorf("read components file: %s", err)
+		}
+
+		err = yaml.Unmarshal(contents, &file)
+		if err != nil {
+			return storage.State{}, fmt.Errorf("parse components file: %s", err)
+		}
+	}
+
+	changes := map[string]bool{}
+
+	for _, names := range [][]string{file.Enable, file.Disable, config.EnableComponents, config.DisableComponents} {
+		err := storage.ValidateComponentNames(state.IAAS, splitComponentNames(names))
+		if err != nil {
+			return storage.State{}, err
+		}
+	}
+
+	err := setComponents(changes, file.Enable, file.Disable)
+	if err != nil {
+		return storage.State{}, fmt.Errorf("components file: %s", err)
+	}
+
+	err = setComponents(changes, config.EnableComponents, config.DisableComponents)
+	if err != nil {
+		return storage.State{}, err
+	}
+
+	if len(changes) == 0 {
+		return state, nil
+	}
+
+	// The director disks are encrypted with the KMS key, so they could not
+	// be read anymore once terraform scheduled its deletion.
+	kmsEnabled, ok := changes[storage.KMSComponent]
+	if ok && !kmsEnabled && state.EnvID != "" && state.ComponentEnabled(storage.KMSComponent) {
+		return storage.State{}, errors.New("The kms component cannot be disabled for an existing environment.")
+	}
+
+	components := storage.Components{}
+	for name, enabled := range state.Components {
+		components[name] = enabled
+	}
+	for name, enabled := range changes {
+		components[name] = enabled
+	}
+	state.Components = components
+
+	return state, nil
+}
+
+func setComponents(components map[string]bool, enable, disable []string) error {
+	disabled := map[string]bool{}
+	for _, name := range splitComponentNames(disable) {
+		disabled[name] = true
+	}
+
+	for _, name := range splitComponentNames(enable) {
+		if disabled[name] {
+			return fmt.Errorf("component %q cannot be both enabled and disabled", name)
+		}
+		components[name] = true
+	}
+
+	for name := range disabled {
+		components[name] = false
+	}
+
+	return nil
+}
+
+// splitComponentNames accepts both repeated flags and comma separated
+// lists, e.g. --disable flow-logs,kms.
+func splitComponentNames(values []string) []string {
+	var names []string
+	for _, value := range values {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+
+	return names
+}
@@ -3,6 +3,7 @@ package commands
 import (
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 
 	"github.com/cloudfoundry/bosh-bootloader/aws/cloudformation"
@@ -32,6 +33,7 @@ type destroyConfig struct {
 
 type NetworkDeletionValidator interface {
 	ValidateSafeToDelete(networkName string, envID string) error
+	ValidateSafeToDeleteSubnets(subnets []string, envID string) error
 }
 
 func NewDestroy(logger logger, stdin io.Reader,
@@ -85,7 +87,7 @@ func (d Destroy) CheckFastFails(subcommandFlags []string, state storage.State) e
 
 	var networkName string
 
-	if state.IAAS == "aws" && state.TFState == "" {
+	if state.IAAS == "aws" && !state.HasTerraformState() {
 		stackExists := true
 		var err error
 		stack, err := d.stackManager.Describe(state.Stack.Name)
@@ -109,6 +111,10 @@ func (d Destroy) CheckFastFails(subcommandFlags []string, state storage.State) e
 			return nil
 		}
 
+		if state.ExistingNetwork() != "" {
+			return d.validateSubnetsSafeToDelete(terraformOutputs, state)
+		}
+
 		if state.IAAS == "gcp" {
 			networkNameOutput, ok := terraformOutputs["network_name"]
 			if !ok {
@@ -130,6 +136,40 @@ func (d Destroy) CheckFastFails(subcommandFlags []string, state storage.State) e
 	return nil
 }
 
+// validateSubnetsSafeToDelete checks an environment in a shared network. The
+// network itself is left alone on destroy and may hold VMs that do not
+// belong to the environment, so only the environment's subnets are checked.
+func (d Destroy) validateSubnetsSafeToDelete(terraformOutputs map[string]interface{}, state storage.State) error {
+	var subnets []string
+
+	switch state.IAAS {
+	case "gcp":
+		if subnetworkName, ok := terraformOutputs["subnetwork_name"].(string); ok {
+			subnets = append(subnets, subnetworkName)
+		}
+	case "aws":
+		if boshSubnetID, ok := terraformOutputs["bosh_subnet_id"].(string); ok {
+			subnets = append(subnets, boshSubnetID)
+		}
+
+		internalSubnetIDs, _ := terraformOutputs["internal_az_subnet_id_mapping"].(map[string]interface{})
+		var azs []string
+		for az := range internalSubnetIDs {
+			azs = append(azs, az)
+		}
+		sort.Strings(azs)
+		for _, az := range azs {
+			if subnetID, ok := internalSubnetIDs[az].(string); ok {
+				subnets = append(subnets, subnetID)
+			}
+		}
+	default:
+		return nil
+	}
+
+	return d.networkDeletionValidator.ValidateSafeToDeleteSubnets(subnets, state.EnvID)
+}
+
 func (d Destroy) Execute(subcommandFlags []string, state storage.State) error {
 	config, err := d.parseFlags(subcommandFlags)
 	if err != nil {
@@ -189,7 +229,7 @@ func (d Destroy) Execute(subcommandFlags []string, state storage.State) error {
 		return err
 	}
 
-	if state.IAAS == "aws" && state.TFState == "" {
+	if state.IAAS == "aws" && !state.HasTerraformState() {
 		state, err = d.deleteStack(stack, state)
 		if err != nil {
 			return err
@@ -182,6 +182,20 @@ var _ = Describe("Destroy", func() {
 				Expect(err).To(MatchError("validation failed"))
 			})
 
+			Context("when the environment uses an existing network", func() {
+				It("only checks the environment's subnetwork", func() {
+					networkDeletionValidator.ValidateSafeToDeleteSubnetsCall.Returns.Error = errors.New("validation failed")
+
+					bblState.GCP.ExistingNetwork = "some-network-name"
+					err := destroy.CheckFastFails([]string{}, bblState)
+					Expect(err).To(MatchError("validation failed"))
+
+					Expect(networkDeletionValidator.ValidateSafeToDeleteCall.CallCount).To(Equal(0))
+					Expect(networkDeletionValidator.ValidateSafeToDeleteSubnetsCall.Receives.Subnets).To(Equal([]string{"some-subnetwork-name"}))
+					Expect(networkDeletionValidator.ValidateSafeToDeleteSubnetsCall.Receives.EnvID).To(Equal("some-env-id"))
+				})
+			})
+
 			Context("when terraform output provider fails to get terraform outputs", func() {
 				It("does not fast fail", func() {
 					terraformManager.GetOutputsCall.Returns.Error = errors.New("terraform output provider failed")
@@ -301,6 +315,32 @@ var _ = Describe("Destroy", func() {
 					Expect(networkDeletionValidator.ValidateSafeToDeleteCall.Receives.NetworkName).To(Equal("some-vpc-id"))
 					Expect(networkDeletionValidator.ValidateSafeToDeleteCall.Receives.EnvID).To(Equal("some-env-id"))
 				})
+
+				Context("when the environment uses an existing vpc", func() {
+					It("only checks the environment's subnets", func() {
+						terraformManager.GetOutputsCall.Returns.Outputs = map[string]interface{}{
+							"vpc_id":         "some-vpc-id",
+							"bosh_subnet_id": "some-bosh-subnet-id",
+							"internal_az_subnet_id_mapping": map[string]interface{}{
+								"z2": "some-internal-subnet-id-2",
+								"z1": "some-internal-subnet-id-1",
+							},
+						}
+						networkDeletionValidator.ValidateSafeToDeleteSubnetsCall.Returns.Error = errors.New("subnets are not safe to delete")
+
+						state.AWS.ExistingVPCID = "some-vpc-id"
+						err := destroy.CheckFastFails([]string{}, state)
+						Expect(err).To(MatchError("subnets are not safe to delete"))
+
+						Expect(networkDeletionValidator.ValidateSafeToDeleteCall.CallCount).To(Equal(0))
+						Expect(networkDeletionValidator.ValidateSafeToDeleteSubnetsCall.Receives.Subnets).To(Equal([]string{
+							"some-bosh-subnet-id",
+							"some-internal-subnet-id-1",
+							"some-internal-subnet-id-2",
+						}))
+						Expect(networkDeletionValidator.ValidateSafeToDeleteSubnetsCall.Receives.EnvID).To(Equal("some-env-id"))
+					})
+				})
 			})
 		})
 	})
@@ -0,0 +1,157 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cloudfoundry/bosh-bootloader/flags"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+	"github.com/cloudfoundry/bosh-bootloader/terraform"
+)
+
+// DriftExitCode is the exit status of bbl drift when the infrastructure has
+// drifted, set apart from the status of other errors for pipelines.
+const DriftExitCode = 2
+
+var driftStatusSymbols = map[string]string{
+	terraform.DriftChanged: "~",
+	terraform.DriftDeleted: "-",
+}
+
+type Drift struct {
+	logger         logger
+	stateValidator stateValidator
+	driftDetector  driftDetector
+}
+
+type driftDetector interface {
+	Drift(storage.State) (terraform.Drift, error)
+}
+
+// DriftDetectedError is returned by bbl drift when it finds drift.
+type DriftDetectedError struct {
+	resources int
+}
+
+func (e DriftDetectedError) Error() string {
+	return fmt.Sprintf("%d resource(s) drifted from the terraform state", e.resources)
+}
+
+func (DriftDetectedError) ExitCode() int {
+	return DriftExitCode
+}
+
+func NewDrift(logger logger, stateValidator stateValidator, driftDetector driftDetector) Drift {
+	return Drift{
+		logger:         logger,
+		stateValidator: stateValidator,
+		driftDetector:  driftDetector,
+	}
+}
+
+func (d Drift) CheckFastFails(subcommandFlags []string, state storage.State) error {
+	_, err := parseDriftJSON(subcommandFlags)
+	if err != nil {
+		return err
+	}
+
+	err = d.stateValidator.Validate()
+	if err != nil {
+		return err
+	}
+
+	if !state.HasTerraformState() {
+		return errors.New("bbl drift needs terraform state, run `bbl up` to create the environment")
+	}
+
+	return nil
+}
+
+func (d Drift) Execute(subcommandFlags []string, state storage.State) error {
+	jsonOutput, err := parseDriftJSON(subcommandFlags)
+	if err != nil {
+		return err
+	}
+
+	drift, err := d.driftDetector.Drift(state)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		err = d.printJSON(drift)
+	} else {
+		d.print(drift)
+	}
+	if err != nil {
+		return err
+	}
+
+	if drift.HasDrift() {
+		return DriftDetectedError{resources: len(drift.Resources)}
+	}
+
+	return nil
+}
+
+func (d Drift) print(drift terraform.Drift) {
+	if !drift.HasDrift() {
+		d.logger.Println("no drift detected")
+		return
+	}
+
+	for _, resource := range drift.Resources {
+		switch resource.Status {
+		case terraform.DriftDeleted:
+			d.logger.Println(fmt.Sprintf("%s %s has been deleted", driftStatusSymbols[resource.Status], resource.Address))
+		default:
+			d.logger.Println(fmt.Sprintf("%s %s has changed", driftStatusSymbols[resource.Status], resource.Address))
+		}
+
+		for _, attribute := range resource.Attributes {
+			d.logger.Println(fmt.Sprintf("    %s: %q => %q", attribute.Name, attribute.Stored, attribute.Actual))
+		}
+	}
+}
+
+func (d Drift) printJSON(drift terraform.Drift) error {
+	resources := drift.Resources
+	if resources == nil {
+		resources = []terraform.DriftedResource{}
+	}
+
+	buffer := &bytes.Buffer{}
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+
+	err := encoder.Encode(struct {
+		Drifted   bool                        `json:"drifted"`
+		Resources []terraform.DriftedResource `json:"resources"`
+	}{
+		Drifted:   drift.HasDrift(),
+		Resources: resources,
+	})
+	if err != nil {
+		return err
+	}
+
+	d.logger.Println(strings.TrimSuffix(buffer.String(), "\n"))
+	return nil
+}
+
+func parseDriftJSON(subcommandFlags []string) (bool, error) {
+	var jsonOutput bool
+	driftFlags := flags.New("drift")
+	driftFlags.Bool(&jsonOutput, "", "json", false)
+
+	err := driftFlags.Parse(subcommandFlags)
+	if err != nil {
+		return false, err
+	}
+
+	return jsonOutput, nil
+}
@@ -0,0 +1,132 @@
+package commands_test
+
+import (
+	"errors"
+
+	"github.com/cloudfoundry/bosh-bootloader/commands"
+	"github.com/cloudfoundry/bosh-bootloader/fakes"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+	"github.com/cloudfoundry/bosh-bootloader/terraform"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Drift", func() {
+	var (
+		logger           *fakes.Logger
+		stateValidator   *fakes.StateValidator
+		terraformManager *fakes.TerraformManager
+
+		command commands.Drift
+		state   storage.State
+		drift   terraform.Drift
+	)
+
+	BeforeEach(func() {
+		logger = &fakes.Logger{}
+		stateValidator = &fakes.StateValidator{}
+		terraformManager = &fakes.TerraformManager{}
+
+		command = commands.NewDrift(logger, stateValidator, terraformManager)
+		state = storage.State{IAAS: "aws", TFState: "some-tf-state"}
+
+		drift = terraform.Drift{
+			Resources: []terraform.DriftedResource{
+				{
+					Address: "aws_security_group.internal_security_group",
+					Status:  terraform.DriftChanged,
+					Attributes: []terraform.DriftedAttribute{
+						{Name: "ingress.#", Stored: "2", Actual: "3"},
+					},
+				},
+				{
+					Address: "aws_instance.nat",
+					Status:  terraform.DriftDeleted,
+				},
+			},
+		}
+	})
+
+	Describe("CheckFastFails", func() {
+		It("returns an error when the state does not exist", func() {
+			stateValidator.ValidateCall.Returns.Error = errors.New("state file not found")
+
+			err := command.CheckFastFails([]string{}, state)
+			Expect(err).To(MatchError("state file not found"))
+		})
+
+		It("returns an error when there is no terraform state", func() {
+			err := command.CheckFastFails([]string{}, storage.State{IAAS: "aws"})
+			Expect(err).To(MatchError("bbl drift needs terraform state, run `bbl up` to create the environment"))
+		})
+
+		It("returns an error when undefined flags are passed", func() {
+			err := command.CheckFastFails([]string{"--foo"}, state)
+			Expect(err).To(MatchError("flag provided but not defined: -foo"))
+		})
+	})
+
+	Describe("Execute", func() {
+		It("prints that there is no drift", func() {
+			err := command.Execute([]string{}, state)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(terraformManager.DriftCall.Receives.BBLState).To(Equal(state))
+			Expect(logger.PrintlnCall.Messages).To(Equal([]string{"no drift detected"}))
+		})
+
+		It("prints the drifted resources and fails with the drift exit code", func() {
+			terraformManager.DriftCall.Returns.Drift = drift
+
+			err := command.Execute([]string{}, state)
+			Expect(err).To(MatchError("2 resource(s) drifted from the terraform state"))
+			Expect(err.(commands.DriftDetectedError).ExitCode()).To(Equal(commands.DriftExitCode))
+
+			Expect(logger.PrintlnCall.Messages).To(Equal([]string{
+				"~ aws_security_group.internal_security_group has changed",
+				`    ingress.#: "2" => "3"`,
+				"- aws_instance.nat has been deleted",
+			}))
+		})
+
+		Context("when --json is passed", func() {
+			It("prints the drifted resources as JSON", func() {
+				terraformManager.DriftCall.Returns.Drift = drift
+
+				err := command.Execute([]string{"--json"}, state)
+				Expect(err).To(BeAssignableToTypeOf(commands.DriftDetectedError{}))
+
+				Expect(logger.PrintlnCall.Messages).To(HaveLen(1))
+				Expect(logger.PrintlnCall.Messages[0]).To(MatchJSON(`{
+					"drifted": true,
+					"resources": [
+						{
+							"address": "aws_security_group.internal_security_group",
+							"status": "changed",
+							"attributes": [{"name": "ingress.#", "stored": "2", "actual": "3"}]
+						},
+						{
+							"address": "aws_instance.nat",
+							"status": "deleted"
+						}
+					]
+				}`))
+			})
+
+			It("prints an empty list when there is no drift", func() {
+				err := command.Execute([]string{"--json"}, state)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(logger.PrintlnCall.Messages[0]).To(MatchJSON(`{"drifted": false, "resources": []}`))
+			})
+		})
+
+		It("returns an error when the drift cannot be detected", func() {
+			terraformManager.DriftCall.Returns.Error = errors.New("failed to refresh")
+
+			err := command.Execute([]string{}, state)
+			Expect(err).To(MatchError("failed to refresh"))
+		})
+	})
+})
@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+)
+
+// withExistingNetwork records the pre-provisioned network requested by the
+// --existing-vpc-id, --existing-network and --existing-vnet flags in the
+// state, so that terraform adopts it instead of creating a network.
+func withExistingNetwork(config UpConfig, state storage.State) (storage.State, error) {
+	existingNetworkFlags := []struct {
+		iaas  string
+		name  string
+		value string
+	}{
+		{"aws", "--existing-vpc-id", config.ExistingVPCID},
+		{"gcp", "--existing-network", config.ExistingNetwork},
+		{"azure", "--existing-vnet", config.ExistingVNet},
+	}
+
+	for _, flag := range existingNetworkFlags {
+		if flag.value != "" && flag.iaas != state.IAAS {
+			return storage.State{}, fmt.Errorf("%s can only be used with --iaas %s", flag.name, flag.iaas)
+		}
+	}
+
+	switch {
+	case config.ExistingVPCID != "":
+		state.AWS.ExistingVPCID = config.ExistingVPCID
+	case config.ExistingNetwork != "":
+		state.GCP.ExistingNetwork = config.ExistingNetwork
+	case config.ExistingVNet != "":
+		parts := strings.Split(config.ExistingVNet, "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return storage.State{}, errors.New("--existing-vnet must be given as <resource-group>/<vnet>")
+		}
+		state.Azure.ExistingVNetResourceGroup = parts[0]
+		state.Azure.ExistingVNet = parts[1]
+	}
+
+	return state, nil
+}
@@ -0,0 +1,120 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cloudfoundry/bosh-bootloader/flags"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+)
+
+type Export struct {
+	logger            logger
+	stateValidator    stateValidator
+	terraform         terraformTemplater
+	bblVersion        string
+	componentVersions map[string]string
+}
+
+type terraformTemplater interface {
+	GetTemplate(storage.State) string
+}
+
+func NewExport(logger logger, stateValidator stateValidator, terraform terraformTemplater, bblVersion string, componentVersions map[string]string) Export {
+	return Export{
+		logger:            logger,
+		stateValidator:    stateValidator,
+		terraform:         terraform,
+		bblVersion:        bblVersion,
+		componentVersions: componentVersions,
+	}
+}
+
+func (e Export) CheckFastFails(subcommandFlags []string, state storage.State) error {
+	output, err := parseExportOutput(subcommandFlags)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		return errors.New("--output must be provided")
+	}
+
+	return e.stateValidator.Validate()
+}
+
+func (e Export) Execute(subcommandFlags []string, state storage.State) error {
+	output, err := parseExportOutput(subcommandFlags)
+	if err != nil {
+		return err
+	}
+
+	bundle := storage.Bundle{
+		Metadata: storage.BundleMetadata{
+			StateVersion:      state.Version,
+			IAAS:              state.IAAS,
+			EnvID:             state.EnvID,
+			BBLVersion:        e.bblVersion,
+			ComponentVersions: e.deployedVersions(state),
+			CreatedAt:         time.Now().UTC(),
+		},
+		State:             state,
+		TerraformTemplate: e.terraform.GetTemplate(state),
+	}
+
+	file, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	err = storage.WriteBundle(file, bundle)
+	if err != nil {
+		return err
+	}
+
+	e.logger.Step("exported %s to %s", state.EnvID, output)
+	return nil
+}
+
+// deployedVersions returns the component versions of bbl, with the
+// bosh-deployment and jumpbox-deployment the environment was last deployed
+// from in their place when the state records them.
+func (e Export) deployedVersions(state storage.State) map[string]string {
+	versions := map[string]string{}
+	for name, version := range e.componentVersions {
+		versions[name] = version
+	}
+
+	sources := map[string]*storage.DeploymentSource{
+		"bosh-deployment":    state.BOSH.DeploymentSource,
+		"jumpbox-deployment": state.Jumpbox.DeploymentSource,
+	}
+	for name, source := range sources {
+		if source == nil {
+			continue
+		}
+
+		versions[name] = source.Source
+		if source.SHA != "" {
+			versions[name] = fmt.Sprintf("%s@%s", source.Source, source.SHA)
+		}
+	}
+
+	return versions
+}
+
+func parseExportOutput(subcommandFlags []string) (string, error) {
+	var output string
+	exportFlags := flags.New("export")
+	exportFlags.String(&output, "output", "")
+
+	err := exportFlags.Parse(subcommandFlags)
+	if err != nil {
+		return "", err
+	}
+
+	return output, nil
+}
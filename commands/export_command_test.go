@@ -0,0 +1,109 @@
+package commands_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/bosh-bootloader/commands"
+	"github.com/cloudfoundry/bosh-bootloader/fakes"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Export", func() {
+	var (
+		logger           *fakes.Logger
+		stateValidator   *fakes.StateValidator
+		terraformManager *fakes.TerraformManager
+		output           string
+		state            storage.State
+
+		command commands.Export
+	)
+
+	BeforeEach(func() {
+		logger = &fakes.Logger{}
+		stateValidator = &fakes.StateValidator{}
+		terraformManager = &fakes.TerraformManager{}
+		terraformManager.GetTemplateCall.Returns.Template = "some-terraform-template"
+
+		tempDir, err := ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+		output = filepath.Join(tempDir, "env.tgz")
+
+		state = storage.State{
+			Version: storage.STATE_VERSION,
+			IAAS:    "gcp",
+			EnvID:   "some-env-id",
+		}
+
+		command = commands.NewExport(logger, stateValidator, terraformManager, "some-bbl-version", map[string]string{
+			"bosh-deployment": "some-bosh-deployment-sha",
+		})
+	})
+
+	Describe("CheckFastFails", func() {
+		It("returns an error when --output is missing", func() {
+			err := command.CheckFastFails([]string{}, state)
+			Expect(err).To(MatchError("--output must be provided"))
+		})
+
+		It("returns an error when the state does not exist", func() {
+			stateValidator.ValidateCall.Returns.Error = errors.New("failed to validate state")
+
+			err := command.CheckFastFails([]string{"--output", output}, state)
+			Expect(err).To(MatchError("failed to validate state"))
+		})
+	})
+
+	Describe("Execute", func() {
+		It("writes a bundle of the environment", func() {
+			err := command.Execute([]string{"--output", output}, state)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(terraformManager.GetTemplateCall.Receives.BBLState).To(Equal(state))
+			Expect(logger.StepCall.Messages).To(ContainElement("exported some-env-id to " + output))
+
+			file, err := os.Open(output)
+			Expect(err).NotTo(HaveOccurred())
+			defer file.Close()
+
+			bundle, err := storage.ReadBundle(file)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bundle.State).To(Equal(state))
+			Expect(bundle.TerraformTemplate).To(Equal("some-terraform-template"))
+			Expect(bundle.Metadata.BBLVersion).To(Equal("some-bbl-version"))
+			Expect(bundle.Metadata.ComponentVersions).To(Equal(map[string]string{
+				"bosh-deployment": "some-bosh-deployment-sha",
+			}))
+		})
+
+		It("records the deployments the environment was deployed from", func() {
+			state.BOSH.DeploymentSource = &storage.DeploymentSource{Source: "/some/bosh-deployment", SHA: "some-checkout-sha"}
+			state.Jumpbox.DeploymentSource = &storage.DeploymentSource{Source: "/some/jumpbox-deployment"}
+
+			err := command.Execute([]string{"--output", output}, state)
+			Expect(err).NotTo(HaveOccurred())
+
+			file, err := os.Open(output)
+			Expect(err).NotTo(HaveOccurred())
+			defer file.Close()
+
+			bundle, err := storage.ReadBundle(file)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bundle.Metadata.ComponentVersions).To(Equal(map[string]string{
+				"bosh-deployment":    "/some/bosh-deployment@some-checkout-sha",
+				"jumpbox-deployment": "/some/jumpbox-deployment",
+			}))
+		})
+
+		It("returns an error when the bundle cannot be written", func() {
+			err := command.Execute([]string{"--output", "/some/missing/dir/env.tgz"}, state)
+			Expect(err).To(MatchError(ContainSubstring("no such file or directory")))
+		})
+	})
+})
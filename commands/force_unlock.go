@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+)
+
+type ForceUnlock struct {
+	logger        logger
+	stateUnlocker stateUnlocker
+}
+
+type stateUnlocker interface {
+	ForceUnlock() (storage.LockInfo, error)
+}
+
+func NewForceUnlock(logger logger, stateUnlocker stateUnlocker) ForceUnlock {
+	return ForceUnlock{
+		logger:        logger,
+		stateUnlocker: stateUnlocker,
+	}
+}
+
+func (f ForceUnlock) CheckFastFails(subcommandFlags []string, state storage.State) error {
+	return nil
+}
+
+func (f ForceUnlock) Execute(subcommandFlags []string, state storage.State) error {
+	holder, err := f.stateUnlocker.ForceUnlock()
+	if err != nil {
+		return fmt.Errorf("force unlock: %s", err)
+	}
+
+	if holder.Command == "" {
+		f.logger.Step("removed unreadable state lock")
+		return nil
+	}
+
+	f.logger.Step("removed state lock held by `bbl %s` (pid %d on %s) since %s",
+		holder.Command, holder.PID, holder.Host, holder.StartedAt.Format(time.RFC3339))
+
+	return nil
+}
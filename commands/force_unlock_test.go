@@ -0,0 +1,60 @@
+package commands_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/cloudfoundry/bosh-bootloader/commands"
+	"github.com/cloudfoundry/bosh-bootloader/fakes"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ForceUnlock", func() {
+	var (
+		logger        *fakes.Logger
+		stateUnlocker *fakes.StateUnlocker
+
+		command commands.ForceUnlock
+	)
+
+	BeforeEach(func() {
+		logger = &fakes.Logger{}
+		stateUnlocker = &fakes.StateUnlocker{}
+
+		command = commands.NewForceUnlock(logger, stateUnlocker)
+	})
+
+	Describe("Execute", func() {
+		It("removes the lock and prints its previous holder", func() {
+			stateUnlocker.ForceUnlockCall.Returns.Holder = storage.LockInfo{
+				PID:       1234,
+				Host:      "some-host",
+				Command:   "up",
+				StartedAt: time.Date(2017, time.June, 1, 12, 0, 0, 0, time.UTC),
+			}
+
+			err := command.Execute([]string{}, storage.State{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(stateUnlocker.ForceUnlockCall.CallCount).To(Equal(1))
+			Expect(logger.StepCall.Messages).To(ContainElement("removed state lock held by `bbl up` (pid 1234 on some-host) since 2017-06-01T12:00:00Z"))
+		})
+
+		It("reports when the lock could not be read", func() {
+			err := command.Execute([]string{}, storage.State{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(logger.StepCall.Messages).To(ContainElement("removed unreadable state lock"))
+		})
+
+		It("returns an error when the lock cannot be removed", func() {
+			stateUnlocker.ForceUnlockCall.Returns.Error = errors.New("not locked")
+
+			err := command.Execute([]string{}, storage.State{})
+			Expect(err).To(MatchError("force unlock: not locked"))
+		})
+	})
+})
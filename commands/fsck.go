@@ -0,0 +1,127 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/cloudfoundry/bosh-bootloader/flags"
+	"github.com/cloudfoundry/bosh-bootloader/fsck"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+)
+
+type Fsck struct {
+	logger             logger
+	stateValidator     stateValidator
+	terraformOutputter terraformOutputter
+	stateChecker       stateChecker
+	stateStore         stateStore
+}
+
+type stateChecker interface {
+	Check(storage.State, map[string]interface{}) []fsck.Problem
+	Repair(storage.State, map[string]interface{}) (storage.State, bool)
+}
+
+func NewFsck(logger logger, stateValidator stateValidator, terraformOutputter terraformOutputter,
+	stateChecker stateChecker, stateStore stateStore) Fsck {
+	return Fsck{
+		logger:             logger,
+		stateValidator:     stateValidator,
+		terraformOutputter: terraformOutputter,
+		stateChecker:       stateChecker,
+		stateStore:         stateStore,
+	}
+}
+
+func (f Fsck) CheckFastFails(subcommandFlags []string, state storage.State) error {
+	_, err := parseFsckRepair(subcommandFlags)
+	if err != nil {
+		return err
+	}
+
+	return f.stateValidator.Validate()
+}
+
+func (f Fsck) Execute(subcommandFlags []string, state storage.State) error {
+	repair, err := parseFsckRepair(subcommandFlags)
+	if err != nil {
+		return err
+	}
+
+	var terraformOutputs map[string]interface{}
+	var outputsProblem *fsck.Problem
+	if state.HasTerraformState() {
+		terraformOutputs, err = f.terraformOutputter.GetOutputs(state)
+		if err != nil {
+			outputsProblem = &fsck.Problem{
+				Check:    "terraform-outputs",
+				Severity: fsck.SeverityError,
+				Message:  fmt.Sprintf("terraform outputs cannot be read: %s", err),
+			}
+		}
+	}
+
+	problems := f.stateChecker.Check(state, terraformOutputs)
+
+	if repair && hasRepairable(problems) {
+		repairedState, repaired := f.stateChecker.Repair(state, terraformOutputs)
+		if repaired {
+			err = f.stateStore.Set(repairedState)
+			if err != nil {
+				return fmt.Errorf("save repaired state: %s", err)
+			}
+			f.logger.Step("repaired bbl-state.json")
+
+			problems = f.stateChecker.Check(repairedState, terraformOutputs)
+		}
+	}
+
+	if outputsProblem != nil {
+		problems = append([]fsck.Problem{*outputsProblem}, problems...)
+	}
+
+	if len(problems) == 0 {
+		f.logger.Println("no problems found")
+		return nil
+	}
+
+	errorCount := 0
+	for _, problem := range problems {
+		if problem.Severity == fsck.SeverityError {
+			errorCount++
+		}
+
+		line := fmt.Sprintf("[%s] %s: %s", problem.Severity, problem.Check, problem.Message)
+		if problem.Repairable && !repair {
+			line += " (repairable with --repair)"
+		}
+		f.logger.Println(line)
+	}
+
+	if errorCount > 0 {
+		return fmt.Errorf("found %d error(s) in bbl-state.json", errorCount)
+	}
+
+	return nil
+}
+
+func hasRepairable(problems []fsck.Problem) bool {
+	for _, problem := range problems {
+		if problem.Repairable {
+			return true
+		}
+	}
+	return false
+}
+
+func parseFsckRepair(subcommandFlags []string) (bool, error) {
+	var repair bool
+	fsckFlags := flags.New("fsck")
+	fsckFlags.Bool(&repair, "", "repair", false)
+
+	err := fsckFlags.Parse(subcommandFlags)
+	if err != nil {
+		return false, err
+	}
+
+	return repair, nil
+}
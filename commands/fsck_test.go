@@ -0,0 +1,158 @@
+package commands_test
+
+import (
+	"errors"
+
+	"github.com/cloudfoundry/bosh-bootloader/commands"
+	"github.com/cloudfoundry/bosh-bootloader/fakes"
+	"github.com/cloudfoundry/bosh-bootloader/fsck"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fsck", func() {
+	var (
+		logger           *fakes.Logger
+		stateValidator   *fakes.StateValidator
+		terraformManager *fakes.TerraformManager
+		stateChecker     *fakes.StateChecker
+		stateStore       *fakes.StateStore
+
+		command commands.Fsck
+		state   storage.State
+	)
+
+	BeforeEach(func() {
+		logger = &fakes.Logger{}
+		stateValidator = &fakes.StateValidator{}
+		terraformManager = &fakes.TerraformManager{}
+		stateChecker = &fakes.StateChecker{}
+		stateStore = &fakes.StateStore{}
+
+		command = commands.NewFsck(logger, stateValidator, terraformManager, stateChecker, stateStore)
+		state = storage.State{IAAS: "gcp", TFState: "some-tf-state"}
+
+		terraformManager.GetOutputsCall.Returns.Outputs = map[string]interface{}{"jumpbox_url": "10.0.0.5:22"}
+	})
+
+	Describe("CheckFastFails", func() {
+		It("returns an error when the state does not exist", func() {
+			stateValidator.ValidateCall.Returns.Error = errors.New("state file not found")
+
+			err := command.CheckFastFails([]string{}, state)
+			Expect(err).To(MatchError("state file not found"))
+		})
+	})
+
+	Describe("Execute", func() {
+		It("checks the state against the terraform outputs", func() {
+			err := command.Execute([]string{}, state)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(terraformManager.GetOutputsCall.Receives.BBLState).To(Equal(state))
+			Expect(stateChecker.CheckCall.Receives).To(Equal([]fakes.CheckCallReceive{{
+				State:            state,
+				TerraformOutputs: map[string]interface{}{"jumpbox_url": "10.0.0.5:22"},
+			}}))
+			Expect(logger.PrintlnCall.Messages).To(Equal([]string{"no problems found"}))
+		})
+
+		It("does not read terraform outputs when there is no terraform state", func() {
+			err := command.Execute([]string{}, storage.State{IAAS: "gcp"})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(terraformManager.GetOutputsCall.CallCount).To(Equal(0))
+			Expect(stateChecker.CheckCall.Receives[0].TerraformOutputs).To(BeNil())
+		})
+
+		It("prints warnings without failing", func() {
+			stateChecker.CheckCall.Returns = []fakes.CheckCallReturn{{
+				Problems: []fsck.Problem{
+					{Check: "director-vm", Severity: fsck.SeverityWarning, Message: "no current VM"},
+				},
+			}}
+
+			err := command.Execute([]string{}, state)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(logger.PrintlnCall.Messages).To(Equal([]string{"[warning] director-vm: no current VM"}))
+		})
+
+		It("prints errors, suggests --repair and fails", func() {
+			stateChecker.CheckCall.Returns = []fakes.CheckCallReturn{{
+				Problems: []fsck.Problem{
+					{Check: "iaas", Severity: fsck.SeverityError, Message: "iaas is not set"},
+					{Check: "jumpbox-url", Severity: fsck.SeverityError, Message: "no URL", Repairable: true},
+				},
+			}}
+
+			err := command.Execute([]string{}, state)
+			Expect(err).To(MatchError("found 2 error(s) in bbl-state.json"))
+
+			Expect(logger.PrintlnCall.Messages).To(Equal([]string{
+				"[error] iaas: iaas is not set",
+				"[error] jumpbox-url: no URL (repairable with --repair)",
+			}))
+			Expect(stateChecker.RepairCall.CallCount).To(Equal(0))
+			Expect(stateStore.SetCall.CallCount).To(Equal(0))
+		})
+
+		It("reports terraform outputs that cannot be read", func() {
+			terraformManager.GetOutputsCall.Returns.Error = errors.New("bad tfstate")
+
+			err := command.Execute([]string{}, state)
+			Expect(err).To(MatchError("found 1 error(s) in bbl-state.json"))
+
+			Expect(logger.PrintlnCall.Messages).To(Equal([]string{
+				"[error] terraform-outputs: terraform outputs cannot be read: bad tfstate",
+			}))
+		})
+
+		Context("when --repair is passed", func() {
+			var repairedState storage.State
+
+			BeforeEach(func() {
+				repairedState = storage.State{IAAS: "gcp", TFState: "some-tf-state", Jumpbox: storage.Jumpbox{URL: "10.0.0.5:22"}}
+
+				stateChecker.CheckCall.Returns = []fakes.CheckCallReturn{
+					{Problems: []fsck.Problem{{Check: "jumpbox-url", Severity: fsck.SeverityError, Message: "no URL", Repairable: true}}},
+					{Problems: nil},
+				}
+				stateChecker.RepairCall.Returns.State = repairedState
+				stateChecker.RepairCall.Returns.Repaired = true
+			})
+
+			It("saves the repaired state and checks it again", func() {
+				err := command.Execute([]string{"--repair"}, state)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(stateChecker.RepairCall.Receives.State).To(Equal(state))
+				Expect(stateStore.SetCall.Receives).To(Equal([]fakes.SetCallReceive{{State: repairedState}}))
+				Expect(stateChecker.CheckCall.Receives[1].State).To(Equal(repairedState))
+				Expect(logger.StepCall.Messages).To(ContainElement("repaired bbl-state.json"))
+				Expect(logger.PrintlnCall.Messages).To(Equal([]string{"no problems found"}))
+			})
+
+			It("does not repair when no problem is repairable", func() {
+				stateChecker.CheckCall.Returns = []fakes.CheckCallReturn{
+					{Problems: []fsck.Problem{{Check: "iaas", Severity: fsck.SeverityError, Message: "iaas is not set"}}},
+				}
+
+				err := command.Execute([]string{"--repair"}, state)
+				Expect(err).To(MatchError("found 1 error(s) in bbl-state.json"))
+
+				Expect(stateChecker.RepairCall.CallCount).To(Equal(0))
+				Expect(stateStore.SetCall.CallCount).To(Equal(0))
+			})
+
+			It("returns an error when the repaired state cannot be saved", func() {
+				stateStore.SetCall.Returns = []fakes.SetCallReturn{{Error: errors.New("disk full")}}
+
+				err := command.Execute([]string{"--repair"}, state)
+				Expect(err).To(MatchError("save repaired state: disk full"))
+			})
+		})
+	})
+})
@@ -2,8 +2,6 @@ package commands
 
 import (
 	"errors"
-	"fmt"
-	"io/ioutil"
 
 	"github.com/cloudfoundry/bosh-bootloader/bosh"
 	yaml "gopkg.in/yaml.v2"
@@ -24,6 +22,7 @@ type GCPUp struct {
 	stateStore                   stateStore
 	boshManager                  boshManager
 	cloudConfigManager           cloudConfigManager
+	runtimeConfigManager         runtimeConfigManager
 	terraformManager             terraformApplier
 	envIDManager                 envIDManager
 	gcpAvailabilityZoneRetriever gcpAvailabilityZoneRetriever
@@ -34,12 +33,13 @@ type gcpAvailabilityZoneRetriever interface {
 }
 
 func NewGCPUp(stateStore stateStore, terraformManager terraformApplier, boshManager boshManager,
-	cloudConfigManager cloudConfigManager, envIDManager envIDManager, gcpAvailabilityZoneRetriever gcpAvailabilityZoneRetriever) GCPUp {
+	cloudConfigManager cloudConfigManager, runtimeConfigManager runtimeConfigManager, envIDManager envIDManager, gcpAvailabilityZoneRetriever gcpAvailabilityZoneRetriever) GCPUp {
 	return GCPUp{
 		stateStore:                   stateStore,
 		terraformManager:             terraformManager,
 		boshManager:                  boshManager,
 		cloudConfigManager:           cloudConfigManager,
+		runtimeConfigManager:         runtimeConfigManager,
 		envIDManager:                 envIDManager,
 		gcpAvailabilityZoneRetriever: gcpAvailabilityZoneRetriever,
 	}
@@ -51,14 +51,6 @@ func (u GCPUp) Execute(upConfig UpConfig, state storage.State) error {
 		return err
 	}
 
-	var opsFileContents []byte
-	if upConfig.OpsFile != "" {
-		opsFileContents, err = ioutil.ReadFile(upConfig.OpsFile)
-		if err != nil {
-			return fmt.Errorf("error reading ops-file contents: %v", err)
-		}
-	}
-
 	if upConfig.NoDirector {
 		if !state.BOSH.IsEmpty() {
 			return errors.New(`Director already exists, you must re-create your environment to use "--no-director"`)
@@ -102,6 +94,7 @@ func (u GCPUp) Execute(upConfig UpConfig, state storage.State) error {
 	if !state.NoDirector {
 		if upConfig.Jumpbox {
 			state.Jumpbox.Enabled = true
+			state.Jumpbox.OpsFiles = upConfig.JumpboxOpsFiles
 			state, err = u.boshManager.CreateJumpbox(state, terraformOutputs)
 			if err != nil {
 				return err
@@ -113,7 +106,10 @@ func (u GCPUp) Execute(upConfig UpConfig, state storage.State) error {
 			}
 		}
 
-		state.BOSH.UserOpsFile = string(opsFileContents)
+		state.BOSH.OpsFiles = upConfig.OpsFiles
+		state.BOSH.VarsFiles = upConfig.VarsFiles
+		state.BOSH.Vars = upConfig.Vars
+		state.BOSH.RuntimeConfigOpsFiles = upConfig.RuntimeConfigOpsFiles
 
 		state, err = u.boshManager.CreateDirector(state, terraformOutputs)
 		switch err.(type) {
@@ -139,6 +135,11 @@ func (u GCPUp) Execute(upConfig UpConfig, state storage.State) error {
 		if err != nil {
 			return err
 		}
+
+		err = u.runtimeConfigManager.Update(state)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
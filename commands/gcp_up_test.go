@@ -3,7 +3,6 @@ package commands_test
 import (
 	"errors"
 	"io/ioutil"
-	"os"
 
 	"github.com/cloudfoundry/bosh-bootloader/bosh"
 	"github.com/cloudfoundry/bosh-bootloader/commands"
@@ -30,6 +29,7 @@ var _ = Describe("GCPUp", func() {
 		terraformManager      *fakes.TerraformManager
 		boshManager           *fakes.BOSHManager
 		cloudConfigManager    *fakes.CloudConfigManager
+		runtimeConfigManager  *fakes.RuntimeConfigManager
 		envIDManager          *fakes.EnvIDManager
 		terraformManagerError *fakes.TerraformManagerError
 		gcpZones              *fakes.GCPClient
@@ -47,6 +47,7 @@ var _ = Describe("GCPUp", func() {
 	BeforeEach(func() {
 		boshManager = &fakes.BOSHManager{}
 		cloudConfigManager = &fakes.CloudConfigManager{}
+		runtimeConfigManager = &fakes.RuntimeConfigManager{}
 		envIDManager = &fakes.EnvIDManager{}
 		gcpZones = &fakes.GCPClient{}
 		stateStore = &fakes.StateStore{}
@@ -96,6 +97,7 @@ var _ = Describe("GCPUp", func() {
 			terraformManager,
 			boshManager,
 			cloudConfigManager,
+			runtimeConfigManager,
 			envIDManager,
 			gcpZones,
 		)
@@ -165,6 +167,11 @@ var _ = Describe("GCPUp", func() {
 				Expect(cloudConfigManager.UpdateCall.CallCount).To(Equal(1))
 				Expect(cloudConfigManager.UpdateCall.Receives.State).To(Equal(expectedBOSHState))
 			})
+
+			By("updating the runtime config", func() {
+				Expect(runtimeConfigManager.UpdateCall.CallCount).To(Equal(1))
+				Expect(runtimeConfigManager.UpdateCall.Receives.State).To(Equal(expectedBOSHState))
+			})
 		})
 
 		Context("when a name is passed in for env-id", func() {
@@ -179,22 +186,24 @@ var _ = Describe("GCPUp", func() {
 			})
 		})
 
-		Context("when ops file are passed in", func() {
-			It("passes the ops file contents to the bosh manager", func() {
-				opsFile, err := ioutil.TempFile("", "ops-file")
-				Expect(err).NotTo(HaveOccurred())
-
-				opsFilePath := opsFile.Name()
-				opsFileContents := "some-ops-file-contents"
-				err = ioutil.WriteFile(opsFilePath, []byte(opsFileContents), os.ModePerm)
-				Expect(err).NotTo(HaveOccurred())
+		Context("when ops files and vars are passed in", func() {
+			It("passes them to the bosh manager", func() {
+				opsFiles := []storage.UserFile{{Path: "some-ops-file-path", Contents: "some-ops-file-contents"}}
+				varsFiles := []storage.UserFile{{Path: "some-vars-file-path", Contents: "some-vars-file-contents"}}
+				runtimeConfigOpsFiles := []storage.UserFile{{Path: "some-runtime-config-ops-file-path", Contents: "some-runtime-config-ops-file-contents"}}
 
-				err = gcpUp.Execute(commands.UpConfig{
-					OpsFile: opsFilePath,
+				err := gcpUp.Execute(commands.UpConfig{
+					OpsFiles:              opsFiles,
+					VarsFiles:             varsFiles,
+					Vars:                  []string{"some-var=some-value"},
+					RuntimeConfigOpsFiles: runtimeConfigOpsFiles,
 				}, storage.State{})
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(boshManager.CreateDirectorCall.Receives.State.BOSH.UserOpsFile).To(Equal("some-ops-file-contents"))
+				Expect(boshManager.CreateDirectorCall.Receives.State.BOSH.OpsFiles).To(Equal(opsFiles))
+				Expect(boshManager.CreateDirectorCall.Receives.State.BOSH.VarsFiles).To(Equal(varsFiles))
+				Expect(boshManager.CreateDirectorCall.Receives.State.BOSH.Vars).To(Equal([]string{"some-var=some-value"}))
+				Expect(boshManager.CreateDirectorCall.Receives.State.BOSH.RuntimeConfigOpsFiles).To(Equal(runtimeConfigOpsFiles))
 			})
 		})
 
@@ -253,6 +262,18 @@ var _ = Describe("GCPUp", func() {
 				Expect(stateStore.SetCall.CallCount).To(Equal(5))
 				Expect(stateStore.SetCall.Receives[3].State.Jumpbox.Enabled).To(Equal(true))
 			})
+
+			It("passes the jumpbox ops files to the bosh manager", func() {
+				opsFiles := []storage.UserFile{{Path: "some-jumpbox-ops-file-path", Contents: "some-jumpbox-ops-file-contents"}}
+
+				err := gcpUp.Execute(commands.UpConfig{
+					Jumpbox:         true,
+					JumpboxOpsFiles: opsFiles,
+				}, storage.State{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(boshManager.CreateJumpboxCall.Receives.State.Jumpbox.OpsFiles).To(Equal(opsFiles))
+			})
 		})
 
 		Context("reentrance", func() {
@@ -274,13 +295,6 @@ var _ = Describe("GCPUp", func() {
 				Expect(err).To(MatchError("cannot validate version"))
 			})
 
-			It("returns an error when the ops file cannot be read", func() {
-				err := gcpUp.Execute(commands.UpConfig{
-					OpsFile: "some/fake/path",
-				}, storage.State{})
-				Expect(err).To(MatchError("error reading ops-file contents: open some/fake/path: no such file or directory"))
-			})
-
 			Context("when a bbl environment exists with a bosh director", func() {
 				It("fast fails before creating any infrastructure", func() {
 					err := gcpUp.Execute(commands.UpConfig{
@@ -435,6 +449,12 @@ var _ = Describe("GCPUp", func() {
 				err := gcpUp.Execute(commands.UpConfig{}, storage.State{})
 				Expect(err).To(MatchError("failed to update"))
 			})
+
+			It("returns an error when the runtime config manager fails to update", func() {
+				runtimeConfigManager.UpdateCall.Returns.Error = errors.New("failed to update runtime config")
+				err := gcpUp.Execute(commands.UpConfig{}, storage.State{})
+				Expect(err).To(MatchError("failed to update runtime config"))
+			})
 		})
 	})
 })
@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+)
+
+type Import struct {
+	logger     logger
+	stateStore stateStore
+}
+
+func NewImport(logger logger, stateStore stateStore) Import {
+	return Import{
+		logger:     logger,
+		stateStore: stateStore,
+	}
+}
+
+func (i Import) CheckFastFails(subcommandFlags []string, state storage.State) error {
+	if len(subcommandFlags) == 0 {
+		return errors.New("a bundle path must be provided")
+	}
+
+	if state.EnvID != "" {
+		return fmt.Errorf("the state directory already contains environment %s", state.EnvID)
+	}
+
+	return nil
+}
+
+func (i Import) Execute(subcommandFlags []string, state storage.State) error {
+	file, err := os.Open(subcommandFlags[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	bundle, err := storage.ReadBundle(file)
+	if err != nil {
+		return err
+	}
+
+	if state.IAAS != "" && state.IAAS != bundle.State.IAAS {
+		return fmt.Errorf("bundle iaas %q does not match the requested iaas %q", bundle.State.IAAS, state.IAAS)
+	}
+
+	err = i.stateStore.Set(bundle.State)
+	if err != nil {
+		return err
+	}
+
+	i.logger.Step("imported %s exported by bbl %s", bundle.Metadata.EnvID, bundle.Metadata.BBLVersion)
+	return nil
+}
@@ -0,0 +1,99 @@
+package commands_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/bosh-bootloader/commands"
+	"github.com/cloudfoundry/bosh-bootloader/fakes"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Import", func() {
+	var (
+		logger     *fakes.Logger
+		stateStore *fakes.StateStore
+		bundlePath string
+		state      storage.State
+
+		command commands.Import
+	)
+
+	BeforeEach(func() {
+		logger = &fakes.Logger{}
+		stateStore = &fakes.StateStore{}
+
+		tempDir, err := ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+		bundlePath = filepath.Join(tempDir, "env.tgz")
+
+		state = storage.State{
+			Version: storage.STATE_VERSION,
+			IAAS:    "gcp",
+			EnvID:   "some-env-id",
+		}
+
+		file, err := os.Create(bundlePath)
+		Expect(err).NotTo(HaveOccurred())
+		defer file.Close()
+
+		err = storage.WriteBundle(file, storage.Bundle{
+			Metadata: storage.BundleMetadata{
+				StateVersion: storage.STATE_VERSION,
+				IAAS:         "gcp",
+				EnvID:        "some-env-id",
+				BBLVersion:   "some-bbl-version",
+			},
+			State: state,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		command = commands.NewImport(logger, stateStore)
+	})
+
+	Describe("CheckFastFails", func() {
+		It("returns an error when no bundle is provided", func() {
+			err := command.CheckFastFails([]string{}, storage.State{})
+			Expect(err).To(MatchError("a bundle path must be provided"))
+		})
+
+		It("returns an error when the state dir already contains an environment", func() {
+			err := command.CheckFastFails([]string{bundlePath}, storage.State{EnvID: "some-other-env-id"})
+			Expect(err).To(MatchError("the state directory already contains environment some-other-env-id"))
+		})
+	})
+
+	Describe("Execute", func() {
+		It("saves the state from the bundle", func() {
+			err := command.Execute([]string{bundlePath}, storage.State{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(stateStore.SetCall.Receives[0].State).To(Equal(state))
+			Expect(logger.StepCall.Messages).To(ContainElement("imported some-env-id exported by bbl some-bbl-version"))
+		})
+
+		Context("failure cases", func() {
+			It("returns an error when the bundle iaas does not match the requested iaas", func() {
+				err := command.Execute([]string{bundlePath}, storage.State{IAAS: "aws"})
+				Expect(err).To(MatchError(`bundle iaas "gcp" does not match the requested iaas "aws"`))
+			})
+
+			It("returns an error when the bundle cannot be read", func() {
+				err := command.Execute([]string{"/some/missing/env.tgz"}, storage.State{})
+				Expect(err).To(MatchError(ContainSubstring("no such file or directory")))
+			})
+
+			It("returns an error when the state cannot be saved", func() {
+				stateStore.SetCall.Returns = []fakes.SetCallReturn{{Error: errors.New("failed to save state")}}
+
+				err := command.Execute([]string{bundlePath}, storage.State{})
+				Expect(err).To(MatchError("failed to save state"))
+			})
+		})
+	})
+})
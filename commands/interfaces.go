@@ -28,6 +28,7 @@ type boshManager interface {
 	Delete(bblState storage.State, terraformOutputs map[string]interface{}) error
 	DeleteJumpbox(bblState storage.State, terraformOutputs map[string]interface{}) error
 	GetDirectorDeploymentVars(bblState storage.State, terraformOutputs map[string]interface{}) string
+	StartJumpboxProxy(bblState storage.State) error
 	Version() (string, error)
 }
 
@@ -89,3 +90,8 @@ type cloudConfigManager interface {
 	Update(state storage.State) error
 	Generate(state storage.State) (string, error)
 }
+
+type runtimeConfigManager interface {
+	Update(state storage.State) error
+	Generate(state storage.State) (string, error)
+}
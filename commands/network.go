@@ -0,0 +1,36 @@
+package commands
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+)
+
+// withNetwork records the ranges given with --network-cidr,
+// --director-subnet-cidr and --internal-subnet-cidr in the state after
+// checking that they fit in the network and do not overlap.
+func withNetwork(config UpConfig, state storage.State) (storage.State, error) {
+	if config.NetworkCIDR == "" && config.DirectorSubnetCIDR == "" && len(config.InternalSubnetCIDRs) == 0 {
+		return state, nil
+	}
+
+	network := storage.Network{
+		CIDR:                config.NetworkCIDR,
+		DirectorSubnetCIDR:  config.DirectorSubnetCIDR,
+		InternalSubnetCIDRs: config.InternalSubnetCIDRs,
+	}
+
+	_, err := network.Layout()
+	if err != nil {
+		return storage.State{}, err
+	}
+
+	if state.EnvID != "" && !reflect.DeepEqual(state.Network, &network) {
+		return storage.State{}, errors.New("The network cidrs cannot be changed for an existing environment.")
+	}
+
+	state.Network = &network
+
+	return state, nil
+}
@@ -0,0 +1,26 @@
+package commands
+
+import "github.com/cloudfoundry/bosh-bootloader/storage"
+
+// Plan is `bbl up --dry-run` under its own name.
+type Plan struct {
+	up up
+}
+
+func NewPlan(up up) Plan {
+	return Plan{
+		up: up,
+	}
+}
+
+func (p Plan) CheckFastFails(subcommandFlags []string, state storage.State) error {
+	return p.up.CheckFastFails(dryRunFlags(subcommandFlags), state)
+}
+
+func (p Plan) Execute(subcommandFlags []string, state storage.State) error {
+	return p.up.Execute(dryRunFlags(subcommandFlags), state)
+}
+
+func dryRunFlags(subcommandFlags []string) []string {
+	return append([]string{"--dry-run"}, subcommandFlags...)
+}
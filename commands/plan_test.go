@@ -0,0 +1,48 @@
+package commands_test
+
+import (
+	"errors"
+
+	"github.com/cloudfoundry/bosh-bootloader/commands"
+	"github.com/cloudfoundry/bosh-bootloader/fakes"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Plan", func() {
+	var (
+		up      *fakes.Up
+		command commands.Plan
+		state   storage.State
+	)
+
+	BeforeEach(func() {
+		up = &fakes.Up{}
+		command = commands.NewPlan(up)
+		state = storage.State{EnvID: "some-env-id"}
+	})
+
+	Describe("CheckFastFails", func() {
+		It("checks up with --dry-run", func() {
+			up.CheckFastFailsCall.Returns.Error = errors.New("some up error")
+
+			err := command.CheckFastFails([]string{"--credhub"}, state)
+			Expect(err).To(MatchError("some up error"))
+
+			Expect(up.CheckFastFailsCall.Receives.SubcommandFlags).To(Equal([]string{"--dry-run", "--credhub"}))
+			Expect(up.CheckFastFailsCall.Receives.State).To(Equal(state))
+		})
+	})
+
+	Describe("Execute", func() {
+		It("runs up with --dry-run", func() {
+			err := command.Execute([]string{"--ops-file", "some-ops-file"}, state)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(up.ExecuteCall.Receives.Args).To(Equal([]string{"--dry-run", "--ops-file", "some-ops-file"}))
+			Expect(up.ExecuteCall.Receives.State).To(Equal(state))
+		})
+	})
+})
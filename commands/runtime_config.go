@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/cloudfoundry/bosh-bootloader/flags"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+)
+
+const (
+	RuntimeConfigCommand = "runtime-config"
+)
+
+type RuntimeConfig struct {
+	logger               logger
+	stateValidator       stateValidator
+	runtimeConfigManager runtimeConfigManager
+}
+
+func NewRuntimeConfig(logger logger, stateValidator stateValidator, runtimeConfigManager runtimeConfigManager) RuntimeConfig {
+	return RuntimeConfig{
+		logger:               logger,
+		stateValidator:       stateValidator,
+		runtimeConfigManager: runtimeConfigManager,
+	}
+}
+
+func (r RuntimeConfig) CheckFastFails(subcommandFlags []string, state storage.State) error {
+	_, err := parseRuntimeConfigOpsFiles(subcommandFlags)
+	if err != nil {
+		return err
+	}
+
+	return r.stateValidator.Validate()
+}
+
+// Execute prints the runtime config with the ops files passed with --ops-file,
+// or with the ones stored by "bbl up --runtime-config-ops-file". The stored
+// ops files are not changed.
+func (r RuntimeConfig) Execute(subcommandFlags []string, state storage.State) error {
+	opsFilePaths, err := parseRuntimeConfigOpsFiles(subcommandFlags)
+	if err != nil {
+		return err
+	}
+
+	if len(opsFilePaths) > 0 {
+		state.BOSH.RuntimeConfigOpsFiles, err = readUserFiles(opsFilePaths)
+		if err != nil {
+			return fmt.Errorf("error reading ops-file contents: %v", err)
+		}
+	}
+
+	contents, err := r.runtimeConfigManager.Generate(state)
+	if err != nil {
+		return err
+	}
+	r.logger.Println(contents)
+	return nil
+}
+
+func parseRuntimeConfigOpsFiles(subcommandFlags []string) ([]string, error) {
+	var opsFilePaths []string
+	runtimeConfigFlags := flags.New("runtime-config")
+	runtimeConfigFlags.StringSlice(&opsFilePaths, "ops-file")
+
+	err := runtimeConfigFlags.Parse(subcommandFlags)
+	if err != nil {
+		return nil, err
+	}
+
+	return opsFilePaths, nil
+}
@@ -0,0 +1,102 @@
+package commands_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+
+	"github.com/cloudfoundry/bosh-bootloader/commands"
+	"github.com/cloudfoundry/bosh-bootloader/fakes"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RuntimeConfig", func() {
+	var (
+		logger               *fakes.Logger
+		stateValidator       *fakes.StateValidator
+		runtimeConfig        commands.RuntimeConfig
+		state                storage.State
+		runtimeConfigManager *fakes.RuntimeConfigManager
+	)
+
+	BeforeEach(func() {
+		logger = &fakes.Logger{}
+		stateValidator = &fakes.StateValidator{}
+		runtimeConfigManager = &fakes.RuntimeConfigManager{}
+
+		runtimeConfigManager.GenerateCall.Returns.RuntimeConfig = "some-runtime-config"
+
+		state = storage.State{
+			BOSH: storage.BOSH{
+				DirectorAddress:       "some-director-address",
+				RuntimeConfigOpsFiles: []storage.UserFile{{Path: "some-stored-ops-file", Contents: "some-stored-ops"}},
+			},
+		}
+
+		runtimeConfig = commands.NewRuntimeConfig(logger, stateValidator, runtimeConfigManager)
+	})
+
+	Describe("CheckFastFails", func() {
+		It("returns an error when the state validator fails", func() {
+			stateValidator.ValidateCall.Returns.Error = errors.New("failed to validate state")
+			err := runtimeConfig.CheckFastFails([]string{}, storage.State{})
+			Expect(err).To(MatchError("failed to validate state"))
+		})
+
+		It("returns an error when the flags cannot be parsed", func() {
+			err := runtimeConfig.CheckFastFails([]string{"--unknown-flag"}, storage.State{})
+			Expect(err).To(MatchError("flag provided but not defined: -unknown-flag"))
+		})
+	})
+
+	Describe("Execute", func() {
+		It("prints the runtime config with the stored ops files", func() {
+			err := runtimeConfig.Execute([]string{}, state)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(runtimeConfigManager.GenerateCall.Receives.State).To(Equal(state))
+			Expect(logger.PrintlnCall.Messages).To(ContainElement("some-runtime-config"))
+		})
+
+		Context("when ops files are passed", func() {
+			var opsFilePath string
+
+			BeforeEach(func() {
+				opsFile, err := ioutil.TempFile("", "")
+				Expect(err).NotTo(HaveOccurred())
+				opsFilePath = opsFile.Name()
+
+				err = ioutil.WriteFile(opsFilePath, []byte("some-ops"), os.ModePerm)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			AfterEach(func() {
+				os.Remove(opsFilePath)
+			})
+
+			It("prints the runtime config with the given ops files instead", func() {
+				err := runtimeConfig.Execute([]string{"--ops-file", opsFilePath}, state)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(runtimeConfigManager.GenerateCall.Receives.State.BOSH.RuntimeConfigOpsFiles).To(Equal([]storage.UserFile{
+					{Path: opsFilePath, Contents: "some-ops"},
+				}))
+				Expect(logger.PrintlnCall.Messages).To(ContainElement("some-runtime-config"))
+			})
+		})
+
+		Context("failure cases", func() {
+			It("returns an error when an ops file cannot be read", func() {
+				err := runtimeConfig.Execute([]string{"--ops-file", "some/fake/path"}, state)
+				Expect(err).To(MatchError("error reading ops-file contents: open some/fake/path: no such file or directory"))
+			})
+
+			It("returns an error when the runtime config manager fails to generate", func() {
+				runtimeConfigManager.GenerateCall.Returns.Error = errors.New("failed to generate runtime config")
+				err := runtimeConfig.Execute([]string{}, state)
+				Expect(err).To(MatchError("failed to generate runtime config"))
+			})
+		})
+	})
+})
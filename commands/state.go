@@ -0,0 +1,197 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/bosh-bootloader/flags"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+)
+
+type State struct {
+	logger         logger
+	stateValidator stateValidator
+	stateEncrypter stateEncrypter
+	stateHistory   stateHistory
+	stateLayout    stateLayout
+}
+
+type stateEncrypter interface {
+	Encrypt() error
+	Decrypt() error
+}
+
+type stateHistory interface {
+	History() ([]storage.HistoryEntry, error)
+	Rollback(id int) error
+}
+
+type stateLayout interface {
+	Split() error
+	Join() error
+}
+
+func NewState(logger logger, stateValidator stateValidator, stateEncrypter stateEncrypter, stateHistory stateHistory, stateLayout stateLayout) State {
+	return State{
+		logger:         logger,
+		stateValidator: stateValidator,
+		stateEncrypter: stateEncrypter,
+		stateHistory:   stateHistory,
+		stateLayout:    stateLayout,
+	}
+}
+
+func (s State) CheckFastFails(subcommandFlags []string, state storage.State) error {
+	if len(subcommandFlags) == 0 {
+		return errors.New("a state subcommand must be provided")
+	}
+
+	// history and rollback only need the state history, which outlives
+	// bbl-state.json, so that an environment can be recovered after it.
+	switch subcommandFlags[0] {
+	case "history", "rollback":
+		return nil
+	}
+
+	err := s.stateValidator.Validate()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s State) Execute(subcommandFlags []string, state storage.State) error {
+	switch subcommandFlags[0] {
+	case "encrypt":
+		err := s.stateEncrypter.Encrypt()
+		if err != nil {
+			return fmt.Errorf("encrypt state: %s", err)
+		}
+		s.logger.Step("encrypted bbl-state.json")
+	case "decrypt":
+		err := s.stateEncrypter.Decrypt()
+		if err != nil {
+			return fmt.Errorf("decrypt state: %s", err)
+		}
+		s.logger.Step("decrypted bbl-state.json")
+	case "split":
+		err := s.stateLayout.Split()
+		if err != nil {
+			return fmt.Errorf("split state: %s", err)
+		}
+		s.logger.Step("split bbl-state.json into the state directory")
+	case "join":
+		err := s.stateLayout.Join()
+		if err != nil {
+			return fmt.Errorf("join state: %s", err)
+		}
+		s.logger.Step("joined the state directory into bbl-state.json")
+	case "show":
+		return s.show(subcommandFlags[1:], state)
+	case "history":
+		return s.history()
+	case "rollback":
+		return s.rollback(subcommandFlags[1:])
+	default:
+		return fmt.Errorf("unknown state subcommand: %s", subcommandFlags[0])
+	}
+
+	return nil
+}
+
+func (s State) show(subcommandFlags []string, state storage.State) error {
+	var (
+		format string
+		reveal []string
+	)
+	showFlags := flags.New("state show")
+	showFlags.String(&format, "format", "json")
+	showFlags.StringSlice(&reveal, "reveal")
+
+	err := showFlags.Parse(subcommandFlags)
+	if err != nil {
+		return err
+	}
+
+	redacted, err := storage.Redact(state, reveal)
+	if err != nil {
+		return err
+	}
+
+	var output []byte
+	switch format {
+	case "json":
+		buffer := &bytes.Buffer{}
+		encoder := json.NewEncoder(buffer)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(redacted)
+		output = buffer.Bytes()
+	case "yaml":
+		output, err = marshal(redacted)
+	default:
+		return fmt.Errorf("unknown format %q, valid options are: json, yaml", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.logger.Println(strings.TrimSuffix(string(output), "\n"))
+	return nil
+}
+
+func (s State) history() error {
+	entries, err := s.stateHistory.History()
+	if err != nil {
+		return fmt.Errorf("state history: %s", err)
+	}
+
+	if len(entries) == 0 {
+		s.logger.Println("no state history recorded")
+		return nil
+	}
+
+	for _, entry := range entries {
+		command := entry.Command
+		if command == "" {
+			command = "-"
+		}
+		s.logger.Printf("%-4d %s  %-16s %s\n", entry.ID, entry.CreatedAt.Format(time.RFC3339), command, entry.Summary)
+	}
+
+	return nil
+}
+
+func (s State) rollback(subcommandFlags []string) error {
+	var to string
+	rollbackFlags := flags.New("state rollback")
+	rollbackFlags.String(&to, "to", "")
+
+	err := rollbackFlags.Parse(subcommandFlags)
+	if err != nil {
+		return err
+	}
+
+	if to == "" {
+		return errors.New("--to must be provided, run `bbl state history` to list the recorded states")
+	}
+
+	id, err := strconv.Atoi(to)
+	if err != nil {
+		return fmt.Errorf("invalid state history id %q", to)
+	}
+
+	err = s.stateHistory.Rollback(id)
+	if err != nil {
+		return fmt.Errorf("rollback state: %s", err)
+	}
+
+	s.logger.Step("restored bbl-state.json from state history entry %d", id)
+	return nil
+}
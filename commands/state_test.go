@@ -0,0 +1,227 @@
+package commands_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/cloudfoundry/bosh-bootloader/commands"
+	"github.com/cloudfoundry/bosh-bootloader/fakes"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("State", func() {
+	var (
+		logger         *fakes.Logger
+		stateValidator *fakes.StateValidator
+		stateEncrypter *fakes.StateEncrypter
+		stateHistory   *fakes.StateHistory
+		stateLayout    *fakes.StateLayout
+
+		command commands.State
+	)
+
+	BeforeEach(func() {
+		logger = &fakes.Logger{}
+		stateValidator = &fakes.StateValidator{}
+		stateEncrypter = &fakes.StateEncrypter{}
+		stateHistory = &fakes.StateHistory{}
+		stateLayout = &fakes.StateLayout{}
+
+		command = commands.NewState(logger, stateValidator, stateEncrypter, stateHistory, stateLayout)
+	})
+
+	Describe("CheckFastFails", func() {
+		It("returns an error when no subcommand is provided", func() {
+			err := command.CheckFastFails([]string{}, storage.State{})
+			Expect(err).To(MatchError("a state subcommand must be provided"))
+		})
+
+		It("returns an error when the state does not exist", func() {
+			stateValidator.ValidateCall.Returns.Error = errors.New("failed to validate state")
+			err := command.CheckFastFails([]string{"encrypt"}, storage.State{})
+			Expect(err).To(MatchError("failed to validate state"))
+		})
+
+		It("does not require bbl-state.json to list or roll back the history", func() {
+			stateValidator.ValidateCall.Returns.Error = errors.New("failed to validate state")
+
+			err := command.CheckFastFails([]string{"history"}, storage.State{})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = command.CheckFastFails([]string{"rollback", "--to", "1"}, storage.State{})
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("Execute", func() {
+		It("encrypts the state", func() {
+			err := command.Execute([]string{"encrypt"}, storage.State{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(stateEncrypter.EncryptCall.CallCount).To(Equal(1))
+			Expect(logger.StepCall.Messages).To(ContainElement("encrypted bbl-state.json"))
+		})
+
+		It("decrypts the state", func() {
+			err := command.Execute([]string{"decrypt"}, storage.State{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(stateEncrypter.DecryptCall.CallCount).To(Equal(1))
+			Expect(logger.StepCall.Messages).To(ContainElement("decrypted bbl-state.json"))
+		})
+
+		Describe("show", func() {
+			var state storage.State
+
+			BeforeEach(func() {
+				state = storage.State{
+					IAAS: "gcp",
+					BOSH: storage.BOSH{
+						DirectorUsername: "admin",
+						DirectorPassword: "some-password",
+					},
+				}
+			})
+
+			It("prints the state as JSON with secrets redacted", func() {
+				err := command.Execute([]string{"show"}, state)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(logger.PrintlnCall.Receives.Message).To(ContainSubstring(`"directorPassword": "<redacted>"`))
+				Expect(logger.PrintlnCall.Receives.Message).To(ContainSubstring(`"directorUsername": "admin"`))
+			})
+
+			It("prints the state as YAML", func() {
+				err := command.Execute([]string{"show", "--format", "yaml"}, state)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(logger.PrintlnCall.Receives.Message).To(ContainSubstring("directorPassword: <redacted>"))
+			})
+
+			It("reveals the requested fields", func() {
+				err := command.Execute([]string{"show", "--reveal", "bosh.directorPassword"}, state)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(logger.PrintlnCall.Receives.Message).To(ContainSubstring(`"directorPassword": "some-password"`))
+			})
+
+			It("returns an error for an unknown format", func() {
+				err := command.Execute([]string{"show", "--format", "toml"}, state)
+				Expect(err).To(MatchError(`unknown format "toml", valid options are: json, yaml`))
+			})
+
+			It("returns an error when a revealed path is not redacted", func() {
+				err := command.Execute([]string{"show", "--reveal", "iaas"}, state)
+				Expect(err).To(MatchError("--reveal iaas does not match a redacted field"))
+			})
+		})
+
+		It("splits the state", func() {
+			err := command.Execute([]string{"split"}, storage.State{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(stateLayout.SplitCall.CallCount).To(Equal(1))
+			Expect(logger.StepCall.Messages).To(ContainElement("split bbl-state.json into the state directory"))
+		})
+
+		It("joins the state", func() {
+			err := command.Execute([]string{"join"}, storage.State{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(stateLayout.JoinCall.CallCount).To(Equal(1))
+			Expect(logger.StepCall.Messages).To(ContainElement("joined the state directory into bbl-state.json"))
+		})
+
+		It("lists the state history", func() {
+			stateHistory.HistoryCall.Returns.Entries = []storage.HistoryEntry{
+				{ID: 1, CreatedAt: time.Date(2017, time.June, 1, 12, 0, 0, 0, time.UTC), Command: "up", Summary: "created"},
+				{ID: 2, CreatedAt: time.Date(2017, time.June, 1, 12, 5, 0, 0, time.UTC), Command: "create-lbs", Summary: "changed lb.type, tfState"},
+			}
+
+			err := command.Execute([]string{"history"}, storage.State{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(logger.PrintfCall.Messages).To(Equal([]string{
+				"1    2017-06-01T12:00:00Z  up               created\n",
+				"2    2017-06-01T12:05:00Z  create-lbs       changed lb.type, tfState\n",
+			}))
+		})
+
+		It("reports when there is no state history", func() {
+			err := command.Execute([]string{"history"}, storage.State{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(logger.PrintlnCall.Receives.Message).To(Equal("no state history recorded"))
+		})
+
+		It("rolls the state back to a history entry", func() {
+			err := command.Execute([]string{"rollback", "--to", "3"}, storage.State{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(stateHistory.RollbackCall.Receives.ID).To(Equal(3))
+			Expect(logger.StepCall.Messages).To(ContainElement("restored bbl-state.json from state history entry 3"))
+		})
+
+		Context("failure cases", func() {
+			It("returns an error when the history cannot be read", func() {
+				stateHistory.HistoryCall.Returns.Error = errors.New("failed to read history")
+
+				err := command.Execute([]string{"history"}, storage.State{})
+				Expect(err).To(MatchError("state history: failed to read history"))
+			})
+
+			It("returns an error when splitting fails", func() {
+				stateLayout.SplitCall.Returns.Error = errors.New("failed to split")
+
+				err := command.Execute([]string{"split"}, storage.State{})
+				Expect(err).To(MatchError("split state: failed to split"))
+			})
+
+			It("returns an error when joining fails", func() {
+				stateLayout.JoinCall.Returns.Error = errors.New("failed to join")
+
+				err := command.Execute([]string{"join"}, storage.State{})
+				Expect(err).To(MatchError("join state: failed to join"))
+			})
+
+			It("returns an error when rollback is missing --to", func() {
+				err := command.Execute([]string{"rollback"}, storage.State{})
+				Expect(err).To(MatchError("--to must be provided, run `bbl state history` to list the recorded states"))
+			})
+
+			It("returns an error when the history id is not a number", func() {
+				err := command.Execute([]string{"rollback", "--to", "latest"}, storage.State{})
+				Expect(err).To(MatchError(`invalid state history id "latest"`))
+			})
+
+			It("returns an error when the rollback fails", func() {
+				stateHistory.RollbackCall.Returns.Error = errors.New("failed to roll back")
+
+				err := command.Execute([]string{"rollback", "--to", "3"}, storage.State{})
+				Expect(err).To(MatchError("rollback state: failed to roll back"))
+			})
+
+			It("returns an error when encryption fails", func() {
+				stateEncrypter.EncryptCall.Returns.Error = errors.New("failed to encrypt")
+
+				err := command.Execute([]string{"encrypt"}, storage.State{})
+				Expect(err).To(MatchError("encrypt state: failed to encrypt"))
+			})
+
+			It("returns an error when decryption fails", func() {
+				stateEncrypter.DecryptCall.Returns.Error = errors.New("failed to decrypt")
+
+				err := command.Execute([]string{"decrypt"}, storage.State{})
+				Expect(err).To(MatchError("decrypt state: failed to decrypt"))
+			})
+
+			It("returns an error for an unknown subcommand", func() {
+				err := command.Execute([]string{"frobnicate"}, storage.State{})
+				Expect(err).To(MatchError("unknown state subcommand: frobnicate"))
+			})
+		})
+	})
+})
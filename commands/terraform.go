@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+)
+
+// terraformStateSubcommands are the subcommands of bbl terraform state,
+// mapped to whether they change the terraform state.
+var terraformStateSubcommands = map[string]bool{
+	"list":   false,
+	"show":   false,
+	"rm":     true,
+	"mv":     true,
+	"import": true,
+}
+
+type Terraform struct {
+	logger             logger
+	stateValidator     stateValidator
+	terraformCommander terraformCommander
+	stateStore         stateStore
+	stdout             io.Writer
+}
+
+type terraformCommander interface {
+	StateCommand(bblState storage.State, args []string, stdout io.Writer) (storage.State, error)
+}
+
+func NewTerraform(logger logger, stateValidator stateValidator, terraformCommander terraformCommander, stateStore stateStore, stdout io.Writer) Terraform {
+	return Terraform{
+		logger:             logger,
+		stateValidator:     stateValidator,
+		terraformCommander: terraformCommander,
+		stateStore:         stateStore,
+		stdout:             stdout,
+	}
+}
+
+func (t Terraform) CheckFastFails(subcommandFlags []string, state storage.State) error {
+	if len(subcommandFlags) == 0 || subcommandFlags[0] != "state" {
+		return errors.New("a terraform subcommand must be provided, valid options are: state")
+	}
+
+	if len(subcommandFlags) == 1 {
+		return errors.New("a terraform state subcommand must be provided, valid options are: list, show, rm, mv, import")
+	}
+
+	if _, ok := terraformStateSubcommands[subcommandFlags[1]]; !ok {
+		return fmt.Errorf("unknown terraform state subcommand: %s", subcommandFlags[1])
+	}
+
+	err := t.stateValidator.Validate()
+	if err != nil {
+		return err
+	}
+
+	if !state.HasTerraformState() {
+		return errors.New("bbl terraform state needs terraform state, run `bbl up` to create the environment")
+	}
+
+	return nil
+}
+
+func (t Terraform) Execute(subcommandFlags []string, state storage.State) error {
+	args := subcommandFlags[1:]
+
+	updatedState, err := t.terraformCommander.StateCommand(state, args, t.stdout)
+	if err != nil {
+		return err
+	}
+
+	if !terraformStateSubcommands[args[0]] {
+		return nil
+	}
+
+	err = t.stateStore.Set(updatedState)
+	if err != nil {
+		return fmt.Errorf("save the terraform state: %s", err)
+	}
+
+	t.logger.Step("saved the updated terraform state")
+
+	return nil
+}
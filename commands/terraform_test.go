@@ -0,0 +1,106 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/cloudfoundry/bosh-bootloader/commands"
+	"github.com/cloudfoundry/bosh-bootloader/fakes"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Terraform", func() {
+	var (
+		logger           *fakes.Logger
+		stateValidator   *fakes.StateValidator
+		terraformManager *fakes.TerraformManager
+		stateStore       *fakes.StateStore
+		stdout           *bytes.Buffer
+
+		command commands.Terraform
+		state   storage.State
+	)
+
+	BeforeEach(func() {
+		logger = &fakes.Logger{}
+		stateValidator = &fakes.StateValidator{}
+		terraformManager = &fakes.TerraformManager{}
+		stateStore = &fakes.StateStore{}
+		stdout = bytes.NewBuffer([]byte{})
+
+		command = commands.NewTerraform(logger, stateValidator, terraformManager, stateStore, stdout)
+		state = storage.State{IAAS: "gcp", TFState: "some-tf-state"}
+	})
+
+	Describe("CheckFastFails", func() {
+		It("returns an error when no terraform subcommand is provided", func() {
+			err := command.CheckFastFails([]string{}, state)
+			Expect(err).To(MatchError("a terraform subcommand must be provided, valid options are: state"))
+		})
+
+		It("returns an error when no terraform state subcommand is provided", func() {
+			err := command.CheckFastFails([]string{"state"}, state)
+			Expect(err).To(MatchError("a terraform state subcommand must be provided, valid options are: list, show, rm, mv, import"))
+		})
+
+		It("returns an error for an unknown terraform state subcommand", func() {
+			err := command.CheckFastFails([]string{"state", "push"}, state)
+			Expect(err).To(MatchError("unknown terraform state subcommand: push"))
+		})
+
+		It("returns an error when the state does not exist", func() {
+			stateValidator.ValidateCall.Returns.Error = errors.New("state file not found")
+
+			err := command.CheckFastFails([]string{"state", "list"}, state)
+			Expect(err).To(MatchError("state file not found"))
+		})
+
+		It("returns an error when there is no terraform state", func() {
+			err := command.CheckFastFails([]string{"state", "list"}, storage.State{IAAS: "gcp"})
+			Expect(err).To(MatchError("bbl terraform state needs terraform state, run `bbl up` to create the environment"))
+		})
+	})
+
+	Describe("Execute", func() {
+		It("runs read only subcommands without saving the state", func() {
+			err := command.Execute([]string{"state", "show", "google_compute_network.bbl-network"}, state)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(terraformManager.StateCommandCall.Receives.BBLState).To(Equal(state))
+			Expect(terraformManager.StateCommandCall.Receives.Args).To(Equal([]string{"show", "google_compute_network.bbl-network"}))
+			Expect(terraformManager.StateCommandCall.Receives.Stdout).To(Equal(stdout))
+			Expect(stateStore.SetCall.CallCount).To(Equal(0))
+		})
+
+		It("saves the updated terraform state", func() {
+			updatedState := storage.State{IAAS: "gcp", TFState: "some-updated-tf-state"}
+			terraformManager.StateCommandCall.Returns.BBLState = updatedState
+
+			err := command.Execute([]string{"state", "mv", "some.address", "some.other-address"}, state)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(terraformManager.StateCommandCall.Receives.Args).To(Equal([]string{"mv", "some.address", "some.other-address"}))
+			Expect(stateStore.SetCall.CallCount).To(Equal(1))
+			Expect(stateStore.SetCall.Receives[0].State).To(Equal(updatedState))
+			Expect(logger.StepCall.Messages).To(ContainElement("saved the updated terraform state"))
+		})
+
+		It("does not save the state when the terraform command fails", func() {
+			terraformManager.StateCommandCall.Returns.Error = errors.New("failed to remove resource")
+
+			err := command.Execute([]string{"state", "rm", "some.address"}, state)
+			Expect(err).To(MatchError("failed to remove resource"))
+			Expect(stateStore.SetCall.CallCount).To(Equal(0))
+		})
+
+		It("returns an error when the state cannot be saved", func() {
+			stateStore.SetCall.Returns = []fakes.SetCallReturn{{Error: errors.New("failed to save state")}}
+
+			err := command.Execute([]string{"state", "import", "some.address", "some-id"}, state)
+			Expect(err).To(MatchError("save the terraform state: failed to save state"))
+		})
+	})
+})
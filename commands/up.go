@@ -3,16 +3,17 @@ package commands
 import (
 	"errors"
 	"fmt"
-	"io/ioutil"
-	"os"
-	"path/filepath"
+	"reflect"
 
 	"github.com/cloudfoundry/bosh-bootloader/flags"
 	"github.com/cloudfoundry/bosh-bootloader/storage"
+	"github.com/cloudfoundry/bosh-bootloader/terraform"
 )
 
 type Up struct {
+	logger      logger
 	upCmd       UpCmd
+	planCmd     UpCmd
 	boshManager boshManager
 }
 
@@ -22,14 +23,41 @@ type UpCmd interface {
 
 type UpConfig struct {
 	Name       string
-	OpsFile    string
 	NoDirector bool
 	Jumpbox    bool
+	DryRun     bool
+
+	TerraformBackend       string
+	TerraformBackendConfig []string
+
+	ExistingVPCID   string
+	ExistingNetwork string
+	ExistingVNet    string
+
+	NetworkCIDR         string
+	DirectorSubnetCIDR  string
+	InternalSubnetCIDRs []string
+
+	EnableComponents  []string
+	DisableComponents []string
+	ComponentsFile    string
+
+	OpsFilePaths              []string
+	VarsFilePaths             []string
+	JumpboxOpsFilePaths       []string
+	RuntimeConfigOpsFilePaths []string
+	OpsFiles                  []storage.UserFile
+	VarsFiles                 []storage.UserFile
+	Vars                      []string
+	JumpboxOpsFiles           []storage.UserFile
+	RuntimeConfigOpsFiles     []storage.UserFile
 }
 
-func NewUp(upCmd UpCmd, boshManager boshManager) Up {
+func NewUp(logger logger, upCmd UpCmd, planCmd UpCmd, boshManager boshManager) Up {
 	return Up{
+		logger:      logger,
 		upCmd:       upCmd,
+		planCmd:     planCmd,
 		boshManager: boshManager,
 	}
 }
@@ -51,10 +79,53 @@ func (u Up) CheckFastFails(args []string, state storage.State) error {
 		return errors.New(`Environment without credhub already exists, you must recreate your environment to use "--credhub"`)
 	}
 
+	if len(withoutEmpty(config.JumpboxOpsFilePaths)) > 0 && !config.Jumpbox {
+		return errors.New(`--jumpbox-ops-file requires a jumpbox, pass "--credhub"`)
+	}
+
+	if len(withoutEmpty(config.RuntimeConfigOpsFilePaths)) > 0 && config.NoDirector {
+		return errors.New(`--runtime-config-ops-file requires a director, it cannot be used with "--no-director"`)
+	}
+
 	if state.EnvID != "" && config.Name != "" && config.Name != state.EnvID {
 		return fmt.Errorf("The director name cannot be changed for an existing environment. Current name is %s.", state.EnvID)
 	}
 
+	networkState, err := withExistingNetwork(config, state)
+	if err != nil {
+		return err
+	}
+
+	if state.EnvID != "" && networkState.ExistingNetwork() != state.ExistingNetwork() {
+		return errors.New("The network cannot be changed for an existing environment.")
+	}
+
+	_, err = withNetwork(config, state)
+	if err != nil {
+		return err
+	}
+
+	_, err = withComponents(config, state)
+	if err != nil {
+		return err
+	}
+
+	if config.TerraformBackend == "" {
+		if len(config.TerraformBackendConfig) > 0 {
+			return errors.New("--terraform-backend-config requires --terraform-backend")
+		}
+		return nil
+	}
+
+	backend, err := terraform.NewBackend(config.TerraformBackend, config.TerraformBackendConfig)
+	if err != nil {
+		return err
+	}
+
+	if state.TFBackend != nil && !reflect.DeepEqual(*state.TFBackend, backend) {
+		return fmt.Errorf("The terraform backend cannot be changed for an existing environment. Current backend is %s.", state.TFBackend.Type)
+	}
+
 	return nil
 }
 
@@ -64,36 +135,81 @@ func (u Up) Execute(args []string, state storage.State) error {
 		return err
 	}
 
-	return u.upCmd.Execute(UpConfig{
-		OpsFile:    config.OpsFile,
+	config, err = withUserFiles(u.logger, config, state)
+	if err != nil {
+		return err
+	}
+
+	upConfig := UpConfig{
 		Name:       config.Name,
 		NoDirector: config.NoDirector,
 		Jumpbox:    config.Jumpbox,
-	}, state)
-}
+		DryRun:     config.DryRun,
+		OpsFiles:   config.OpsFiles,
+		VarsFiles:  config.VarsFiles,
+		Vars:       config.Vars,
 
-func (u Up) parseArgs(state storage.State, args []string) (UpConfig, error) {
-	var config UpConfig
+		JumpboxOpsFiles:       config.JumpboxOpsFiles,
+		RuntimeConfigOpsFiles: config.RuntimeConfigOpsFiles,
+	}
 
-	tempDir, err := ioutil.TempDir("", "")
+	if config.TerraformBackend != "" {
+		backend, err := terraform.NewBackend(config.TerraformBackend, config.TerraformBackendConfig)
+		if err != nil {
+			return err
+		}
+		state.TFBackend = &backend
+	}
+
+	state, err = withExistingNetwork(config, state)
 	if err != nil {
-		return UpConfig{}, err //not tested
+		return err
 	}
 
-	prevOpsFilePath := filepath.Join(tempDir, "user-ops-file")
-	err = ioutil.WriteFile(prevOpsFilePath, []byte(state.BOSH.UserOpsFile), os.ModePerm)
+	state, err = withNetwork(config, state)
 	if err != nil {
-		return UpConfig{}, err //not tested
+		return err
+	}
+
+	state, err = withComponents(config, state)
+	if err != nil {
+		return err
+	}
+
+	if config.DryRun {
+		return u.planCmd.Execute(upConfig, state)
 	}
 
+	return u.upCmd.Execute(upConfig, state)
+}
+
+func (u Up) parseArgs(state storage.State, args []string) (UpConfig, error) {
+	var config UpConfig
+
 	upFlags := flags.New("up")
 
 	upFlags.String(&config.Name, "name", "")
-	upFlags.String(&config.OpsFile, "ops-file", prevOpsFilePath)
+	upFlags.StringSlice(&config.OpsFilePaths, "ops-file")
+	upFlags.StringSlice(&config.VarsFilePaths, "vars-file")
+	upFlags.StringSlice(&config.Vars, "var")
+	upFlags.StringSlice(&config.JumpboxOpsFilePaths, "jumpbox-ops-file")
+	upFlags.StringSlice(&config.RuntimeConfigOpsFilePaths, "runtime-config-ops-file")
 	upFlags.Bool(&config.NoDirector, "", "no-director", state.NoDirector)
 	upFlags.Bool(&config.Jumpbox, "", "credhub", state.Jumpbox.Enabled)
-
-	err = upFlags.Parse(args)
+	upFlags.Bool(&config.DryRun, "", "dry-run", false)
+	upFlags.String(&config.TerraformBackend, "terraform-backend", "")
+	upFlags.StringSlice(&config.TerraformBackendConfig, "terraform-backend-config")
+	upFlags.String(&config.ExistingVPCID, "existing-vpc-id", "")
+	upFlags.String(&config.ExistingNetwork, "existing-network", "")
+	upFlags.String(&config.ExistingVNet, "existing-vnet", "")
+	upFlags.String(&config.NetworkCIDR, "network-cidr", "")
+	upFlags.String(&config.DirectorSubnetCIDR, "director-subnet-cidr", "")
+	upFlags.StringSlice(&config.InternalSubnetCIDRs, "internal-subnet-cidr")
+	upFlags.StringSlice(&config.EnableComponents, "enable")
+	upFlags.StringSlice(&config.DisableComponents, "disable")
+	upFlags.String(&config.ComponentsFile, "components-file", "")
+
+	err := upFlags.Parse(args)
 	if err != nil {
 		return UpConfig{}, err
 	}
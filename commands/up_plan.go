@@ -0,0 +1,125 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cloudfoundry/bosh-bootloader/bosh"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+	"github.com/cloudfoundry/bosh-bootloader/terraform"
+)
+
+var planActionSymbols = map[string]string{
+	terraform.PlanCreate:  "+",
+	terraform.PlanUpdate:  "~",
+	terraform.PlanReplace: "-/+",
+	terraform.PlanDestroy: "-",
+}
+
+type UpPlan struct {
+	logger           logger
+	terraformManager terraformPlanner
+	boshManager      manifestPlanner
+}
+
+type terraformPlanner interface {
+	ValidateVersion() error
+	Plan(storage.State) (terraform.Plan, error)
+	GetOutputs(storage.State) (map[string]interface{}, error)
+}
+
+type manifestPlanner interface {
+	PlanManifests(storage.State, map[string]interface{}) (bosh.ManifestPlan, error)
+}
+
+func NewUpPlan(logger logger, terraformManager terraformPlanner, boshManager manifestPlanner) UpPlan {
+	return UpPlan{
+		logger:           logger,
+		terraformManager: terraformManager,
+		boshManager:      boshManager,
+	}
+}
+
+func (p UpPlan) Execute(upConfig UpConfig, state storage.State) error {
+	err := p.terraformManager.ValidateVersion()
+	if err != nil {
+		return err
+	}
+
+	if state.EnvID == "" {
+		return errors.New("bbl plan needs an existing environment, run `bbl up` to create one")
+	}
+
+	if state.Stack.Name != "" && !state.HasTerraformState() {
+		return errors.New("bbl plan cannot preview the migration from cloudformation to terraform, run `bbl up` to migrate")
+	}
+
+	state.BOSH.OpsFiles = upConfig.OpsFiles
+	state.BOSH.VarsFiles = upConfig.VarsFiles
+	state.BOSH.Vars = upConfig.Vars
+
+	if upConfig.NoDirector {
+		if !state.BOSH.IsEmpty() {
+			return errors.New(`Director already exists, you must re-create your environment to use "--no-director"`)
+		}
+
+		state.NoDirector = true
+	}
+
+	if upConfig.Jumpbox {
+		state.Jumpbox.Enabled = true
+		state.Jumpbox.OpsFiles = upConfig.JumpboxOpsFiles
+	}
+
+	plan, err := p.terraformManager.Plan(state)
+	if err != nil {
+		return err
+	}
+
+	p.logger.Println("infrastructure:")
+	if !plan.HasChanges() {
+		p.logger.Println("  no changes")
+	} else {
+		for _, resource := range plan.Resources {
+			p.logger.Println(fmt.Sprintf("  %3s %s", planActionSymbols[resource.Action], resource.Address))
+		}
+		p.logger.Println(fmt.Sprintf("  %d to add, %d to change, %d to destroy", plan.Add, plan.Change, plan.Destroy))
+	}
+
+	if state.NoDirector && !state.Jumpbox.Enabled {
+		return nil
+	}
+
+	terraformOutputs, err := p.terraformManager.GetOutputs(state)
+	if err != nil {
+		return err
+	}
+
+	manifests, err := p.boshManager.PlanManifests(state, terraformOutputs)
+	if err != nil {
+		return err
+	}
+
+	if state.Jumpbox.Enabled {
+		p.printManifestDiff("jumpbox manifest:", manifests.JumpboxDiff)
+	}
+
+	if !state.NoDirector {
+		p.printManifestDiff("director manifest:", manifests.DirectorDiff)
+	}
+
+	return nil
+}
+
+func (p UpPlan) printManifestDiff(title, diff string) {
+	p.logger.Println(title)
+	if diff == "" {
+		p.logger.Println("  no changes")
+		return
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		p.logger.Println("  " + line)
+	}
+}
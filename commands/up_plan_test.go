@@ -0,0 +1,164 @@
+package commands_test
+
+import (
+	"errors"
+
+	"github.com/cloudfoundry/bosh-bootloader/bosh"
+	"github.com/cloudfoundry/bosh-bootloader/commands"
+	"github.com/cloudfoundry/bosh-bootloader/fakes"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+	"github.com/cloudfoundry/bosh-bootloader/terraform"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UpPlan", func() {
+	var (
+		logger           *fakes.Logger
+		terraformManager *fakes.TerraformManager
+		boshManager      *fakes.BOSHManager
+
+		command commands.UpPlan
+		state   storage.State
+	)
+
+	BeforeEach(func() {
+		logger = &fakes.Logger{}
+		terraformManager = &fakes.TerraformManager{}
+		boshManager = &fakes.BOSHManager{}
+
+		command = commands.NewUpPlan(logger, terraformManager, boshManager)
+
+		state = storage.State{
+			IAAS:    "gcp",
+			EnvID:   "some-env-id",
+			TFState: "some-tf-state",
+			BOSH:    storage.BOSH{Manifest: "some-manifest"},
+		}
+
+		terraformManager.PlanCall.Returns.Plan = terraform.Plan{
+			Add:     1,
+			Destroy: 1,
+			Resources: []terraform.PlannedResource{
+				{Action: terraform.PlanCreate, Address: "google_compute_firewall.bosh-open"},
+				{Action: terraform.PlanReplace, Address: "google_compute_address.bosh-external-ip"},
+			},
+		}
+		terraformManager.GetOutputsCall.Returns.Outputs = map[string]interface{}{"external_ip": "some-ip"}
+		boshManager.PlanManifestsCall.Returns.Plan = bosh.ManifestPlan{
+			DirectorDiff: "  name: bosh\n- version: 1\n+ version: 2\n",
+		}
+	})
+
+	It("prints the terraform plan and the director manifest diff", func() {
+		err := command.Execute(commands.UpConfig{}, state)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(terraformManager.PlanCall.Receives.BBLState).To(Equal(state))
+		Expect(terraformManager.ApplyCall.CallCount).To(Equal(0))
+		Expect(boshManager.PlanManifestsCall.Receives.State).To(Equal(state))
+		Expect(boshManager.PlanManifestsCall.Receives.TerraformOutputs).To(Equal(map[string]interface{}{"external_ip": "some-ip"}))
+		Expect(boshManager.CreateDirectorCall.CallCount).To(Equal(0))
+
+		Expect(logger.PrintlnCall.Messages).To(Equal([]string{
+			"infrastructure:",
+			"    + google_compute_firewall.bosh-open",
+			"  -/+ google_compute_address.bosh-external-ip",
+			"  1 to add, 0 to change, 1 to destroy",
+			"director manifest:",
+			"    name: bosh",
+			"  - version: 1",
+			"  + version: 2",
+		}))
+	})
+
+	It("reports when nothing changes", func() {
+		terraformManager.PlanCall.Returns.Plan = terraform.Plan{}
+		boshManager.PlanManifestsCall.Returns.Plan = bosh.ManifestPlan{}
+		state.Jumpbox.Enabled = true
+
+		err := command.Execute(commands.UpConfig{}, state)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(logger.PrintlnCall.Messages).To(Equal([]string{
+			"infrastructure:",
+			"  no changes",
+			"jumpbox manifest:",
+			"  no changes",
+			"director manifest:",
+			"  no changes",
+		}))
+	})
+
+	It("plans the director with the given ops files and vars", func() {
+		opsFiles := []storage.UserFile{{Path: "some-ops-file-path", Contents: "some-ops"}}
+		varsFiles := []storage.UserFile{{Path: "some-vars-file-path", Contents: "some-vars"}}
+
+		err := command.Execute(commands.UpConfig{
+			OpsFiles:  opsFiles,
+			VarsFiles: varsFiles,
+			Vars:      []string{"some-var=some-value"},
+		}, state)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(boshManager.PlanManifestsCall.Receives.State.BOSH.OpsFiles).To(Equal(opsFiles))
+		Expect(boshManager.PlanManifestsCall.Receives.State.BOSH.VarsFiles).To(Equal(varsFiles))
+		Expect(boshManager.PlanManifestsCall.Receives.State.BOSH.Vars).To(Equal([]string{"some-var=some-value"}))
+	})
+
+	It("plans the jumpbox with the given jumpbox ops files", func() {
+		opsFiles := []storage.UserFile{{Path: "some-jumpbox-ops-file-path", Contents: "some-jumpbox-ops"}}
+
+		err := command.Execute(commands.UpConfig{Jumpbox: true, JumpboxOpsFiles: opsFiles}, state)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(boshManager.PlanManifestsCall.Receives.State.Jumpbox.OpsFiles).To(Equal(opsFiles))
+	})
+
+	It("skips the manifests when there is no director or jumpbox", func() {
+		state.BOSH = storage.BOSH{}
+		state.NoDirector = true
+
+		err := command.Execute(commands.UpConfig{}, state)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(boshManager.PlanManifestsCall.CallCount).To(Equal(0))
+	})
+
+	Context("failure cases", func() {
+		It("returns an error when the environment does not exist", func() {
+			err := command.Execute(commands.UpConfig{}, storage.State{IAAS: "gcp"})
+			Expect(err).To(MatchError("bbl plan needs an existing environment, run `bbl up` to create one"))
+		})
+
+		It("returns an error when the environment still uses cloudformation", func() {
+			state.TFState = ""
+			state.Stack.Name = "some-stack"
+
+			err := command.Execute(commands.UpConfig{}, state)
+			Expect(err).To(MatchError("bbl plan cannot preview the migration from cloudformation to terraform, run `bbl up` to migrate"))
+		})
+
+		It("returns an error when the terraform version is invalid", func() {
+			terraformManager.ValidateVersionCall.Returns.Error = errors.New("bad version")
+
+			err := command.Execute(commands.UpConfig{}, state)
+			Expect(err).To(MatchError("bad version"))
+		})
+
+		It("returns an error when terraform plan fails", func() {
+			terraformManager.PlanCall.Returns.Error = errors.New("failed to plan")
+
+			err := command.Execute(commands.UpConfig{}, state)
+			Expect(err).To(MatchError("failed to plan"))
+		})
+
+		It("returns an error when the manifests cannot be interpolated", func() {
+			boshManager.PlanManifestsCall.Returns.Error = errors.New("failed to interpolate")
+
+			err := command.Execute(commands.UpConfig{}, state)
+			Expect(err).To(MatchError("failed to interpolate"))
+		})
+	})
+})
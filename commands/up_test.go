@@ -2,7 +2,10 @@ package commands_test
 
 import (
 	"errors"
+	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 
 	"github.com/cloudfoundry/bosh-bootloader/bosh"
 	"github.com/cloudfoundry/bosh-bootloader/commands"
@@ -17,16 +20,20 @@ var _ = Describe("Up", func() {
 	var (
 		command commands.Up
 
+		logger          *fakes.Logger
 		fakeUp          *fakes.UpCmd
+		fakePlan        *fakes.UpCmd
 		fakeBOSHManager *fakes.BOSHManager
 	)
 
 	BeforeEach(func() {
+		logger = &fakes.Logger{}
 		fakeUp = &fakes.UpCmd{}
+		fakePlan = &fakes.UpCmd{}
 		fakeBOSHManager = &fakes.BOSHManager{}
 		fakeBOSHManager.VersionCall.Returns.Version = "2.0.24"
 
-		command = commands.NewUp(fakeUp, fakeBOSHManager)
+		command = commands.NewUp(logger, fakeUp, fakePlan, fakeBOSHManager)
 	})
 
 	Describe("CheckFastFails", func() {
@@ -78,6 +85,32 @@ var _ = Describe("Up", func() {
 			})
 		})
 
+		Context("when jumpbox ops files are passed without a jumpbox", func() {
+			It("returns an error", func() {
+				err := command.CheckFastFails([]string{
+					"--jumpbox-ops-file", "some-jumpbox-ops-file",
+				}, storage.State{})
+				Expect(err).To(MatchError(`--jumpbox-ops-file requires a jumpbox, pass "--credhub"`))
+			})
+
+			It("does not return an error for an existing jumpbox", func() {
+				err := command.CheckFastFails([]string{
+					"--jumpbox-ops-file", "some-jumpbox-ops-file",
+				}, storage.State{Jumpbox: storage.Jumpbox{Enabled: true}})
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
+		Context("when runtime config ops files are passed without a director", func() {
+			It("returns an error", func() {
+				err := command.CheckFastFails([]string{
+					"--no-director",
+					"--runtime-config-ops-file", "some-runtime-config-ops-file",
+				}, storage.State{})
+				Expect(err).To(MatchError(`--runtime-config-ops-file requires a director, it cannot be used with "--no-director"`))
+			})
+		})
+
 		Context("when bbl-state contains an env-id", func() {
 			Context("when the passed in name matches the env-id", func() {
 				It("returns no error", func() {
@@ -97,6 +130,127 @@ var _ = Describe("Up", func() {
 				})
 			})
 		})
+
+		Context("when the --terraform-backend flag is specified", func() {
+			It("returns an error when the backend is invalid", func() {
+				err := command.CheckFastFails([]string{
+					"--terraform-backend", "gcs",
+				}, storage.State{Version: 999})
+				Expect(err).To(MatchError("the gcs terraform backend requires --terraform-backend-config bucket=<value>"))
+			})
+
+			It("returns an error when the environment already uses a different backend", func() {
+				err := command.CheckFastFails([]string{
+					"--terraform-backend", "s3",
+					"--terraform-backend-config", "bucket=some-other-bucket",
+				}, storage.State{
+					EnvID: "some-name",
+					TFBackend: &storage.TFBackend{
+						Type:   "s3",
+						Config: map[string]string{"bucket": "some-bucket"},
+					},
+				})
+				Expect(err).To(MatchError("The terraform backend cannot be changed for an existing environment. Current backend is s3."))
+			})
+
+			It("returns an error when --terraform-backend-config is given without a backend", func() {
+				err := command.CheckFastFails([]string{
+					"--terraform-backend-config", "bucket=some-bucket",
+				}, storage.State{Version: 999})
+				Expect(err).To(MatchError("--terraform-backend-config requires --terraform-backend"))
+			})
+		})
+		Context("when the --network-cidr flag is specified", func() {
+			It("returns an error when the subnets overlap", func() {
+				err := command.CheckFastFails([]string{
+					"--network-cidr", "172.16.0.0/16",
+					"--internal-subnet-cidr", "172.16.0.0/20",
+				}, storage.State{Version: 999})
+				Expect(err).To(MatchError("director subnet 172.16.0.0/24 overlaps internal subnet 1 172.16.0.0/20"))
+			})
+
+			It("returns an error when the environment already uses different cidrs", func() {
+				err := command.CheckFastFails([]string{
+					"--network-cidr", "172.16.0.0/16",
+				}, storage.State{
+					EnvID:   "some-name",
+					Network: &storage.Network{CIDR: "172.17.0.0/16"},
+					Version: 999,
+				})
+				Expect(err).To(MatchError("The network cidrs cannot be changed for an existing environment."))
+			})
+		})
+
+		Context("when components are enabled or disabled", func() {
+			It("returns an error for an unknown component", func() {
+				err := command.CheckFastFails([]string{
+					"--disable", "flow-logs,nat",
+				}, storage.State{IAAS: "aws", Version: 999})
+				Expect(err).To(MatchError(`unknown component "nat" for aws, valid options are: flow-logs, kms, s3-endpoint`))
+			})
+
+			It("returns an error when a component is both enabled and disabled", func() {
+				err := command.CheckFastFails([]string{
+					"--enable", "s3-endpoint",
+					"--disable", "s3-endpoint",
+				}, storage.State{IAAS: "aws", Version: 999})
+				Expect(err).To(MatchError(`component "s3-endpoint" cannot be both enabled and disabled`))
+			})
+
+			It("returns an error when the kms key of an existing environment would be deleted", func() {
+				err := command.CheckFastFails([]string{
+					"--disable", "kms",
+				}, storage.State{IAAS: "aws", EnvID: "some-name", Version: 999})
+				Expect(err).To(MatchError("The kms component cannot be disabled for an existing environment."))
+			})
+		})
+
+		Context("when an --existing-* network flag is specified", func() {
+			It("returns an error when the flag does not match the iaas", func() {
+				err := command.CheckFastFails([]string{
+					"--existing-vpc-id", "some-vpc-id",
+				}, storage.State{IAAS: "gcp", Version: 999})
+				Expect(err).To(MatchError("--existing-vpc-id can only be used with --iaas aws"))
+			})
+
+			It("returns an error when the vnet is not qualified by its resource group", func() {
+				err := command.CheckFastFails([]string{
+					"--existing-vnet", "some-vnet",
+				}, storage.State{IAAS: "azure", Version: 999})
+				Expect(err).To(MatchError("--existing-vnet must be given as <resource-group>/<vnet>"))
+			})
+
+			It("returns an error when an existing environment created its own network", func() {
+				err := command.CheckFastFails([]string{
+					"--existing-network", "some-network",
+				}, storage.State{IAAS: "gcp", EnvID: "some-name", Version: 999})
+				Expect(err).To(MatchError("The network cannot be changed for an existing environment."))
+			})
+
+			It("returns an error when the environment uses a different existing network", func() {
+				err := command.CheckFastFails([]string{
+					"--existing-vpc-id", "some-other-vpc-id",
+				}, storage.State{
+					IAAS:    "aws",
+					EnvID:   "some-name",
+					AWS:     storage.AWS{ExistingVPCID: "some-vpc-id"},
+					Version: 999,
+				})
+				Expect(err).To(MatchError("The network cannot be changed for an existing environment."))
+			})
+
+			It("does not return an error when the environment already uses the network", func() {
+				err := command.CheckFastFails([]string{
+					"--existing-vpc-id", "some-vpc-id",
+				}, storage.State{
+					IAAS:    "aws",
+					EnvID:   "some-name",
+					AWS:     storage.AWS{ExistingVPCID: "some-vpc-id"},
+					Version: 999,
+				})
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
 	})
 
 	Describe("Execute", func() {
@@ -107,31 +261,144 @@ var _ = Describe("Up", func() {
 			Expect(fakeUp.ExecuteCall.CallCount).To(Equal(1))
 		})
 
-		Context("when the --ops-file flag is specified", func() {
-			It("populates the aws config with the correct ops-file path", func() {
+		Context("when the --ops-file, --vars-file and --var flags are specified", func() {
+			var (
+				opsFilePath      string
+				otherOpsFilePath string
+				varsFilePath     string
+			)
+
+			BeforeEach(func() {
+				tempDir, err := ioutil.TempDir("", "")
+				Expect(err).NotTo(HaveOccurred())
+
+				opsFilePath = filepath.Join(tempDir, "ops-file.yml")
+				otherOpsFilePath = filepath.Join(tempDir, "other-ops-file.yml")
+				varsFilePath = filepath.Join(tempDir, "vars-file.yml")
+
+				for path, contents := range map[string]string{
+					opsFilePath:      "some-ops-file-contents",
+					otherOpsFilePath: "some-other-ops-file-contents",
+					varsFilePath:     "some-vars-file-contents",
+				} {
+					err = ioutil.WriteFile(path, []byte(contents), os.ModePerm)
+					Expect(err).NotTo(HaveOccurred())
+				}
+			})
+
+			It("reads the files in the order they were passed", func() {
 				err := command.Execute([]string{
-					"--ops-file", "some-ops-file-path",
-				}, storage.State{})
+					"--ops-file", otherOpsFilePath,
+					"--ops-file", opsFilePath,
+					"--vars-file", varsFilePath,
+					"--var", "some-var=some-value",
+				}, storage.State{
+					BOSH: storage.BOSH{
+						OpsFiles: []storage.UserFile{{Path: "some-stored-path", Contents: "some-stored-contents"}},
+					},
+				})
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(fakeUp.ExecuteCall.Receives.UpConfig.OpsFile).To(Equal("some-ops-file-path"))
+				upConfig := fakeUp.ExecuteCall.Receives.UpConfig
+				Expect(upConfig.OpsFiles).To(Equal([]storage.UserFile{
+					{Path: otherOpsFilePath, Contents: "some-other-ops-file-contents"},
+					{Path: opsFilePath, Contents: "some-ops-file-contents"},
+				}))
+				Expect(upConfig.VarsFiles).To(Equal([]storage.UserFile{
+					{Path: varsFilePath, Contents: "some-vars-file-contents"},
+				}))
+				Expect(upConfig.Vars).To(Equal([]string{"some-var=some-value"}))
 			})
 
-			Context("when the --ops-file flag is not specified", func() {
-				It("creates a default ops-file with the contents of state.BOSH.UserOpsFile", func() {
+			Context("when the flags are not specified", func() {
+				It("reuses the files and vars in state", func() {
+					bosh := storage.BOSH{
+						OpsFiles:  []storage.UserFile{{Path: opsFilePath, Contents: "some-ops-file-contents"}},
+						VarsFiles: []storage.UserFile{{Path: "some-deleted-path", Contents: "some-vars-file-contents"}},
+						Vars:      []string{"some-var=some-value"},
+					}
+
+					err := command.Execute([]string{}, storage.State{BOSH: bosh})
+					Expect(err).NotTo(HaveOccurred())
+
+					upConfig := fakeUp.ExecuteCall.Receives.UpConfig
+					Expect(upConfig.OpsFiles).To(Equal(bosh.OpsFiles))
+					Expect(upConfig.VarsFiles).To(Equal(bosh.VarsFiles))
+					Expect(upConfig.Vars).To(Equal(bosh.Vars))
+					Expect(logger.PrintfCall.Messages).To(BeEmpty())
+				})
+
+				It("warns when a stored file has changed on disk", func() {
 					err := command.Execute([]string{}, storage.State{
 						BOSH: storage.BOSH{
-							UserOpsFile: "some-ops-file-contents",
+							OpsFiles: []storage.UserFile{{Path: opsFilePath, Contents: "some-old-contents"}},
 						},
 					})
 					Expect(err).NotTo(HaveOccurred())
 
-					filePath := fakeUp.ExecuteCall.Receives.UpConfig.OpsFile
-					fileContents, err := ioutil.ReadFile(filePath)
-					Expect(err).NotTo(HaveOccurred())
+					Expect(fakeUp.ExecuteCall.Receives.UpConfig.OpsFiles).To(Equal([]storage.UserFile{
+						{Path: opsFilePath, Contents: "some-old-contents"},
+					}))
+					Expect(logger.PrintfCall.Messages).To(Equal([]string{
+						fmt.Sprintf("warning: ops-file %s has changed since it was stored, pass --ops-file %s to apply the new contents\n", opsFilePath, opsFilePath),
+					}))
+				})
+			})
+
+			It("removes the stored files and vars when the flags are empty", func() {
+				err := command.Execute([]string{"--ops-file", "", "--vars-file", "", "--var", ""}, storage.State{
+					BOSH: storage.BOSH{
+						OpsFiles:  []storage.UserFile{{Path: opsFilePath, Contents: "some-ops-file-contents"}},
+						VarsFiles: []storage.UserFile{{Path: varsFilePath, Contents: "some-vars-file-contents"}},
+						Vars:      []string{"some-var=some-value"},
+					},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				upConfig := fakeUp.ExecuteCall.Receives.UpConfig
+				Expect(upConfig.OpsFiles).To(BeEmpty())
+				Expect(upConfig.VarsFiles).To(BeEmpty())
+				Expect(upConfig.Vars).To(BeEmpty())
+			})
 
-					Expect(string(fileContents)).To(Equal("some-ops-file-contents"))
+			It("reads the jumpbox ops files and reuses the stored ones without the flag", func() {
+				err := command.Execute([]string{"--credhub", "--jumpbox-ops-file", opsFilePath}, storage.State{})
+				Expect(err).NotTo(HaveOccurred())
+
+				jumpboxOpsFiles := []storage.UserFile{{Path: opsFilePath, Contents: "some-ops-file-contents"}}
+				Expect(fakeUp.ExecuteCall.Receives.UpConfig.JumpboxOpsFiles).To(Equal(jumpboxOpsFiles))
+
+				err = command.Execute([]string{}, storage.State{
+					Jumpbox: storage.Jumpbox{Enabled: true, OpsFiles: jumpboxOpsFiles},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeUp.ExecuteCall.Receives.UpConfig.JumpboxOpsFiles).To(Equal(jumpboxOpsFiles))
+			})
+
+			It("reads the runtime config ops files and reuses the stored ones without the flag", func() {
+				err := command.Execute([]string{"--runtime-config-ops-file", opsFilePath}, storage.State{})
+				Expect(err).NotTo(HaveOccurred())
+
+				runtimeConfigOpsFiles := []storage.UserFile{{Path: opsFilePath, Contents: "some-ops-file-contents"}}
+				Expect(fakeUp.ExecuteCall.Receives.UpConfig.RuntimeConfigOpsFiles).To(Equal(runtimeConfigOpsFiles))
+
+				err = command.Execute([]string{}, storage.State{
+					BOSH: storage.BOSH{RuntimeConfigOpsFiles: runtimeConfigOpsFiles},
 				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeUp.ExecuteCall.Receives.UpConfig.RuntimeConfigOpsFiles).To(Equal(runtimeConfigOpsFiles))
+			})
+
+			It("returns an error when an ops file cannot be read", func() {
+				err := command.Execute([]string{"--ops-file", "some/fake/path"}, storage.State{})
+				Expect(err).To(MatchError("error reading ops-file contents: open some/fake/path: no such file or directory"))
+			})
+
+			It("returns an error when a vars file cannot be read", func() {
+				err := command.Execute([]string{"--vars-file", "some/fake/path"}, storage.State{})
+				Expect(err).To(MatchError("error reading vars-file contents: open some/fake/path: no such file or directory"))
 			})
 		})
 
@@ -196,6 +463,127 @@ var _ = Describe("Up", func() {
 			})
 		})
 
+		Context("when the --terraform-backend flag is specified", func() {
+			It("stores the backend in the state passed to up", func() {
+				err := command.Execute([]string{
+					"--terraform-backend", "s3",
+					"--terraform-backend-config", "bucket=some-bucket",
+					"--terraform-backend-config", "region=some-region",
+				}, storage.State{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeUp.ExecuteCall.Receives.State.TFBackend).To(Equal(&storage.TFBackend{
+					Type: "s3",
+					Config: map[string]string{
+						"bucket": "some-bucket",
+						"region": "some-region",
+					},
+				}))
+			})
+		})
+
+		Context("when the --network-cidr flag is specified", func() {
+			It("stores the network in the state passed to up", func() {
+				err := command.Execute([]string{
+					"--network-cidr", "172.16.0.0/16",
+					"--director-subnet-cidr", "172.16.1.0/24",
+					"--internal-subnet-cidr", "172.16.128.0/20",
+					"--internal-subnet-cidr", "172.16.144.0/20",
+				}, storage.State{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeUp.ExecuteCall.Receives.State.Network).To(Equal(&storage.Network{
+					CIDR:                "172.16.0.0/16",
+					DirectorSubnetCIDR:  "172.16.1.0/24",
+					InternalSubnetCIDRs: []string{"172.16.128.0/20", "172.16.144.0/20"},
+				}))
+			})
+
+			It("keeps the network of the state when the flag is omitted", func() {
+				network := &storage.Network{CIDR: "172.16.0.0/16"}
+				err := command.Execute([]string{}, storage.State{Network: network})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeUp.ExecuteCall.Receives.State.Network).To(Equal(network))
+			})
+		})
+
+		Context("when components are enabled or disabled", func() {
+			It("stores the components in the state passed to up", func() {
+				componentsFile, err := ioutil.TempFile("", "")
+				Expect(err).NotTo(HaveOccurred())
+				_, err = componentsFile.WriteString("enable: [s3-endpoint]\ndisable: [kms]\n")
+				Expect(err).NotTo(HaveOccurred())
+
+				err = command.Execute([]string{
+					"--components-file", componentsFile.Name(),
+					"--disable", "flow-logs",
+					"--enable", "kms",
+				}, storage.State{IAAS: "aws"})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeUp.ExecuteCall.Receives.State.Components).To(Equal(storage.Components{
+					"flow-logs":   false,
+					"kms":         true,
+					"s3-endpoint": true,
+				}))
+			})
+
+			It("keeps the components of the state when no flags are given", func() {
+				components := storage.Components{"flow-logs": false}
+				err := command.Execute([]string{}, storage.State{IAAS: "aws", Components: components})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeUp.ExecuteCall.Receives.State.Components).To(Equal(components))
+			})
+		})
+
+		Context("when an --existing-* network flag is specified", func() {
+			It("stores the existing vpc in the state passed to up", func() {
+				err := command.Execute([]string{
+					"--existing-vpc-id", "some-vpc-id",
+				}, storage.State{IAAS: "aws"})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeUp.ExecuteCall.Receives.State.AWS.ExistingVPCID).To(Equal("some-vpc-id"))
+			})
+
+			It("stores the existing network in the state passed to up", func() {
+				err := command.Execute([]string{
+					"--existing-network", "some-network",
+				}, storage.State{IAAS: "gcp"})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeUp.ExecuteCall.Receives.State.GCP.ExistingNetwork).To(Equal("some-network"))
+			})
+
+			It("stores the existing vnet and its resource group in the state passed to up", func() {
+				err := command.Execute([]string{
+					"--existing-vnet", "some-resource-group/some-vnet",
+				}, storage.State{IAAS: "azure"})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeUp.ExecuteCall.Receives.State.Azure.ExistingVNet).To(Equal("some-vnet"))
+				Expect(fakeUp.ExecuteCall.Receives.State.Azure.ExistingVNetResourceGroup).To(Equal("some-resource-group"))
+			})
+		})
+
+		Context("when the --dry-run flag is specified", func() {
+			It("plans instead of executing up", func() {
+				err := command.Execute([]string{
+					"--dry-run",
+					"--credhub",
+				}, storage.State{EnvID: "some-env-id"})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeUp.ExecuteCall.CallCount).To(Equal(0))
+				Expect(fakePlan.ExecuteCall.CallCount).To(Equal(1))
+				Expect(fakePlan.ExecuteCall.Receives.UpConfig.DryRun).To(BeTrue())
+				Expect(fakePlan.ExecuteCall.Receives.UpConfig.Jumpbox).To(BeTrue())
+				Expect(fakePlan.ExecuteCall.Receives.State).To(Equal(storage.State{EnvID: "some-env-id"}))
+			})
+		})
+
 		Context("failure cases", func() {
 			It("returns an error when the up command fails", func() {
 				fakeUp.ExecuteCall.Returns.Error = errors.New("failed execution")
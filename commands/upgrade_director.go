@@ -0,0 +1,137 @@
+package commands
+
+import (
+	"errors"
+
+	"github.com/cloudfoundry/bosh-bootloader/bosh"
+	"github.com/cloudfoundry/bosh-bootloader/flags"
+	"github.com/cloudfoundry/bosh-bootloader/helpers"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+)
+
+type UpgradeDirector struct {
+	stateValidator     stateValidator
+	terraformOutputter terraformOutputter
+	boshManager        boshManager
+	stateStore         stateStore
+}
+
+type upgradeDirectorConfig struct {
+	jumpboxOnly  bool
+	directorOnly bool
+}
+
+func NewUpgradeDirector(stateValidator stateValidator, terraformOutputter terraformOutputter,
+	boshManager boshManager, stateStore stateStore) UpgradeDirector {
+	return UpgradeDirector{
+		stateValidator:     stateValidator,
+		terraformOutputter: terraformOutputter,
+		boshManager:        boshManager,
+		stateStore:         stateStore,
+	}
+}
+
+func (u UpgradeDirector) CheckFastFails(subcommandFlags []string, state storage.State) error {
+	config, err := parseUpgradeDirectorArgs(subcommandFlags)
+	if err != nil {
+		return err
+	}
+
+	err = u.stateValidator.Validate()
+	if err != nil {
+		return err
+	}
+
+	if config.jumpboxOnly && config.directorOnly {
+		return errors.New("--jumpbox-only and --director-only cannot be used together")
+	}
+
+	if state.NoDirector || state.BOSH.IsEmpty() {
+		return errors.New(`Environment has no director to upgrade, run "bbl up" instead`)
+	}
+
+	if config.jumpboxOnly && !state.Jumpbox.Enabled {
+		return errors.New("--jumpbox-only requires an environment with a jumpbox")
+	}
+
+	return fastFailBOSHVersion(u.boshManager)
+}
+
+// Execute redeploys the jumpbox and the director with the manifests, vars
+// stores and ops files in the state. The infrastructure is left untouched:
+// the terraform outputs are read, never applied.
+func (u UpgradeDirector) Execute(subcommandFlags []string, state storage.State) error {
+	config, err := parseUpgradeDirectorArgs(subcommandFlags)
+	if err != nil {
+		return err
+	}
+
+	terraformOutputs, err := u.terraformOutputter.GetOutputs(state)
+	if err != nil {
+		return err
+	}
+
+	if state.Jumpbox.Enabled && !config.directorOnly {
+		state, err = u.boshManager.CreateJumpbox(state, terraformOutputs)
+		if err != nil {
+			return u.saveCreateErrorState(err)
+		}
+
+		err = u.stateStore.Set(state)
+		if err != nil {
+			return err
+		}
+	}
+
+	if config.jumpboxOnly {
+		return nil
+	}
+
+	// Deploying the jumpbox starts the proxy the director is reached through,
+	// so it has to be started here when the jumpbox is left alone.
+	if state.Jumpbox.Enabled && config.directorOnly {
+		err = u.boshManager.StartJumpboxProxy(state)
+		if err != nil {
+			return err
+		}
+	}
+
+	state, err = u.boshManager.CreateDirector(state, terraformOutputs)
+	if err != nil {
+		return u.saveCreateErrorState(err)
+	}
+
+	return u.stateStore.Set(state)
+}
+
+// saveCreateErrorState saves the state a failed create-env returned with its
+// error, so that the next run picks up the partially created vm and vars.
+func (u UpgradeDirector) saveCreateErrorState(err error) error {
+	bcErr, ok := err.(bosh.ManagerCreateError)
+	if !ok {
+		return err
+	}
+
+	if setErr := u.stateStore.Set(bcErr.State()); setErr != nil {
+		errorList := helpers.Errors{}
+		errorList.Add(err)
+		errorList.Add(setErr)
+		return errorList
+	}
+
+	return err
+}
+
+func parseUpgradeDirectorArgs(subcommandFlags []string) (upgradeDirectorConfig, error) {
+	var config upgradeDirectorConfig
+	upgradeDirectorFlags := flags.New("upgrade-director")
+	upgradeDirectorFlags.Bool(&config.jumpboxOnly, "", "jumpbox-only", false)
+	upgradeDirectorFlags.Bool(&config.directorOnly, "", "director-only", false)
+
+	err := upgradeDirectorFlags.Parse(subcommandFlags)
+	if err != nil {
+		return upgradeDirectorConfig{}, err
+	}
+
+	return config, nil
+}
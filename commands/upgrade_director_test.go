@@ -0,0 +1,241 @@
+package commands_test
+
+import (
+	"errors"
+
+	"github.com/cloudfoundry/bosh-bootloader/bosh"
+	"github.com/cloudfoundry/bosh-bootloader/commands"
+	"github.com/cloudfoundry/bosh-bootloader/fakes"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UpgradeDirector", func() {
+	var (
+		stateValidator   *fakes.StateValidator
+		terraformManager *fakes.TerraformManager
+		boshManager      *fakes.BOSHManager
+		stateStore       *fakes.StateStore
+
+		command commands.UpgradeDirector
+		state   storage.State
+	)
+
+	BeforeEach(func() {
+		stateValidator = &fakes.StateValidator{}
+		terraformManager = &fakes.TerraformManager{}
+		boshManager = &fakes.BOSHManager{}
+		stateStore = &fakes.StateStore{}
+
+		command = commands.NewUpgradeDirector(stateValidator, terraformManager, boshManager, stateStore)
+		state = storage.State{
+			IAAS:    "gcp",
+			Jumpbox: storage.Jumpbox{Enabled: true, Manifest: "some-jumpbox-manifest"},
+			BOSH: storage.BOSH{
+				Manifest:  "some-director-manifest",
+				OpsFiles:  []storage.UserFile{{Path: "some-ops-file.yml", Contents: "some-ops"}},
+				Variables: "some-vars-store",
+			},
+		}
+
+		boshManager.VersionCall.Returns.Version = "2.0.24"
+		terraformManager.GetOutputsCall.Returns.Outputs = map[string]interface{}{"director_address": "10.0.0.6"}
+	})
+
+	Describe("CheckFastFails", func() {
+		It("returns an error when the state does not exist", func() {
+			stateValidator.ValidateCall.Returns.Error = errors.New("state file not found")
+
+			err := command.CheckFastFails([]string{}, state)
+			Expect(err).To(MatchError("state file not found"))
+		})
+
+		It("returns an error when there is no director", func() {
+			err := command.CheckFastFails([]string{}, storage.State{IAAS: "gcp", NoDirector: true})
+			Expect(err).To(MatchError(`Environment has no director to upgrade, run "bbl up" instead`))
+
+			err = command.CheckFastFails([]string{}, storage.State{IAAS: "gcp"})
+			Expect(err).To(MatchError(`Environment has no director to upgrade, run "bbl up" instead`))
+		})
+
+		It("returns an error when --jumpbox-only and --director-only are both passed", func() {
+			err := command.CheckFastFails([]string{"--jumpbox-only", "--director-only"}, state)
+			Expect(err).To(MatchError("--jumpbox-only and --director-only cannot be used together"))
+		})
+
+		It("returns an error when --jumpbox-only is passed without a jumpbox", func() {
+			state.Jumpbox = storage.Jumpbox{}
+
+			err := command.CheckFastFails([]string{"--jumpbox-only"}, state)
+			Expect(err).To(MatchError("--jumpbox-only requires an environment with a jumpbox"))
+		})
+
+		It("returns an error when the bosh version is too old", func() {
+			boshManager.VersionCall.Returns.Version = "1.9.0"
+
+			err := command.CheckFastFails([]string{}, state)
+			Expect(err).To(MatchError("BOSH version must be at least v2.0.24"))
+		})
+
+		It("returns an error when the flags cannot be parsed", func() {
+			err := command.CheckFastFails([]string{"--unknown-flag"}, state)
+			Expect(err).To(MatchError("flag provided but not defined: -unknown-flag"))
+		})
+	})
+
+	Describe("Execute", func() {
+		var (
+			jumpboxState  storage.State
+			directorState storage.State
+		)
+
+		BeforeEach(func() {
+			jumpboxState = state
+			jumpboxState.BOSH.DirectorAddress = "10.0.0.6"
+			directorState = jumpboxState
+			directorState.BOSH.State = map[string]interface{}{"some-key": "director"}
+
+			boshManager.CreateJumpboxCall.Returns.State = jumpboxState
+			boshManager.CreateDirectorCall.Returns.State = directorState
+		})
+
+		It("redeploys the jumpbox and the director with the current terraform outputs", func() {
+			err := command.Execute([]string{}, state)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(terraformManager.GetOutputsCall.Receives.BBLState).To(Equal(state))
+			Expect(terraformManager.ApplyCall.CallCount).To(Equal(0))
+
+			Expect(boshManager.CreateJumpboxCall.Receives.State).To(Equal(state))
+			Expect(boshManager.CreateJumpboxCall.Receives.TerraformOutputs).To(Equal(map[string]interface{}{"director_address": "10.0.0.6"}))
+			Expect(boshManager.CreateDirectorCall.Receives.State).To(Equal(jumpboxState))
+			Expect(boshManager.CreateDirectorCall.Receives.TerraformOutputs).To(Equal(map[string]interface{}{"director_address": "10.0.0.6"}))
+
+			Expect(stateStore.SetCall.Receives).To(Equal([]fakes.SetCallReceive{
+				{State: jumpboxState},
+				{State: directorState},
+			}))
+		})
+
+		It("only redeploys the director when there is no jumpbox", func() {
+			state.Jumpbox = storage.Jumpbox{}
+
+			err := command.Execute([]string{}, state)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(boshManager.CreateJumpboxCall.CallCount).To(Equal(0))
+			Expect(boshManager.CreateDirectorCall.Receives.State).To(Equal(state))
+		})
+
+		It("only redeploys the jumpbox when --jumpbox-only is passed", func() {
+			err := command.Execute([]string{"--jumpbox-only"}, state)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(boshManager.CreateJumpboxCall.CallCount).To(Equal(1))
+			Expect(boshManager.CreateDirectorCall.CallCount).To(Equal(0))
+			Expect(stateStore.SetCall.Receives).To(Equal([]fakes.SetCallReceive{{State: jumpboxState}}))
+		})
+
+		It("only redeploys the director when --director-only is passed", func() {
+			err := command.Execute([]string{"--director-only"}, state)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(boshManager.CreateJumpboxCall.CallCount).To(Equal(0))
+			Expect(boshManager.CreateDirectorCall.Receives.State).To(Equal(state))
+			Expect(stateStore.SetCall.Receives).To(Equal([]fakes.SetCallReceive{{State: directorState}}))
+		})
+
+		It("starts the proxy to the existing jumpbox when --director-only is passed", func() {
+			err := command.Execute([]string{"--director-only"}, state)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(boshManager.StartJumpboxProxyCall.CallCount).To(Equal(1))
+			Expect(boshManager.StartJumpboxProxyCall.Receives.State).To(Equal(state))
+		})
+
+		It("does not start a proxy when the jumpbox is redeployed or there is none", func() {
+			err := command.Execute([]string{}, state)
+			Expect(err).NotTo(HaveOccurred())
+
+			state.Jumpbox = storage.Jumpbox{}
+			err = command.Execute([]string{"--director-only"}, state)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(boshManager.StartJumpboxProxyCall.CallCount).To(Equal(0))
+		})
+
+		It("returns an error when the proxy to the jumpbox cannot be started", func() {
+			boshManager.StartJumpboxProxyCall.Returns.Error = errors.New("failed to start proxy")
+
+			err := command.Execute([]string{"--director-only"}, state)
+			Expect(err).To(MatchError("failed to start proxy"))
+			Expect(boshManager.CreateDirectorCall.CallCount).To(Equal(0))
+		})
+
+		It("returns an error when the terraform outputs cannot be read", func() {
+			terraformManager.GetOutputsCall.Returns.Error = errors.New("failed to get outputs")
+
+			err := command.Execute([]string{}, state)
+			Expect(err).To(MatchError("failed to get outputs"))
+			Expect(boshManager.CreateJumpboxCall.CallCount).To(Equal(0))
+		})
+
+		It("returns an error when the jumpbox cannot be created", func() {
+			boshManager.CreateJumpboxCall.Returns.Error = errors.New("failed to create jumpbox")
+
+			err := command.Execute([]string{}, state)
+			Expect(err).To(MatchError("failed to create jumpbox"))
+			Expect(boshManager.CreateDirectorCall.CallCount).To(Equal(0))
+			Expect(stateStore.SetCall.CallCount).To(Equal(0))
+		})
+
+		It("saves the state returned with the error when the jumpbox cannot be created", func() {
+			failedState := state
+			failedState.Jumpbox.State = map[string]interface{}{"some-key": "partial"}
+			boshManager.CreateJumpboxCall.Returns.Error = bosh.NewManagerCreateError(failedState, errors.New("failed to create jumpbox"))
+
+			err := command.Execute([]string{"--jumpbox-only"}, state)
+			Expect(err).To(MatchError("failed to create jumpbox"))
+
+			Expect(stateStore.SetCall.Receives).To(Equal([]fakes.SetCallReceive{{State: failedState}}))
+			Expect(boshManager.CreateDirectorCall.CallCount).To(Equal(0))
+		})
+
+		Context("when the director cannot be created", func() {
+			var failedState storage.State
+
+			BeforeEach(func() {
+				failedState = jumpboxState
+				failedState.BOSH.State = map[string]interface{}{"some-key": "partial"}
+				boshManager.CreateDirectorCall.Returns.Error = bosh.NewManagerCreateError(failedState, errors.New("failed to create"))
+			})
+
+			It("saves the state returned with the error", func() {
+				err := command.Execute([]string{}, state)
+				Expect(err).To(MatchError("failed to create"))
+
+				Expect(stateStore.SetCall.Receives).To(Equal([]fakes.SetCallReceive{
+					{State: jumpboxState},
+					{State: failedState},
+				}))
+			})
+
+			It("returns a compound error when the state cannot be saved", func() {
+				stateStore.SetCall.Returns = []fakes.SetCallReturn{{}, {Error: errors.New("state failed to be set")}}
+
+				err := command.Execute([]string{}, state)
+				Expect(err).To(MatchError("the following errors occurred:\nfailed to create,\nstate failed to be set"))
+			})
+		})
+
+		It("returns an error when the director cannot be created", func() {
+			boshManager.CreateDirectorCall.Returns.Error = errors.New("failed to create director")
+
+			err := command.Execute([]string{}, state)
+			Expect(err).To(MatchError("failed to create director"))
+			Expect(stateStore.SetCall.CallCount).To(Equal(1))
+		})
+	})
+})
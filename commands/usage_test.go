@@ -41,6 +41,18 @@ Usage:
 Global Options:
   --help      [-h]       Prints usage
   --state-dir            Directory containing bbl-state.json
+  --state-backend        Where bbl-state.json is stored. Valid options: "local", "s3", "gcs" (Defaults to environment variable BBL_STATE_BACKEND)
+  --state-bucket         Bucket bbl-state.json is stored in with the "s3" or "gcs" state backend (Defaults to environment variable BBL_STATE_BUCKET)
+  --state-prefix         Prefix of the state files in the bucket (Defaults to environment variable BBL_STATE_PREFIX)
+  --state-endpoint       Endpoint of an S3 compatible object store (Defaults to environment variable BBL_STATE_ENDPOINT)
+  --state-region         Region of the bucket (Defaults to "us-east-1" for "s3", or environment variable BBL_STATE_REGION)
+  --state-access-key-id  Access key ID for the bucket, an HMAC key for "gcs" (Defaults to environment variable BBL_STATE_ACCESS_KEY_ID)
+  --state-secret-access-key Secret access key for the bucket (Defaults to environment variable BBL_STATE_SECRET_ACCESS_KEY)
+  --lock-timeout         How long to wait for a locked state directory, e.g. "5m" (Defaults to environment variable BBL_LOCK_TIMEOUT)
+  --terraform-retries    How often a transient terraform failure is retried (Defaults to 0, or environment variable BBL_TERRAFORM_RETRIES)
+  --terraform-retry-backoff How long to wait before the first retry, doubled for every retry (Defaults to "10s", or environment variable BBL_TERRAFORM_RETRY_BACKOFF)
+  --bosh-deployment-dir  Local bosh-deployment checkout to use instead of the one compiled into bbl (Defaults to environment variable BBL_BOSH_DEPLOYMENT_DIR)
+  --jumpbox-deployment-dir Local jumpbox-deployment checkout to use instead of the one compiled into bbl (Defaults to environment variable BBL_JUMPBOX_DEPLOYMENT_DIR)
   --debug                Prints debugging output
   --version              Prints version
 
@@ -48,14 +60,19 @@ Commands:
   help                   Prints usage
   version                Prints version
   up                     Deploys BOSH director on an IAAS
+  plan                   Prints the changes "bbl up" would make
+  drift                  Checks the infrastructure for changes made outside of bbl
+  terraform              Lists, shows, removes, moves or imports resources in the terraform state
   destroy                Tears down BOSH director infrastructure
   lbs                    Prints attached load balancer(s)
   create-lbs             Attaches load balancer(s)
   update-lbs             Updates load balancer(s)
   delete-lbs             Deletes attached load balancer(s)
   rotate                 Rotates SSH key for the jumpbox user
+  upgrade-director       Redeploys the jumpbox and BOSH director without changing the infrastructure
   bosh-deployment-vars   Prints required variables for BOSH deployment
   cloud-config           Prints suggested cloud configuration for BOSH environment
+  runtime-config         Prints the runtime config bbl applies to the BOSH director
   jumpbox-address        Prints BOSH jumpbox address
   director-address       Prints BOSH director address
   director-username      Prints BOSH director username
@@ -65,6 +82,11 @@ Commands:
   latest-error           Prints the output from the latest call to terraform
   print-env              Prints BOSH friendly environment variables
   ssh-key                Prints SSH private key
+  state                  Shows, encrypts, splits, lists the history of, or rolls back bbl-state.json
+  force-unlock           Removes a stale lock on the state directory
+  export                 Packages the environment into a portable bundle
+  import                 Imports an environment bundle into the state directory
+  fsck                   Checks bbl-state.json for inconsistencies
 
   Use "bbl [command] --help" for more information about a command.
 `, "\n")))
@@ -81,6 +103,18 @@ Commands:
 Global Options:
   --help      [-h]       Prints usage
   --state-dir            Directory containing bbl-state.json
+  --state-backend        Where bbl-state.json is stored. Valid options: "local", "s3", "gcs" (Defaults to environment variable BBL_STATE_BACKEND)
+  --state-bucket         Bucket bbl-state.json is stored in with the "s3" or "gcs" state backend (Defaults to environment variable BBL_STATE_BUCKET)
+  --state-prefix         Prefix of the state files in the bucket (Defaults to environment variable BBL_STATE_PREFIX)
+  --state-endpoint       Endpoint of an S3 compatible object store (Defaults to environment variable BBL_STATE_ENDPOINT)
+  --state-region         Region of the bucket (Defaults to "us-east-1" for "s3", or environment variable BBL_STATE_REGION)
+  --state-access-key-id  Access key ID for the bucket, an HMAC key for "gcs" (Defaults to environment variable BBL_STATE_ACCESS_KEY_ID)
+  --state-secret-access-key Secret access key for the bucket (Defaults to environment variable BBL_STATE_SECRET_ACCESS_KEY)
+  --lock-timeout         How long to wait for a locked state directory, e.g. "5m" (Defaults to environment variable BBL_LOCK_TIMEOUT)
+  --terraform-retries    How often a transient terraform failure is retried (Defaults to 0, or environment variable BBL_TERRAFORM_RETRIES)
+  --terraform-retry-backoff How long to wait before the first retry, doubled for every retry (Defaults to "10s", or environment variable BBL_TERRAFORM_RETRY_BACKOFF)
+  --bosh-deployment-dir  Local bosh-deployment checkout to use instead of the one compiled into bbl (Defaults to environment variable BBL_BOSH_DEPLOYMENT_DIR)
+  --jumpbox-deployment-dir Local jumpbox-deployment checkout to use instead of the one compiled into bbl (Defaults to environment variable BBL_JUMPBOX_DEPLOYMENT_DIR)
   --debug                Prints debugging output
   --version              Prints version
 
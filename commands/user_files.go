@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+)
+
+// withUserFiles reads the files passed with --ops-file, --vars-file,
+// --jumpbox-ops-file and --runtime-config-ops-file into the config, in the
+// order they were passed. Without the flags the files and vars stored by the
+// previous bbl up are applied again, and a warning is logged for every stored
+// file whose path now holds different contents.
+// Passing a flag with an empty value removes the stored files or vars.
+func withUserFiles(logger logger, config UpConfig, state storage.State) (UpConfig, error) {
+	var err error
+
+	config.OpsFiles = state.BOSH.OpsFiles
+	if len(config.OpsFilePaths) > 0 {
+		config.OpsFiles, err = readUserFiles(config.OpsFilePaths)
+		if err != nil {
+			return UpConfig{}, fmt.Errorf("error reading ops-file contents: %v", err)
+		}
+	} else {
+		warnChangedUserFiles(logger, "ops-file", config.OpsFiles)
+	}
+
+	config.VarsFiles = state.BOSH.VarsFiles
+	if len(config.VarsFilePaths) > 0 {
+		config.VarsFiles, err = readUserFiles(config.VarsFilePaths)
+		if err != nil {
+			return UpConfig{}, fmt.Errorf("error reading vars-file contents: %v", err)
+		}
+	} else {
+		warnChangedUserFiles(logger, "vars-file", config.VarsFiles)
+	}
+
+	config.JumpboxOpsFiles = state.Jumpbox.OpsFiles
+	if len(config.JumpboxOpsFilePaths) > 0 {
+		config.JumpboxOpsFiles, err = readUserFiles(config.JumpboxOpsFilePaths)
+		if err != nil {
+			return UpConfig{}, fmt.Errorf("error reading jumpbox-ops-file contents: %v", err)
+		}
+	} else {
+		warnChangedUserFiles(logger, "jumpbox-ops-file", config.JumpboxOpsFiles)
+	}
+
+	config.RuntimeConfigOpsFiles = state.BOSH.RuntimeConfigOpsFiles
+	if len(config.RuntimeConfigOpsFilePaths) > 0 {
+		config.RuntimeConfigOpsFiles, err = readUserFiles(config.RuntimeConfigOpsFilePaths)
+		if err != nil {
+			return UpConfig{}, fmt.Errorf("error reading runtime-config-ops-file contents: %v", err)
+		}
+	} else {
+		warnChangedUserFiles(logger, "runtime-config-ops-file", config.RuntimeConfigOpsFiles)
+	}
+
+	if len(config.Vars) == 0 {
+		config.Vars = state.BOSH.Vars
+	} else {
+		config.Vars = withoutEmpty(config.Vars)
+	}
+
+	return config, nil
+}
+
+func readUserFiles(paths []string) ([]storage.UserFile, error) {
+	var files []storage.UserFile
+	for _, path := range withoutEmpty(paths) {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, storage.UserFile{Path: path, Contents: string(contents)})
+	}
+
+	return files, nil
+}
+
+func withoutEmpty(values []string) []string {
+	var nonEmpty []string
+	for _, value := range values {
+		if value != "" {
+			nonEmpty = append(nonEmpty, value)
+		}
+	}
+
+	return nonEmpty
+}
+
+// warnChangedUserFiles skips files that no longer exist, since bbl up keeps
+// applying their stored contents either way.
+func warnChangedUserFiles(logger logger, flag string, files []storage.UserFile) {
+	for _, file := range files {
+		if file.Path == "" {
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(file.Path)
+		if err != nil {
+			continue
+		}
+
+		if string(contents) != file.Contents {
+			logger.Printf("warning: %s %s has changed since it was stored, pass --%s %s to apply the new contents\n", flag, file.Path, flag, file.Path)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"time"
 
 	"github.com/cloudfoundry/bosh-bootloader/application"
 	"github.com/cloudfoundry/bosh-bootloader/storage"
@@ -19,6 +20,22 @@ type globalFlags struct {
 	StateDir string `short:"s" long:"state-dir"`
 	IAAS     string `long:"iaas"                    env:"BBL_IAAS"`
 
+	LockTimeout time.Duration `long:"lock-timeout" env:"BBL_LOCK_TIMEOUT"`
+
+	TerraformRetries      int           `long:"terraform-retries"       env:"BBL_TERRAFORM_RETRIES"       default:"0"`
+	TerraformRetryBackoff time.Duration `long:"terraform-retry-backoff" env:"BBL_TERRAFORM_RETRY_BACKOFF" default:"10s"`
+
+	BOSHDeploymentDir    string `long:"bosh-deployment-dir"    env:"BBL_BOSH_DEPLOYMENT_DIR"`
+	JumpboxDeploymentDir string `long:"jumpbox-deployment-dir" env:"BBL_JUMPBOX_DEPLOYMENT_DIR"`
+
+	StateBackend         string `long:"state-backend"           env:"BBL_STATE_BACKEND"`
+	StateBucket          string `long:"state-bucket"            env:"BBL_STATE_BUCKET"`
+	StatePrefix          string `long:"state-prefix"            env:"BBL_STATE_PREFIX"`
+	StateEndpoint        string `long:"state-endpoint"          env:"BBL_STATE_ENDPOINT"`
+	StateRegion          string `long:"state-region"            env:"BBL_STATE_REGION"`
+	StateAccessKeyID     string `long:"state-access-key-id"     env:"BBL_STATE_ACCESS_KEY_ID"`
+	StateSecretAccessKey string `long:"state-secret-access-key" env:"BBL_STATE_SECRET_ACCESS_KEY"`
+
 	AWSAccessKeyID     string `long:"aws-access-key-id"       env:"BBL_AWS_ACCESS_KEY_ID"`
 	AWSSecretAccessKey string `long:"aws-secret-access-key"   env:"BBL_AWS_SECRET_ACCESS_KEY"`
 	AWSRegion          string `long:"aws-region"              env:"BBL_AWS_REGION"`
@@ -35,14 +52,16 @@ type globalFlags struct {
 	GCPRegion            string `long:"gcp-region"              env:"BBL_GCP_REGION"`
 }
 
-func NewConfig(getState func(string) (storage.State, error)) Config {
+func NewConfig(getState func(storage.StateBackend) (storage.State, error), acquireLock func(dir, command string, timeout time.Duration) (application.StateLock, error)) Config {
 	return Config{
-		getState: getState,
+		getState:    getState,
+		acquireLock: acquireLock,
 	}
 }
 
 type Config struct {
-	getState func(string) (storage.State, error)
+	getState    func(storage.StateBackend) (storage.State, error)
+	acquireLock func(dir, command string, timeout time.Duration) (application.StateLock, error)
 }
 
 func (c Config) Bootstrap(args []string) (application.Configuration, error) {
@@ -87,20 +106,50 @@ func (c Config) Bootstrap(args []string) (application.Configuration, error) {
 		}
 	}
 
-	state, err := c.getState(globalFlags.StateDir)
+	stateBackend, err := storage.NewStateBackend(storage.BackendConfig{
+		Type: globalFlags.StateBackend,
+		Dir:  globalFlags.StateDir,
+		ObjectStore: storage.ObjectStoreConfig{
+			Endpoint:        globalFlags.StateEndpoint,
+			Bucket:          globalFlags.StateBucket,
+			Prefix:          globalFlags.StatePrefix,
+			Region:          globalFlags.StateRegion,
+			AccessKeyID:     globalFlags.StateAccessKeyID,
+			SecretAccessKey: globalFlags.StateSecretAccessKey,
+		},
+	})
 	if err != nil {
 		return application.Configuration{}, err
 	}
 
+	var stateLock application.StateLock
+	if MutatesState(remainingArgs[0], remainingArgs[1:]) && !globalFlags.Help {
+		stateLock, err = c.acquireLock(globalFlags.StateDir, remainingArgs[0], globalFlags.LockTimeout)
+		if err != nil {
+			return application.Configuration{}, err
+		}
+	}
+
+	state, err := c.getState(stateBackend)
+	if err != nil {
+		return application.Configuration{}, releaseOnError(stateLock, err)
+	}
+
 	state, err = updateIAASState(globalFlags, state)
 	if err != nil {
-		return application.Configuration{}, err
+		return application.Configuration{}, releaseOnError(stateLock, err)
 	}
 
 	return application.Configuration{
 		Global: application.GlobalConfiguration{
-			Debug:    globalFlags.Debug,
-			StateDir: globalFlags.StateDir,
+			Debug:                 globalFlags.Debug,
+			StateDir:              globalFlags.StateDir,
+			StateBackend:          stateBackend,
+			StateLock:             stateLock,
+			TerraformRetries:      globalFlags.TerraformRetries,
+			TerraformRetryBackoff: globalFlags.TerraformRetryBackoff,
+			BOSHDeploymentDir:     globalFlags.BOSHDeploymentDir,
+			JumpboxDeploymentDir:  globalFlags.JumpboxDeploymentDir,
 		},
 		State:           state,
 		Command:         remainingArgs[0],
@@ -109,6 +158,19 @@ func (c Config) Bootstrap(args []string) (application.Configuration, error) {
 	}, nil
 }
 
+func releaseOnError(stateLock application.StateLock, err error) error {
+	if stateLock == nil {
+		return err
+	}
+
+	releaseErr := stateLock.Release()
+	if releaseErr != nil {
+		return fmt.Errorf("%s\nfailed to release state lock: %s", err, releaseErr)
+	}
+
+	return err
+}
+
 func updateIAASState(globalFlags globalFlags, state storage.State) (storage.State, error) {
 	if globalFlags.IAAS != "" {
 		if state.IAAS != "" && globalFlags.IAAS != state.IAAS {
@@ -227,17 +289,51 @@ func ValidateIAAS(state storage.State, command string) error {
 
 func NeedsIAASConfig(command string) bool {
 	_, ok := map[string]struct{}{
-		"up":         struct{}{},
-		"down":       struct{}{},
-		"destroy":    struct{}{},
-		"create-lbs": struct{}{},
-		"delete-lbs": struct{}{},
-		"update-lbs": struct{}{},
-		"rotate":     struct{}{},
+		"up":               struct{}{},
+		"plan":             struct{}{},
+		"drift":            struct{}{},
+		"terraform":        struct{}{},
+		"down":             struct{}{},
+		"destroy":          struct{}{},
+		"create-lbs":       struct{}{},
+		"delete-lbs":       struct{}{},
+		"update-lbs":       struct{}{},
+		"rotate":           struct{}{},
+		"upgrade-director": struct{}{},
 	}[command]
 	return ok
 }
 
+// MutatesState returns whether the command changes the state, so that the
+// state directory has to be locked while it runs.
+func MutatesState(command string, subcommandFlags []string) bool {
+	if NeedsIAASConfig(command) {
+		return true
+	}
+
+	switch command {
+	case "import":
+		return true
+	case "state":
+		if len(subcommandFlags) == 0 {
+			return false
+		}
+		switch subcommandFlags[0] {
+		case "rollback", "split", "join", "encrypt", "decrypt":
+			return true
+		}
+	case "fsck":
+		for _, flag := range subcommandFlags {
+			switch flag {
+			case "-repair", "--repair", "-repair=true", "--repair=true":
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 func validateAWS(aws storage.AWS) error {
 	if aws.AccessKeyID == "" {
 		return errors.New("AWS access key ID must be provided")
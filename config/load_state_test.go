@@ -4,9 +4,11 @@ import (
 	"errors"
 	"io/ioutil"
 	"os"
+	"time"
 
 	"github.com/cloudfoundry/bosh-bootloader/application"
 	"github.com/cloudfoundry/bosh-bootloader/config"
+	"github.com/cloudfoundry/bosh-bootloader/fakes"
 	"github.com/cloudfoundry/bosh-bootloader/storage"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
@@ -14,13 +16,19 @@ import (
 )
 
 var _ = Describe("LoadState", func() {
-	var c config.Config
+	var (
+		c           config.Config
+		acquireLock func(string, string, time.Duration) (application.StateLock, error)
+	)
 
 	BeforeEach(func() {
-		getState := func(string) (storage.State, error) {
+		getState := func(storage.StateBackend) (storage.State, error) {
 			return storage.State{}, nil
 		}
-		c = config.NewConfig(getState)
+		acquireLock = func(string, string, time.Duration) (application.StateLock, error) {
+			return &fakes.StateLock{}, nil
+		}
+		c = config.NewConfig(getState, acquireLock)
 		os.Clearenv()
 	})
 
@@ -136,18 +144,18 @@ var _ = Describe("LoadState", func() {
 		})
 
 		Describe("reading a previous state file", func() {
-			var getStateArg string
+			var getStateArg storage.StateBackend
 
 			BeforeEach(func() {
-				getState := func(dir string) (storage.State, error) {
-					getStateArg = dir
+				getState := func(backend storage.StateBackend) (storage.State, error) {
+					getStateArg = backend
 
 					return storage.State{
 						IAAS:  "aws",
 						EnvID: "some-env-id",
 					}, nil
 				}
-				c = config.NewConfig(getState)
+				c = config.NewConfig(getState, acquireLock)
 			})
 
 			It("returns the existing state", func() {
@@ -170,7 +178,7 @@ var _ = Describe("LoadState", func() {
 				workingDir, err := os.Getwd()
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(getStateArg).To(Equal(workingDir))
+				Expect(getStateArg).To(Equal(storage.NewLocalBackend(workingDir)))
 				Expect(appConfig.Global.StateDir).To(Equal(workingDir))
 			})
 
@@ -183,17 +191,81 @@ var _ = Describe("LoadState", func() {
 					})
 					Expect(err).NotTo(HaveOccurred())
 
-					Expect(getStateArg).To(Equal("some-state-dir"))
+					Expect(getStateArg).To(Equal(storage.NewLocalBackend("some-state-dir")))
 					Expect(appConfig.Global.StateDir).To(Equal("some-state-dir"))
 				})
 			})
 
+			Context("when a remote state backend is specified", func() {
+				It("reads the state from the object store", func() {
+					appConfig, err := c.Bootstrap([]string{
+						"bbl",
+						"--state-backend", "s3",
+						"--state-bucket", "some-bucket",
+						"--state-access-key-id", "some-access-key-id",
+						"--state-secret-access-key", "some-secret-access-key",
+						"create-lbs",
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(getStateArg).To(BeAssignableToTypeOf(storage.ObjectStoreBackend{}))
+					Expect(getStateArg.Location()).To(Equal("s3://some-bucket/"))
+					Expect(appConfig.Global.StateBackend).To(Equal(getStateArg))
+				})
+
+				It("returns an error when the backend is unknown", func() {
+					_, err := c.Bootstrap([]string{
+						"bbl",
+						"--state-backend", "some-backend",
+						"create-lbs",
+					})
+					Expect(err).To(MatchError(`unknown state backend "some-backend", valid options are: local, s3, gcs`))
+				})
+
+				It("returns an error when the bucket is missing", func() {
+					_, err := c.Bootstrap([]string{
+						"bbl",
+						"--state-backend", "gcs",
+						"create-lbs",
+					})
+					Expect(err).To(MatchError("--state-bucket must be provided when using a remote state backend"))
+				})
+			})
+
+			Context("when deployment dirs are specified", func() {
+				It("returns the deployment dirs", func() {
+					appConfig, err := c.Bootstrap([]string{
+						"bbl",
+						"--bosh-deployment-dir", "some-bosh-deployment-dir",
+						"--jumpbox-deployment-dir", "some-jumpbox-deployment-dir",
+						"create-lbs",
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(appConfig.Global.BOSHDeploymentDir).To(Equal("some-bosh-deployment-dir"))
+					Expect(appConfig.Global.JumpboxDeploymentDir).To(Equal("some-jumpbox-deployment-dir"))
+				})
+			})
+
+			Context("when terraform retries are not specified", func() {
+				It("does not retry terraform", func() {
+					appConfig, err := c.Bootstrap([]string{
+						"bbl",
+						"create-lbs",
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(appConfig.Global.TerraformRetries).To(Equal(0))
+					Expect(appConfig.Global.TerraformRetryBackoff).To(Equal(10 * time.Second))
+				})
+			})
+
 			Context("when invalid state dir is passed in", func() {
 				BeforeEach(func() {
-					getState := func(string) (storage.State, error) {
+					getState := func(storage.StateBackend) (storage.State, error) {
 						return storage.State{}, errors.New("some state dir error")
 					}
-					c = config.NewConfig(getState)
+					c = config.NewConfig(getState, acquireLock)
 					os.Clearenv()
 				})
 
@@ -208,6 +280,104 @@ var _ = Describe("LoadState", func() {
 				})
 			})
 
+			Context("locking the state directory", func() {
+				var (
+					stateLock       *fakes.StateLock
+					acquireLockArgs []interface{}
+					getState        func(storage.StateBackend) (storage.State, error)
+				)
+
+				BeforeEach(func() {
+					stateLock = &fakes.StateLock{}
+					acquireLockArgs = nil
+					acquireLock = func(dir, command string, timeout time.Duration) (application.StateLock, error) {
+						acquireLockArgs = []interface{}{dir, command, timeout}
+						return stateLock, nil
+					}
+					getState = func(storage.StateBackend) (storage.State, error) {
+						return storage.State{}, nil
+					}
+				})
+
+				It("locks the state dir for commands that modify the environment", func() {
+					c = config.NewConfig(getState, acquireLock)
+					appConfig, err := c.Bootstrap([]string{
+						"bbl",
+						"--state-dir", "some-state-dir",
+						"--lock-timeout", "5m",
+						"create-lbs",
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(acquireLockArgs).To(Equal([]interface{}{"some-state-dir", "create-lbs", 5 * time.Minute}))
+					Expect(appConfig.Global.StateLock).To(Equal(stateLock))
+				})
+
+				DescribeTable("locks the state dir for commands that modify the state", func(args ...string) {
+					c = config.NewConfig(getState, acquireLock)
+					appConfig, err := c.Bootstrap(append([]string{"bbl", "--state-dir", "some-state-dir"}, args...))
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(acquireLockArgs).To(Equal([]interface{}{"some-state-dir", args[0], time.Duration(0)}))
+					Expect(appConfig.Global.StateLock).To(Equal(stateLock))
+				},
+					Entry("state rollback", "state", "rollback"),
+					Entry("state split", "state", "split"),
+					Entry("state join", "state", "join"),
+					Entry("state encrypt", "state", "encrypt"),
+					Entry("state decrypt", "state", "decrypt"),
+					Entry("import", "import", "some-env.tgz"),
+					Entry("fsck --repair", "fsck", "--repair"),
+				)
+
+				DescribeTable("does not lock the state dir for commands that only read the state", func(args ...string) {
+					c = config.NewConfig(getState, acquireLock)
+					appConfig, err := c.Bootstrap(append([]string{"bbl", "--state-dir", "some-state-dir"}, args...))
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(acquireLockArgs).To(BeNil())
+					Expect(appConfig.Global.StateLock).To(BeNil())
+				},
+					Entry("state show", "state", "show"),
+					Entry("state history", "state", "history"),
+					Entry("fsck", "fsck"),
+				)
+
+				It("does not lock the state dir for read only commands", func() {
+					c = config.NewConfig(getState, acquireLock)
+					appConfig, err := c.Bootstrap([]string{
+						"bbl",
+						"--state-dir", "some-state-dir",
+						"director-address",
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(acquireLockArgs).To(BeNil())
+					Expect(appConfig.Global.StateLock).To(BeNil())
+				})
+
+				It("returns an error when the lock cannot be acquired", func() {
+					acquireLock = func(string, string, time.Duration) (application.StateLock, error) {
+						return nil, errors.New("state directory is locked")
+					}
+					c = config.NewConfig(getState, acquireLock)
+
+					_, err := c.Bootstrap([]string{"bbl", "--state-dir", "some-state-dir", "up"})
+					Expect(err).To(MatchError("state directory is locked"))
+				})
+
+				It("releases the lock when the state cannot be loaded", func() {
+					getState = func(storage.StateBackend) (storage.State, error) {
+						return storage.State{}, errors.New("some state error")
+					}
+					c = config.NewConfig(getState, acquireLock)
+
+					_, err := c.Bootstrap([]string{"bbl", "--state-dir", "some-state-dir", "up"})
+					Expect(err).To(MatchError("some state error"))
+					Expect(stateLock.ReleaseCall.CallCount).To(Equal(1))
+				})
+			})
+
 			Context("when state-dir flag is passed without an argument", func() {
 				It("returns an error", func() {
 					_, err := c.Bootstrap([]string{
@@ -303,11 +473,11 @@ var _ = Describe("LoadState", func() {
 			})
 
 			Context("when a previous state exists", func() {
-				var getStateArg string
+				var getStateArg storage.StateBackend
 
 				BeforeEach(func() {
-					getState := func(dir string) (storage.State, error) {
-						getStateArg = dir
+					getState := func(backend storage.StateBackend) (storage.State, error) {
+						getStateArg = backend
 
 						return storage.State{
 							IAAS: "aws",
@@ -319,7 +489,7 @@ var _ = Describe("LoadState", func() {
 							EnvID: "some-env-id",
 						}, nil
 					}
-					c = config.NewConfig(getState)
+					c = config.NewConfig(getState, acquireLock)
 				})
 
 				Context("when valid matching configuration is passed in", func() {
@@ -520,11 +690,11 @@ var _ = Describe("LoadState", func() {
 			})
 
 			Context("when a previous state exists", func() {
-				var getStateArg string
+				var getStateArg storage.StateBackend
 
 				BeforeEach(func() {
-					getState := func(dir string) (storage.State, error) {
-						getStateArg = dir
+					getState := func(backend storage.StateBackend) (storage.State, error) {
+						getStateArg = backend
 
 						return storage.State{
 							IAAS: "gcp",
@@ -537,7 +707,7 @@ var _ = Describe("LoadState", func() {
 							EnvID: "some-env-id",
 						}, nil
 					}
-					c = config.NewConfig(getState)
+					c = config.NewConfig(getState, acquireLock)
 				})
 
 				Context("when valid matching configuration is passed in", func() {
@@ -657,11 +827,11 @@ var _ = Describe("LoadState", func() {
 			})
 
 			Context("when a previous state exists", func() {
-				var getStateArg string
+				var getStateArg storage.StateBackend
 
 				BeforeEach(func() {
-					getState := func(dir string) (storage.State, error) {
-						getStateArg = dir
+					getState := func(backend storage.StateBackend) (storage.State, error) {
+						getStateArg = backend
 
 						return storage.State{
 							IAAS: "azure",
@@ -675,7 +845,7 @@ var _ = Describe("LoadState", func() {
 							EnvID: "some-env-id",
 						}, nil
 					}
-					c = config.NewConfig(getState)
+					c = config.NewConfig(getState, acquireLock)
 				})
 
 				Context("when no configuration is passed in", func() {
@@ -691,7 +861,7 @@ var _ = Describe("LoadState", func() {
 						workingDir, err := os.Getwd()
 						Expect(err).NotTo(HaveOccurred())
 
-						Expect(getStateArg).To(Equal(workingDir))
+						Expect(getStateArg).To(Equal(storage.NewLocalBackend(workingDir)))
 					})
 				})
 
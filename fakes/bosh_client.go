@@ -16,6 +16,17 @@ type BOSHClient struct {
 		}
 	}
 
+	UpdateRuntimeConfigCall struct {
+		CallCount int
+		Receives  struct {
+			Name string
+			Yaml []byte
+		}
+		Returns struct {
+			Error error
+		}
+	}
+
 	ConfigureHTTPClientCall struct {
 		CallCount int
 		Receives  struct {
@@ -38,6 +49,13 @@ func (c *BOSHClient) UpdateCloudConfig(yaml []byte) error {
 	return c.UpdateCloudConfigCall.Returns.Error
 }
 
+func (c *BOSHClient) UpdateRuntimeConfig(name string, yaml []byte) error {
+	c.UpdateRuntimeConfigCall.CallCount++
+	c.UpdateRuntimeConfigCall.Receives.Name = name
+	c.UpdateRuntimeConfigCall.Receives.Yaml = yaml
+	return c.UpdateRuntimeConfigCall.Returns.Error
+}
+
 func (c *BOSHClient) ConfigureHTTPClient(socks5Client proxy.Dialer) {
 	c.ConfigureHTTPClientCall.CallCount++
 	c.ConfigureHTTPClientCall.Receives.Socks5Client = socks5Client
@@ -46,6 +46,14 @@ type BOSHExecutor struct {
 		}
 	}
 
+	DNSRuntimeConfigCall struct {
+		CallCount int
+		Returns   struct {
+			RuntimeConfig []byte
+			Error         error
+		}
+	}
+
 	VersionCall struct {
 		CallCount int
 		Returns   struct {
@@ -83,6 +91,11 @@ func (e *BOSHExecutor) DirectorInterpolate(input bosh.InterpolateInput) (bosh.In
 	return e.DirectorInterpolateCall.Returns.Output, e.DirectorInterpolateCall.Returns.Error
 }
 
+func (e *BOSHExecutor) DNSRuntimeConfig() ([]byte, error) {
+	e.DNSRuntimeConfigCall.CallCount++
+	return e.DNSRuntimeConfigCall.Returns.RuntimeConfig, e.DNSRuntimeConfigCall.Returns.Error
+}
+
 func (e *BOSHExecutor) Version() (string, error) {
 	e.VersionCall.CallCount++
 	return e.VersionCall.Returns.Version, e.VersionCall.Returns.Error
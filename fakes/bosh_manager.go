@@ -1,6 +1,9 @@
 package fakes
 
-import "github.com/cloudfoundry/bosh-bootloader/storage"
+import (
+	"github.com/cloudfoundry/bosh-bootloader/bosh"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+)
 
 type BOSHManager struct {
 	CreateJumpboxCall struct {
@@ -25,6 +28,26 @@ type BOSHManager struct {
 			Error error
 		}
 	}
+	PlanManifestsCall struct {
+		CallCount int
+		Receives  struct {
+			State            storage.State
+			TerraformOutputs map[string]interface{}
+		}
+		Returns struct {
+			Plan  bosh.ManifestPlan
+			Error error
+		}
+	}
+	StartJumpboxProxyCall struct {
+		CallCount int
+		Receives  struct {
+			State storage.State
+		}
+		Returns struct {
+			Error error
+		}
+	}
 	VersionCall struct {
 		CallCount int
 		Returns   struct {
@@ -77,7 +100,7 @@ type BOSHManager struct {
 func (b *BOSHManager) CreateJumpbox(state storage.State, terraformOutputs map[string]interface{}) (storage.State, error) {
 	b.CreateJumpboxCall.CallCount++
 	b.CreateJumpboxCall.Receives.State = state
-	b.GetDirectorDeploymentVarsCall.Receives.TerraformOutputs = terraformOutputs
+	b.CreateJumpboxCall.Receives.TerraformOutputs = terraformOutputs
 	state.BOSH = b.CreateJumpboxCall.Returns.State.BOSH
 	return state, b.CreateJumpboxCall.Returns.Error
 }
@@ -85,11 +108,18 @@ func (b *BOSHManager) CreateJumpbox(state storage.State, terraformOutputs map[st
 func (b *BOSHManager) CreateDirector(state storage.State, terraformOutputs map[string]interface{}) (storage.State, error) {
 	b.CreateDirectorCall.CallCount++
 	b.CreateDirectorCall.Receives.State = state
-	b.GetDirectorDeploymentVarsCall.Receives.TerraformOutputs = terraformOutputs
+	b.CreateDirectorCall.Receives.TerraformOutputs = terraformOutputs
 	state.BOSH = b.CreateDirectorCall.Returns.State.BOSH
 	return state, b.CreateDirectorCall.Returns.Error
 }
 
+func (b *BOSHManager) PlanManifests(state storage.State, terraformOutputs map[string]interface{}) (bosh.ManifestPlan, error) {
+	b.PlanManifestsCall.CallCount++
+	b.PlanManifestsCall.Receives.State = state
+	b.PlanManifestsCall.Receives.TerraformOutputs = terraformOutputs
+	return b.PlanManifestsCall.Returns.Plan, b.PlanManifestsCall.Returns.Error
+}
+
 func (b *BOSHManager) Delete(state storage.State, terraformOutputs map[string]interface{}) error {
 	b.DeleteCall.CallCount++
 	b.DeleteCall.Receives.State = state
@@ -118,6 +148,12 @@ func (b *BOSHManager) GetJumpboxDeploymentVars(state storage.State, terraformOut
 	return b.GetJumpboxDeploymentVarsCall.Returns.Vars
 }
 
+func (b *BOSHManager) StartJumpboxProxy(state storage.State) error {
+	b.StartJumpboxProxyCall.CallCount++
+	b.StartJumpboxProxyCall.Receives.State = state
+	return b.StartJumpboxProxyCall.Returns.Error
+}
+
 func (b *BOSHManager) Version() (string, error) {
 	b.VersionCall.CallCount++
 	return b.VersionCall.Returns.Version, b.VersionCall.Returns.Error
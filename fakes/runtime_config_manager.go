@@ -0,0 +1,39 @@
+package fakes
+
+import (
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+)
+
+type RuntimeConfigManager struct {
+	UpdateCall struct {
+		CallCount int
+		Receives  struct {
+			State storage.State
+		}
+		Returns struct {
+			Error error
+		}
+	}
+	GenerateCall struct {
+		CallCount int
+		Receives  struct {
+			State storage.State
+		}
+		Returns struct {
+			RuntimeConfig string
+			Error         error
+		}
+	}
+}
+
+func (c *RuntimeConfigManager) Update(state storage.State) error {
+	c.UpdateCall.CallCount++
+	c.UpdateCall.Receives.State = state
+	return c.UpdateCall.Returns.Error
+}
+
+func (c *RuntimeConfigManager) Generate(state storage.State) (string, error) {
+	c.GenerateCall.CallCount++
+	c.GenerateCall.Receives.State = state
+	return c.GenerateCall.Returns.RuntimeConfig, c.GenerateCall.Returns.Error
+}
@@ -0,0 +1,56 @@
+package fakes
+
+import (
+	"github.com/cloudfoundry/bosh-bootloader/fsck"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+)
+
+type StateChecker struct {
+	CheckCall struct {
+		CallCount int
+		Receives  []CheckCallReceive
+		Returns   []CheckCallReturn
+	}
+
+	RepairCall struct {
+		CallCount int
+		Receives  struct {
+			State            storage.State
+			TerraformOutputs map[string]interface{}
+		}
+		Returns struct {
+			State    storage.State
+			Repaired bool
+		}
+	}
+}
+
+type CheckCallReceive struct {
+	State            storage.State
+	TerraformOutputs map[string]interface{}
+}
+
+type CheckCallReturn struct {
+	Problems []fsck.Problem
+}
+
+func (s *StateChecker) Check(state storage.State, terraformOutputs map[string]interface{}) []fsck.Problem {
+	s.CheckCall.CallCount++
+	s.CheckCall.Receives = append(s.CheckCall.Receives, CheckCallReceive{
+		State:            state,
+		TerraformOutputs: terraformOutputs,
+	})
+
+	if len(s.CheckCall.Returns) < s.CheckCall.CallCount {
+		return nil
+	}
+
+	return s.CheckCall.Returns[s.CheckCall.CallCount-1].Problems
+}
+
+func (s *StateChecker) Repair(state storage.State, terraformOutputs map[string]interface{}) (storage.State, bool) {
+	s.RepairCall.CallCount++
+	s.RepairCall.Receives.State = state
+	s.RepairCall.Receives.TerraformOutputs = terraformOutputs
+	return s.RepairCall.Returns.State, s.RepairCall.Returns.Repaired
+}
@@ -0,0 +1,34 @@
+package fakes
+
+import "github.com/cloudfoundry/bosh-bootloader/storage"
+
+type StateHistory struct {
+	HistoryCall struct {
+		CallCount int
+		Returns   struct {
+			Entries []storage.HistoryEntry
+			Error   error
+		}
+	}
+
+	RollbackCall struct {
+		CallCount int
+		Receives  struct {
+			ID int
+		}
+		Returns struct {
+			Error error
+		}
+	}
+}
+
+func (s *StateHistory) History() ([]storage.HistoryEntry, error) {
+	s.HistoryCall.CallCount++
+	return s.HistoryCall.Returns.Entries, s.HistoryCall.Returns.Error
+}
+
+func (s *StateHistory) Rollback(id int) error {
+	s.RollbackCall.CallCount++
+	s.RollbackCall.Receives.ID = id
+	return s.RollbackCall.Returns.Error
+}
@@ -0,0 +1,27 @@
+package fakes
+
+type StateLayout struct {
+	SplitCall struct {
+		CallCount int
+		Returns   struct {
+			Error error
+		}
+	}
+
+	JoinCall struct {
+		CallCount int
+		Returns   struct {
+			Error error
+		}
+	}
+}
+
+func (s *StateLayout) Split() error {
+	s.SplitCall.CallCount++
+	return s.SplitCall.Returns.Error
+}
+
+func (s *StateLayout) Join() error {
+	s.JoinCall.CallCount++
+	return s.JoinCall.Returns.Error
+}
@@ -0,0 +1,15 @@
+package fakes
+
+type StateLock struct {
+	ReleaseCall struct {
+		CallCount int
+		Returns   struct {
+			Error error
+		}
+	}
+}
+
+func (s *StateLock) Release() error {
+	s.ReleaseCall.CallCount++
+	return s.ReleaseCall.Returns.Error
+}
@@ -0,0 +1,18 @@
+package fakes
+
+import "github.com/cloudfoundry/bosh-bootloader/storage"
+
+type StateUnlocker struct {
+	ForceUnlockCall struct {
+		CallCount int
+		Returns   struct {
+			Holder storage.LockInfo
+			Error  error
+		}
+	}
+}
+
+func (s *StateUnlocker) ForceUnlock() (storage.LockInfo, error) {
+	s.ForceUnlockCall.CallCount++
+	return s.ForceUnlockCall.Returns.Holder, s.ForceUnlockCall.Returns.Error
+}
@@ -1,15 +1,13 @@
 package fakes
 
-import "github.com/cloudfoundry/bosh-bootloader/storage"
-
-type Import struct {
-	Addr string
-	ID   string
-}
+import (
+	"io"
+)
 
 type TerraformExecutor struct {
 	ApplyCall struct {
 		CallCount int
+		Stub      func(tfState string) (string, error)
 		Receives  struct {
 			Inputs   map[string]string
 			Template string
@@ -32,12 +30,48 @@ type TerraformExecutor struct {
 			Error   error
 		}
 	}
-	ImportCall struct {
+	PlanCall struct {
+		CallCount int
+		Receives  struct {
+			Inputs   map[string]string
+			Template string
+			TFState  string
+		}
+		Returns struct {
+			Output string
+			Error  error
+		}
+	}
+	RefreshCall struct {
+		CallCount int
+		Receives  struct {
+			Inputs   map[string]string
+			Template string
+			TFState  string
+		}
+		Returns struct {
+			TFState string
+			Error   error
+		}
+	}
+	PullStateCall struct {
 		CallCount int
 		Receives  struct {
+			BackendTemplate string
+		}
+		Returns struct {
 			TFState string
-			Imports []Import
-			Creds   storage.AWS
+			Error   error
+		}
+	}
+	StateCommandCall struct {
+		CallCount int
+		Receives  struct {
+			Inputs   map[string]string
+			Template string
+			TFState  string
+			Args     []string
+			Stdout   io.Writer
 		}
 		Returns struct {
 			TFState string
@@ -81,6 +115,9 @@ func (t *TerraformExecutor) Apply(inputs map[string]string, template, tfState st
 	t.ApplyCall.Receives.Inputs = inputs
 	t.ApplyCall.Receives.Template = template
 	t.ApplyCall.Receives.TFState = tfState
+	if t.ApplyCall.Stub != nil {
+		return t.ApplyCall.Stub(tfState)
+	}
 	return t.ApplyCall.Returns.TFState, t.ApplyCall.Returns.Error
 }
 
@@ -92,16 +129,36 @@ func (t *TerraformExecutor) Destroy(inputs map[string]string, template, tfState
 	return t.DestroyCall.Returns.TFState, t.DestroyCall.Returns.Error
 }
 
-func (t *TerraformExecutor) Import(addr, id, tfstate string, creds storage.AWS) (string, error) {
-	t.ImportCall.CallCount++
-	t.ImportCall.Receives.Imports = append(t.ImportCall.Receives.Imports, Import{
-		Addr: addr,
-		ID:   id,
-	})
-	t.ImportCall.Receives.TFState = tfstate
-	t.ImportCall.Receives.Creds = creds
+func (t *TerraformExecutor) Plan(inputs map[string]string, template, tfState string) (string, error) {
+	t.PlanCall.CallCount++
+	t.PlanCall.Receives.Inputs = inputs
+	t.PlanCall.Receives.Template = template
+	t.PlanCall.Receives.TFState = tfState
+	return t.PlanCall.Returns.Output, t.PlanCall.Returns.Error
+}
+
+func (t *TerraformExecutor) Refresh(inputs map[string]string, template, tfState string) (string, error) {
+	t.RefreshCall.CallCount++
+	t.RefreshCall.Receives.Inputs = inputs
+	t.RefreshCall.Receives.Template = template
+	t.RefreshCall.Receives.TFState = tfState
+	return t.RefreshCall.Returns.TFState, t.RefreshCall.Returns.Error
+}
+
+func (t *TerraformExecutor) PullState(backendTemplate string) (string, error) {
+	t.PullStateCall.CallCount++
+	t.PullStateCall.Receives.BackendTemplate = backendTemplate
+	return t.PullStateCall.Returns.TFState, t.PullStateCall.Returns.Error
+}
 
-	return t.ImportCall.Returns.TFState, t.ImportCall.Returns.Error
+func (t *TerraformExecutor) StateCommand(inputs map[string]string, template, tfState string, args []string, stdout io.Writer) (string, error) {
+	t.StateCommandCall.CallCount++
+	t.StateCommandCall.Receives.Inputs = inputs
+	t.StateCommandCall.Receives.Template = template
+	t.StateCommandCall.Receives.TFState = tfState
+	t.StateCommandCall.Receives.Args = args
+	t.StateCommandCall.Receives.Stdout = stdout
+	return t.StateCommandCall.Returns.TFState, t.StateCommandCall.Returns.Error
 }
 
 func (t *TerraformExecutor) Version() (string, error) {
@@ -1,7 +1,10 @@
 package fakes
 
 import (
+	"io"
+
 	"github.com/cloudfoundry/bosh-bootloader/storage"
+	"github.com/cloudfoundry/bosh-bootloader/terraform"
 )
 
 type TerraformManager struct {
@@ -15,6 +18,38 @@ type TerraformManager struct {
 			Error    error
 		}
 	}
+	PlanCall struct {
+		CallCount int
+		Receives  struct {
+			BBLState storage.State
+		}
+		Returns struct {
+			Plan  terraform.Plan
+			Error error
+		}
+	}
+	DriftCall struct {
+		CallCount int
+		Receives  struct {
+			BBLState storage.State
+		}
+		Returns struct {
+			Drift terraform.Drift
+			Error error
+		}
+	}
+	StateCommandCall struct {
+		CallCount int
+		Receives  struct {
+			BBLState storage.State
+			Args     []string
+			Stdout   io.Writer
+		}
+		Returns struct {
+			BBLState storage.State
+			Error    error
+		}
+	}
 	DestroyCall struct {
 		CallCount int
 		Receives  struct {
@@ -46,6 +81,15 @@ type TerraformManager struct {
 			Error   error
 		}
 	}
+	GetTemplateCall struct {
+		CallCount int
+		Receives  struct {
+			BBLState storage.State
+		}
+		Returns struct {
+			Template string
+		}
+	}
 	VersionCall struct {
 		CallCount int
 		Returns   struct {
@@ -68,6 +112,26 @@ func (t *TerraformManager) Apply(bblState storage.State) (storage.State, error)
 	return t.ApplyCall.Returns.BBLState, t.ApplyCall.Returns.Error
 }
 
+func (t *TerraformManager) Plan(bblState storage.State) (terraform.Plan, error) {
+	t.PlanCall.CallCount++
+	t.PlanCall.Receives.BBLState = bblState
+	return t.PlanCall.Returns.Plan, t.PlanCall.Returns.Error
+}
+
+func (t *TerraformManager) Drift(bblState storage.State) (terraform.Drift, error) {
+	t.DriftCall.CallCount++
+	t.DriftCall.Receives.BBLState = bblState
+	return t.DriftCall.Returns.Drift, t.DriftCall.Returns.Error
+}
+
+func (t *TerraformManager) StateCommand(bblState storage.State, args []string, stdout io.Writer) (storage.State, error) {
+	t.StateCommandCall.CallCount++
+	t.StateCommandCall.Receives.BBLState = bblState
+	t.StateCommandCall.Receives.Args = args
+	t.StateCommandCall.Receives.Stdout = stdout
+	return t.StateCommandCall.Returns.BBLState, t.StateCommandCall.Returns.Error
+}
+
 func (t *TerraformManager) Destroy(bblState storage.State) (storage.State, error) {
 	t.DestroyCall.CallCount++
 	t.DestroyCall.Receives.BBLState = bblState
@@ -90,6 +154,13 @@ func (t *TerraformManager) GetOutputs(bblState storage.State) (map[string]interf
 	return t.GetOutputsCall.Returns.Outputs, t.GetOutputsCall.Returns.Error
 }
 
+func (t *TerraformManager) GetTemplate(bblState storage.State) string {
+	t.GetTemplateCall.CallCount++
+	t.GetTemplateCall.Receives.BBLState = bblState
+
+	return t.GetTemplateCall.Returns.Template
+}
+
 func (t *TerraformManager) Version() (string, error) {
 	t.VersionCall.CallCount++
 	return t.VersionCall.Returns.Version, t.VersionCall.Returns.Error
@@ -12,12 +12,15 @@ var _ = Describe("Flags", func() {
 		f         flags.Flags
 		boolVal   bool
 		stringVal string
+		sliceVal  []string
 	)
 
 	BeforeEach(func() {
 		f = flags.New("test")
 		f.Bool(&boolVal, "b", "bool", false)
 		f.String(&stringVal, "string", "")
+		sliceVal = nil
+		f.StringSlice(&sliceVal, "slice")
 	})
 
 	Describe("Parse", func() {
@@ -49,6 +52,14 @@ var _ = Describe("Flags", func() {
 				Expect(stringVal).To(Equal("string_value"))
 			})
 		})
+
+		Context("StringSlice flags", func() {
+			It("collects every occurrence in order", func() {
+				err := f.Parse([]string{"--slice", "first", "--slice", "second"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(sliceVal).To(Equal([]string{"first", "second"}))
+			})
+		})
 	})
 
 	Describe("Args", func() {
@@ -0,0 +1,182 @@
+package fsck
+
+import (
+	"fmt"
+
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+type Problem struct {
+	Check      string
+	Severity   Severity
+	Message    string
+	Repairable bool
+}
+
+// check inspects the state and the terraform outputs, which are nil when the
+// state has no terraform state. repair is nil when there is no safe fix.
+type check struct {
+	name     string
+	severity Severity
+	check    func(state storage.State, outputs map[string]interface{}) (string, bool)
+	repair   func(state storage.State, outputs map[string]interface{}) (storage.State, bool)
+}
+
+var checks = []check{
+	{
+		name:     "iaas",
+		severity: SeverityError,
+		check: func(state storage.State, _ map[string]interface{}) (string, bool) {
+			return "iaas is not set", state.IAAS == ""
+		},
+	},
+	{
+		name:     "jumpbox-url",
+		severity: SeverityError,
+		check: func(state storage.State, _ map[string]interface{}) (string, bool) {
+			return "jumpbox is enabled but has no URL", state.Jumpbox.Enabled && state.Jumpbox.URL == ""
+		},
+		repair: func(state storage.State, outputs map[string]interface{}) (storage.State, bool) {
+			url, ok := outputs["jumpbox_url"].(string)
+			if !ok || url == "" {
+				return state, false
+			}
+			state.Jumpbox.URL = url
+			return state, true
+		},
+	},
+	{
+		name:     "stale-jumpbox-url",
+		severity: SeverityWarning,
+		check: func(state storage.State, _ map[string]interface{}) (string, bool) {
+			return "jumpbox is disabled but still has a URL", !state.Jumpbox.Enabled && state.Jumpbox.URL != "" && len(state.Jumpbox.State) == 0
+		},
+		repair: func(state storage.State, _ map[string]interface{}) (storage.State, bool) {
+			state.Jumpbox.URL = ""
+			return state, true
+		},
+	},
+	{
+		name:     "director-manifest",
+		severity: SeverityError,
+		check: func(state storage.State, _ map[string]interface{}) (string, bool) {
+			return "director create-env state exists without a manifest, so the director cannot be updated or deleted", len(state.BOSH.State) > 0 && state.BOSH.Manifest == ""
+		},
+	},
+	{
+		name:     "jumpbox-manifest",
+		severity: SeverityError,
+		check: func(state storage.State, _ map[string]interface{}) (string, bool) {
+			return "jumpbox create-env state exists without a manifest, so the jumpbox cannot be updated or deleted", len(state.Jumpbox.State) > 0 && state.Jumpbox.Manifest == ""
+		},
+	},
+	{
+		name:     "director-vm",
+		severity: SeverityWarning,
+		check: func(state storage.State, _ map[string]interface{}) (string, bool) {
+			return "director create-env state has no current VM", len(state.BOSH.State) > 0 && !hasCurrentVM(state.BOSH.State)
+		},
+	},
+	{
+		name:     "jumpbox-vm",
+		severity: SeverityWarning,
+		check: func(state storage.State, _ map[string]interface{}) (string, bool) {
+			return "jumpbox create-env state has no current VM", len(state.Jumpbox.State) > 0 && !hasCurrentVM(state.Jumpbox.State)
+		},
+	},
+	{
+		name:     "no-director",
+		severity: SeverityWarning,
+		check: func(state storage.State, _ map[string]interface{}) (string, bool) {
+			return "state is marked --no-director but has a director create-env state", state.NoDirector && len(state.BOSH.State) > 0
+		},
+	},
+	{
+		name:     "stack-migration",
+		severity: SeverityError,
+		check: func(state storage.State, _ map[string]interface{}) (string, bool) {
+			return fmt.Sprintf("cloudformation stack %s is still recorded alongside terraform state, the migration to terraform did not finish", state.Stack.Name), state.Stack.Name != "" && state.HasTerraformState()
+		},
+	},
+	{
+		name:     "lb-outputs",
+		severity: SeverityError,
+		check: func(state storage.State, outputs map[string]interface{}) (string, bool) {
+			output, ok := lbOutputs[state.IAAS][state.LB.Type]
+			if !ok || outputs == nil {
+				return "", false
+			}
+
+			_, exists := outputs[output]
+			return fmt.Sprintf("%s load balancer is recorded but terraform has no %s output", state.LB.Type, output), !exists
+		},
+	},
+}
+
+// lbOutputs is the terraform output each load balancer type always creates.
+var lbOutputs = map[string]map[string]string{
+	"aws": {
+		"cf":        "cf_router_lb_name",
+		"concourse": "concourse_lb_name",
+	},
+	"gcp": {
+		"cf":        "router_lb_ip",
+		"concourse": "concourse_lb_ip",
+	},
+}
+
+func hasCurrentVM(createEnvState map[string]interface{}) bool {
+	vmCID, _ := createEnvState["current_vm_cid"].(string)
+	return vmCID != ""
+}
+
+type Checker struct{}
+
+func NewChecker() Checker {
+	return Checker{}
+}
+
+func (Checker) Check(state storage.State, terraformOutputs map[string]interface{}) []Problem {
+	var problems []Problem
+	for _, c := range checks {
+		message, failed := c.check(state, terraformOutputs)
+		if !failed {
+			continue
+		}
+
+		problems = append(problems, Problem{
+			Check:      c.name,
+			Severity:   c.severity,
+			Message:    message,
+			Repairable: c.repair != nil,
+		})
+	}
+	return problems
+}
+
+// Repair applies the safe fixes for every failing check and reports whether
+// the state changed.
+func (Checker) Repair(state storage.State, terraformOutputs map[string]interface{}) (storage.State, bool) {
+	repaired := false
+	for _, c := range checks {
+		if c.repair == nil {
+			continue
+		}
+
+		if _, failed := c.check(state, terraformOutputs); !failed {
+			continue
+		}
+
+		var changed bool
+		state, changed = c.repair(state, terraformOutputs)
+		repaired = repaired || changed
+	}
+	return state, repaired
+}
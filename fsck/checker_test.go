@@ -0,0 +1,161 @@
+package fsck_test
+
+import (
+	"github.com/cloudfoundry/bosh-bootloader/fsck"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Checker", func() {
+	var (
+		checker fsck.Checker
+		state   storage.State
+		outputs map[string]interface{}
+	)
+
+	BeforeEach(func() {
+		checker = fsck.NewChecker()
+
+		state = storage.State{
+			IAAS:    "gcp",
+			TFState: "some-tf-state",
+			Jumpbox: storage.Jumpbox{
+				Enabled:  true,
+				URL:      "10.0.0.5:22",
+				Manifest: "some-jumpbox-manifest",
+				State:    map[string]interface{}{"current_vm_cid": "some-jumpbox-vm"},
+			},
+			BOSH: storage.BOSH{
+				Manifest: "some-director-manifest",
+				State:    map[string]interface{}{"current_vm_cid": "some-director-vm"},
+			},
+			LB: storage.LB{Type: "cf"},
+		}
+		outputs = map[string]interface{}{
+			"jumpbox_url":  "10.0.0.5:22",
+			"router_lb_ip": "10.0.0.6",
+		}
+	})
+
+	checkNames := func(problems []fsck.Problem) []string {
+		names := []string{}
+		for _, problem := range problems {
+			names = append(names, problem.Check)
+		}
+		return names
+	}
+
+	Describe("Check", func() {
+		It("finds no problems in a consistent state", func() {
+			Expect(checker.Check(state, outputs)).To(BeEmpty())
+		})
+
+		It("reports a missing iaas", func() {
+			state.IAAS = ""
+
+			Expect(checker.Check(state, outputs)).To(ContainElement(fsck.Problem{
+				Check:    "iaas",
+				Severity: fsck.SeverityError,
+				Message:  "iaas is not set",
+			}))
+		})
+
+		It("reports an enabled jumpbox without a URL as repairable", func() {
+			state.Jumpbox.URL = ""
+
+			Expect(checker.Check(state, outputs)).To(Equal([]fsck.Problem{{
+				Check:      "jumpbox-url",
+				Severity:   fsck.SeverityError,
+				Message:    "jumpbox is enabled but has no URL",
+				Repairable: true,
+			}}))
+		})
+
+		It("warns about a URL left behind by a deleted jumpbox", func() {
+			state.Jumpbox = storage.Jumpbox{URL: "10.0.0.5:22"}
+
+			Expect(checkNames(checker.Check(state, outputs))).To(Equal([]string{"stale-jumpbox-url"}))
+		})
+
+		It("reports create-env states without manifests", func() {
+			state.BOSH.Manifest = ""
+			state.Jumpbox.Manifest = ""
+
+			Expect(checkNames(checker.Check(state, outputs))).To(Equal([]string{"director-manifest", "jumpbox-manifest"}))
+		})
+
+		It("warns about create-env states without a current VM", func() {
+			state.BOSH.State = map[string]interface{}{"director_id": "some-id"}
+			state.Jumpbox.State = map[string]interface{}{"current_vm_cid": ""}
+
+			problems := checker.Check(state, outputs)
+			Expect(checkNames(problems)).To(Equal([]string{"director-vm", "jumpbox-vm"}))
+			Expect(problems[0].Severity).To(Equal(fsck.SeverityWarning))
+		})
+
+		It("warns about a director state on a --no-director environment", func() {
+			state.NoDirector = true
+
+			Expect(checkNames(checker.Check(state, outputs))).To(Equal([]string{"no-director"}))
+		})
+
+		It("reports an unfinished cloudformation migration", func() {
+			state.Stack.Name = "some-stack"
+
+			Expect(checker.Check(state, outputs)).To(Equal([]fsck.Problem{{
+				Check:    "stack-migration",
+				Severity: fsck.SeverityError,
+				Message:  "cloudformation stack some-stack is still recorded alongside terraform state, the migration to terraform did not finish",
+			}}))
+		})
+
+		It("reports a load balancer missing from the terraform outputs", func() {
+			delete(outputs, "router_lb_ip")
+
+			Expect(checker.Check(state, outputs)).To(Equal([]fsck.Problem{{
+				Check:    "lb-outputs",
+				Severity: fsck.SeverityError,
+				Message:  "cf load balancer is recorded but terraform has no router_lb_ip output",
+			}}))
+		})
+
+		It("does not check load balancers without terraform outputs", func() {
+			Expect(checker.Check(state, nil)).To(BeEmpty())
+		})
+	})
+
+	Describe("Repair", func() {
+		It("fills in the jumpbox URL from the terraform outputs", func() {
+			state.Jumpbox.URL = ""
+
+			repairedState, repaired := checker.Repair(state, outputs)
+			Expect(repaired).To(BeTrue())
+			Expect(repairedState.Jumpbox.URL).To(Equal("10.0.0.5:22"))
+		})
+
+		It("does not repair the jumpbox URL when terraform has none", func() {
+			state.Jumpbox.URL = ""
+
+			_, repaired := checker.Repair(state, map[string]interface{}{})
+			Expect(repaired).To(BeFalse())
+		})
+
+		It("clears a URL left behind by a deleted jumpbox", func() {
+			state.Jumpbox = storage.Jumpbox{URL: "10.0.0.5:22"}
+
+			repairedState, repaired := checker.Repair(state, outputs)
+			Expect(repaired).To(BeTrue())
+			Expect(repairedState.Jumpbox.URL).To(BeEmpty())
+		})
+
+		It("leaves problems without a safe fix alone", func() {
+			state.Stack.Name = "some-stack"
+
+			repairedState, repaired := checker.Repair(state, outputs)
+			Expect(repaired).To(BeFalse())
+			Expect(repairedState).To(Equal(state))
+		})
+	})
+})
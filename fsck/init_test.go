@@ -0,0 +1,13 @@
+package fsck_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestFsck(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "fsck")
+}
@@ -59,6 +59,26 @@ func (c Client) CheckExists(networkName string) (bool, error) {
 }
 
 func (c Client) ValidateSafeToDelete(networkName string, envID string) error {
+	return c.validateNoVMs("network", func(networkInterface *compute.NetworkInterface) bool {
+		return strings.Contains(networkInterface.Network, networkName)
+	})
+}
+
+// ValidateSafeToDeleteSubnets only considers the VMs in the given
+// subnetworks, for environments that live in a network shared with other
+// workloads.
+func (c Client) ValidateSafeToDeleteSubnets(subnetworkNames []string, envID string) error {
+	return c.validateNoVMs("subnetwork", func(networkInterface *compute.NetworkInterface) bool {
+		for _, subnetworkName := range subnetworkNames {
+			if strings.HasSuffix(networkInterface.Subnetwork, "/"+subnetworkName) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func (c Client) validateNoVMs(location string, matches func(*compute.NetworkInterface) bool) error {
 	instanceList, err := c.listInstances()
 	if err != nil {
 		return err
@@ -66,7 +86,7 @@ func (c Client) ValidateSafeToDelete(networkName string, envID string) error {
 
 	var runningInstances []*compute.Instance
 	for _, instance := range instanceList.Items {
-		isInNetwork := c.isInNetwork(networkName, instance.NetworkInterfaces)
+		isInNetwork := c.isInNetwork(matches, instance.NetworkInterfaces)
 		isBoshDirector := c.isBoshDirector(instance.Metadata)
 
 		if isInNetwork && !isBoshDirector {
@@ -94,13 +114,13 @@ func (c Client) ValidateSafeToDelete(networkName string, envID string) error {
 		}
 	}
 
-	return fmt.Errorf("bbl environment is not safe to delete; vms still exist in network:\n%s",
-		strings.Join(errorMessages, "\n"))
+	return fmt.Errorf("bbl environment is not safe to delete; vms still exist in %s:\n%s",
+		location, strings.Join(errorMessages, "\n"))
 }
 
-func (c Client) isInNetwork(networkName string, networkInterfaces []*compute.NetworkInterface) bool {
+func (c Client) isInNetwork(matches func(*compute.NetworkInterface) bool, networkInterfaces []*compute.NetworkInterface) bool {
 	for _, networkInterface := range networkInterfaces {
-		if strings.Contains(networkInterface.Network, networkName) {
+		if matches(networkInterface) {
 			return true
 		}
 	}
@@ -177,4 +177,54 @@ var _ = Describe("Client", func() {
 		})
 	})
 
+	Describe("ValidateSafeToDeleteSubnets", func() {
+		BeforeEach(func() {
+			computeClient = &fakes.GCPComputeClient{}
+			client = gcp.NewClientWithInjectedComputeClient(computeClient, "some-project-id", "some-zone")
+		})
+
+		It("only returns an error for vms in the given subnetworks", func() {
+			deploymentName := "some-deployment"
+
+			computeClient.ListInstancesCall.Returns.InstanceList = &compute.InstanceList{
+				Items: []*compute.Instance{
+					{
+						Name: "some-vm",
+						NetworkInterfaces: []*compute.NetworkInterface{
+							{
+								Network:    "http://some-host/shared-network",
+								Subnetwork: "http://some-host/subnetworks/some-subnetwork",
+							},
+						},
+						Metadata: &compute.Metadata{
+							Items: []*compute.MetadataItems{
+								{
+									Key:   "deployment",
+									Value: &deploymentName,
+								},
+							},
+						},
+					},
+					{
+						Name: "other-team-vm",
+						NetworkInterfaces: []*compute.NetworkInterface{
+							{
+								Network:    "http://some-host/shared-network",
+								Subnetwork: "http://some-host/subnetworks/other-team-subnetwork",
+							},
+						},
+						Metadata: &compute.Metadata{
+							Items: []*compute.MetadataItems{},
+						},
+					},
+				},
+			}
+
+			err := client.ValidateSafeToDeleteSubnets([]string{"some-subnetwork"}, "some-env-id")
+
+			Expect(err).To(MatchError(`bbl environment is not safe to delete; vms still exist in subnetwork:
+some-vm (deployment: some-deployment)`))
+		})
+	})
+
 })
@@ -41,7 +41,7 @@ func (e EnvIDManager) Sync(state storage.State, envID string) (storage.State, er
 		return state, nil
 	}
 
-	err := e.checkFastFail(state.IAAS, envID)
+	err := e.checkFastFail(state, envID)
 	if err != nil {
 		return storage.State{}, err
 	}
@@ -63,7 +63,8 @@ func (e EnvIDManager) Sync(state storage.State, envID string) (storage.State, er
 	return state, nil
 }
 
-func (e EnvIDManager) checkFastFail(iaas, envID string) error {
+func (e EnvIDManager) checkFastFail(state storage.State, envID string) error {
+	iaas := state.IAAS
 	if iaas == "aws" {
 		stackName := "stack-" + envID
 		stackExists, err := e.infrastructureManager.Exists(stackName)
@@ -83,6 +84,29 @@ func (e EnvIDManager) checkFastFail(iaas, envID string) error {
 		networkName = envID + "-vpc"
 	}
 
+	// An environment in a shared network does not create a network of its
+	// own. Only GCP networks can be looked up by the name bbl was given; AWS
+	// VPCs are looked up by their Name tag rather than the shared VPC ID.
+	existingNetwork := state.ExistingNetwork()
+	if existingNetwork != "" {
+		if iaas != "gcp" {
+			return nil
+		}
+
+		exists, err := e.networkClient.CheckExists(existingNetwork)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			return fmt.Errorf("The existing network '%s' could not be found.", existingNetwork)
+		}
+
+		if networkName == existingNetwork {
+			return nil
+		}
+	}
+
 	exists, err := e.networkClient.CheckExists(networkName)
 	if err != nil {
 		return err
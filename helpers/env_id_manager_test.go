@@ -58,6 +58,67 @@ var _ = Describe("EnvIDManager", func() {
 				Expect(err).To(MatchError("It looks like a bbl environment already exists with the name 'existing'. Please provide a different name."))
 			})
 
+			Context("when the environment uses an existing network", func() {
+				It("checks that the existing gcp network can be found", func() {
+					networkClient.CheckExistsCall.Returns.Exists = false
+					_, err := envIDManager.Sync(storage.State{
+						IAAS: "gcp",
+						GCP: storage.GCP{
+							ExistingNetwork: "shared-network",
+						},
+					}, "some-env")
+
+					Expect(networkClient.CheckExistsCall.CallCount).To(Equal(1))
+					Expect(networkClient.CheckExistsCall.Receives.Name).To(Equal("shared-network"))
+
+					Expect(err).To(MatchError("The existing network 'shared-network' could not be found."))
+				})
+
+				It("does not treat the existing gcp network as a pre-existing environment", func() {
+					networkClient.CheckExistsCall.Returns.Exists = true
+					state, err := envIDManager.Sync(storage.State{
+						IAAS: "gcp",
+						GCP: storage.GCP{
+							ExistingNetwork: "some-env-network",
+						},
+					}, "some-env")
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(networkClient.CheckExistsCall.CallCount).To(Equal(1))
+					Expect(state.EnvID).To(Equal("some-env"))
+				})
+
+				It("still fails if another gcp environment has the name", func() {
+					networkClient.CheckExistsCall.Returns.Exists = true
+					_, err := envIDManager.Sync(storage.State{
+						IAAS: "gcp",
+						GCP: storage.GCP{
+							ExistingNetwork: "shared-network",
+						},
+					}, "existing")
+
+					Expect(networkClient.CheckExistsCall.CallCount).To(Equal(2))
+					Expect(networkClient.CheckExistsCall.Receives.Name).To(Equal("existing-network"))
+
+					Expect(err).To(MatchError("It looks like a bbl environment already exists with the name 'existing'. Please provide a different name."))
+				})
+
+				It("does not look up a vpc named after the aws environment", func() {
+					networkClient.CheckExistsCall.Returns.Exists = true
+					state, err := envIDManager.Sync(storage.State{
+						IAAS: "aws",
+						AWS: storage.AWS{
+							ExistingVPCID: "vpc-12345",
+						},
+					}, "some-env")
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(infrastructureManager.ExistsCall.CallCount).To(Equal(1))
+					Expect(networkClient.CheckExistsCall.CallCount).To(Equal(0))
+					Expect(state.EnvID).To(Equal("some-env"))
+				})
+			})
+
 			Context("for aws", func() {
 				It("fails if an environment with that name was already created by cloudformation", func() {
 					infrastructureManager.ExistsCall.Returns.Exists = true
@@ -0,0 +1,49 @@
+package helpers
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+var (
+	tempDirsMutex sync.Mutex
+	tempDirs      []string
+)
+
+// TempDir creates a temp dir like ioutil.TempDir and records it, so that
+// RemoveTempDirs can remove it with any credentials written into it before
+// bbl exits.
+func TempDir(dir, prefix string) (string, error) {
+	name, err := ioutil.TempDir(dir, prefix)
+	if err != nil {
+		return "", err
+	}
+
+	tempDirsMutex.Lock()
+	defer tempDirsMutex.Unlock()
+	tempDirs = append(tempDirs, name)
+
+	return name, nil
+}
+
+// RemoveTempDirs removes the temp dirs created by TempDir.
+func RemoveTempDirs() error {
+	tempDirsMutex.Lock()
+	defer tempDirsMutex.Unlock()
+
+	errs := NewErrors()
+	for _, dir := range tempDirs {
+		err := os.RemoveAll(dir)
+		if err != nil {
+			errs.Add(err)
+		}
+	}
+	tempDirs = nil
+
+	if len(errs.errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package helpers_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/bosh-bootloader/helpers"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TempDirs", func() {
+	It("removes the temp dirs it created", func() {
+		dir, err := helpers.TempDir("", "some-prefix")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(filepath.Base(dir)).To(HavePrefix("some-prefix"))
+
+		err = ioutil.WriteFile(filepath.Join(dir, "credentials.json"), []byte("some-credentials"), os.FileMode(0600))
+		Expect(err).NotTo(HaveOccurred())
+
+		err = helpers.RemoveTempDirs()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(dir).NotTo(BeAnExistingFile())
+	})
+
+	It("returns an error when the temp dir cannot be created", func() {
+		_, err := helpers.TempDir("/some/missing/dir", "")
+		Expect(err).To(HaveOccurred())
+	})
+})
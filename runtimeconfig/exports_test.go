@@ -0,0 +1,24 @@
+package runtimeconfig
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/cloudfoundry/bosh-bootloader/helpers"
+)
+
+func SetTempDir(f func(string, string) (string, error)) {
+	tempDir = f
+}
+
+func ResetTempDir() {
+	tempDir = helpers.TempDir
+}
+
+func SetWriteFile(f func(string, []byte, os.FileMode) error) {
+	writeFile = f
+}
+
+func ResetWriteFile() {
+	writeFile = ioutil.WriteFile
+}
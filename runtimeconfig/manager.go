@@ -0,0 +1,116 @@
+package runtimeconfig
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/bosh-bootloader/bosh"
+	"github.com/cloudfoundry/bosh-bootloader/helpers"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+)
+
+// Name is the name the runtime config is applied with, so that it does not
+// replace runtime configs that are managed outside of bbl.
+const Name = "bbl"
+
+var (
+	tempDir   func(string, string) (string, error)    = helpers.TempDir
+	writeFile func(string, []byte, os.FileMode) error = ioutil.WriteFile
+)
+
+type Manager struct {
+	logger             logger
+	command            command
+	boshClientProvider boshClientProvider
+	boshExecutor       boshExecutor
+}
+
+type logger interface {
+	Step(string, ...interface{})
+}
+
+type command interface {
+	Run(stdout io.Writer, workingDirectory string, args []string) error
+}
+
+type boshClientProvider interface {
+	Client(jumpbox storage.Jumpbox, directorAddress, directorUsername, directorPassword, caCert string) (bosh.Client, error)
+}
+
+type boshExecutor interface {
+	DNSRuntimeConfig() ([]byte, error)
+}
+
+func NewManager(logger logger, cmd command, boshClientProvider boshClientProvider, boshExecutor boshExecutor) Manager {
+	return Manager{
+		logger:             logger,
+		command:            cmd,
+		boshClientProvider: boshClientProvider,
+		boshExecutor:       boshExecutor,
+	}
+}
+
+// Generate interpolates the BOSH DNS runtime config of the bosh-deployment
+// the director is deployed from with the runtime config ops files in the
+// state, in the order they were given.
+func (m Manager) Generate(state storage.State) (string, error) {
+	dnsRuntimeConfig, err := m.boshExecutor.DNSRuntimeConfig()
+	if err != nil {
+		return "", err
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	workingDir, err := tempDir("", "")
+	if err != nil {
+		return "", err
+	}
+
+	err = writeFile(filepath.Join(workingDir, "runtime-config.yml"), dnsRuntimeConfig, 0600)
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{"interpolate", fmt.Sprintf("%s/runtime-config.yml", workingDir)}
+
+	for i, opsFile := range state.BOSH.RuntimeConfigOpsFiles {
+		opsFileName := fmt.Sprintf("ops-file-%d.yml", i)
+		err = writeFile(filepath.Join(workingDir, opsFileName), []byte(opsFile.Contents), 0600)
+		if err != nil {
+			return "", err
+		}
+
+		args = append(args, "-o", fmt.Sprintf("%s/%s", workingDir, opsFileName))
+	}
+
+	err = m.command.Run(buf, workingDir, args)
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func (m Manager) Update(state storage.State) error {
+	boshClient, err := m.boshClientProvider.Client(state.Jumpbox, state.BOSH.DirectorAddress, state.BOSH.DirectorUsername, state.BOSH.DirectorPassword, state.BOSH.DirectorSSLCA)
+	if err != nil {
+		return err // not tested
+	}
+
+	m.logger.Step("generating runtime config")
+	runtimeConfig, err := m.Generate(state)
+	if err != nil {
+		return err
+	}
+
+	m.logger.Step("applying runtime config")
+	err = boshClient.UpdateRuntimeConfig(Name, []byte(runtimeConfig))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
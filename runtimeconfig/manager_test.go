@@ -0,0 +1,211 @@
+package runtimeconfig_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/cloudfoundry/bosh-bootloader/fakes"
+	"github.com/cloudfoundry/bosh-bootloader/runtimeconfig"
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Manager", func() {
+	var (
+		logger             *fakes.Logger
+		cmd                *fakes.BOSHCommand
+		boshClientProvider *fakes.BOSHClientProvider
+		boshClient         *fakes.BOSHClient
+		boshExecutor       *fakes.BOSHExecutor
+		manager            runtimeconfig.Manager
+
+		tempDir       string
+		incomingState storage.State
+	)
+
+	BeforeEach(func() {
+		logger = &fakes.Logger{}
+		cmd = &fakes.BOSHCommand{}
+		boshClient = &fakes.BOSHClient{}
+		boshClientProvider = &fakes.BOSHClientProvider{}
+		boshExecutor = &fakes.BOSHExecutor{}
+
+		boshClientProvider.ClientCall.Returns.Client = boshClient
+
+		var err error
+		tempDir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		runtimeconfig.SetTempDir(func(string, string) (string, error) {
+			return tempDir, nil
+		})
+
+		cmd.RunStub = func(stdout io.Writer, workingDirectory string, args []string) error {
+			stdout.Write([]byte("some-runtime-config"))
+			return nil
+		}
+
+		incomingState = storage.State{
+			IAAS:    "gcp",
+			Jumpbox: storage.Jumpbox{Enabled: true},
+			BOSH: storage.BOSH{
+				DirectorAddress:  "some-director-address",
+				DirectorUsername: "some-director-username",
+				DirectorPassword: "some-director-password",
+				DirectorSSLCA:    "some-director-ca",
+				RuntimeConfigOpsFiles: []storage.UserFile{
+					{Path: "os-conf.yml", Contents: "some-os-conf-ops"},
+					{Path: "syslog.yml", Contents: "some-syslog-ops"},
+				},
+			},
+		}
+
+		expectedRuntimeConfig, err := ioutil.ReadFile("fixtures/dns-runtime-config.yml")
+		Expect(err).NotTo(HaveOccurred())
+		boshExecutor.DNSRuntimeConfigCall.Returns.RuntimeConfig = expectedRuntimeConfig
+
+		manager = runtimeconfig.NewManager(logger, cmd, boshClientProvider, boshExecutor)
+	})
+
+	AfterEach(func() {
+		runtimeconfig.ResetTempDir()
+	})
+
+	Describe("Generate", func() {
+		It("interpolates the bosh dns runtime config with the ops files in order", func() {
+			runtimeConfigYAML, err := manager.Generate(incomingState)
+			Expect(err).NotTo(HaveOccurred())
+
+			expectedRuntimeConfig, err := ioutil.ReadFile("fixtures/dns-runtime-config.yml")
+			Expect(err).NotTo(HaveOccurred())
+
+			runtimeConfig, err := ioutil.ReadFile(fmt.Sprintf("%s/runtime-config.yml", tempDir))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(runtimeConfig).To(Equal(expectedRuntimeConfig))
+
+			opsFile, err := ioutil.ReadFile(fmt.Sprintf("%s/ops-file-0.yml", tempDir))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(opsFile)).To(Equal("some-os-conf-ops"))
+
+			opsFile, err = ioutil.ReadFile(fmt.Sprintf("%s/ops-file-1.yml", tempDir))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(opsFile)).To(Equal("some-syslog-ops"))
+
+			for _, file := range []string{"runtime-config.yml", "ops-file-0.yml", "ops-file-1.yml"} {
+				info, err := os.Stat(fmt.Sprintf("%s/%s", tempDir, file))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(info.Mode()).To(Equal(os.FileMode(0600)))
+			}
+
+			Expect(cmd.RunCallCount()).To(Equal(1))
+			_, workingDirectory, args := cmd.RunArgsForCall(0)
+			Expect(workingDirectory).To(Equal(tempDir))
+			Expect(args).To(Equal([]string{
+				"interpolate", fmt.Sprintf("%s/runtime-config.yml", tempDir),
+				"-o", fmt.Sprintf("%s/ops-file-0.yml", tempDir),
+				"-o", fmt.Sprintf("%s/ops-file-1.yml", tempDir),
+			}))
+
+			Expect(runtimeConfigYAML).To(Equal("some-runtime-config"))
+		})
+
+		It("interpolates the bosh dns runtime config without ops files", func() {
+			_, err := manager.Generate(storage.State{})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, _, args := cmd.RunArgsForCall(0)
+			Expect(args).To(Equal([]string{"interpolate", fmt.Sprintf("%s/runtime-config.yml", tempDir)}))
+		})
+
+		Context("failure cases", func() {
+			It("returns an error when the bosh dns runtime config cannot be read", func() {
+				boshExecutor.DNSRuntimeConfigCall.Returns.Error = errors.New("bosh-deployment checkout some-dir is missing runtime-configs/dns.yml")
+
+				_, err := manager.Generate(incomingState)
+				Expect(err).To(MatchError("bosh-deployment checkout some-dir is missing runtime-configs/dns.yml"))
+				Expect(cmd.RunCallCount()).To(Equal(0))
+			})
+
+			It("returns an error when the temp dir cannot be created", func() {
+				runtimeconfig.SetTempDir(func(string, string) (string, error) {
+					return "", errors.New("failed to create temp dir")
+				})
+
+				_, err := manager.Generate(incomingState)
+				Expect(err).To(MatchError("failed to create temp dir"))
+			})
+
+			DescribeTable("returns an error when a file cannot be written", func(file string) {
+				runtimeconfig.SetWriteFile(func(filename string, body []byte, mode os.FileMode) error {
+					if strings.HasSuffix(filename, file) {
+						return errors.New("failed to write file")
+					}
+					return nil
+				})
+				defer runtimeconfig.ResetWriteFile()
+
+				_, err := manager.Generate(incomingState)
+				Expect(err).To(MatchError("failed to write file"))
+			},
+				Entry("runtime-config.yml", "runtime-config.yml"),
+				Entry("an ops file", "ops-file-1.yml"),
+			)
+
+			It("returns an error when the command fails to run", func() {
+				cmd.RunReturns(errors.New("failed to run"))
+
+				_, err := manager.Generate(incomingState)
+				Expect(err).To(MatchError("failed to run"))
+			})
+		})
+	})
+
+	Describe("Update", func() {
+		It("logs steps taken", func() {
+			err := manager.Update(incomingState)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(logger.StepCall.Messages).To(Equal([]string{
+				"generating runtime config",
+				"applying runtime config",
+			}))
+		})
+
+		It("applies the runtime config through the jumpbox", func() {
+			err := manager.Update(incomingState)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(boshClientProvider.ClientCall.Receives.Jumpbox).To(Equal(storage.Jumpbox{Enabled: true}))
+			Expect(boshClientProvider.ClientCall.Receives.DirectorAddress).To(Equal("some-director-address"))
+			Expect(boshClientProvider.ClientCall.Receives.DirectorUsername).To(Equal("some-director-username"))
+			Expect(boshClientProvider.ClientCall.Receives.DirectorPassword).To(Equal("some-director-password"))
+			Expect(boshClientProvider.ClientCall.Receives.DirectorCACert).To(Equal("some-director-ca"))
+
+			Expect(boshClient.UpdateRuntimeConfigCall.Receives.Name).To(Equal("bbl"))
+			Expect(boshClient.UpdateRuntimeConfigCall.Receives.Yaml).To(Equal([]byte("some-runtime-config")))
+		})
+
+		Context("failure cases", func() {
+			It("returns an error when the runtime config cannot be generated", func() {
+				cmd.RunReturns(errors.New("failed to run"))
+
+				err := manager.Update(incomingState)
+				Expect(err).To(MatchError("failed to run"))
+				Expect(boshClient.UpdateRuntimeConfigCall.CallCount).To(Equal(0))
+			})
+
+			It("returns an error when the bosh client fails to update the runtime config", func() {
+				boshClient.UpdateRuntimeConfigCall.Returns.Error = errors.New("failed to update")
+
+				err := manager.Update(incomingState)
+				Expect(err).To(MatchError("failed to update"))
+			})
+		})
+	})
+})
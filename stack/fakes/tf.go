@@ -2,22 +2,25 @@
 package fakes
 
 import (
+	"io"
 	"sync"
-
-	"github.com/cloudfoundry/bosh-bootloader/terraform"
 )
 
 type TF struct {
-	ImportStub        func(terraform.ImportInput) (string, error)
-	importMutex       sync.RWMutex
-	importArgsForCall []struct {
-		arg1 terraform.ImportInput
+	StateCommandStub        func(inputs map[string]string, terraformTemplate, tfState string, args []string, stdout io.Writer) (string, error)
+	stateCommandMutex       sync.RWMutex
+	stateCommandArgsForCall []struct {
+		inputs            map[string]string
+		terraformTemplate string
+		tfState           string
+		args              []string
+		stdout            io.Writer
 	}
-	importReturns struct {
+	stateCommandReturns struct {
 		result1 string
 		result2 error
 	}
-	importReturnsOnCall map[int]struct {
+	stateCommandReturnsOnCall map[int]struct {
 		result1 string
 		result2 error
 	}
@@ -25,52 +28,61 @@ type TF struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *TF) Import(arg1 terraform.ImportInput) (string, error) {
-	fake.importMutex.Lock()
-	ret, specificReturn := fake.importReturnsOnCall[len(fake.importArgsForCall)]
-	fake.importArgsForCall = append(fake.importArgsForCall, struct {
-		arg1 terraform.ImportInput
-	}{arg1})
-	fake.recordInvocation("Import", []interface{}{arg1})
-	fake.importMutex.Unlock()
-	if fake.ImportStub != nil {
-		return fake.ImportStub(arg1)
+func (fake *TF) StateCommand(inputs map[string]string, terraformTemplate string, tfState string, args []string, stdout io.Writer) (string, error) {
+	var argsCopy []string
+	if args != nil {
+		argsCopy = make([]string, len(args))
+		copy(argsCopy, args)
+	}
+	fake.stateCommandMutex.Lock()
+	ret, specificReturn := fake.stateCommandReturnsOnCall[len(fake.stateCommandArgsForCall)]
+	fake.stateCommandArgsForCall = append(fake.stateCommandArgsForCall, struct {
+		inputs            map[string]string
+		terraformTemplate string
+		tfState           string
+		args              []string
+		stdout            io.Writer
+	}{inputs, terraformTemplate, tfState, argsCopy, stdout})
+	fake.recordInvocation("StateCommand", []interface{}{inputs, terraformTemplate, tfState, argsCopy, stdout})
+	fake.stateCommandMutex.Unlock()
+	if fake.StateCommandStub != nil {
+		return fake.StateCommandStub(inputs, terraformTemplate, tfState, args, stdout)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	return fake.importReturns.result1, fake.importReturns.result2
+	return fake.stateCommandReturns.result1, fake.stateCommandReturns.result2
 }
 
-func (fake *TF) ImportCallCount() int {
-	fake.importMutex.RLock()
-	defer fake.importMutex.RUnlock()
-	return len(fake.importArgsForCall)
+func (fake *TF) StateCommandCallCount() int {
+	fake.stateCommandMutex.RLock()
+	defer fake.stateCommandMutex.RUnlock()
+	return len(fake.stateCommandArgsForCall)
 }
 
-func (fake *TF) ImportArgsForCall(i int) terraform.ImportInput {
-	fake.importMutex.RLock()
-	defer fake.importMutex.RUnlock()
-	return fake.importArgsForCall[i].arg1
+func (fake *TF) StateCommandArgsForCall(i int) (map[string]string, string, string, []string, io.Writer) {
+	fake.stateCommandMutex.RLock()
+	defer fake.stateCommandMutex.RUnlock()
+	return fake.stateCommandArgsForCall[i].inputs, fake.stateCommandArgsForCall[i].terraformTemplate, fake.stateCommandArgsForCall[i].tfState, fake.stateCommandArgsForCall[i].args, fake.stateCommandArgsForCall[i].stdout
 }
 
-func (fake *TF) ImportReturns(result1 string, result2 error) {
-	fake.ImportStub = nil
-	fake.importReturns = struct {
+func (fake *TF) StateCommandReturns(result1 string, result2 error) {
+	fake.StateCommandStub = nil
+	fake.stateCommandReturns = struct {
 		result1 string
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *TF) ImportReturnsOnCall(i int, result1 string, result2 error) {
-	fake.ImportStub = nil
-	if fake.importReturnsOnCall == nil {
-		fake.importReturnsOnCall = make(map[int]struct {
+func (fake *TF) StateCommandReturnsOnCall(i int, result1 string, result2 error) {
+	fake.StateCommandStub = nil
+	if fake.stateCommandReturnsOnCall == nil {
+		fake.stateCommandReturnsOnCall = make(map[int]struct {
 			result1 string
 			result2 error
 		})
 	}
-	fake.importReturnsOnCall[i] = struct {
+	fake.stateCommandReturnsOnCall[i] = struct {
 		result1 string
 		result2 error
 	}{result1, result2}
@@ -79,8 +91,8 @@ func (fake *TF) ImportReturnsOnCall(i int, result1 string, result2 error) {
 func (fake *TF) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
-	fake.importMutex.RLock()
-	defer fake.importMutex.RUnlock()
+	fake.stateCommandMutex.RLock()
+	defer fake.stateCommandMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -2,6 +2,8 @@ package stack
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
 
 	"github.com/cloudfoundry/bosh-bootloader/aws/cloudformation"
@@ -40,7 +42,7 @@ var (
 
 //go:generate counterfeiter -o ./fakes/tf.go --fake-name TF . tf
 type tf interface {
-	Import(terraform.ImportInput) (string, error)
+	StateCommand(inputs map[string]string, terraformTemplate, tfState string, args []string, stdout io.Writer) (string, error)
 }
 
 //go:generate counterfeiter -o ./fakes/infrastructure.go --fake-name Infrastructure . infrastructure
@@ -66,6 +68,8 @@ type keyPair interface {
 
 type Migrator struct {
 	terraform                 tf
+	templateGenerator         terraform.TemplateGenerator
+	inputGenerator            terraform.InputGenerator
 	infrastructure            infrastructure
 	certificate               certificate
 	userPolicy                userPolicy
@@ -74,6 +78,8 @@ type Migrator struct {
 }
 
 func NewMigrator(terraform tf,
+	templateGenerator terraform.TemplateGenerator,
+	inputGenerator terraform.InputGenerator,
 	infrastructure infrastructure,
 	certificate certificate,
 	userPolicy userPolicy,
@@ -81,6 +87,8 @@ func NewMigrator(terraform tf,
 	keyPair keyPair) Migrator {
 	return Migrator{
 		terraform:                 terraform,
+		templateGenerator:         templateGenerator,
+		inputGenerator:            inputGenerator,
 		infrastructure:            infrastructure,
 		certificate:               certificate,
 		userPolicy:                userPolicy,
@@ -123,6 +131,14 @@ func (m Migrator) Migrate(state storage.State) (storage.State, error) {
 		stack.Outputs["LoadBalancerCert"] = certificateName
 	}
 
+	// The resources are imported against the template bbl applies next, so
+	// that they are taken over rather than replaced.
+	template := m.templateGenerator.Generate(state)
+	input, err := m.inputGenerator.Generate(state)
+	if err != nil {
+		return storage.State{}, err
+	}
+
 	var (
 		internalSubnetIndex     int
 		loadBalancerSubnetIndex int
@@ -141,12 +157,7 @@ func (m Migrator) Migrate(state storage.State) (storage.State, error) {
 		}
 
 		var err error
-		state.TFState, err = m.terraform.Import(terraform.ImportInput{
-			TerraformAddr: addr,
-			AWSResourceID: value,
-			TFState:       state.TFState,
-			Creds:         state.AWS,
-		})
+		state.TFState, err = m.terraform.StateCommand(input, template, state.TFState, []string{"import", addr, value}, os.Stdout)
 		if err != nil {
 			return storage.State{}, err
 		}
@@ -9,21 +9,21 @@ import (
 	"github.com/cloudfoundry/bosh-bootloader/stack"
 	counterfeits "github.com/cloudfoundry/bosh-bootloader/stack/fakes"
 	"github.com/cloudfoundry/bosh-bootloader/storage"
-	"github.com/cloudfoundry/bosh-bootloader/terraform"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
-	"github.com/onsi/gomega/gstruct"
 )
 
 var _ = Describe("Migrate", func() {
 	var (
-		tf             *counterfeits.TF
-		infrastructure *counterfeits.Infrastructure
-		certificate    *counterfeits.Certificate
-		userPolicy     *counterfeits.UserPolicy
-		zone           *fakes.AvailabilityZoneRetriever
-		keyPair        *counterfeits.KeyPair
+		tf                *counterfeits.TF
+		templateGenerator *fakes.TemplateGenerator
+		inputGenerator    *fakes.InputGenerator
+		infrastructure    *counterfeits.Infrastructure
+		certificate       *counterfeits.Certificate
+		userPolicy        *counterfeits.UserPolicy
+		zone              *fakes.AvailabilityZoneRetriever
+		keyPair           *counterfeits.KeyPair
 
 		migrator stack.Migrator
 
@@ -32,13 +32,15 @@ var _ = Describe("Migrate", func() {
 
 	BeforeEach(func() {
 		tf = &counterfeits.TF{}
+		templateGenerator = &fakes.TemplateGenerator{}
+		inputGenerator = &fakes.InputGenerator{}
 		infrastructure = &counterfeits.Infrastructure{}
 		certificate = &counterfeits.Certificate{}
 		userPolicy = &counterfeits.UserPolicy{}
 		keyPair = &counterfeits.KeyPair{}
 		zone = &fakes.AvailabilityZoneRetriever{}
 
-		migrator = stack.NewMigrator(tf, infrastructure, certificate, userPolicy, zone, keyPair)
+		migrator = stack.NewMigrator(tf, templateGenerator, inputGenerator, infrastructure, certificate, userPolicy, zone, keyPair)
 
 		zone.RetrieveAvailabilityZonesCall.Returns.AZs = []string{"some-az"}
 		zone.RetrieveAvailabilityZonesCall.Returns.Error = nil
@@ -88,7 +90,7 @@ var _ = Describe("Migrate", func() {
 	})
 
 	It("migrates infrastructure created by cloudformation to terraform", func() {
-		tf.ImportReturns("some-magic-tfstate", nil)
+		tf.StateCommandReturns("some-magic-tfstate", nil)
 
 		incomingState.AWS = storage.AWS{
 			Region: "some-region",
@@ -137,90 +139,66 @@ var _ = Describe("Migrate", func() {
 		}))
 	})
 
-	It("passes the incoming state to import", func() {
-		tf.ImportReturns("some-tfstate", nil)
+	It("imports against the template and inputs of the incoming state", func() {
+		tf.StateCommandReturns("some-tfstate", nil)
 		incomingState.AWS = storage.AWS{
 			AccessKeyID:     "some-access-key-id",
 			SecretAccessKey: "some-secret-access-key",
 			Region:          "some-region",
 		}
 
+		templateGenerator.GenerateCall.Returns.Template = "some-template"
+		inputGenerator.GenerateCall.Returns.Inputs = map[string]string{"access_key": "some-access-key-id"}
+
 		_, err := migrator.Migrate(incomingState)
 		Expect(err).NotTo(HaveOccurred())
 
-		Expect(tf.ImportArgsForCall(0).TFState).To(Equal(""))
-		Expect(tf.ImportArgsForCall(0).Creds).To(Equal(incomingState.AWS))
+		Expect(templateGenerator.GenerateCall.Receives.State.AWS).To(Equal(incomingState.AWS))
+		Expect(inputGenerator.GenerateCall.Receives.State.AWS).To(Equal(incomingState.AWS))
+
+		inputs, template, tfState, _, _ := tf.StateCommandArgsForCall(0)
+		Expect(inputs).To(Equal(map[string]string{"access_key": "some-access-key-id"}))
+		Expect(template).To(Equal("some-template"))
+		Expect(tfState).To(Equal(""))
 
-		Expect(tf.ImportArgsForCall(1).TFState).To(Equal("some-tfstate"))
+		_, _, tfState, _, _ = tf.StateCommandArgsForCall(1)
+		Expect(tfState).To(Equal("some-tfstate"))
 	})
 
 	It("maps each resource to terraform", func() {
 		_, err := migrator.Migrate(incomingState)
 		Expect(err).NotTo(HaveOccurred())
 
-		importInputs := []terraform.ImportInput{}
-		for _, importCall := range tf.Invocations()["Import"] {
-			importInputs = append(importInputs, importCall[0].(terraform.ImportInput))
-		}
-
-		Expect(tf.ImportCallCount()).To(Equal(27))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_vpc.vpc", AWSResourceID: "some-vpc"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_internet_gateway.ig", AWSResourceID: "some-vpc-gateway-internet-gateway"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_eip.nat_eip", AWSResourceID: "some-nat-eip"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_instance.nat", AWSResourceID: "some-nat-instance"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_security_group.nat_security_group", AWSResourceID: "some-nat-security-group"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_eip.bosh_eip", AWSResourceID: "some-bosh-eip"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_security_group.bosh_security_group", AWSResourceID: "some-bosh-security-group"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_subnet.bosh_subnet", AWSResourceID: "some-bosh-subnet"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_route_table.bosh_route_table", AWSResourceID: "some-bosh-route-table"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_security_group.internal_security_group", AWSResourceID: "some-internal-security-group"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_route_table.internal_route_table", AWSResourceID: "some-internal-route-table"}))
-		Expect(importInputs).To(ContainElement(gstruct.MatchFields(gstruct.IgnoreExtras,
-			gstruct.Fields{
-				"TerraformAddr": MatchRegexp(`aws_subnet.internal_subnets\[\d\]`),
-				"AWSResourceID": Equal("some-internal-subnet-1"),
-			},
-		)))
-		Expect(importInputs).To(ContainElement(gstruct.MatchFields(gstruct.IgnoreExtras,
-			gstruct.Fields{
-				"TerraformAddr": MatchRegexp(`aws_subnet.internal_subnets\[\d\]`),
-				"AWSResourceID": Equal("some-internal-subnet-2"),
-			},
-		)))
-		Expect(importInputs).To(ContainElement(gstruct.MatchFields(gstruct.IgnoreExtras,
-			gstruct.Fields{
-				"TerraformAddr": MatchRegexp(`aws_subnet.internal_subnets\[\d\]`),
-				"AWSResourceID": Equal("some-internal-subnet-3"),
-			},
-		)))
-		Expect(importInputs).To(ContainElement(gstruct.MatchFields(gstruct.IgnoreExtras,
-			gstruct.Fields{
-				"TerraformAddr": MatchRegexp(`aws_subnet.internal_subnets\[\d\]`),
-				"AWSResourceID": Equal("some-internal-subnet-4"),
-			},
-		)))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_security_group.cf_router_lb_internal_security_group", AWSResourceID: "some-cf-router-internal-security-group"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_security_group.cf_router_lb_security_group", AWSResourceID: "some-cf-router-security-group"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_elb.cf_router_lb", AWSResourceID: "some-cf-router-load-balancer"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_security_group.cf_ssh_lb_internal_security_group", AWSResourceID: "some-cf-ssh-proxy-internal-security-group"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_security_group.cf_ssh_lb_security_group", AWSResourceID: "some-cf-ssh-proxy-security-group"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_elb.cf_ssh_lb", AWSResourceID: "some-cf-ssh-proxy-load-balancer"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_security_group.concourse_lb_internal_security_group", AWSResourceID: "some-concourse-internal-security-group"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_security_group.concourse_lb_security_group", AWSResourceID: "some-concourse-security-group"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_elb.concourse_lb", AWSResourceID: "some-concourse-load-balancer"}))
-		Expect(importInputs).To(ContainElement(terraform.ImportInput{TerraformAddr: "aws_route_table.lb_route_table", AWSResourceID: "some-lb-route-table"}))
-		Expect(importInputs).To(ContainElement(gstruct.MatchFields(gstruct.IgnoreExtras,
-			gstruct.Fields{
-				"TerraformAddr": MatchRegexp(`aws_subnet.lb_subnets\[\d\]`),
-				"AWSResourceID": Equal("some-lb-subnet-1"),
-			},
-		)))
-		Expect(importInputs).To(ContainElement(gstruct.MatchFields(gstruct.IgnoreExtras,
-			gstruct.Fields{
-				"TerraformAddr": MatchRegexp(`aws_subnet.lb_subnets\[\d\]`),
-				"AWSResourceID": Equal("some-lb-subnet-2"),
-			},
-		)))
+		imported := importedResources(tf)
+
+		Expect(tf.StateCommandCallCount()).To(Equal(27))
+		Expect(imported).To(HaveKeyWithValue("some-vpc", "aws_vpc.vpc"))
+		Expect(imported).To(HaveKeyWithValue("some-vpc-gateway-internet-gateway", "aws_internet_gateway.ig"))
+		Expect(imported).To(HaveKeyWithValue("some-nat-eip", "aws_eip.nat_eip"))
+		Expect(imported).To(HaveKeyWithValue("some-nat-instance", "aws_instance.nat"))
+		Expect(imported).To(HaveKeyWithValue("some-nat-security-group", "aws_security_group.nat_security_group"))
+		Expect(imported).To(HaveKeyWithValue("some-bosh-eip", "aws_eip.bosh_eip"))
+		Expect(imported).To(HaveKeyWithValue("some-bosh-security-group", "aws_security_group.bosh_security_group"))
+		Expect(imported).To(HaveKeyWithValue("some-bosh-subnet", "aws_subnet.bosh_subnet"))
+		Expect(imported).To(HaveKeyWithValue("some-bosh-route-table", "aws_route_table.bosh_route_table"))
+		Expect(imported).To(HaveKeyWithValue("some-internal-security-group", "aws_security_group.internal_security_group"))
+		Expect(imported).To(HaveKeyWithValue("some-internal-route-table", "aws_route_table.internal_route_table"))
+		Expect(imported).To(HaveKeyWithValue("some-internal-subnet-1", MatchRegexp(`aws_subnet.internal_subnets\[\d\]`)))
+		Expect(imported).To(HaveKeyWithValue("some-internal-subnet-2", MatchRegexp(`aws_subnet.internal_subnets\[\d\]`)))
+		Expect(imported).To(HaveKeyWithValue("some-internal-subnet-3", MatchRegexp(`aws_subnet.internal_subnets\[\d\]`)))
+		Expect(imported).To(HaveKeyWithValue("some-internal-subnet-4", MatchRegexp(`aws_subnet.internal_subnets\[\d\]`)))
+		Expect(imported).To(HaveKeyWithValue("some-cf-router-internal-security-group", "aws_security_group.cf_router_lb_internal_security_group"))
+		Expect(imported).To(HaveKeyWithValue("some-cf-router-security-group", "aws_security_group.cf_router_lb_security_group"))
+		Expect(imported).To(HaveKeyWithValue("some-cf-router-load-balancer", "aws_elb.cf_router_lb"))
+		Expect(imported).To(HaveKeyWithValue("some-cf-ssh-proxy-internal-security-group", "aws_security_group.cf_ssh_lb_internal_security_group"))
+		Expect(imported).To(HaveKeyWithValue("some-cf-ssh-proxy-security-group", "aws_security_group.cf_ssh_lb_security_group"))
+		Expect(imported).To(HaveKeyWithValue("some-cf-ssh-proxy-load-balancer", "aws_elb.cf_ssh_lb"))
+		Expect(imported).To(HaveKeyWithValue("some-concourse-internal-security-group", "aws_security_group.concourse_lb_internal_security_group"))
+		Expect(imported).To(HaveKeyWithValue("some-concourse-security-group", "aws_security_group.concourse_lb_security_group"))
+		Expect(imported).To(HaveKeyWithValue("some-concourse-load-balancer", "aws_elb.concourse_lb"))
+		Expect(imported).To(HaveKeyWithValue("some-lb-route-table", "aws_route_table.lb_route_table"))
+		Expect(imported).To(HaveKeyWithValue("some-lb-subnet-1", MatchRegexp(`aws_subnet.lb_subnets\[\d\]`)))
+		Expect(imported).To(HaveKeyWithValue("some-lb-subnet-2", MatchRegexp(`aws_subnet.lb_subnets\[\d\]`)))
 	})
 
 	Context("when there is no stack", func() {
@@ -237,7 +215,7 @@ var _ = Describe("Migrate", func() {
 			Expect(certificate.DescribeCallCount()).To(Equal(0))
 			Expect(zone.RetrieveAvailabilityZonesCall.CallCount).To(Equal(0))
 			Expect(infrastructure.UpdateCallCount()).To(Equal(0))
-			Expect(tf.ImportCallCount()).To(Equal(0))
+			Expect(tf.StateCommandCallCount()).To(Equal(0))
 			Expect(infrastructure.DeleteCallCount()).To(Equal(0))
 
 			Expect(state).To(Equal(incomingState))
@@ -262,10 +240,7 @@ var _ = Describe("Migrate", func() {
 			returnedState, err := migrator.Migrate(incomingState)
 			Expect(err).NotTo(HaveOccurred())
 
-			importInputs := []terraform.ImportInput{}
-			for _, importCall := range tf.Invocations()["Import"] {
-				importInputs = append(importInputs, importCall[0].(terraform.ImportInput))
-			}
+			imported := importedResources(tf)
 
 			Expect(certificate.DescribeCallCount()).To(Equal(1))
 			Expect(certificate.DescribeArgsForCall(0)).To(Equal("some-certificate-name"))
@@ -275,10 +250,7 @@ var _ = Describe("Migrate", func() {
 			Expect(certificateARN).To(Equal("some-dumb-arn"))
 			Expect(returnedState.LB.Type).To(Equal(lbType))
 
-			Expect(importInputs).To(ContainElement(terraform.ImportInput{
-				TerraformAddr: "aws_iam_server_certificate.lb_cert",
-				AWSResourceID: "some-certificate-name",
-			}))
+			Expect(imported).To(HaveKeyWithValue("some-certificate-name", "aws_iam_server_certificate.lb_cert"))
 		})
 	})
 
@@ -314,13 +286,23 @@ var _ = Describe("Migrate", func() {
 
 		Context("when terraform fails to import the stack", func() {
 			It("returns an error", func() {
-				tf.ImportReturns("", errors.New("no import"))
+				tf.StateCommandReturns("", errors.New("no import"))
 
 				_, err := migrator.Migrate(incomingState)
 				Expect(err).To(MatchError("no import"))
 			})
 		})
 
+		Context("when the terraform inputs cannot be generated", func() {
+			It("returns an error", func() {
+				inputGenerator.GenerateCall.Returns.Error = errors.New("no inputs")
+
+				_, err := migrator.Migrate(incomingState)
+				Expect(err).To(MatchError("no inputs"))
+				Expect(tf.StateCommandCallCount()).To(Equal(0))
+			})
+		})
+
 		Context("when the user policy cannot be deleted", func() {
 			It("returns an error", func() {
 				userPolicy.DeleteReturns(errors.New("no"))
@@ -349,3 +331,16 @@ var _ = Describe("Migrate", func() {
 		})
 	})
 })
+
+// importedResources maps the id of each resource imported through tf to its
+// terraform address.
+func importedResources(tf *counterfeits.TF) map[string]string {
+	imported := map[string]string{}
+	for i := 0; i < tf.StateCommandCallCount(); i++ {
+		_, _, _, args, _ := tf.StateCommandArgsForCall(i)
+		Expect(args).To(HaveLen(3))
+		Expect(args[0]).To(Equal("import"))
+		imported[args[2]] = args[1]
+	}
+	return imported
+}
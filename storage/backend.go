@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	LocalBackendType = "local"
+	S3BackendType    = "s3"
+	GCSBackendType   = "gcs"
+)
+
+// privateFileMode is the mode of the encrypted state files and of the state
+// history, which keeps full copies of the state, credentials included.
+const privateFileMode = os.FileMode(0600)
+
+var StateFileNotFound = errors.New("state file not found")
+
+// StateBackend stores the named files that make up the state of a bbl
+// environment.
+type StateBackend interface {
+	// Read returns StateFileNotFound when the named file does not exist.
+	Read(name string) ([]byte, error)
+	Write(name string, contents []byte) error
+	// Remove does not return an error when the named file does not exist.
+	Remove(name string) error
+	Location() string
+}
+
+type BackendConfig struct {
+	Type        string
+	Dir         string
+	ObjectStore ObjectStoreConfig
+}
+
+func NewStateBackend(config BackendConfig) (StateBackend, error) {
+	switch config.Type {
+	case "", LocalBackendType:
+		return NewLocalBackend(config.Dir), nil
+	case S3BackendType, GCSBackendType:
+		objectStoreConfig := config.ObjectStore
+		objectStoreConfig.Provider = config.Type
+		return NewObjectStoreBackend(objectStoreConfig)
+	}
+
+	return nil, fmt.Errorf("unknown state backend %q, valid options are: %s, %s, %s", config.Type, LocalBackendType, S3BackendType, GCSBackendType)
+}
+
+type LocalBackend struct {
+	dir string
+}
+
+func NewLocalBackend(dir string) LocalBackend {
+	return LocalBackend{dir: dir}
+}
+
+func (l LocalBackend) Read(name string) ([]byte, error) {
+	_, err := os.Stat(l.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := ioutil.ReadFile(filepath.Join(l.dir, name))
+	if os.IsNotExist(err) {
+		return nil, StateFileNotFound
+	}
+
+	return contents, err
+}
+
+func (l LocalBackend) Write(name string, contents []byte) error {
+	_, err := os.Stat(l.dir)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(l.dir, name)
+	err = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	mode := OS_READ_WRITE_MODE
+	if IsEncrypted(contents) || strings.HasPrefix(name, HistoryDirName+"/") {
+		mode = privateFileMode
+	}
+
+	return writeFileAtomically(path, contents, mode)
+}
+
+// writeFileAtomically replaces the file at path through a rename, so that
+// an interrupted write never leaves a partially written file behind.
+func writeFileAtomically(path string, contents []byte, mode os.FileMode) error {
+	file, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(contents)
+	if err == nil {
+		err = file.Chmod(mode)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+func (l LocalBackend) Remove(name string) error {
+	_, err := os.Stat(l.dir)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(filepath.Join(l.dir, name))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (l LocalBackend) Location() string {
+	return l.dir
+}
@@ -14,7 +14,26 @@ type BOSH struct {
 	Variables              string                 `json:"variables"`
 	State                  map[string]interface{} `json:"state"`
 	Manifest               string                 `json:"manifest"`
-	UserOpsFile            string                 `json:"userOpsFile"`
+	OpsFiles               []UserFile             `json:"opsFiles,omitempty"`
+	VarsFiles              []UserFile             `json:"varsFiles,omitempty"`
+	Vars                   []string               `json:"vars,omitempty"`
+	RuntimeConfigOpsFiles  []UserFile             `json:"runtimeConfigOpsFiles,omitempty"`
+	DeploymentSource       *DeploymentSource      `json:"deploymentSource,omitempty"`
+}
+
+// UserFile is an ops file or vars file passed to bbl up, stored with the
+// path it was read from so that later runs can tell when it has changed.
+type UserFile struct {
+	Path     string `json:"path"`
+	Contents string `json:"contents"`
+}
+
+// DeploymentSource is the bosh-deployment or jumpbox-deployment a manifest
+// was interpolated from: the repository compiled into bbl or the path of a
+// local checkout, and its commit.
+type DeploymentSource struct {
+	Source string `json:"source"`
+	SHA    string `json:"sha"`
 }
 
 func (b BOSH) IsEmpty() bool {
@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"time"
+)
+
+const (
+	BundleMetadataFileName     = "bundle.json"
+	BundleTemplateFileName     = "terraform/template.tf"
+	BundleDirectorManifestName = "manifests/director.yml"
+	BundleJumpboxManifestName  = "manifests/jumpbox.yml"
+)
+
+var supportedIAASes = map[string]struct{}{
+	"aws":   struct{}{},
+	"azure": struct{}{},
+	"gcp":   struct{}{},
+}
+
+type BundleMetadata struct {
+	StateVersion      int               `json:"stateVersion"`
+	IAAS              string            `json:"iaas"`
+	EnvID             string            `json:"envID"`
+	BBLVersion        string            `json:"bblVersion"`
+	ComponentVersions map[string]string `json:"componentVersions"`
+	CreatedAt         time.Time         `json:"createdAt"`
+}
+
+// Bundle is a portable copy of an environment: its state together with the
+// terraform template and manifests that were generated for it.
+type Bundle struct {
+	Metadata          BundleMetadata
+	State             State
+	TerraformTemplate string
+}
+
+// WriteBundle writes the bundle as a gzipped tarball. The state is encrypted
+// when BBL_STATE_KEY is set.
+func WriteBundle(w io.Writer, bundle Bundle) error {
+	metadata, err := marshalIndent(bundle.Metadata, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	state, err := marshalIndent(bundle.State, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	encrypt, err := keyConfigured()
+	if err != nil {
+		return err
+	}
+
+	if encrypt {
+		state, err = encryptWithStateKey(state)
+		if err != nil {
+			return err
+		}
+	}
+
+	files := map[string][]byte{
+		BundleMetadataFileName: metadata,
+		StateFileName:          state,
+		BundleTemplateFileName: []byte(bundle.TerraformTemplate),
+	}
+	if bundle.State.BOSH.Manifest != "" {
+		files[BundleDirectorManifestName] = []byte(bundle.State.BOSH.Manifest)
+	}
+	if bundle.State.Jumpbox.Manifest != "" {
+		files[BundleJumpboxManifestName] = []byte(bundle.State.Jumpbox.Manifest)
+	}
+
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	for _, name := range names {
+		err = tarWriter.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0600,
+			Size:    int64(len(files[name])),
+			ModTime: bundle.Metadata.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = tarWriter.Write(files[name])
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tarWriter.Close()
+	if err != nil {
+		return err
+	}
+
+	return gzipWriter.Close()
+}
+
+// ReadBundle reads a bundle written by WriteBundle and refuses bundles whose
+// state version or IAAS this bbl cannot use.
+func ReadBundle(r io.Reader) (Bundle, error) {
+	gzipReader, err := gzip.NewReader(r)
+	if err != nil {
+		return Bundle{}, fmt.Errorf("invalid bundle: %s", err)
+	}
+
+	files := map[string][]byte{}
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Bundle{}, fmt.Errorf("invalid bundle: %s", err)
+		}
+
+		switch header.Name {
+		case BundleMetadataFileName, StateFileName, BundleTemplateFileName:
+			files[header.Name], err = ioutil.ReadAll(tarReader)
+			if err != nil {
+				return Bundle{}, fmt.Errorf("invalid bundle: %s", err)
+			}
+		}
+	}
+
+	for _, name := range []string{BundleMetadataFileName, StateFileName} {
+		if _, ok := files[name]; !ok {
+			return Bundle{}, fmt.Errorf("invalid bundle: missing %s", name)
+		}
+	}
+
+	var bundle Bundle
+	err = json.Unmarshal(files[BundleMetadataFileName], &bundle.Metadata)
+	if err != nil {
+		return Bundle{}, fmt.Errorf("invalid bundle: %s: %s", BundleMetadataFileName, err)
+	}
+
+	if bundle.Metadata.StateVersion != STATE_VERSION {
+		return Bundle{}, fmt.Errorf("bundle has state version %d, but this bbl uses state version %d", bundle.Metadata.StateVersion, STATE_VERSION)
+	}
+
+	if _, ok := supportedIAASes[bundle.Metadata.IAAS]; !ok {
+		return Bundle{}, fmt.Errorf("bundle iaas %q is not supported by this bbl", bundle.Metadata.IAAS)
+	}
+
+	contents, err := decodeStateFile(files[StateFileName])
+	if err != nil {
+		return Bundle{}, err
+	}
+
+	err = json.Unmarshal(contents, &bundle.State)
+	if err != nil {
+		return Bundle{}, fmt.Errorf("invalid bundle: %s: %s", StateFileName, err)
+	}
+
+	if bundle.State.Version != bundle.Metadata.StateVersion {
+		return Bundle{}, fmt.Errorf("invalid bundle: %s has state version %d, but %s has %d", StateFileName, bundle.State.Version, BundleMetadataFileName, bundle.Metadata.StateVersion)
+	}
+
+	if bundle.State.IAAS != bundle.Metadata.IAAS {
+		return Bundle{}, fmt.Errorf("invalid bundle: %s has iaas %q, but %s has %q", StateFileName, bundle.State.IAAS, BundleMetadataFileName, bundle.Metadata.IAAS)
+	}
+
+	err = validateNetwork(bundle.State)
+	if err != nil {
+		return Bundle{}, fmt.Errorf("invalid bundle: %s", err)
+	}
+
+	bundle.TerraformTemplate = string(files[BundleTemplateFileName])
+
+	return bundle, nil
+}
@@ -0,0 +1,159 @@
+package storage_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bundle", func() {
+	var bundle storage.Bundle
+
+	BeforeEach(func() {
+		bundle = storage.Bundle{
+			Metadata: storage.BundleMetadata{
+				StateVersion:      storage.STATE_VERSION,
+				IAAS:              "gcp",
+				EnvID:             "some-env-id",
+				BBLVersion:        "some-bbl-version",
+				ComponentVersions: map[string]string{"bosh-deployment": "some-sha"},
+				CreatedAt:         time.Date(2017, time.June, 1, 12, 0, 0, 0, time.UTC),
+			},
+			State: storage.State{
+				Version: storage.STATE_VERSION,
+				IAAS:    "gcp",
+				EnvID:   "some-env-id",
+				BOSH:    storage.BOSH{Manifest: "name: bosh"},
+				Jumpbox: storage.Jumpbox{Manifest: "name: jumpbox"},
+			},
+			TerraformTemplate: "some-terraform-template",
+		}
+	})
+
+	AfterEach(func() {
+		os.Unsetenv("BBL_STATE_KEY")
+	})
+
+	writeTarball := func(files map[string]string) *bytes.Buffer {
+		buffer := &bytes.Buffer{}
+		gzipWriter := gzip.NewWriter(buffer)
+		tarWriter := tar.NewWriter(gzipWriter)
+		for name, contents := range files {
+			Expect(tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(contents))})).To(Succeed())
+			_, err := tarWriter.Write([]byte(contents))
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(tarWriter.Close()).To(Succeed())
+		Expect(gzipWriter.Close()).To(Succeed())
+		return buffer
+	}
+
+	It("round trips a bundle", func() {
+		buffer := &bytes.Buffer{}
+		err := storage.WriteBundle(buffer, bundle)
+		Expect(err).NotTo(HaveOccurred())
+
+		readBundle, err := storage.ReadBundle(buffer)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(readBundle).To(Equal(bundle))
+	})
+
+	It("includes the manifests in the bundle", func() {
+		buffer := &bytes.Buffer{}
+		err := storage.WriteBundle(buffer, bundle)
+		Expect(err).NotTo(HaveOccurred())
+
+		gzipReader, err := gzip.NewReader(buffer)
+		Expect(err).NotTo(HaveOccurred())
+
+		files := map[string]string{}
+		tarReader := tar.NewReader(gzipReader)
+		for {
+			header, err := tarReader.Next()
+			if err != nil {
+				break
+			}
+			contents, err := ioutil.ReadAll(tarReader)
+			Expect(err).NotTo(HaveOccurred())
+			files[header.Name] = string(contents)
+		}
+
+		Expect(files).To(HaveKeyWithValue("manifests/director.yml", "name: bosh"))
+		Expect(files).To(HaveKeyWithValue("manifests/jumpbox.yml", "name: jumpbox"))
+		Expect(files).To(HaveKeyWithValue("terraform/template.tf", "some-terraform-template"))
+		Expect(files).To(HaveKey("bundle.json"))
+	})
+
+	It("encrypts the state when a state key is set", func() {
+		os.Setenv("BBL_STATE_KEY", "some-passphrase")
+
+		buffer := &bytes.Buffer{}
+		err := storage.WriteBundle(buffer, bundle)
+		Expect(err).NotTo(HaveOccurred())
+
+		os.Unsetenv("BBL_STATE_KEY")
+		_, err = storage.ReadBundle(bytes.NewReader(buffer.Bytes()))
+		Expect(err).To(Equal(storage.MissingStateKeyError))
+
+		os.Setenv("BBL_STATE_KEY", "some-passphrase")
+		readBundle, err := storage.ReadBundle(bytes.NewReader(buffer.Bytes()))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(readBundle.State).To(Equal(bundle.State))
+	})
+
+	Context("failure cases", func() {
+		It("refuses a bundle with a different state version", func() {
+			bundle.Metadata.StateVersion = 9
+			buffer := &bytes.Buffer{}
+			Expect(storage.WriteBundle(buffer, bundle)).To(Succeed())
+
+			_, err := storage.ReadBundle(buffer)
+			Expect(err).To(MatchError("bundle has state version 9, but this bbl uses state version 11"))
+		})
+
+		It("refuses a bundle with an unsupported iaas", func() {
+			bundle.Metadata.IAAS = "openstack"
+			buffer := &bytes.Buffer{}
+			Expect(storage.WriteBundle(buffer, bundle)).To(Succeed())
+
+			_, err := storage.ReadBundle(buffer)
+			Expect(err).To(MatchError(`bundle iaas "openstack" is not supported by this bbl`))
+		})
+
+		It("refuses a bundle whose state does not match its metadata", func() {
+			bundle.State.IAAS = "aws"
+			buffer := &bytes.Buffer{}
+			Expect(storage.WriteBundle(buffer, bundle)).To(Succeed())
+
+			_, err := storage.ReadBundle(buffer)
+			Expect(err).To(MatchError(`invalid bundle: bbl-state.json has iaas "aws", but bundle.json has "gcp"`))
+		})
+
+		It("refuses a bundle with an invalid network", func() {
+			bundle.State.Network = &storage.Network{CIDR: "some-cidr"}
+			buffer := &bytes.Buffer{}
+			Expect(storage.WriteBundle(buffer, bundle)).To(Succeed())
+
+			_, err := storage.ReadBundle(buffer)
+			Expect(err).To(MatchError(`invalid bundle: invalid network in bbl-state.json: network cidr "some-cidr" is not a valid IPv4 CIDR`))
+		})
+
+		It("refuses a bundle without a state file", func() {
+			_, err := storage.ReadBundle(writeTarball(map[string]string{"bundle.json": "{}"}))
+			Expect(err).To(MatchError("invalid bundle: missing bbl-state.json"))
+		})
+
+		It("refuses a file that is not a bundle", func() {
+			_, err := storage.ReadBundle(bytes.NewBufferString("some-text"))
+			Expect(err).To(MatchError(ContainSubstring("invalid bundle: ")))
+		})
+	})
+})
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	FlowLogsComponent   = "flow-logs"
+	KMSComponent        = "kms"
+	S3EndpointComponent = "s3-endpoint"
+)
+
+// Component is an optional part of the infrastructure of an IAAS, which the
+// template generator of the IAAS adds to the template when it is enabled.
+type Component struct {
+	Name        string
+	IAAS        string
+	Default     bool
+	Description string
+}
+
+// AllComponents are the optional components, in the order they are added
+// to the templates.
+var AllComponents = []Component{
+	{Name: FlowLogsComponent, IAAS: "aws", Default: true, Description: "VPC flow logs of rejected traffic"},
+	{Name: KMSComponent, IAAS: "aws", Default: true, Description: "KMS key the director disks are encrypted with"},
+	{Name: S3EndpointComponent, IAAS: "aws", Default: false, Description: "VPC endpoint for S3 in the internal subnets"},
+}
+
+// Components holds the components that were enabled or disabled with
+// --enable and --disable. Components that are not in it have their default.
+type Components map[string]bool
+
+// ComponentsFor returns the optional components of the IAAS.
+func ComponentsFor(iaas string) []Component {
+	var components []Component
+	for _, component := range AllComponents {
+		if component.IAAS == iaas {
+			components = append(components, component)
+		}
+	}
+
+	return components
+}
+
+// ComponentEnabled reports whether the named component is part of the
+// infrastructure of the environment.
+func (s State) ComponentEnabled(name string) bool {
+	if enabled, ok := s.Components[name]; ok {
+		return enabled
+	}
+
+	for _, component := range AllComponents {
+		if component.Name == name {
+			return component.Default
+		}
+	}
+
+	return false
+}
+
+// ValidateComponentNames returns an error when one of the names is not a
+// component of the IAAS.
+func ValidateComponentNames(iaas string, names []string) error {
+	valid := map[string]bool{}
+	var validNames []string
+	for _, component := range ComponentsFor(iaas) {
+		valid[component.Name] = true
+		validNames = append(validNames, component.Name)
+	}
+	sort.Strings(validNames)
+
+	for _, name := range names {
+		if valid[name] {
+			continue
+		}
+
+		if len(validNames) == 0 {
+			return fmt.Errorf("unknown component %q, %s has no optional components", name, iaas)
+		}
+		return fmt.Errorf("unknown component %q for %s, valid options are: %s", name, iaas, strings.Join(validNames, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package storage_test
+
+import (
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Components", func() {
+	Describe("ComponentEnabled", func() {
+		It("uses the default of components that were not toggled", func() {
+			state := storage.State{IAAS: "aws"}
+
+			Expect(state.ComponentEnabled(storage.FlowLogsComponent)).To(BeTrue())
+			Expect(state.ComponentEnabled(storage.KMSComponent)).To(BeTrue())
+			Expect(state.ComponentEnabled(storage.S3EndpointComponent)).To(BeFalse())
+		})
+
+		It("uses the components that were enabled or disabled", func() {
+			state := storage.State{
+				IAAS:       "aws",
+				Components: storage.Components{"flow-logs": false, "s3-endpoint": true},
+			}
+
+			Expect(state.ComponentEnabled(storage.FlowLogsComponent)).To(BeFalse())
+			Expect(state.ComponentEnabled(storage.S3EndpointComponent)).To(BeTrue())
+		})
+	})
+
+	Describe("ValidateComponentNames", func() {
+		It("accepts the components of the iaas", func() {
+			err := storage.ValidateComponentNames("aws", []string{"flow-logs", "kms"})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns an error for components of other iaases", func() {
+			err := storage.ValidateComponentNames("gcp", []string{"kms"})
+			Expect(err).To(MatchError(`unknown component "kms", gcp has no optional components`))
+		})
+	})
+})
@@ -0,0 +1,241 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+const (
+	StateKeyEnvVar = "BBL_STATE_KEY"
+
+	// stateKeyFilePrefix marks a BBL_STATE_KEY that names a key file rather
+	// than holding the passphrase itself.
+	stateKeyFilePrefix = "file:"
+
+	ENCRYPTED_STATE_VERSION = 1
+
+	encryptedStateCipher     = "aes-256-gcm"
+	encryptedStateKDF        = "pbkdf2-sha256"
+	encryptedStateIterations = 100000
+	encryptedStateSaltSize   = 16
+	encryptedStateKeySize    = 32
+)
+
+var (
+	MissingStateKeyError = fmt.Errorf("bbl-state.json is encrypted, set %s to the passphrase or key file used to encrypt it", StateKeyEnvVar)
+)
+
+type encryptedStateEnvelope struct {
+	EncryptedState *encryptedState `json:"bblEncryptedState"`
+}
+
+type encryptedState struct {
+	Version    int    `json:"version"`
+	Cipher     string `json:"cipher"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// IsEncrypted reports whether the given state file contents are wrapped in
+// an encrypted state envelope.
+func IsEncrypted(contents []byte) bool {
+	var envelope encryptedStateEnvelope
+	err := json.Unmarshal(contents, &envelope)
+	if err != nil {
+		return false
+	}
+
+	return envelope.EncryptedState != nil
+}
+
+func encryptState(plaintext, passphrase []byte) ([]byte, error) {
+	salt := make([]byte, encryptedStateSaltSize)
+	_, err := io.ReadFull(rand.Reader, salt)
+	if err != nil {
+		return nil, fmt.Errorf("generate salt: %s", err)
+	}
+
+	header := encryptedState{
+		Version:    ENCRYPTED_STATE_VERSION,
+		Cipher:     encryptedStateCipher,
+		KDF:        encryptedStateKDF,
+		Iterations: encryptedStateIterations,
+		Salt:       salt,
+	}
+
+	aead, err := newStateAEAD(passphrase, header)
+	if err != nil {
+		return nil, err
+	}
+
+	header.Nonce = make([]byte, aead.NonceSize())
+	_, err = io.ReadFull(rand.Reader, header.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("generate nonce: %s", err)
+	}
+
+	header.Ciphertext = aead.Seal(nil, header.Nonce, plaintext, header.additionalData())
+
+	return marshalIndent(encryptedStateEnvelope{EncryptedState: &header}, "", "\t")
+}
+
+func decryptState(contents, passphrase []byte) ([]byte, error) {
+	var envelope encryptedStateEnvelope
+	err := json.Unmarshal(contents, &envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	header := envelope.EncryptedState
+	if header == nil {
+		return nil, errors.New("state is not encrypted")
+	}
+
+	if header.Version > ENCRYPTED_STATE_VERSION {
+		return nil, fmt.Errorf("Encrypted state was created with a newer version of bbl. Please upgrade to a version of bbl compatible with encryption version %d.", header.Version)
+	}
+
+	if header.Cipher != encryptedStateCipher || header.KDF != encryptedStateKDF {
+		return nil, fmt.Errorf("unsupported state encryption %s/%s", header.Cipher, header.KDF)
+	}
+
+	aead, err := newStateAEAD(passphrase, *header)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(header.Nonce) != aead.NonceSize() {
+		return nil, errors.New("decrypt state: invalid nonce")
+	}
+
+	plaintext, err := aead.Open(nil, header.Nonce, header.Ciphertext, header.additionalData())
+	if err != nil {
+		return nil, errors.New("decrypt state: wrong key or state has been tampered with")
+	}
+
+	return plaintext, nil
+}
+
+func newStateAEAD(passphrase []byte, header encryptedState) (cipher.AEAD, error) {
+	if header.Iterations < 1 {
+		return nil, errors.New("invalid key derivation iterations")
+	}
+
+	key := pbkdf2SHA256(passphrase, header.Salt, header.Iterations, encryptedStateKeySize)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// additionalData binds the envelope header to the ciphertext so that the
+// recorded version and key derivation parameters cannot be altered.
+func (e encryptedState) additionalData() []byte {
+	return []byte(fmt.Sprintf("bbl-state|%d|%s|%s|%d|%x", e.Version, e.Cipher, e.KDF, e.Iterations, e.Salt))
+}
+
+func pbkdf2SHA256(password, salt []byte, iterations, keyLength int) []byte {
+	prf := hmac.New(sha256.New, password)
+	hashLength := prf.Size()
+	numBlocks := (keyLength + hashLength - 1) / hashLength
+
+	var key []byte
+	blockIndex := make([]byte, 4)
+	for block := 1; block <= numBlocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(blockIndex, uint32(block))
+		prf.Write(blockIndex)
+		u := prf.Sum(nil)
+
+		t := make([]byte, len(u))
+		copy(t, u)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+
+	return key[:keyLength]
+}
+
+// stateKey returns the passphrase configured through BBL_STATE_KEY. The
+// variable holds either the passphrase itself or, prefixed with "file:", the
+// path to a key file, which must exist.
+func stateKey() ([]byte, error) {
+	value := os.Getenv(StateKeyEnvVar)
+	if value == "" {
+		return nil, nil
+	}
+
+	if !strings.HasPrefix(value, stateKeyFilePrefix) {
+		return []byte(value), nil
+	}
+
+	path := strings.TrimPrefix(value, stateKeyFilePrefix)
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading state key from file: %v", err)
+	}
+
+	key := bytes.TrimSpace(contents)
+	if len(key) == 0 {
+		return nil, fmt.Errorf("state key file %q is empty", path)
+	}
+
+	return key, nil
+}
+
+func keyConfigured() (bool, error) {
+	key, err := stateKey()
+	if err != nil {
+		return false, err
+	}
+
+	return key != nil, nil
+}
+
+func decodeStateFile(contents []byte) ([]byte, error) {
+	if !IsEncrypted(contents) {
+		return contents, nil
+	}
+
+	key, err := stateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	if key == nil {
+		return nil, MissingStateKeyError
+	}
+
+	plaintext, err := decryptState(contents, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return plaintext, nil
+}
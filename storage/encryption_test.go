@@ -0,0 +1,220 @@
+package storage_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("State encryption", func() {
+	var (
+		store     storage.Store
+		tempDir   string
+		stateFile string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		stateFile = filepath.Join(tempDir, "bbl-state.json")
+		store = storage.NewStore(tempDir)
+	})
+
+	AfterEach(func() {
+		os.Unsetenv("BBL_STATE_KEY")
+	})
+
+	Context("when BBL_STATE_KEY is set", func() {
+		BeforeEach(func() {
+			os.Setenv("BBL_STATE_KEY", "some-passphrase")
+		})
+
+		It("writes new state as an encrypted envelope", func() {
+			err := store.Set(storage.State{
+				IAAS: "gcp",
+				ID:   "some-id",
+				BOSH: storage.BOSH{
+					DirectorPassword: "some-director-password",
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := ioutil.ReadFile(stateFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(storage.IsEncrypted(contents)).To(BeTrue())
+			Expect(string(contents)).To(ContainSubstring(`"version": 1`))
+			Expect(string(contents)).To(ContainSubstring(`"cipher": "aes-256-gcm"`))
+			Expect(string(contents)).NotTo(ContainSubstring("some-director-password"))
+
+			state, err := storage.GetState(tempDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(state.BOSH.DirectorPassword).To(Equal("some-director-password"))
+		})
+
+		It("reads the passphrase from a key file", func() {
+			keyFile := filepath.Join(tempDir, "state.key")
+			err := ioutil.WriteFile(keyFile, []byte("some-file-passphrase\n"), os.ModePerm)
+			Expect(err).NotTo(HaveOccurred())
+			os.Setenv("BBL_STATE_KEY", "file:"+keyFile)
+
+			err = store.Set(storage.State{IAAS: "gcp", ID: "some-id"})
+			Expect(err).NotTo(HaveOccurred())
+
+			os.Setenv("BBL_STATE_KEY", "some-file-passphrase")
+			state, err := storage.GetState(tempDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(state.IAAS).To(Equal("gcp"))
+		})
+
+		It("returns an error when the key file does not exist", func() {
+			os.Setenv("BBL_STATE_KEY", "file:"+filepath.Join(tempDir, "missing.key"))
+
+			err := store.Set(storage.State{IAAS: "gcp", ID: "some-id"})
+			Expect(err).To(MatchError(ContainSubstring("error reading state key from file")))
+
+			Expect(stateFile).NotTo(BeAnExistingFile())
+		})
+
+		It("writes the encrypted state and its history only the user can read", func() {
+			err := store.Set(storage.State{IAAS: "gcp", ID: "some-id"})
+			Expect(err).NotTo(HaveOccurred())
+
+			fileInfo, err := os.Stat(stateFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fileInfo.Mode()).To(Equal(os.FileMode(0600)))
+
+			snapshots, err := filepath.Glob(filepath.Join(tempDir, ".bbl-history", "*.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(snapshots).NotTo(BeEmpty())
+			for _, snapshot := range snapshots {
+				fileInfo, err := os.Stat(snapshot)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fileInfo.Mode()).To(Equal(os.FileMode(0600)))
+			}
+		})
+
+		It("keeps an existing plaintext state in plaintext", func() {
+			err := ioutil.WriteFile(stateFile, []byte(`{"version": 11, "iaas": "aws"}`), os.ModePerm)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = store.Set(storage.State{IAAS: "aws", ID: "some-id"})
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := ioutil.ReadFile(stateFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(storage.IsEncrypted(contents)).To(BeFalse())
+		})
+
+		Context("failure cases", func() {
+			It("returns an error when the key is wrong", func() {
+				err := store.Set(storage.State{IAAS: "gcp", ID: "some-id"})
+				Expect(err).NotTo(HaveOccurred())
+
+				os.Setenv("BBL_STATE_KEY", "some-other-passphrase")
+				_, err = storage.GetState(tempDir)
+				Expect(err).To(MatchError("decrypt state: wrong key or state has been tampered with"))
+			})
+
+			It("returns an error when the envelope header has been tampered with", func() {
+				err := store.Set(storage.State{IAAS: "gcp", ID: "some-id"})
+				Expect(err).NotTo(HaveOccurred())
+
+				contents, err := ioutil.ReadFile(stateFile)
+				Expect(err).NotTo(HaveOccurred())
+				tampered := strings.Replace(string(contents), `"iterations": 100000`, `"iterations": 100001`, 1)
+				err = ioutil.WriteFile(stateFile, []byte(tampered), os.ModePerm)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = storage.GetState(tempDir)
+				Expect(err).To(MatchError("decrypt state: wrong key or state has been tampered with"))
+			})
+
+			It("returns an error when the envelope was written by a newer bbl", func() {
+				err := ioutil.WriteFile(stateFile, []byte(`{"bblEncryptedState": {"version": 2}}`), os.ModePerm)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = storage.GetState(tempDir)
+				Expect(err).To(MatchError(ContainSubstring("compatible with encryption version 2")))
+			})
+		})
+	})
+
+	Context("when BBL_STATE_KEY is not set", func() {
+		It("writes new state as plaintext", func() {
+			err := store.Set(storage.State{IAAS: "gcp", ID: "some-id"})
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := ioutil.ReadFile(stateFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(storage.IsEncrypted(contents)).To(BeFalse())
+		})
+
+		It("returns an error when reading an encrypted state", func() {
+			os.Setenv("BBL_STATE_KEY", "some-passphrase")
+			err := store.Set(storage.State{IAAS: "gcp", ID: "some-id"})
+			Expect(err).NotTo(HaveOccurred())
+			os.Unsetenv("BBL_STATE_KEY")
+
+			_, err = storage.GetState(tempDir)
+			Expect(err).To(Equal(storage.MissingStateKeyError))
+		})
+	})
+
+	Describe("Encrypt and Decrypt", func() {
+		BeforeEach(func() {
+			err := ioutil.WriteFile(stateFile, []byte(`{"version": 11, "iaas": "aws", "envID": "some-env-id"}`), os.ModePerm)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("converts an existing state in both directions", func() {
+			os.Setenv("BBL_STATE_KEY", "some-passphrase")
+
+			err := store.Encrypt()
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := ioutil.ReadFile(stateFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(storage.IsEncrypted(contents)).To(BeTrue())
+
+			state, err := storage.GetState(tempDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(state.EnvID).To(Equal("some-env-id"))
+
+			err = store.Decrypt()
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err = ioutil.ReadFile(stateFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(MatchJSON(`{"version": 11, "iaas": "aws", "envID": "some-env-id"}`))
+		})
+
+		Context("failure cases", func() {
+			It("returns an error when encrypting without a key", func() {
+				err := store.Encrypt()
+				Expect(err).To(MatchError("encrypting bbl-state.json requires BBL_STATE_KEY to be set to a passphrase or key file"))
+			})
+
+			It("returns an error when encrypting an encrypted state", func() {
+				os.Setenv("BBL_STATE_KEY", "some-passphrase")
+				err := store.Encrypt()
+				Expect(err).NotTo(HaveOccurred())
+
+				err = store.Encrypt()
+				Expect(err).To(MatchError("bbl-state.json is already encrypted"))
+			})
+
+			It("returns an error when decrypting a plaintext state", func() {
+				err := store.Decrypt()
+				Expect(err).To(MatchError("bbl-state.json is not encrypted"))
+			})
+		})
+	})
+})
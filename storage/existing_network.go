@@ -0,0 +1,20 @@
+package storage
+
+// ExistingNetwork returns the pre-provisioned network that the environment
+// uses instead of creating its own: the VPC ID on AWS, the network name on
+// GCP and "<resource-group>/<vnet>" on Azure. It is empty when bbl manages
+// the network itself.
+func (s State) ExistingNetwork() string {
+	switch s.IAAS {
+	case "aws":
+		return s.AWS.ExistingVPCID
+	case "gcp":
+		return s.GCP.ExistingNetwork
+	case "azure":
+		if s.Azure.ExistingVNet != "" {
+			return s.Azure.ExistingVNetResourceGroup + "/" + s.Azure.ExistingVNet
+		}
+	}
+
+	return ""
+}
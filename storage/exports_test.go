@@ -2,6 +2,8 @@ package storage
 
 import (
 	"encoding/json"
+	"os"
+	"time"
 
 	uuid "github.com/nu7hatch/gouuid"
 )
@@ -21,3 +23,37 @@ func SetUUIDNewV4(f func() (uuid *uuid.UUID, err error)) {
 func ResetUUIDNewV4() {
 	uuidNewV4 = uuid.NewV4
 }
+
+func SetGetpid(f func() int) {
+	getpid = f
+}
+
+func ResetGetpid() {
+	getpid = os.Getpid
+}
+
+func SetNow(f func() time.Time) {
+	now = f
+}
+
+func ResetNow() {
+	now = time.Now
+}
+
+func SetSleep(f func(time.Duration)) {
+	sleep = f
+}
+
+func ResetSleep() {
+	sleep = time.Sleep
+}
+
+func SetMigrations(m []Migration) {
+	migrations = m
+}
+
+func ResetMigrations() {
+	migrations = defaultMigrations
+}
+
+var defaultMigrations = migrations
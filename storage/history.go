@@ -0,0 +1,259 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	HistoryDirName = ".bbl-history"
+	HISTORY_SIZE   = 50
+)
+
+var historyIndexName = path.Join(HistoryDirName, "index.json")
+
+type HistoryEntry struct {
+	ID        int       `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	Command   string    `json:"command"`
+	Summary   string    `json:"summary"`
+}
+
+type historyIndex struct {
+	NextID  int            `json:"nextID"`
+	Entries []HistoryEntry `json:"entries"`
+}
+
+// History keeps a bounded ring of the state files written by bbl, oldest
+// first. Snapshots are stored exactly as written, so an encrypted state
+// produces encrypted snapshots.
+type History struct {
+	backend StateBackend
+	size    int
+}
+
+func NewHistory(backend StateBackend, size int) History {
+	return History{
+		backend: backend,
+		size:    size,
+	}
+}
+
+func (h History) Entries() ([]HistoryEntry, error) {
+	index, err := h.readIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	return index.Entries, nil
+}
+
+func (h History) Record(command string, contents []byte, summary string) error {
+	index, err := h.readIndex()
+	if err != nil {
+		return err
+	}
+
+	if index.NextID == 0 {
+		index.NextID = 1
+	}
+
+	entry := HistoryEntry{
+		ID:        index.NextID,
+		CreatedAt: now().UTC(),
+		Command:   command,
+		Summary:   summary,
+	}
+
+	err = h.backend.Write(snapshotName(entry.ID), contents)
+	if err != nil {
+		return err
+	}
+
+	index.NextID++
+	index.Entries = append(index.Entries, entry)
+
+	var expired []HistoryEntry
+	if len(index.Entries) > h.size {
+		expired = index.Entries[:len(index.Entries)-h.size]
+		index.Entries = index.Entries[len(index.Entries)-h.size:]
+	}
+
+	err = h.writeIndex(index)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range expired {
+		err = h.backend.Remove(snapshotName(e.ID))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (h History) Read(id int) ([]byte, error) {
+	index, err := h.readIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range index.Entries {
+		if entry.ID == id {
+			return h.backend.Read(snapshotName(id))
+		}
+	}
+
+	return nil, fmt.Errorf("state history entry %d does not exist", id)
+}
+
+// Remove deletes every snapshot and the index. The snapshots hold full copies
+// of the state, so they go with the environment they belong to.
+func (h History) Remove() error {
+	index, err := h.readIndex()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range index.Entries {
+		err = h.backend.Remove(snapshotName(entry.ID))
+		if err != nil {
+			return err
+		}
+	}
+
+	return h.backend.Remove(historyIndexName)
+}
+
+// rewrite replaces every snapshot with the result of transform. It is used
+// to keep snapshots in line with bbl-state.json when it is encrypted or
+// decrypted.
+func (h History) rewrite(transform func([]byte) ([]byte, error)) error {
+	index, err := h.readIndex()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range index.Entries {
+		contents, err := h.backend.Read(snapshotName(entry.ID))
+		if err != nil {
+			return err
+		}
+
+		contents, err = transform(contents)
+		if err != nil {
+			return err
+		}
+
+		err = h.backend.Write(snapshotName(entry.ID), contents)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (h History) readIndex() (historyIndex, error) {
+	var index historyIndex
+
+	contents, err := h.backend.Read(historyIndexName)
+	switch {
+	case err == StateFileNotFound:
+		return index, nil
+	case err != nil:
+		return index, err
+	}
+
+	err = json.Unmarshal(contents, &index)
+	if err != nil {
+		return index, fmt.Errorf("read state history index: %s", err)
+	}
+
+	return index, nil
+}
+
+func (h History) writeIndex(index historyIndex) error {
+	contents, err := marshalIndent(index, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return h.backend.Write(historyIndexName, contents)
+}
+
+func snapshotName(id int) string {
+	return path.Join(HistoryDirName, fmt.Sprintf("%d.json", id))
+}
+
+// summarizeChanges lists the state fields that differ between two states,
+// descending one level into nested objects such as bosh and lb.
+func summarizeChanges(previous, current State) string {
+	previousFields, err := stateFields(previous)
+	if err != nil {
+		return "unknown changes"
+	}
+
+	currentFields, err := stateFields(current)
+	if err != nil {
+		return "unknown changes"
+	}
+
+	changed := changedFields("", previousFields, currentFields, 2)
+	if len(changed) == 0 {
+		return "no changes"
+	}
+
+	return "changed " + strings.Join(changed, ", ")
+}
+
+func stateFields(state State) (map[string]interface{}, error) {
+	contents, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+	err = json.Unmarshal(contents, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+func changedFields(prefix string, previous, current map[string]interface{}, depth int) []string {
+	keys := map[string]struct{}{}
+	for key := range previous {
+		keys[key] = struct{}{}
+	}
+	for key := range current {
+		keys[key] = struct{}{}
+	}
+
+	var changed []string
+	for key := range keys {
+		if reflect.DeepEqual(previous[key], current[key]) {
+			continue
+		}
+
+		previousValue, previousIsMap := previous[key].(map[string]interface{})
+		currentValue, currentIsMap := current[key].(map[string]interface{})
+		if depth > 1 && previousIsMap && currentIsMap {
+			changed = append(changed, changedFields(prefix+key+".", previousValue, currentValue, depth-1)...)
+			continue
+		}
+
+		changed = append(changed, prefix+key)
+	}
+
+	sort.Strings(changed)
+	return changed
+}
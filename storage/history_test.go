@@ -0,0 +1,179 @@
+package storage_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("History", func() {
+	var (
+		store   storage.Store
+		tempDir string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		storage.SetNow(func() time.Time { return time.Date(2017, time.June, 1, 12, 0, 0, 0, time.UTC) })
+		store = storage.NewStoreWithBackend(storage.NewLocalBackend(tempDir), "up")
+	})
+
+	AfterEach(func() {
+		storage.ResetNow()
+		os.Unsetenv("BBL_STATE_KEY")
+	})
+
+	readStateFile := func(name string) storage.State {
+		contents, err := ioutil.ReadFile(filepath.Join(tempDir, name))
+		Expect(err).NotTo(HaveOccurred())
+
+		var state storage.State
+		err = json.Unmarshal(contents, &state)
+		Expect(err).NotTo(HaveOccurred())
+		return state
+	}
+
+	Describe("Set", func() {
+		It("records every state written with the command and a summary of the changes", func() {
+			err := store.Set(storage.State{IAAS: "gcp", ID: "some-id", EnvID: "some-env-id"})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = store.Set(storage.State{IAAS: "gcp", ID: "some-id", EnvID: "some-env-id", TFState: "some-tf-state", LB: storage.LB{Type: "cf"}})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = store.Set(storage.State{IAAS: "gcp", ID: "some-id", EnvID: "some-env-id", TFState: "some-tf-state", LB: storage.LB{Type: "cf"}})
+			Expect(err).NotTo(HaveOccurred())
+
+			entries, err := store.History()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(Equal([]storage.HistoryEntry{
+				{ID: 1, CreatedAt: time.Date(2017, time.June, 1, 12, 0, 0, 0, time.UTC), Command: "up", Summary: "created"},
+				{ID: 2, CreatedAt: time.Date(2017, time.June, 1, 12, 0, 0, 0, time.UTC), Command: "up", Summary: "changed lb.type, tfState"},
+				{ID: 3, CreatedAt: time.Date(2017, time.June, 1, 12, 0, 0, 0, time.UTC), Command: "up", Summary: "no changes"},
+			}))
+
+			Expect(readStateFile(".bbl-history/2.json").TFState).To(Equal("some-tf-state"))
+		})
+
+		It("summarizes the changes against the loaded state", func() {
+			err := ioutil.WriteFile(filepath.Join(tempDir, "bbl-state.json"), []byte(`{"version": 11, "iaas": "gcp", "id": "some-id", "envID": "some-env-id"}`), os.ModePerm)
+			Expect(err).NotTo(HaveOccurred())
+
+			store.Loaded(storage.State{Version: 11, IAAS: "gcp", ID: "some-id", EnvID: "some-env-id"})
+			err = store.Set(storage.State{IAAS: "gcp", ID: "some-id", EnvID: "some-env-id", LB: storage.LB{Type: "cf"}})
+			Expect(err).NotTo(HaveOccurred())
+
+			entries, err := store.History()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries[0].Summary).To(Equal("changed lb.type"))
+		})
+
+		It("does not summarize the changes to a state file it did not load", func() {
+			err := ioutil.WriteFile(filepath.Join(tempDir, "bbl-state.json"), []byte(`{"version": 11, "iaas": "gcp", "id": "some-id"}`), os.ModePerm)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = store.Set(storage.State{IAAS: "gcp", ID: "some-id", LB: storage.LB{Type: "cf"}})
+			Expect(err).NotTo(HaveOccurred())
+
+			entries, err := store.History()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries[0].Summary).To(Equal("unknown changes"))
+		})
+
+		It("removes the recorded states with the state", func() {
+			err := store.Set(storage.State{IAAS: "gcp", ID: "some-id"})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = store.Set(storage.State{})
+			Expect(err).NotTo(HaveOccurred())
+
+			entries, err := store.History()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(BeEmpty())
+			Expect(filepath.Join(tempDir, ".bbl-history", "1.json")).NotTo(BeAnExistingFile())
+		})
+
+		It("keeps a bounded number of states", func() {
+			for i := 0; i < storage.HISTORY_SIZE+2; i++ {
+				err := store.Set(storage.State{IAAS: "gcp", ID: "some-id", EnvID: fmt.Sprintf("env-%d", i)})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			entries, err := store.History()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(storage.HISTORY_SIZE))
+			Expect(entries[0].ID).To(Equal(3))
+
+			Expect(filepath.Join(tempDir, ".bbl-history", "2.json")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(tempDir, ".bbl-history", "3.json")).To(BeAnExistingFile())
+		})
+
+		It("encrypts the recorded states of an encrypted state file", func() {
+			os.Setenv("BBL_STATE_KEY", "some-passphrase")
+
+			err := store.Set(storage.State{IAAS: "gcp", ID: "some-id"})
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := ioutil.ReadFile(filepath.Join(tempDir, ".bbl-history", "1.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(storage.IsEncrypted(contents)).To(BeTrue())
+		})
+	})
+
+	Describe("Encrypt", func() {
+		It("encrypts the recorded states", func() {
+			err := store.Set(storage.State{IAAS: "gcp", ID: "some-id"})
+			Expect(err).NotTo(HaveOccurred())
+
+			os.Setenv("BBL_STATE_KEY", "some-passphrase")
+			err = store.Encrypt()
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := ioutil.ReadFile(filepath.Join(tempDir, ".bbl-history", "1.json"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(storage.IsEncrypted(contents)).To(BeTrue())
+
+			err = store.Decrypt()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(readStateFile(".bbl-history/1.json").IAAS).To(Equal("gcp"))
+		})
+	})
+
+	Describe("Rollback", func() {
+		BeforeEach(func() {
+			err := store.Set(storage.State{IAAS: "gcp", ID: "some-id", EnvID: "some-env-id"})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = store.Set(storage.State{IAAS: "gcp", ID: "some-id", EnvID: "some-env-id", TFState: "some-half-applied-tf-state"})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("restores bbl-state.json and records the rollback", func() {
+			err := store.Rollback(1)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(readStateFile("bbl-state.json").TFState).To(BeEmpty())
+
+			entries, err := store.History()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries[2].Command).To(Equal("state rollback"))
+			Expect(entries[2].Summary).To(Equal("restored 1"))
+		})
+
+		It("returns an error when the entry does not exist", func() {
+			err := store.Rollback(42)
+			Expect(err).To(MatchError("state history entry 42 does not exist"))
+		})
+	})
+})
@@ -8,6 +8,9 @@ type Jumpbox struct {
 	Variables string                 `json:"variables"`
 	Manifest  string                 `json:"manifest"`
 	State     map[string]interface{} `json:"state"`
+	OpsFiles  []UserFile             `json:"opsFiles,omitempty"`
+
+	DeploymentSource *DeploymentSource `json:"deploymentSource,omitempty"`
 }
 
 func (j Jumpbox) IsEmpty() bool {
@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	SplitLayout = "split"
+
+	DirectorVarsStoreFileName = "vars/director-vars-store.yml"
+	JumpboxVarsStoreFileName  = "vars/jumpbox-vars-store.yml"
+	TerraformStateFileName    = "terraform/terraform.tfstate"
+	LatestTFOutputFileName    = "terraform/latest-output.log"
+	DirectorStateFileName     = "create-env/director-state.json"
+	JumpboxStateFileName      = "create-env/jumpbox-state.json"
+	DirectorManifestFileName  = "create-env/director-manifest.yml"
+	JumpboxManifestFileName   = "create-env/jumpbox-manifest.yml"
+)
+
+// splitIndex is the slim bbl-state.json written by the split layout. The
+// fields moved to their own files are left empty.
+type splitIndex struct {
+	State
+	Layout string `json:"layout"`
+}
+
+type statePart struct {
+	name  string
+	split func(state *State) ([]byte, error)
+	join  func(state *State, contents []byte) error
+}
+
+var stateParts = []statePart{
+	stringPart(DirectorVarsStoreFileName, func(s *State) *string { return &s.BOSH.Variables }),
+	stringPart(JumpboxVarsStoreFileName, func(s *State) *string { return &s.Jumpbox.Variables }),
+	stringPart(TerraformStateFileName, func(s *State) *string { return &s.TFState }),
+	stringPart(LatestTFOutputFileName, func(s *State) *string { return &s.LatestTFOutput }),
+	mapPart(DirectorStateFileName, func(s *State) *map[string]interface{} { return &s.BOSH.State }),
+	mapPart(JumpboxStateFileName, func(s *State) *map[string]interface{} { return &s.Jumpbox.State }),
+	stringPart(DirectorManifestFileName, func(s *State) *string { return &s.BOSH.Manifest }),
+	stringPart(JumpboxManifestFileName, func(s *State) *string { return &s.Jumpbox.Manifest }),
+}
+
+func stringPart(name string, field func(*State) *string) statePart {
+	return statePart{
+		name: name,
+		split: func(state *State) ([]byte, error) {
+			value := *field(state)
+			*field(state) = ""
+			if value == "" {
+				return nil, nil
+			}
+			return []byte(value), nil
+		},
+		join: func(state *State, contents []byte) error {
+			*field(state) = string(contents)
+			return nil
+		},
+	}
+}
+
+func mapPart(name string, field func(*State) *map[string]interface{}) statePart {
+	return statePart{
+		name: name,
+		split: func(state *State) ([]byte, error) {
+			value := *field(state)
+			*field(state) = nil
+			if value == nil {
+				return nil, nil
+			}
+			return marshalIndent(value, "", "\t")
+		},
+		join: func(state *State, contents []byte) error {
+			return json.Unmarshal(contents, field(state))
+		},
+	}
+}
+
+// isSplitLayout reports whether the decoded bbl-state.json contents are the
+// index of a split state directory.
+func isSplitLayout(contents []byte) bool {
+	var index struct {
+		Layout string `json:"layout"`
+	}
+	err := json.Unmarshal(contents, &index)
+	if err != nil {
+		return false
+	}
+
+	return index.Layout == SplitLayout
+}
+
+// writeSplit writes every part of the state to its own file before writing
+// the index, and removes the files of parts that are empty.
+func writeSplit(backend StateBackend, state State, encrypt bool) error {
+	for _, part := range stateParts {
+		contents, err := part.split(&state)
+		if err != nil {
+			return err
+		}
+
+		if contents == nil {
+			err = backend.Remove(part.name)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		err = writeStateFile(backend, part.name, contents, encrypt)
+		if err != nil {
+			return err
+		}
+	}
+
+	index, err := marshalIndent(splitIndex{State: state, Layout: SplitLayout}, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return writeStateFile(backend, StateFileName, index, encrypt)
+}
+
+func joinSplit(backend StateBackend, state State) (State, error) {
+	for _, part := range stateParts {
+		contents, err := backend.Read(part.name)
+		switch {
+		case err == StateFileNotFound:
+			continue
+		case err != nil:
+			return State{}, err
+		}
+
+		contents, err = decodeStateFile(contents)
+		if err != nil {
+			return State{}, err
+		}
+
+		err = part.join(&state, contents)
+		if err != nil {
+			return State{}, fmt.Errorf("read %s: %s", part.name, err)
+		}
+	}
+
+	return state, nil
+}
+
+func removeSplit(backend StateBackend) error {
+	for _, part := range stateParts {
+		err := backend.Remove(part.name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// transformSplit rewrites every part file, keeping them in line with the
+// index when it is encrypted or decrypted.
+func transformSplit(backend StateBackend, transform func([]byte) ([]byte, error)) error {
+	for _, part := range stateParts {
+		contents, err := backend.Read(part.name)
+		switch {
+		case err == StateFileNotFound:
+			continue
+		case err != nil:
+			return err
+		}
+
+		contents, err = transform(contents)
+		if err != nil {
+			return err
+		}
+
+		err = backend.Write(part.name, contents)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeStateFile(backend StateBackend, name string, contents []byte, encrypt bool) error {
+	if encrypt {
+		var err error
+		contents, err = encryptWithStateKey(contents)
+		if err != nil {
+			return err
+		}
+	}
+
+	return backend.Write(name, contents)
+}
@@ -0,0 +1,168 @@
+package storage_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Split layout", func() {
+	var (
+		store   storage.Store
+		tempDir string
+		state   storage.State
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		store = storage.NewStore(tempDir)
+
+		state = storage.State{
+			Version: 11,
+			IAAS:    "gcp",
+			ID:      "some-id",
+			EnvID:   "some-env-id",
+			BOSH: storage.BOSH{
+				DirectorName: "bosh-some-env-id",
+				Variables:    "admin_password: some-password\n",
+				Manifest:     "name: bosh\n",
+				State:        map[string]interface{}{"current_vm_cid": "some-vm-cid"},
+			},
+			Jumpbox: storage.Jumpbox{
+				Enabled:   true,
+				Variables: "jumpbox_ssh: some-key\n",
+				Manifest:  "name: jumpbox\n",
+				State:     map[string]interface{}{"current_vm_cid": "some-jumpbox-cid"},
+			},
+			TFState:        "some-tf-state",
+			LatestTFOutput: "some-tf-output",
+		}
+
+		err = store.Set(state)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.Unsetenv("BBL_STATE_KEY")
+	})
+
+	readFile := func(name string) string {
+		contents, err := ioutil.ReadFile(filepath.Join(tempDir, name))
+		Expect(err).NotTo(HaveOccurred())
+		return string(contents)
+	}
+
+	Describe("Split", func() {
+		It("moves the large and secret fields into their own files", func() {
+			err := store.Split()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(readFile("vars/director-vars-store.yml")).To(Equal("admin_password: some-password\n"))
+			Expect(readFile("vars/jumpbox-vars-store.yml")).To(Equal("jumpbox_ssh: some-key\n"))
+			Expect(readFile("terraform/terraform.tfstate")).To(Equal("some-tf-state"))
+			Expect(readFile("create-env/director-state.json")).To(MatchJSON(`{"current_vm_cid": "some-vm-cid"}`))
+			Expect(readFile("create-env/jumpbox-state.json")).To(MatchJSON(`{"current_vm_cid": "some-jumpbox-cid"}`))
+			Expect(readFile("create-env/director-manifest.yml")).To(Equal("name: bosh\n"))
+
+			var index map[string]interface{}
+			err = json.Unmarshal([]byte(readFile("bbl-state.json")), &index)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(index["layout"]).To(Equal("split"))
+			Expect(index["tfState"]).To(BeEmpty())
+			Expect(index["envID"]).To(Equal("some-env-id"))
+		})
+
+		It("loads the split state transparently", func() {
+			err := store.Split()
+			Expect(err).NotTo(HaveOccurred())
+
+			loaded, err := storage.GetState(tempDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(loaded).To(Equal(state))
+		})
+
+		It("keeps the split layout when the state is saved", func() {
+			err := store.Split()
+			Expect(err).NotTo(HaveOccurred())
+
+			state.TFState = "some-new-tf-state"
+			state.LatestTFOutput = ""
+			err = store.Set(state)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(readFile("terraform/terraform.tfstate")).To(Equal("some-new-tf-state"))
+			Expect(filepath.Join(tempDir, "terraform", "latest-output.log")).NotTo(BeAnExistingFile())
+
+			loaded, err := storage.GetState(tempDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(loaded).To(Equal(state))
+		})
+
+		It("encrypts every file of an encrypted state", func() {
+			os.Setenv("BBL_STATE_KEY", "some-passphrase")
+			err := store.Encrypt()
+			Expect(err).NotTo(HaveOccurred())
+
+			err = store.Split()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(storage.IsEncrypted([]byte(readFile("vars/director-vars-store.yml")))).To(BeTrue())
+			Expect(storage.IsEncrypted([]byte(readFile("bbl-state.json")))).To(BeTrue())
+
+			err = store.Decrypt()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(readFile("vars/director-vars-store.yml")).To(Equal("admin_password: some-password\n"))
+		})
+
+		It("removes every file when the state is removed", func() {
+			err := store.Split()
+			Expect(err).NotTo(HaveOccurred())
+
+			err = store.Set(storage.State{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(tempDir, "bbl-state.json")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(tempDir, "terraform", "terraform.tfstate")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(tempDir, "vars", "director-vars-store.yml")).NotTo(BeAnExistingFile())
+		})
+
+		It("returns an error when the state is already split", func() {
+			err := store.Split()
+			Expect(err).NotTo(HaveOccurred())
+
+			err = store.Split()
+			Expect(err).To(MatchError("bbl-state.json already uses the split layout"))
+		})
+	})
+
+	Describe("Join", func() {
+		It("moves the split files back into bbl-state.json", func() {
+			err := store.Split()
+			Expect(err).NotTo(HaveOccurred())
+
+			err = store.Join()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(tempDir, "terraform", "terraform.tfstate")).NotTo(BeAnExistingFile())
+
+			var joined storage.State
+			err = json.Unmarshal([]byte(readFile("bbl-state.json")), &joined)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(joined).To(Equal(state))
+		})
+
+		It("returns an error when the state is not split", func() {
+			err := store.Join()
+			Expect(err).To(MatchError("bbl-state.json does not use the split layout"))
+		})
+	})
+})
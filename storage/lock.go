@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	LockFileName = "bbl-state.lock"
+
+	lockRetryInterval = 500 * time.Millisecond
+)
+
+var (
+	getpid   = os.Getpid
+	hostname = os.Hostname
+	now      = time.Now
+	sleep    = time.Sleep
+)
+
+type LockInfo struct {
+	PID       int       `json:"pid"`
+	Host      string    `json:"host"`
+	Command   string    `json:"command"`
+	StartedAt time.Time `json:"startedAt"`
+}
+
+type LockedError struct {
+	Holder LockInfo
+}
+
+func (e LockedError) Error() string {
+	return fmt.Sprintf("state directory is locked by `bbl %s` (pid %d on %s) since %s. Wait for it to finish, retry with --lock-timeout, or run `bbl force-unlock` if the lock is stale.",
+		e.Holder.Command, e.Holder.PID, e.Holder.Host, e.Holder.StartedAt.Format(time.RFC3339))
+}
+
+// Lock is an advisory lock on a state directory that keeps concurrent bbl
+// runs from overwriting each other's state.
+type Lock struct {
+	path string
+}
+
+func NewLock(dir string) Lock {
+	return Lock{path: filepath.Join(dir, LockFileName)}
+}
+
+// Acquire creates the lock file, retrying until the timeout has elapsed
+// when another process holds the lock.
+func (l Lock) Acquire(command string, timeout time.Duration) error {
+	host, err := hostname()
+	if err != nil {
+		return fmt.Errorf("get hostname: %s", err)
+	}
+
+	contents, err := json.Marshal(LockInfo{
+		PID:       getpid(),
+		Host:      host,
+		Command:   command,
+		StartedAt: now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	deadline := now().Add(timeout)
+	for {
+		file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, OS_READ_WRITE_MODE)
+		if err == nil {
+			_, err = file.Write(contents)
+			closeErr := file.Close()
+			if err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(l.path)
+				return fmt.Errorf("write lock file: %s", err)
+			}
+			return nil
+		}
+
+		if !os.IsExist(err) {
+			return fmt.Errorf("create lock file: %s", err)
+		}
+
+		if !now().Before(deadline) {
+			holder, err := l.Holder()
+			if err != nil {
+				return err
+			}
+			return LockedError{Holder: holder}
+		}
+
+		sleep(lockRetryInterval)
+	}
+}
+
+// Release removes the lock file if it is held by the current process.
+func (l Lock) Release() error {
+	holder, err := l.Holder()
+	switch {
+	case os.IsNotExist(err):
+		return nil
+	case err != nil:
+		return err
+	}
+
+	host, err := hostname()
+	if err != nil {
+		return fmt.Errorf("get hostname: %s", err)
+	}
+
+	if holder.PID != getpid() || holder.Host != host {
+		return nil
+	}
+
+	return os.Remove(l.path)
+}
+
+func (l Lock) Holder() (LockInfo, error) {
+	contents, err := ioutil.ReadFile(l.path)
+	if err != nil {
+		return LockInfo{}, err
+	}
+
+	var holder LockInfo
+	err = json.Unmarshal(contents, &holder)
+	if err != nil {
+		return LockInfo{}, fmt.Errorf("read lock file %s: %s", l.path, err)
+	}
+
+	return holder, nil
+}
+
+// ForceUnlock removes the lock file regardless of its holder and returns
+// the holder that was recorded in it.
+func (l Lock) ForceUnlock() (LockInfo, error) {
+	holder, err := l.Holder()
+	if os.IsNotExist(err) {
+		return LockInfo{}, fmt.Errorf("%s is not locked", filepath.Dir(l.path))
+	}
+
+	err = os.Remove(l.path)
+	if err != nil && !os.IsNotExist(err) {
+		return LockInfo{}, err
+	}
+
+	return holder, nil
+}
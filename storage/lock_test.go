@@ -0,0 +1,170 @@
+package storage_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Lock", func() {
+	var (
+		lock     storage.Lock
+		tempDir  string
+		lockPath string
+		hostname string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		hostname, err = os.Hostname()
+		Expect(err).NotTo(HaveOccurred())
+
+		lockPath = filepath.Join(tempDir, "bbl-state.lock")
+		lock = storage.NewLock(tempDir)
+	})
+
+	AfterEach(func() {
+		storage.ResetGetpid()
+		storage.ResetNow()
+		storage.ResetSleep()
+	})
+
+	writeLockFile := func(holder storage.LockInfo) {
+		contents, err := json.Marshal(holder)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = ioutil.WriteFile(lockPath, contents, os.ModePerm)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	Describe("Acquire", func() {
+		It("records the holder of the lock", func() {
+			storage.SetGetpid(func() int { return 1234 })
+			storage.SetNow(func() time.Time { return time.Date(2017, time.June, 1, 12, 0, 0, 0, time.UTC) })
+
+			err := lock.Acquire("up", 0)
+			Expect(err).NotTo(HaveOccurred())
+
+			holder, err := lock.Holder()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(holder).To(Equal(storage.LockInfo{
+				PID:       1234,
+				Host:      hostname,
+				Command:   "up",
+				StartedAt: time.Date(2017, time.June, 1, 12, 0, 0, 0, time.UTC),
+			}))
+		})
+
+		Context("when the lock is held by another process", func() {
+			BeforeEach(func() {
+				writeLockFile(storage.LockInfo{
+					PID:       4321,
+					Host:      "some-other-host",
+					Command:   "destroy",
+					StartedAt: time.Date(2017, time.June, 1, 11, 0, 0, 0, time.UTC),
+				})
+			})
+
+			It("returns an error describing the holder", func() {
+				err := lock.Acquire("up", 0)
+				Expect(err).To(MatchError("state directory is locked by `bbl destroy` (pid 4321 on some-other-host) since 2017-06-01T11:00:00Z. Wait for it to finish, retry with --lock-timeout, or run `bbl force-unlock` if the lock is stale."))
+			})
+
+			It("retries until the timeout elapses", func() {
+				currentTime := time.Date(2017, time.June, 1, 12, 0, 0, 0, time.UTC)
+				storage.SetNow(func() time.Time { return currentTime })
+
+				sleepCount := 0
+				storage.SetSleep(func(d time.Duration) {
+					sleepCount++
+					currentTime = currentTime.Add(d)
+				})
+
+				err := lock.Acquire("up", 2*time.Second)
+				Expect(err).To(BeAssignableToTypeOf(storage.LockedError{}))
+				Expect(sleepCount).To(Equal(4))
+			})
+
+			It("acquires the lock once it is released within the timeout", func() {
+				storage.SetSleep(func(time.Duration) {
+					Expect(os.Remove(lockPath)).To(Succeed())
+				})
+
+				err := lock.Acquire("up", time.Minute)
+				Expect(err).NotTo(HaveOccurred())
+
+				holder, err := lock.Holder()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(holder.Command).To(Equal("up"))
+			})
+		})
+
+		Context("failure cases", func() {
+			It("returns an error when the state dir does not exist", func() {
+				lock = storage.NewLock(filepath.Join(tempDir, "missing"))
+				err := lock.Acquire("up", 0)
+				Expect(err).To(MatchError(ContainSubstring("create lock file: ")))
+			})
+		})
+	})
+
+	Describe("Release", func() {
+		It("removes the lock file held by this process", func() {
+			err := lock.Acquire("up", 0)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = lock.Release()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lockPath).NotTo(BeAnExistingFile())
+		})
+
+		It("leaves a lock held by another process in place", func() {
+			writeLockFile(storage.LockInfo{PID: 4321, Host: "some-other-host", Command: "destroy"})
+
+			err := lock.Release()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lockPath).To(BeAnExistingFile())
+		})
+
+		It("does nothing when the state dir is not locked", func() {
+			err := lock.Release()
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("ForceUnlock", func() {
+		It("removes the lock file and returns its holder", func() {
+			writeLockFile(storage.LockInfo{PID: 4321, Host: "some-other-host", Command: "destroy"})
+
+			holder, err := lock.ForceUnlock()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(holder).To(Equal(storage.LockInfo{PID: 4321, Host: "some-other-host", Command: "destroy"}))
+			Expect(lockPath).NotTo(BeAnExistingFile())
+		})
+
+		It("removes a lock file that cannot be read", func() {
+			err := ioutil.WriteFile(lockPath, []byte("%%%"), os.ModePerm)
+			Expect(err).NotTo(HaveOccurred())
+
+			holder, err := lock.ForceUnlock()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(holder).To(Equal(storage.LockInfo{}))
+			Expect(lockPath).NotTo(BeAnExistingFile())
+		})
+
+		It("returns an error when the state dir is not locked", func() {
+			_, err := lock.ForceUnlock()
+			Expect(err).To(MatchError(tempDir + " is not locked"))
+		})
+	})
+})
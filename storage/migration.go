@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Migration upgrades a decoded state file from version From to From+1.
+type Migration struct {
+	From    int
+	Migrate func(state map[string]interface{}) error
+}
+
+// migrations must contain exactly one step for every version from 3 up to
+// STATE_VERSION-1. The steps up to version 10 predate this registry; fields
+// added in those versions decode to their zero values, so they leave the
+// state untouched apart from the version bump.
+var migrations = []Migration{
+	{From: 3, Migrate: noSchemaChange},
+	{From: 4, Migrate: noSchemaChange},
+	{From: 5, Migrate: noSchemaChange},
+	{From: 6, Migrate: noSchemaChange},
+	{From: 7, Migrate: noSchemaChange},
+	{From: 8, Migrate: noSchemaChange},
+	{From: 9, Migrate: noSchemaChange},
+	{From: 10, Migrate: migrateUserOpsFile},
+}
+
+func noSchemaChange(map[string]interface{}) error {
+	return nil
+}
+
+// migrateUserOpsFile moves the single bosh.userOpsFile into bosh.opsFiles.
+// The path it was read from was never stored.
+func migrateUserOpsFile(state map[string]interface{}) error {
+	bosh, ok := state["bosh"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	opsFile, _ := bosh["userOpsFile"].(string)
+	delete(bosh, "userOpsFile")
+
+	if opsFile != "" {
+		bosh["opsFiles"] = []interface{}{
+			map[string]interface{}{"path": "", "contents": opsFile},
+		}
+	}
+
+	return nil
+}
+
+func BackupFileName(version int) string {
+	return fmt.Sprintf("bbl-state.v%d.backup.json", version)
+}
+
+type Migrator struct {
+	backend    StateBackend
+	migrations []Migration
+}
+
+func NewMigrator(backend StateBackend) Migrator {
+	return Migrator{
+		backend:    backend,
+		migrations: migrations,
+	}
+}
+
+// Migrate upgrades state file contents to STATE_VERSION one step at a time.
+// The contents are backed up through the backend before every step, and
+// backups of an encrypted state file are encrypted as well.
+func (m Migrator) Migrate(contents []byte, encrypted bool) ([]byte, error) {
+	var state map[string]interface{}
+	err := json.Unmarshal(contents, &state)
+	if err != nil {
+		return nil, err
+	}
+
+	version, ok := state["version"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("state file has an invalid version: %v", state["version"])
+	}
+
+	for current := int(version); current < STATE_VERSION; current++ {
+		migration, ok := m.find(current)
+		if !ok {
+			return nil, fmt.Errorf("no state migration registered from version %d", current)
+		}
+
+		err = m.backup(current, contents, encrypted)
+		if err != nil {
+			return nil, fmt.Errorf("back up version %d state: %s", current, err)
+		}
+
+		err = migration.Migrate(state)
+		if err != nil {
+			return nil, fmt.Errorf("migrate state from version %d to %d: %s", current, current+1, err)
+		}
+		state["version"] = current + 1
+
+		contents, err = marshalIndent(state, "", "\t")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return contents, nil
+}
+
+func (m Migrator) find(version int) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.From == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+// backup keeps the first backup written for a version so that reloading a
+// state file that has not been saved since cannot overwrite the original.
+func (m Migrator) backup(version int, contents []byte, encrypted bool) error {
+	name := BackupFileName(version)
+
+	_, err := m.backend.Read(name)
+	switch {
+	case err == nil:
+		return nil
+	case err != StateFileNotFound:
+		return err
+	}
+
+	if encrypted {
+		contents, err = encryptWithStateKey(contents)
+		if err != nil {
+			return err
+		}
+	}
+
+	return m.backend.Write(name, contents)
+}
@@ -0,0 +1,161 @@
+package storage_test
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Migrator", func() {
+	var (
+		migrator storage.Migrator
+		tempDir  string
+		v3State  []byte
+		v11State []byte
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		v3State, err = ioutil.ReadFile("fixtures/migrations/v3-bbl-state.json")
+		Expect(err).NotTo(HaveOccurred())
+
+		v11State, err = ioutil.ReadFile("fixtures/migrations/v11-bbl-state.json")
+		Expect(err).NotTo(HaveOccurred())
+
+		migrator = storage.NewMigrator(storage.NewLocalBackend(tempDir))
+	})
+
+	AfterEach(func() {
+		storage.ResetMigrations()
+		os.Unsetenv("BBL_STATE_KEY")
+	})
+
+	readBackup := func(version int) string {
+		contents, err := ioutil.ReadFile(filepath.Join(tempDir, storage.BackupFileName(version)))
+		Expect(err).NotTo(HaveOccurred())
+		return string(contents)
+	}
+
+	It("migrates a v3 state file to the current version", func() {
+		contents, err := migrator.Migrate(v3State, false)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(contents).To(MatchJSON(v11State))
+	})
+
+	It("backs up the state before every step", func() {
+		_, err := migrator.Migrate(v3State, false)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(readBackup(3)).To(Equal(string(v3State)))
+		for version := 4; version < storage.STATE_VERSION; version++ {
+			Expect(readBackup(version)).To(MatchJSON(
+				strings.Replace(string(v11State), `"version": 11`, fmt.Sprintf(`"version": %d`, version), 1),
+			))
+		}
+		Expect(filepath.Join(tempDir, storage.BackupFileName(storage.STATE_VERSION))).NotTo(BeAnExistingFile())
+	})
+
+	It("does not overwrite an existing backup", func() {
+		err := ioutil.WriteFile(filepath.Join(tempDir, storage.BackupFileName(3)), []byte("some-original-backup"), os.ModePerm)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = migrator.Migrate(v3State, false)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(readBackup(3)).To(Equal("some-original-backup"))
+	})
+
+	It("encrypts the backups of an encrypted state file", func() {
+		os.Setenv("BBL_STATE_KEY", "some-passphrase")
+
+		_, err := migrator.Migrate(v3State, true)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(storage.IsEncrypted([]byte(readBackup(3)))).To(BeTrue())
+	})
+
+	It("runs the registered steps in order", func() {
+		storage.SetMigrations([]storage.Migration{
+			{From: 10, Migrate: func(state map[string]interface{}) error {
+				state["envID"] = state["envID"].(string) + "-migrated"
+				return nil
+			}},
+			{From: 9, Migrate: func(state map[string]interface{}) error {
+				state["envID"] = "from-9"
+				return nil
+			}},
+		})
+		migrator = storage.NewMigrator(storage.NewLocalBackend(tempDir))
+
+		contents, err := migrator.Migrate([]byte(`{"version": 9, "envID": "some-env-id"}`), false)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(contents).To(MatchJSON(`{"version": 11, "envID": "from-9-migrated"}`))
+	})
+
+	It("moves the user ops file of a v10 state file into the ops files", func() {
+		contents, err := migrator.Migrate([]byte(`{"version": 10, "bosh": {"directorName": "some-director", "userOpsFile": "some-ops-file"}}`), false)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(contents).To(MatchJSON(`{
+			"version": 11,
+			"bosh": {
+				"directorName": "some-director",
+				"opsFiles": [{"path": "", "contents": "some-ops-file"}]
+			}
+		}`))
+	})
+
+	It("drops an empty user ops file", func() {
+		contents, err := migrator.Migrate([]byte(`{"version": 10, "bosh": {"userOpsFile": ""}}`), false)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(contents).To(MatchJSON(`{"version": 11, "bosh": {}}`))
+	})
+
+	Context("failure cases", func() {
+		It("returns an error when a step is missing", func() {
+			storage.SetMigrations([]storage.Migration{})
+			migrator = storage.NewMigrator(storage.NewLocalBackend(tempDir))
+
+			_, err := migrator.Migrate(v3State, false)
+			Expect(err).To(MatchError("no state migration registered from version 3"))
+		})
+
+		It("returns an error when a step fails", func() {
+			storage.SetMigrations([]storage.Migration{
+				{From: 10, Migrate: func(map[string]interface{}) error {
+					return errors.New("failed to migrate")
+				}},
+			})
+			migrator = storage.NewMigrator(storage.NewLocalBackend(tempDir))
+
+			_, err := migrator.Migrate([]byte(`{"version": 10}`), false)
+			Expect(err).To(MatchError("migrate state from version 10 to 11: failed to migrate"))
+		})
+
+		It("returns an error when the backup cannot be written", func() {
+			migrator = storage.NewMigrator(storage.NewLocalBackend(filepath.Join(tempDir, "missing")))
+
+			_, err := migrator.Migrate(v3State, false)
+			Expect(err).To(MatchError(ContainSubstring("back up version 3 state: ")))
+		})
+
+		It("returns an error when the version is invalid", func() {
+			_, err := migrator.Migrate([]byte(`{"version": "three"}`), false)
+			Expect(err).To(MatchError("state file has an invalid version: three"))
+		})
+	})
+})
@@ -0,0 +1,255 @@
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+const (
+	DefaultNetworkCIDR = "10.0.0.0/16"
+
+	// MaxSubnets is the number of internal and load balancer subnets checked
+	// for overlaps, enough for the availability zones of any AWS region.
+	MaxSubnets = 6
+
+	directorSubnetIndex = 0
+	lbSubnetOffset      = 2
+
+	gatewayHost  = 1
+	jumpboxHost  = 5
+	directorHost = 6
+	natHost      = 7
+)
+
+// Network holds the address ranges chosen with --network-cidr and the
+// per-subnet overrides. Ranges that are not overridden are carved out of
+// CIDR: the director subnet is its first /24 (for a /16), the internal
+// subnets are the following /20s and the load balancer subnets are the /24s
+// after the director subnet.
+type Network struct {
+	CIDR                string   `json:"cidr,omitempty"`
+	DirectorSubnetCIDR  string   `json:"directorSubnetCIDR,omitempty"`
+	InternalSubnetCIDRs []string `json:"internalSubnetCIDRs,omitempty"`
+}
+
+// NetworkLayout is the resolved address plan of an environment.
+type NetworkLayout struct {
+	network Network
+	cidr    *net.IPNet
+}
+
+// NetworkLayout resolves the address plan of the environment, falling back
+// to the ranges bbl has always used when no network was configured.
+func (s State) NetworkLayout() (NetworkLayout, error) {
+	var network Network
+	if s.Network != nil {
+		network = *s.Network
+	}
+
+	return network.Layout()
+}
+
+// validateNetwork checks the network of a state that was not created by
+// bbl up, such as a hand edited or imported one, since bbl relies on the
+// network being valid once it is stored.
+func validateNetwork(state State) error {
+	if state.Network == nil {
+		return nil
+	}
+
+	_, err := state.NetworkLayout()
+	if err != nil {
+		return fmt.Errorf("invalid network in %s: %s", StateFileName, err)
+	}
+
+	return nil
+}
+
+// Layout resolves and validates the address plan of the network.
+func (n Network) Layout() (NetworkLayout, error) {
+	if n.CIDR == "" {
+		n.CIDR = DefaultNetworkCIDR
+	}
+
+	_, cidr, err := net.ParseCIDR(n.CIDR)
+	if err != nil || cidr.IP.To4() == nil {
+		return NetworkLayout{}, fmt.Errorf("network cidr %q is not a valid IPv4 CIDR", n.CIDR)
+	}
+
+	ones, _ := cidr.Mask.Size()
+	if ones > 20 {
+		return NetworkLayout{}, fmt.Errorf("network cidr %s is too small, it must be a /20 or larger", n.CIDR)
+	}
+
+	if n.DirectorSubnetCIDR != "" {
+		n.DirectorSubnetCIDR, err = normalizeCIDR("director subnet", n.DirectorSubnetCIDR)
+		if err != nil {
+			return NetworkLayout{}, err
+		}
+	}
+
+	var internalSubnetCIDRs []string
+	for i, internalSubnetCIDR := range n.InternalSubnetCIDRs {
+		internalSubnetCIDR, err = normalizeCIDR(fmt.Sprintf("internal subnet %d", i+1), internalSubnetCIDR)
+		if err != nil {
+			return NetworkLayout{}, err
+		}
+		internalSubnetCIDRs = append(internalSubnetCIDRs, internalSubnetCIDR)
+	}
+	n.InternalSubnetCIDRs = internalSubnetCIDRs
+
+	layout := NetworkLayout{network: n, cidr: cidr}
+
+	err = layout.validate()
+	if err != nil {
+		return NetworkLayout{}, err
+	}
+
+	return layout, nil
+}
+
+func (l NetworkLayout) CIDR() string {
+	return l.cidr.String()
+}
+
+func (l NetworkLayout) DirectorSubnetCIDR() string {
+	if l.network.DirectorSubnetCIDR != "" {
+		return l.network.DirectorSubnetCIDR
+	}
+
+	return subnet(l.cidr, 8, directorSubnetIndex).String()
+}
+
+// InternalSubnetCIDR returns the range of the internal subnet of the zone
+// with the given index.
+func (l NetworkLayout) InternalSubnetCIDR(index int) string {
+	if index < len(l.network.InternalSubnetCIDRs) {
+		return l.network.InternalSubnetCIDRs[index]
+	}
+
+	return subnet(l.cidr, 4, index+1).String()
+}
+
+func (l NetworkLayout) InternalSubnetCIDRs(count int) []string {
+	var cidrs []string
+	for i := 0; i < count; i++ {
+		cidrs = append(cidrs, l.InternalSubnetCIDR(i))
+	}
+
+	return cidrs
+}
+
+// LBSubnetCIDR returns the range of the load balancer subnet of the zone
+// with the given index.
+func (l NetworkLayout) LBSubnetCIDR(index int) string {
+	return subnet(l.cidr, 8, index+lbSubnetOffset).String()
+}
+
+func (l NetworkLayout) LBSubnetCIDRs(count int) []string {
+	var cidrs []string
+	for i := 0; i < count; i++ {
+		cidrs = append(cidrs, l.LBSubnetCIDR(i))
+	}
+
+	return cidrs
+}
+
+func (l NetworkLayout) DirectorSubnetGateway() string {
+	return l.directorSubnetHost(gatewayHost)
+}
+
+func (l NetworkLayout) DirectorIP() string {
+	return l.directorSubnetHost(directorHost)
+}
+
+func (l NetworkLayout) JumpboxIP() string {
+	return l.directorSubnetHost(jumpboxHost)
+}
+
+func (l NetworkLayout) NATIP() string {
+	return l.directorSubnetHost(natHost)
+}
+
+func (l NetworkLayout) directorSubnetHost(host uint32) string {
+	_, cidr, _ := net.ParseCIDR(l.DirectorSubnetCIDR())
+	return addToIP(cidr.IP, host).String()
+}
+
+type namedSubnet struct {
+	name string
+	cidr string
+}
+
+func (l NetworkLayout) validate() error {
+	subnets := []namedSubnet{{"director subnet", l.DirectorSubnetCIDR()}}
+	for i := 0; i < MaxSubnets; i++ {
+		subnets = append(subnets, namedSubnet{fmt.Sprintf("internal subnet %d", i+1), l.InternalSubnetCIDR(i)})
+	}
+	for i := 0; i < MaxSubnets; i++ {
+		subnets = append(subnets, namedSubnet{fmt.Sprintf("load balancer subnet %d", i+1), l.LBSubnetCIDR(i)})
+	}
+
+	var parsed []*net.IPNet
+	for _, s := range subnets {
+		_, cidr, _ := net.ParseCIDR(s.cidr)
+		if !contains(l.cidr, cidr) {
+			return fmt.Errorf("%s %s is not inside the network %s", s.name, s.cidr, l.cidr)
+		}
+
+		parsed = append(parsed, cidr)
+	}
+
+	for i := range parsed {
+		for j := i + 1; j < len(parsed); j++ {
+			if overlaps(parsed[i], parsed[j]) {
+				return fmt.Errorf("%s %s overlaps %s %s", subnets[i].name, subnets[i].cidr, subnets[j].name, subnets[j].cidr)
+			}
+		}
+	}
+
+	ones, _ := parsed[0].Mask.Size()
+	if ones > 28 {
+		return errors.New("director subnet is too small, it must be a /28 or larger")
+	}
+
+	return nil
+}
+
+func normalizeCIDR(name, value string) (string, error) {
+	_, cidr, err := net.ParseCIDR(value)
+	if err != nil || cidr.IP.To4() == nil {
+		return "", fmt.Errorf("%s cidr %q is not a valid IPv4 CIDR", name, value)
+	}
+
+	return cidr.String(), nil
+}
+
+// subnet is the equivalent of terraform's cidrsubnet function.
+func subnet(cidr *net.IPNet, newBits, index int) *net.IPNet {
+	ones, bits := cidr.Mask.Size()
+	offset := uint32(index) << uint(bits-ones-newBits)
+
+	return &net.IPNet{
+		IP:   addToIP(cidr.IP, offset),
+		Mask: net.CIDRMask(ones+newBits, bits),
+	}
+}
+
+func addToIP(ip net.IP, n uint32) net.IP {
+	result := make(net.IP, 4)
+	binary.BigEndian.PutUint32(result, binary.BigEndian.Uint32(ip.To4())+n)
+	return result
+}
+
+func contains(outer, inner *net.IPNet) bool {
+	outerOnes, _ := outer.Mask.Size()
+	innerOnes, _ := inner.Mask.Size()
+
+	return outerOnes <= innerOnes && outer.Contains(inner.IP)
+}
+
+func overlaps(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
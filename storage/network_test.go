@@ -0,0 +1,72 @@
+package storage_test
+
+import (
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NetworkLayout", func() {
+	It("uses the ranges bbl has always used when no network is configured", func() {
+		network, err := storage.State{}.NetworkLayout()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(network.CIDR()).To(Equal("10.0.0.0/16"))
+		Expect(network.DirectorSubnetCIDR()).To(Equal("10.0.0.0/24"))
+		Expect(network.DirectorSubnetGateway()).To(Equal("10.0.0.1"))
+		Expect(network.JumpboxIP()).To(Equal("10.0.0.5"))
+		Expect(network.DirectorIP()).To(Equal("10.0.0.6"))
+		Expect(network.NATIP()).To(Equal("10.0.0.7"))
+		Expect(network.InternalSubnetCIDRs(3)).To(Equal([]string{"10.0.16.0/20", "10.0.32.0/20", "10.0.48.0/20"}))
+		Expect(network.LBSubnetCIDRs(3)).To(Equal([]string{"10.0.2.0/24", "10.0.3.0/24", "10.0.4.0/24"}))
+	})
+
+	It("carves the subnets out of the network cidr", func() {
+		network, err := storage.State{
+			Network: &storage.Network{CIDR: "192.168.16.0/20"},
+		}.NetworkLayout()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(network.DirectorSubnetCIDR()).To(Equal("192.168.16.0/28"))
+		Expect(network.DirectorIP()).To(Equal("192.168.16.6"))
+		Expect(network.InternalSubnetCIDRs(2)).To(Equal([]string{"192.168.17.0/24", "192.168.18.0/24"}))
+		Expect(network.LBSubnetCIDRs(2)).To(Equal([]string{"192.168.16.32/28", "192.168.16.48/28"}))
+	})
+
+	It("uses the subnet overrides", func() {
+		network, err := storage.Network{
+			CIDR:                "172.16.0.0/16",
+			DirectorSubnetCIDR:  "172.16.200.10/24",
+			InternalSubnetCIDRs: []string{"172.16.128.0/20", "172.16.144.0/20"},
+		}.Layout()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(network.DirectorSubnetCIDR()).To(Equal("172.16.200.0/24"))
+		Expect(network.DirectorSubnetGateway()).To(Equal("172.16.200.1"))
+		Expect(network.DirectorIP()).To(Equal("172.16.200.6"))
+		Expect(network.InternalSubnetCIDRs(3)).To(Equal([]string{"172.16.128.0/20", "172.16.144.0/20", "172.16.48.0/20"}))
+	})
+
+	DescribeTable("validates the network",
+		func(network storage.Network, expectedError string) {
+			_, err := network.Layout()
+			Expect(err).To(MatchError(expectedError))
+		},
+		Entry("invalid network cidr", storage.Network{CIDR: "not-a-cidr"},
+			`network cidr "not-a-cidr" is not a valid IPv4 CIDR`),
+		Entry("network too small", storage.Network{CIDR: "10.0.0.0/24"},
+			"network cidr 10.0.0.0/24 is too small, it must be a /20 or larger"),
+		Entry("invalid subnet cidr", storage.Network{InternalSubnetCIDRs: []string{"10.0.16.0"}},
+			`internal subnet 1 cidr "10.0.16.0" is not a valid IPv4 CIDR`),
+		Entry("subnet outside of the network", storage.Network{DirectorSubnetCIDR: "10.1.0.0/24"},
+			"director subnet 10.1.0.0/24 is not inside the network 10.0.0.0/16"),
+		Entry("overlapping subnets", storage.Network{InternalSubnetCIDRs: []string{"10.0.32.0/20"}},
+			"internal subnet 1 10.0.32.0/20 overlaps internal subnet 2 10.0.32.0/20"),
+		Entry("director subnet overlapping a load balancer subnet", storage.Network{DirectorSubnetCIDR: "10.0.0.0/22"},
+			"director subnet 10.0.0.0/22 overlaps load balancer subnet 1 10.0.2.0/24"),
+		Entry("director subnet too small", storage.Network{DirectorSubnetCIDR: "10.0.0.0/29"},
+			"director subnet is too small, it must be a /28 or larger"),
+	)
+})
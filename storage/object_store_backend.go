@@ -0,0 +1,269 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+)
+
+const (
+	defaultObjectStoreRegion = "us-east-1"
+	gcsEndpoint              = "https://storage.googleapis.com"
+
+	gcsGenerationHeader      = "X-Goog-Generation"
+	gcsGenerationMatchHeader = "X-Goog-If-Generation-Match"
+)
+
+var StateConflict = errors.New("the remote state was modified by another bbl process since it was read, re-run the command to continue with the latest state")
+
+type ObjectStoreConfig struct {
+	Provider        string
+	Endpoint        string
+	Bucket          string
+	Prefix          string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+type httpClient interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// ObjectStoreBackend keeps state files in an S3 compatible object store
+// (AWS S3, GCS interoperability mode, MinIO, ...). Every write is
+// conditional on the object version observed by the last read or write, so
+// concurrent bbl processes fail instead of overwriting each other.
+type ObjectStoreBackend struct {
+	config   ObjectStoreConfig
+	client   httpClient
+	signer   *v4.Signer
+	versions *objectVersions
+}
+
+type objectVersions struct {
+	mutex    sync.Mutex
+	versions map[string]string
+}
+
+// absentObjectVersion records that an object was observed not to exist.
+const absentObjectVersion = ""
+
+func NewObjectStoreBackend(config ObjectStoreConfig) (ObjectStoreBackend, error) {
+	if config.Bucket == "" {
+		return ObjectStoreBackend{}, errors.New("--state-bucket must be provided when using a remote state backend")
+	}
+
+	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return ObjectStoreBackend{}, errors.New("--state-access-key-id and --state-secret-access-key must be provided when using a remote state backend")
+	}
+
+	if config.Region == "" {
+		config.Region = defaultObjectStoreRegion
+		if config.Provider == GCSBackendType {
+			config.Region = "auto"
+		}
+	}
+
+	if config.Endpoint == "" {
+		config.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", config.Region)
+		if config.Provider == GCSBackendType {
+			config.Endpoint = gcsEndpoint
+		}
+	}
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+
+	return ObjectStoreBackend{
+		config: config,
+		client: http.DefaultClient,
+		signer: v4.NewSigner(credentials.NewStaticCredentials(config.AccessKeyID, config.SecretAccessKey, ""), func(s *v4.Signer) {
+			s.DisableURIPathEscaping = true
+		}),
+		versions: &objectVersions{versions: map[string]string{}},
+	}, nil
+}
+
+// Read records the version of the object the first time it is read. Later
+// reads fail with StateConflict if the object has changed in the meantime.
+func (o ObjectStoreBackend) Read(name string) ([]byte, error) {
+	headers := http.Header{}
+	version, observed := o.versions.get(name)
+	if observed && version != absentObjectVersion {
+		o.setPrecondition(headers, version)
+	}
+
+	response, err := o.do("GET", name, nil, headers)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	contents, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		if observed && version == absentObjectVersion {
+			return nil, StateConflict
+		}
+	case http.StatusNotFound:
+		if observed && version != absentObjectVersion {
+			return nil, StateConflict
+		}
+		o.versions.set(name, absentObjectVersion)
+		return nil, StateFileNotFound
+	case http.StatusPreconditionFailed:
+		return nil, StateConflict
+	default:
+		return nil, o.responseError("read", name, response, contents)
+	}
+
+	o.versions.set(name, o.version(response))
+
+	return contents, nil
+}
+
+func (o ObjectStoreBackend) Write(name string, contents []byte) error {
+	headers := http.Header{}
+	if version, ok := o.versions.get(name); ok {
+		o.setPrecondition(headers, version)
+	}
+
+	response, err := o.do("PUT", name, contents, headers)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	body, _ := ioutil.ReadAll(response.Body)
+
+	switch response.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		return StateConflict
+	default:
+		return o.responseError("write", name, response, body)
+	}
+
+	o.versions.set(name, o.version(response))
+
+	return nil
+}
+
+func (o ObjectStoreBackend) Remove(name string) error {
+	headers := http.Header{}
+	if version, ok := o.versions.get(name); ok && version != absentObjectVersion {
+		o.setPrecondition(headers, version)
+	}
+
+	response, err := o.do("DELETE", name, nil, headers)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	body, _ := ioutil.ReadAll(response.Body)
+
+	switch response.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		return StateConflict
+	default:
+		return o.responseError("remove", name, response, body)
+	}
+
+	o.versions.set(name, absentObjectVersion)
+
+	return nil
+}
+
+func (o ObjectStoreBackend) Location() string {
+	return fmt.Sprintf("%s://%s/%s", o.config.Provider, o.config.Bucket, o.config.Prefix)
+}
+
+func (o ObjectStoreBackend) setPrecondition(headers http.Header, version string) {
+	if o.config.Provider == GCSBackendType {
+		if version == absentObjectVersion {
+			version = "0"
+		}
+		headers.Set(gcsGenerationMatchHeader, version)
+		return
+	}
+
+	if version == absentObjectVersion {
+		headers.Set("If-None-Match", "*")
+		return
+	}
+	headers.Set("If-Match", version)
+}
+
+func (o ObjectStoreBackend) version(response *http.Response) string {
+	if o.config.Provider == GCSBackendType {
+		if generation := response.Header.Get(gcsGenerationHeader); generation != "" {
+			return generation
+		}
+	}
+
+	return response.Header.Get("ETag")
+}
+
+func (o ObjectStoreBackend) do(method, name string, body []byte, headers http.Header) (*http.Response, error) {
+	objectURL := fmt.Sprintf("%s/%s/%s", o.config.Endpoint, o.config.Bucket, (&url.URL{Path: path.Join(o.config.Prefix, name)}).EscapedPath())
+
+	request, err := http.NewRequest(method, objectURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, values := range headers {
+		request.Header[key] = values
+	}
+
+	_, err = o.signer.Sign(request, bytes.NewReader(body), "s3", o.config.Region, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("sign request: %s", err)
+	}
+	request.ContentLength = int64(len(body))
+
+	response, err := o.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %s", method, o.describe(name), err)
+	}
+
+	return response, nil
+}
+
+func (o ObjectStoreBackend) describe(name string) string {
+	return fmt.Sprintf("%s://%s/%s", o.config.Provider, o.config.Bucket, path.Join(o.config.Prefix, name))
+}
+
+func (o ObjectStoreBackend) responseError(action, name string, response *http.Response, body []byte) error {
+	return fmt.Errorf("%s %s: unexpected status %s: %s", action, o.describe(name), response.Status, strings.TrimSpace(string(body)))
+}
+
+func (v *objectVersions) get(name string) (string, bool) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	version, ok := v.versions[name]
+	return version, ok
+}
+
+func (v *objectVersions) set(name, version string) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	v.versions[name] = version
+}
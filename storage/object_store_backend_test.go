@@ -0,0 +1,203 @@
+package storage_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeObjectStore struct {
+	mutex    sync.Mutex
+	objects  map[string]string
+	etags    map[string]int
+	requests []*http.Request
+}
+
+func (f *fakeObjectStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.requests = append(f.requests, r)
+	key := r.URL.Path
+	contents, exists := f.objects[key]
+	etag := fmt.Sprintf(`"%d"`, f.etags[key])
+
+	switch r.Method {
+	case "GET":
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && ifMatch != etag {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(contents))
+	case "PUT":
+		if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && (!exists || ifMatch != etag) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		if r.Header.Get("If-None-Match") == "*" && exists {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		f.objects[key] = string(body)
+		f.etags[key]++
+		w.Header().Set("ETag", fmt.Sprintf(`"%d"`, f.etags[key]))
+	case "DELETE":
+		if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && exists && ifMatch != etag {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+var _ = Describe("ObjectStoreBackend", func() {
+	var (
+		objectStore *fakeObjectStore
+		server      *httptest.Server
+		config      storage.ObjectStoreConfig
+	)
+
+	BeforeEach(func() {
+		objectStore = &fakeObjectStore{
+			objects: map[string]string{},
+			etags:   map[string]int{},
+		}
+		server = httptest.NewServer(objectStore)
+
+		config = storage.ObjectStoreConfig{
+			Provider:        "s3",
+			Endpoint:        server.URL,
+			Bucket:          "some-bucket",
+			Prefix:          "some-env",
+			AccessKeyID:     "some-access-key-id",
+			SecretAccessKey: "some-secret-access-key",
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("stores and loads state through the object store", func() {
+		backend, err := storage.NewObjectStoreBackend(config)
+		Expect(err).NotTo(HaveOccurred())
+
+		state, err := storage.LoadState(backend)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(state).To(Equal(storage.State{}))
+
+		err = storage.NewStoreWithBackend(backend, "some-command").Set(storage.State{IAAS: "gcp", ID: "some-id", EnvID: "some-env-id"})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(objectStore.objects).To(HaveKey("/some-bucket/some-env/bbl-state.json"))
+
+		lastRequest := objectStore.requests[len(objectStore.requests)-1]
+		Expect(lastRequest.Header.Get("If-None-Match")).To(Equal("*"))
+		Expect(lastRequest.Header.Get("Authorization")).To(ContainSubstring("Credential=some-access-key-id/"))
+
+		state, err = storage.LoadState(backend)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(state.EnvID).To(Equal("some-env-id"))
+	})
+
+	It("returns a conflict when another process wrote the state since it was read", func() {
+		objectStore.objects["/some-bucket/some-env/bbl-state.json"] = `{"version": 11, "iaas": "gcp"}`
+		objectStore.etags["/some-bucket/some-env/bbl-state.json"] = 1
+
+		firstBackend, err := storage.NewObjectStoreBackend(config)
+		Expect(err).NotTo(HaveOccurred())
+		secondBackend, err := storage.NewObjectStoreBackend(config)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = storage.LoadState(firstBackend)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = storage.LoadState(secondBackend)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = storage.NewStoreWithBackend(firstBackend, "some-command").Set(storage.State{IAAS: "gcp", ID: "first"})
+		Expect(err).NotTo(HaveOccurred())
+
+		err = storage.NewStoreWithBackend(secondBackend, "some-command").Set(storage.State{IAAS: "gcp", ID: "second"})
+		Expect(err).To(Equal(storage.StateConflict))
+
+		Expect(objectStore.objects["/some-bucket/some-env/bbl-state.json"]).To(ContainSubstring(`"id": "first"`))
+
+		_, err = storage.LoadState(secondBackend)
+		Expect(err).To(Equal(storage.StateConflict))
+	})
+
+	It("uses generation preconditions for gcs", func() {
+		config.Provider = "gcs"
+		backend, err := storage.NewObjectStoreBackend(config)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = storage.LoadState(backend)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = storage.NewStoreWithBackend(backend, "some-command").Set(storage.State{IAAS: "gcp", ID: "some-id"})
+		Expect(err).NotTo(HaveOccurred())
+
+		lastRequest := objectStore.requests[len(objectStore.requests)-1]
+		Expect(lastRequest.Header.Get("X-Goog-If-Generation-Match")).To(Equal("0"))
+	})
+
+	It("removes the object when the state is emptied", func() {
+		objectStore.objects["/some-bucket/some-env/bbl-state.json"] = `{"version": 11}`
+
+		backend, err := storage.NewObjectStoreBackend(config)
+		Expect(err).NotTo(HaveOccurred())
+
+		err = storage.NewStoreWithBackend(backend, "some-command").Set(storage.State{})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(objectStore.objects).NotTo(HaveKey("/some-bucket/some-env/bbl-state.json"))
+	})
+
+	Describe("Location", func() {
+		It("describes the bucket and prefix", func() {
+			backend, err := storage.NewObjectStoreBackend(config)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(backend.Location()).To(Equal("s3://some-bucket/some-env"))
+		})
+	})
+
+	Context("failure cases", func() {
+		It("returns an error when credentials are missing", func() {
+			config.SecretAccessKey = ""
+			_, err := storage.NewObjectStoreBackend(config)
+			Expect(err).To(MatchError("--state-access-key-id and --state-secret-access-key must be provided when using a remote state backend"))
+		})
+
+		It("returns an error on unexpected responses", func() {
+			server.Close()
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte("AccessDenied"))
+			}))
+			config.Endpoint = server.URL
+
+			backend, err := storage.NewObjectStoreBackend(config)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = storage.LoadState(backend)
+			Expect(err).To(MatchError(ContainSubstring("read s3://some-bucket/some-env/bbl-state.json: unexpected status 403 Forbidden: AccessDenied")))
+			Expect(strings.Count(err.Error(), "AccessDenied")).To(Equal(1))
+		})
+	})
+})
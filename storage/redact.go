@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const RedactedValue = "<redacted>"
+
+// secretFields are always redacted, including every value nested in them.
+var secretFields = map[string]struct{}{
+	"aws.secretAccessKey":        struct{}{},
+	"azure.clientSecret":         struct{}{},
+	"gcp.serviceAccountKey":      struct{}{},
+	"keyPair.privateKey":         struct{}{},
+	"bosh.directorPassword":      struct{}{},
+	"bosh.directorSSLPrivateKey": struct{}{},
+	"bosh.credentials":           struct{}{},
+	"bosh.vars":                  struct{}{},
+	"bosh.varsFiles":             struct{}{},
+	"lb.key":                     struct{}{},
+}
+
+// embeddedDocuments are state fields that hold a YAML or JSON document. They
+// are shown parsed and scrubbed value by value. A * matches any list index.
+var embeddedDocuments = map[string]func([]byte, interface{}) error{
+	"bosh.variables":                        yaml.Unmarshal,
+	"bosh.manifest":                         yaml.Unmarshal,
+	"bosh.opsFiles.*.contents":              yaml.Unmarshal,
+	"bosh.runtimeConfigOpsFiles.*.contents": yaml.Unmarshal,
+	"jumpbox.variables":                     yaml.Unmarshal,
+	"jumpbox.manifest":                      yaml.Unmarshal,
+	"jumpbox.opsFiles.*.contents":           yaml.Unmarshal,
+	"tfState":                               json.Unmarshal,
+}
+
+// documentFields are state fields that hold already parsed documents, which
+// are scrubbed like the embedded documents.
+var documentFields = map[string]struct{}{
+	"tfOutputs.values": struct{}{},
+	"tfBackend.config": struct{}{},
+}
+
+var secretKeyPattern = regexp.MustCompile(`(?i)password|passwd|secret|private|token|credential|key|cert`)
+
+var listIndexPattern = regexp.MustCompile(`\.[0-9]+(\.|$)`)
+
+// Redact returns the state as a tree of maps with known secret fields and
+// any value inside the vars stores, manifests and terraform state that looks
+// like a key, password or certificate replaced by RedactedValue. Paths are
+// dot separated JSON keys, e.g. bosh.variables.admin_password, and revealing
+// a path also reveals everything below it.
+func Redact(state State, reveal []string) (map[string]interface{}, error) {
+	fields, err := stateFields(state)
+	if err != nil {
+		return nil, err
+	}
+
+	r := redactor{
+		reveal:   reveal,
+		revealed: map[string]bool{},
+	}
+	redacted := r.redact("", fields, false, false).(map[string]interface{})
+
+	for _, path := range reveal {
+		if !r.revealed[path] {
+			return nil, fmt.Errorf("--reveal %s does not match a redacted field", path)
+		}
+	}
+
+	return redacted, nil
+}
+
+type redactor struct {
+	reveal   []string
+	revealed map[string]bool
+}
+
+func (r redactor) redact(path string, value interface{}, secret, inDocument bool) interface{} {
+	if parse, ok := embeddedDocuments[listIndexPattern.ReplaceAllString(path, ".*$1")]; ok {
+		if document, ok := value.(string); ok && document != "" {
+			var parsed interface{}
+			err := parse([]byte(document), &parsed)
+			if err != nil {
+				return r.redactLeaf(path, value, true)
+			}
+			return r.redact(path, normalize(parsed), secret, true)
+		}
+	}
+
+	if _, ok := documentFields[path]; ok {
+		inDocument = true
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		redacted := map[string]interface{}{}
+		secretOp := inDocument && isSecretOp(v)
+		for key, element := range v {
+			childPath := joinPath(path, key)
+			_, isSecretField := secretFields[childPath]
+			childSecret := secret || isSecretField || (inDocument && secretKeyPattern.MatchString(key)) || (secretOp && key == "value")
+			redacted[key] = r.redact(childPath, element, childSecret, inDocument)
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, element := range v {
+			redacted[i] = r.redact(joinPath(path, strconv.Itoa(i)), element, secret, inDocument)
+		}
+		return redacted
+	default:
+		looksSecret := inDocument && isPEM(value)
+		return r.redactLeaf(path, value, secret || looksSecret)
+	}
+}
+
+func (r redactor) redactLeaf(path string, value interface{}, secret bool) interface{} {
+	if !secret || value == nil || value == "" {
+		return value
+	}
+
+	for _, reveal := range r.reveal {
+		if path == reveal || strings.HasPrefix(path, reveal+".") {
+			r.revealed[reveal] = true
+			return value
+		}
+	}
+
+	return RedactedValue
+}
+
+// isSecretOp reports whether the map is an ops file operation that sets a
+// secret looking property, e.g. path: /instance_groups/0/properties/password.
+func isSecretOp(op map[string]interface{}) bool {
+	path, ok := op["path"].(string)
+	if !ok {
+		return false
+	}
+
+	segments := strings.Split(strings.TrimSuffix(path, "?"), "/")
+	return secretKeyPattern.MatchString(segments[len(segments)-1])
+}
+
+func isPEM(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && strings.Contains(s, "-----BEGIN ")
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// normalize converts the map[interface{}]interface{} values produced by the
+// YAML parser into map[string]interface{} so they can be rendered as JSON.
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		normalized := map[string]interface{}{}
+		for key, element := range v {
+			normalized[fmt.Sprintf("%v", key)] = normalize(element)
+		}
+		return normalized
+	case map[string]interface{}:
+		for key, element := range v {
+			v[key] = normalize(element)
+		}
+		return v
+	case []interface{}:
+		for i, element := range v {
+			v[i] = normalize(element)
+		}
+		return v
+	default:
+		return value
+	}
+}
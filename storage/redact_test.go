@@ -0,0 +1,235 @@
+package storage_test
+
+import (
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Redact", func() {
+	var state storage.State
+
+	BeforeEach(func() {
+		state = storage.State{
+			IAAS: "gcp",
+			GCP: storage.GCP{
+				ServiceAccountKey: "some-service-account-key",
+				ProjectID:         "some-project-id",
+			},
+			KeyPair: storage.KeyPair{
+				Name:       "some-keypair",
+				PrivateKey: "some-private-key",
+			},
+			BOSH: storage.BOSH{
+				DirectorUsername: "admin",
+				DirectorPassword: "some-password",
+				Credentials:      map[string]string{"some-credential": "some-value"},
+				Variables: `admin_password: some-admin-password
+blobstore_agent_username: blobstore-user
+director_ssl:
+  ca: |
+    -----BEGIN CERTIFICATE-----
+    some-ca
+    -----END CERTIFICATE-----
+  private_key: some-ssl-private-key
+`,
+			},
+			TFState: `{"modules": [{"resources": {"some-resource": {"primary": {"attributes": {"name": "some-name", "private_key_pem": "some-pem"}}}}}]}`,
+		}
+	})
+
+	It("redacts known secret fields", func() {
+		redacted, err := storage.Redact(state, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(redacted["gcp"]).To(HaveKeyWithValue("serviceAccountKey", "<redacted>"))
+		Expect(redacted["gcp"]).To(HaveKeyWithValue("projectID", "some-project-id"))
+		Expect(redacted["keyPair"]).To(HaveKeyWithValue("privateKey", "<redacted>"))
+		Expect(redacted["keyPair"]).To(HaveKeyWithValue("name", "some-keypair"))
+		Expect(redacted["bosh"]).To(HaveKeyWithValue("directorPassword", "<redacted>"))
+		Expect(redacted["bosh"]).To(HaveKeyWithValue("directorUsername", "admin"))
+		Expect(redacted["bosh"]).To(HaveKeyWithValue("credentials", map[string]interface{}{"some-credential": "<redacted>"}))
+	})
+
+	It("redacts vars store and terraform state values that look like secrets", func() {
+		redacted, err := storage.Redact(state, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(redacted["bosh"]).To(HaveKeyWithValue("variables", map[string]interface{}{
+			"admin_password":           "<redacted>",
+			"blobstore_agent_username": "blobstore-user",
+			"director_ssl": map[string]interface{}{
+				"ca":          "<redacted>",
+				"private_key": "<redacted>",
+			},
+		}))
+
+		attributes := redacted["tfState"].(map[string]interface{})["modules"].([]interface{})[0].(map[string]interface{})["resources"].(map[string]interface{})["some-resource"].(map[string]interface{})["primary"].(map[string]interface{})["attributes"]
+		Expect(attributes).To(Equal(map[string]interface{}{
+			"name":            "some-name",
+			"private_key_pem": "<redacted>",
+		}))
+	})
+
+	It("redacts cached terraform outputs that look like secrets", func() {
+		state.TFOutputs = &storage.TFOutputs{
+			TFStateSHA256: "some-sha",
+			Values: map[string]interface{}{
+				"external_ip":   "some-ip",
+				"client_secret": "some-client-secret",
+			},
+		}
+
+		redacted, err := storage.Redact(state, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(redacted["tfOutputs"]).To(Equal(map[string]interface{}{
+			"tfStateSHA256": "some-sha",
+			"values": map[string]interface{}{
+				"external_ip":   "some-ip",
+				"client_secret": "<redacted>",
+			},
+		}))
+	})
+
+	It("redacts terraform backend config values that look like secrets", func() {
+		state.TFBackend = &storage.TFBackend{
+			Type: "s3",
+			Config: map[string]string{
+				"bucket":     "some-bucket",
+				"access_key": "some-access-key",
+				"secret_key": "some-secret-key",
+				"sas_token":  "some-sas-token",
+			},
+		}
+
+		redacted, err := storage.Redact(state, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(redacted["tfBackend"]).To(Equal(map[string]interface{}{
+			"type": "s3",
+			"config": map[string]interface{}{
+				"bucket":     "some-bucket",
+				"access_key": "<redacted>",
+				"secret_key": "<redacted>",
+				"sas_token":  "<redacted>",
+			},
+		}))
+	})
+
+	It("redacts the vars passed to bbl up", func() {
+		state.BOSH.Vars = []string{"db_password=hunter2"}
+
+		redacted, err := storage.Redact(state, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(redacted["bosh"]).To(HaveKeyWithValue("vars", []interface{}{"<redacted>"}))
+	})
+
+	It("redacts the vars files passed to bbl up", func() {
+		state.BOSH.VarsFiles = []storage.UserFile{{Path: "vars.yml", Contents: "db_password: hunter2"}}
+
+		redacted, err := storage.Redact(state, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(redacted["bosh"]).To(HaveKeyWithValue("varsFiles", []interface{}{
+			map[string]interface{}{"path": "<redacted>", "contents": "<redacted>"},
+		}))
+	})
+
+	Describe("ops files", func() {
+		var (
+			opsFile         storage.UserFile
+			redactedOpsFile []interface{}
+		)
+
+		BeforeEach(func() {
+			opsFile = storage.UserFile{Path: "ops.yml", Contents: `- type: replace
+  path: /instance_groups/name=bosh/properties/director/db_password
+  value: hunter2
+- type: replace
+  path: /instance_groups/name=bosh/properties/director/name
+  value: some-name
+- type: replace
+  path: /instance_groups/name=bosh/properties/blobstore
+  value:
+    secret_access_key: some-secret
+`}
+
+			redactedOpsFile = []interface{}{
+				map[string]interface{}{
+					"path": "ops.yml",
+					"contents": []interface{}{
+						map[string]interface{}{
+							"type":  "replace",
+							"path":  "/instance_groups/name=bosh/properties/director/db_password",
+							"value": "<redacted>",
+						},
+						map[string]interface{}{
+							"type":  "replace",
+							"path":  "/instance_groups/name=bosh/properties/director/name",
+							"value": "some-name",
+						},
+						map[string]interface{}{
+							"type":  "replace",
+							"path":  "/instance_groups/name=bosh/properties/blobstore",
+							"value": map[string]interface{}{"secret_access_key": "<redacted>"},
+						},
+					},
+				},
+			}
+		})
+
+		It("redacts the director ops files", func() {
+			state.BOSH.OpsFiles = []storage.UserFile{opsFile}
+
+			redacted, err := storage.Redact(state, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(redacted["bosh"]).To(HaveKeyWithValue("opsFiles", redactedOpsFile))
+		})
+
+		It("redacts the jumpbox ops files", func() {
+			state.Jumpbox.OpsFiles = []storage.UserFile{opsFile}
+
+			redacted, err := storage.Redact(state, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(redacted["jumpbox"]).To(HaveKeyWithValue("opsFiles", redactedOpsFile))
+		})
+
+		It("redacts the runtime config ops files", func() {
+			state.BOSH.RuntimeConfigOpsFiles = []storage.UserFile{opsFile}
+
+			redacted, err := storage.Redact(state, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(redacted["bosh"]).To(HaveKeyWithValue("runtimeConfigOpsFiles", redactedOpsFile))
+		})
+	})
+
+	It("reveals the requested fields", func() {
+		redacted, err := storage.Redact(state, []string{"bosh.directorPassword", "bosh.variables.director_ssl"})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(redacted["bosh"]).To(HaveKeyWithValue("directorPassword", "some-password"))
+
+		variables := redacted["bosh"].(map[string]interface{})["variables"].(map[string]interface{})
+		Expect(variables["admin_password"]).To(Equal("<redacted>"))
+		Expect(variables["director_ssl"]).To(HaveKeyWithValue("private_key", "some-ssl-private-key"))
+	})
+
+	It("returns an error when a revealed path does not match a redacted field", func() {
+		_, err := storage.Redact(state, []string{"bosh.directorUsername"})
+		Expect(err).To(MatchError("--reveal bosh.directorUsername does not match a redacted field"))
+	})
+
+	It("redacts embedded documents that cannot be parsed", func() {
+		state.TFState = "%%%"
+
+		redacted, err := storage.Redact(state, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(redacted["tfState"]).To(Equal("<redacted>"))
+	})
+})
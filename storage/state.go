@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -18,7 +17,7 @@ var (
 )
 
 const (
-	STATE_VERSION = 10
+	STATE_VERSION = 11
 
 	OS_READ_WRITE_MODE = os.FileMode(0644)
 	StateFileName      = "bbl-state.json"
@@ -32,6 +31,7 @@ type AWS struct {
 	AccessKeyID     string `json:"accessKeyId,omitempty"`
 	SecretAccessKey string `json:"secretAccessKey,omitempty"`
 	Region          string `json:"region"`
+	ExistingVPCID   string `json:"existingVPCID,omitempty"`
 }
 
 type Azure struct {
@@ -40,6 +40,9 @@ type Azure struct {
 	Location       string `json:"location"`
 	SubscriptionID string `json:"subscriptionId"`
 	TenantID       string `json:"tenantId"`
+
+	ExistingVNet              string `json:"existingVNet,omitempty"`
+	ExistingVNetResourceGroup string `json:"existingVNetResourceGroup,omitempty"`
 }
 
 type GCP struct {
@@ -48,6 +51,7 @@ type GCP struct {
 	Zone              string   `json:"zone"`
 	Region            string   `json:"region"`
 	Zones             []string `json:"zones"`
+	ExistingNetwork   string   `json:"existingNetwork,omitempty"`
 }
 
 type Stack struct {
@@ -66,49 +70,87 @@ type LB struct {
 }
 
 type State struct {
-	Version                    int     `json:"version"`
-	IAAS                       string  `json:"iaas"`
-	ID                         string  `json:"id"`
-	NoDirector                 bool    `json:"noDirector"`
-	MigratedFromCloudFormation bool    `json:"migratedFromCloudFormation"`
-	AWS                        AWS     `json:"aws,omitempty"`
-	Azure                      Azure   `json:"azure,omitempty"`
-	GCP                        GCP     `json:"gcp,omitempty"`
-	KeyPair                    KeyPair `json:"keyPair,omitempty"`
-	Jumpbox                    Jumpbox `json:"jumpbox,omitempty"`
-	BOSH                       BOSH    `json:"bosh,omitempty"`
-	Stack                      Stack   `json:"stack"`
-	EnvID                      string  `json:"envID"`
-	TFState                    string  `json:"tfState"`
-	LB                         LB      `json:"lb"`
-	LatestTFOutput             string  `json:"latestTFOutput"`
+	Version                    int        `json:"version"`
+	IAAS                       string     `json:"iaas"`
+	ID                         string     `json:"id"`
+	NoDirector                 bool       `json:"noDirector"`
+	MigratedFromCloudFormation bool       `json:"migratedFromCloudFormation"`
+	AWS                        AWS        `json:"aws,omitempty"`
+	Azure                      Azure      `json:"azure,omitempty"`
+	GCP                        GCP        `json:"gcp,omitempty"`
+	KeyPair                    KeyPair    `json:"keyPair,omitempty"`
+	Jumpbox                    Jumpbox    `json:"jumpbox,omitempty"`
+	BOSH                       BOSH       `json:"bosh,omitempty"`
+	Stack                      Stack      `json:"stack"`
+	EnvID                      string     `json:"envID"`
+	TFState                    string     `json:"tfState"`
+	TFBackend                  *TFBackend `json:"tfBackend,omitempty"`
+	TFOutputs                  *TFOutputs `json:"tfOutputs,omitempty"`
+	Network                    *Network   `json:"network,omitempty"`
+	Components                 Components `json:"components,omitempty"`
+	LB                         LB         `json:"lb"`
+	LatestTFOutput             string     `json:"latestTFOutput"`
 }
 
 type Store struct {
-	version   int
-	stateFile string
+	version  int
+	backend  StateBackend
+	history  History
+	command  string
+	previous *previousState
+}
+
+// previousState is the state a store last loaded or wrote and the format of
+// its file. Set summarizes its changes against it and keeps the format
+// without reading and decrypting bbl-state.json again.
+type previousState struct {
+	state *State
+	file  *stateFile
 }
 
 func NewStore(dir string) Store {
+	return NewStoreWithBackend(NewLocalBackend(dir), "")
+}
+
+// NewStoreWithBackend returns a store that records every state it writes in
+// the state history, attributed to the given bbl command.
+func NewStoreWithBackend(backend StateBackend, command string) Store {
 	return Store{
-		version:   STATE_VERSION,
-		stateFile: filepath.Join(dir, StateFileName),
+		version:  STATE_VERSION,
+		backend:  backend,
+		history:  NewHistory(backend, HISTORY_SIZE),
+		command:  command,
+		previous: &previousState{},
 	}
 }
 
-func (s Store) Set(state State) error {
-	_, err := os.Stat(filepath.Dir(s.stateFile))
-	if err != nil {
-		return err
-	}
+// Loaded records the state bbl loaded from the backend, which the next Set
+// summarizes its changes against.
+func (s Store) Loaded(state State) {
+	s.previous.state = &state
+}
 
+func (s Store) Set(state State) error {
 	if reflect.DeepEqual(state, State{}) {
-		err := os.Remove(s.stateFile)
-		if err != nil && !os.IsNotExist(err) {
+		file, err := s.current()
+		if err != nil {
 			return err
 		}
 
-		return nil
+		if file.split {
+			err = removeSplit(s.backend)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = s.backend.Remove(StateFileName)
+		if err != nil {
+			return err
+		}
+
+		s.forget()
+		return s.history.Remove()
 	}
 
 	state.Version = s.version
@@ -128,18 +170,294 @@ func (s Store) Set(state State) error {
 		state.GCP.ProjectID = ""
 	}
 
-	jsonData, err := marshalIndent(state, "", "\t")
+	file, err := s.previousFile()
 	if err != nil {
 		return err
 	}
-	err = ioutil.WriteFile(s.stateFile, jsonData, os.FileMode(0644))
+
+	summary := "created"
+	if file.exists {
+		summary = "unknown changes"
+		if s.previous.state != nil {
+			summary = summarizeChanges(*s.previous.state, state)
+		}
+	}
+
+	snapshot, err := s.write(state, file)
 	if err != nil {
 		return err
 	}
 
+	file.exists = true
+	s.previous.state = &state
+	s.previous.file = &file
+
+	err = s.history.Record(s.command, snapshot, summary)
+	if err != nil {
+		return fmt.Errorf("record state history: %s", err)
+	}
+
 	return nil
 }
 
+// History lists the recorded states, oldest first.
+func (s Store) History() ([]HistoryEntry, error) {
+	return s.history.Entries()
+}
+
+// Rollback restores bbl-state.json from the state history. The restored state
+// is written with the encryption and layout of the current bbl-state.json.
+func (s Store) Rollback(id int) error {
+	snapshot, err := s.history.Read(id)
+	if err != nil {
+		return err
+	}
+
+	contents, err := decodeStateFile(snapshot)
+	if err != nil {
+		return err
+	}
+
+	var state State
+	err = json.Unmarshal(contents, &state)
+	if err != nil {
+		return err
+	}
+
+	file, err := s.current()
+	if err != nil {
+		return err
+	}
+
+	snapshot, err = s.write(state, file)
+	if err != nil {
+		return err
+	}
+	s.forget()
+
+	err = s.history.Record("state rollback", snapshot, fmt.Sprintf("restored %d", id))
+	if err != nil {
+		return fmt.Errorf("record state history: %s", err)
+	}
+
+	return nil
+}
+
+// Encrypt rewrites an existing plaintext bbl-state.json using the key
+// configured through BBL_STATE_KEY.
+func (s Store) Encrypt() error {
+	contents, err := s.backend.Read(StateFileName)
+	if err != nil {
+		return err
+	}
+
+	if IsEncrypted(contents) {
+		return errors.New("bbl-state.json is already encrypted")
+	}
+
+	encrypted, err := encryptWithStateKey(contents)
+	if err != nil {
+		return err
+	}
+
+	encryptPlaintext := func(contents []byte) ([]byte, error) {
+		if IsEncrypted(contents) {
+			return contents, nil
+		}
+		return encryptWithStateKey(contents)
+	}
+
+	if isSplitLayout(contents) {
+		err = transformSplit(s.backend, encryptPlaintext)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = s.backend.Write(StateFileName, encrypted)
+	if err != nil {
+		return err
+	}
+	s.forget()
+
+	return s.history.rewrite(encryptPlaintext)
+}
+
+// Decrypt rewrites an encrypted bbl-state.json as plaintext.
+func (s Store) Decrypt() error {
+	contents, err := s.backend.Read(StateFileName)
+	if err != nil {
+		return err
+	}
+
+	if !IsEncrypted(contents) {
+		return errors.New("bbl-state.json is not encrypted")
+	}
+
+	plaintext, err := decodeStateFile(contents)
+	if err != nil {
+		return err
+	}
+
+	if isSplitLayout(plaintext) {
+		err = transformSplit(s.backend, decodeStateFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = s.backend.Write(StateFileName, plaintext)
+	if err != nil {
+		return err
+	}
+	s.forget()
+
+	return s.history.rewrite(decodeStateFile)
+}
+
+// Split moves the terraform state, create-env state, manifests and vars
+// stores out of bbl-state.json into files of their own.
+func (s Store) Split() error {
+	file, err := s.current()
+	if err != nil {
+		return err
+	}
+
+	if file.split {
+		return errors.New("bbl-state.json already uses the split layout")
+	}
+
+	state, err := LoadState(s.backend)
+	if err != nil {
+		return err
+	}
+
+	file.split = true
+	_, err = s.write(state, file)
+	s.forget()
+	return err
+}
+
+// Join moves the files of the split layout back into bbl-state.json.
+func (s Store) Join() error {
+	file, err := s.current()
+	if err != nil {
+		return err
+	}
+
+	if !file.split {
+		return errors.New("bbl-state.json does not use the split layout")
+	}
+
+	state, err := LoadState(s.backend)
+	if err != nil {
+		return err
+	}
+
+	file.split = false
+	_, err = s.write(state, file)
+	s.forget()
+	if err != nil {
+		return err
+	}
+
+	return removeSplit(s.backend)
+}
+
+// stateFile describes the format of the bbl-state.json in the backend.
+type stateFile struct {
+	exists    bool
+	encrypted bool
+	split     bool
+}
+
+// previousFile is the format of the state file the store last wrote, or of
+// the one in the backend before its first write.
+func (s Store) previousFile() (stateFile, error) {
+	if s.previous.file != nil {
+		return *s.previous.file, nil
+	}
+
+	return s.current()
+}
+
+// forget drops the previous state once the state file was changed outside
+// of Set.
+func (s Store) forget() {
+	s.previous.state = nil
+	s.previous.file = nil
+}
+
+// current keeps the format of an existing state file. New state files are
+// encrypted whenever a state key is configured.
+func (s Store) current() (stateFile, error) {
+	contents, err := s.backend.Read(StateFileName)
+	switch {
+	case err == StateFileNotFound:
+		encrypt, err := keyConfigured()
+		return stateFile{encrypted: encrypt}, err
+	case err != nil:
+		return stateFile{}, err
+	}
+
+	file := stateFile{
+		exists:    true,
+		encrypted: IsEncrypted(contents),
+	}
+
+	if file.encrypted {
+		contents, err = decodeStateFile(contents)
+		if err != nil {
+			return stateFile{}, err
+		}
+	}
+	file.split = isSplitLayout(contents)
+
+	return file, nil
+}
+
+// write stores the state in the given format and returns it as a single
+// state file for the state history.
+func (s Store) write(state State, file stateFile) ([]byte, error) {
+	contents, err := marshalIndent(state, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+
+	if file.split {
+		err = writeSplit(s.backend, state, file.encrypted)
+	} else {
+		err = writeStateFile(s.backend, StateFileName, contents, file.encrypted)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if file.encrypted {
+		return encryptWithStateKey(contents)
+	}
+
+	return contents, nil
+}
+
+func encryptWithStateKey(contents []byte) ([]byte, error) {
+	key, err := stateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	if key == nil {
+		return nil, fmt.Errorf("encrypting bbl-state.json requires %s to be set to a passphrase or key file", StateKeyEnvVar)
+	}
+
+	encrypted, err := encryptState(contents, key)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt state: %s", err)
+	}
+
+	return encrypted, nil
+}
+
 func (g GCP) Empty() bool {
 	return g.ServiceAccountKey == "" && g.ProjectID == "" && g.Region == "" && g.Zone == ""
 }
@@ -147,22 +465,27 @@ func (g GCP) Empty() bool {
 var GetStateLogger logger
 
 func GetState(dir string) (State, error) {
+	return LoadState(NewLocalBackend(dir))
+}
+
+func LoadState(backend StateBackend) (State, error) {
 	state := State{}
 
-	_, err := os.Stat(dir)
+	contents, err := backend.Read(StateFileName)
 	if err != nil {
+		if err == StateFileNotFound {
+			return state, nil
+		}
 		return state, err
 	}
 
-	file, err := os.Open(filepath.Join(dir, StateFileName))
+	encrypted := IsEncrypted(contents)
+	contents, err = decodeStateFile(contents)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return state, nil
-		}
 		return state, err
 	}
 
-	err = json.NewDecoder(file).Decode(&state)
+	err = json.Unmarshal(contents, &state)
 	if err != nil {
 		return state, err
 	}
@@ -182,6 +505,31 @@ func GetState(dir string) (State, error) {
 		return state, fmt.Errorf("Existing bbl environment was created with a newer version of bbl. Please upgrade to a version of bbl compatible with schema version %d.\n", state.Version)
 	}
 
+	if state.Version < STATE_VERSION {
+		contents, err = NewMigrator(backend).Migrate(contents, encrypted)
+		if err != nil {
+			return State{}, err
+		}
+
+		state = State{}
+		err = json.Unmarshal(contents, &state)
+		if err != nil {
+			return state, err
+		}
+	}
+
+	if isSplitLayout(contents) {
+		state, err = joinSplit(backend, state)
+		if err != nil {
+			return state, err
+		}
+	}
+
+	err = validateNetwork(state)
+	if err != nil {
+		return state, err
+	}
+
 	return state, nil
 }
 
@@ -96,9 +96,11 @@ var _ = Describe("Store", func() {
 						State: map[string]interface{}{
 							"key": "value",
 						},
-						Variables:   "some-vars",
-						Manifest:    "name: bosh",
-						UserOpsFile: "some-ops-file",
+						Variables: "some-vars",
+						Manifest:  "name: bosh",
+						OpsFiles:  []storage.UserFile{{Path: "some-ops-file-path", Contents: "some-ops-file"}},
+						VarsFiles: []storage.UserFile{{Path: "some-vars-file-path", Contents: "some-vars-file"}},
+						Vars:      []string{"some-var=some-value"},
 						Credentials: map[string]string{
 							"mbusUsername":              "some-mbus-username",
 							"natsUsername":              "some-nats-username",
@@ -130,7 +132,7 @@ var _ = Describe("Store", func() {
 				data, err := ioutil.ReadFile(filepath.Join(tempDir, "bbl-state.json"))
 				Expect(err).NotTo(HaveOccurred())
 				Expect(data).To(MatchJSON(`{
-				"version": 10,
+				"version": 11,
 				"iaas": "aws",
 				"noDirector": false,
 				"migratedFromCloudFormation": false,
@@ -197,7 +199,9 @@ var _ = Describe("Store", func() {
 					},
 					"variables":   "some-vars",
 					"manifest": "name: bosh",
-					"userOpsFile": "some-ops-file",
+					"opsFiles": [{"path": "some-ops-file-path", "contents": "some-ops-file"}],
+					"varsFiles": [{"path": "some-vars-file-path", "contents": "some-vars-file"}],
+					"vars": ["some-var=some-value"],
 					"state": {
 						"key": "value"
 					}
@@ -270,9 +274,11 @@ var _ = Describe("Store", func() {
 						State: map[string]interface{}{
 							"key": "value",
 						},
-						Variables:   "some-vars",
-						Manifest:    "name: bosh",
-						UserOpsFile: "some-ops-file",
+						Variables: "some-vars",
+						Manifest:  "name: bosh",
+						OpsFiles:  []storage.UserFile{{Path: "some-ops-file-path", Contents: "some-ops-file"}},
+						VarsFiles: []storage.UserFile{{Path: "some-vars-file-path", Contents: "some-vars-file"}},
+						Vars:      []string{"some-var=some-value"},
 						Credentials: map[string]string{
 							"mbusUsername":              "some-mbus-username",
 							"natsUsername":              "some-nats-username",
@@ -304,7 +310,7 @@ var _ = Describe("Store", func() {
 				data, err := ioutil.ReadFile(filepath.Join(tempDir, "bbl-state.json"))
 				Expect(err).NotTo(HaveOccurred())
 				Expect(data).To(MatchJSON(`{
-					"version": 10,
+					"version": 11,
 					"iaas": "aws",
 					"id": "some-id",
 					"noDirector": false,
@@ -373,7 +379,9 @@ var _ = Describe("Store", func() {
 						},
 						"variables":   "some-vars",
 						"manifest": "name: bosh",
-						"userOpsFile": "some-ops-file",
+						"opsFiles": [{"path": "some-ops-file-path", "contents": "some-ops-file"}],
+					"varsFiles": [{"path": "some-vars-file-path", "contents": "some-vars-file"}],
+					"vars": ["some-var=some-value"],
 						"state": {
 							"key": "value"
 						}
@@ -507,7 +515,7 @@ var _ = Describe("Store", func() {
 				state, err := storage.GetState(tempDir)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(state).To(Equal(storage.State{
-					Version: 10,
+					Version: 11,
 				}))
 			})
 		})
@@ -526,10 +534,10 @@ var _ = Describe("Store", func() {
 			})
 		})
 
-		Context("when there is a v10 state file", func() {
+		Context("when there is a v11 state file", func() {
 			BeforeEach(func() {
 				err := ioutil.WriteFile(filepath.Join(tempDir, "bbl-state.json"), []byte(`{
-					"version": 10,
+					"version": 11,
 					"iaas": "aws",
 					"aws": {
 						"accessKeyId": "some-aws-access-key-id",
@@ -557,7 +565,7 @@ var _ = Describe("Store", func() {
 				Expect(err).NotTo(HaveOccurred())
 
 				Expect(state).To(Equal(storage.State{
-					Version: 10,
+					Version: 11,
 					IAAS:    "aws",
 					AWS: storage.AWS{
 						AccessKeyID:     "some-aws-access-key-id",
@@ -580,6 +588,29 @@ var _ = Describe("Store", func() {
 			})
 		})
 
+		Context("when there is a state file with an older version", func() {
+			BeforeEach(func() {
+				err := ioutil.WriteFile(filepath.Join(tempDir, "bbl-state.json"), []byte(`{
+					"version": 7,
+					"iaas": "aws",
+					"envID": "some-env-id"
+				}`), os.ModePerm)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("migrates the state to the current version", func() {
+				state, err := storage.GetState(tempDir)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(state).To(Equal(storage.State{
+					Version: 11,
+					IAAS:    "aws",
+					EnvID:   "some-env-id",
+				}))
+				Expect(filepath.Join(tempDir, "bbl-state.v7.backup.json")).To(BeAnExistingFile())
+			})
+		})
+
 		Context("when there is a state file with a newer version than internal version", func() {
 			BeforeEach(func() {
 				err := ioutil.WriteFile(filepath.Join(tempDir, "bbl-state.json"), []byte(`{
@@ -594,6 +625,21 @@ var _ = Describe("Store", func() {
 			})
 		})
 
+		Context("when the state file has an invalid network", func() {
+			BeforeEach(func() {
+				err := ioutil.WriteFile(filepath.Join(tempDir, "bbl-state.json"), []byte(`{
+					"version": 11,
+					"network": {"cidr": "10.0.0.0/16", "directorSubnetCIDR": "192.168.0.0/24"}
+				}`), os.ModePerm)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("returns an error", func() {
+				_, err := storage.GetState(tempDir)
+				Expect(err).To(MatchError("invalid network in bbl-state.json: director subnet 192.168.0.0/24 is not inside the network 10.0.0.0/16"))
+			})
+		})
+
 		Context("when the bbl-state.json file doesn't exist", func() {
 			It("returns an empty state object", func() {
 				state, err := storage.GetState(tempDir)
@@ -0,0 +1,15 @@
+package storage
+
+// TFBackend is the terraform backend that holds the terraform state of an
+// environment instead of TFState. Credentials are not stored, they are taken
+// from the IAAS configuration each time terraform runs.
+type TFBackend struct {
+	Type   string            `json:"type"`
+	Config map[string]string `json:"config,omitempty"`
+}
+
+// HasTerraformState reports whether the environment has terraform state,
+// either embedded in TFState or kept in a terraform backend.
+func (s State) HasTerraformState() bool {
+	return s.TFState != "" || s.TFBackend != nil
+}
@@ -0,0 +1,9 @@
+package storage
+
+// TFOutputs are the terraform outputs of an environment as of the terraform
+// state with the sha256 TFStateSHA256, so they can be read without running
+// terraform.
+type TFOutputs struct {
+	TFStateSHA256 string                 `json:"tfStateSHA256"`
+	Values        map[string]interface{} `json:"values"`
+}
@@ -1,7 +1,7 @@
 package aws
 
 const BaseTemplate = `resource "aws_eip" "bosh_eip" {
-  depends_on = ["aws_internet_gateway.ig"]
+  depends_on = ["{{.InternetGateway}}"]
   vpc      = true
 }
 
@@ -135,7 +135,7 @@ variable "nat_ami_map" {
 
 resource "aws_security_group" "nat_security_group" {
   description = "{{.NATDescription}}"
-  vpc_id      = "${aws_vpc.vpc.id}"
+  vpc_id      = "{{.VPCID}}"
 
   ingress {
     protocol    = "tcp"
@@ -171,7 +171,7 @@ resource "aws_security_group" "nat_security_group" {
 }
 
 resource "aws_instance" "nat" {
-  private_ip             = "10.0.0.7"
+  private_ip             = "${cidrhost(var.bosh_subnet_cidr, 7)}"
   instance_type          = "t2.medium"
   subnet_id              = "${aws_subnet.bosh_subnet.id}"
   source_dest_check      = false
@@ -185,7 +185,7 @@ resource "aws_instance" "nat" {
 }
 
 resource "aws_eip" "nat_eip" {
-  depends_on = ["aws_internet_gateway.ig"]
+  depends_on = ["{{.InternetGateway}}"]
   instance = "${aws_instance.nat.id}"
   vpc      = true
 }
@@ -213,12 +213,12 @@ provider "aws" {
 }
 
 resource "aws_default_security_group" "default_security_group" {
-	vpc_id = "${aws_vpc.vpc.id}"
+	vpc_id = "{{.VPCID}}"
 }
 
 resource "aws_security_group" "internal_security_group" {
   description = "{{.InternalDescription}}"
-  vpc_id      = "${aws_vpc.vpc.id}"
+  vpc_id      = "{{.VPCID}}"
 
   tags {
     Name = "${var.env_id}-internal-security-group"
@@ -271,7 +271,7 @@ variable "bosh_inbound_cidr" {
 
 resource "aws_security_group" "bosh_security_group" {
   description = "{{.BOSHDescription}}"
-  vpc_id      = "${aws_vpc.vpc.id}"
+  vpc_id      = "{{.VPCID}}"
 
   tags {
     Name = "${var.env_id}-bosh-security-group"
@@ -347,7 +347,7 @@ resource "aws_security_group_rule" "bosh_security_group_rule_allow_internet" {
 
 resource "aws_security_group" "jumpbox" {
   description = "automatically created jumpbox by BBL"
-  vpc_id      = "${aws_vpc.vpc.id}"
+  vpc_id      = "{{.VPCID}}"
 
   tags {
     Name = "${var.env_id}-jumpbox-security-group"
@@ -422,7 +422,7 @@ variable "bosh_availability_zone" {
 }
 
 resource "aws_subnet" "bosh_subnet" {
-  vpc_id            = "${aws_vpc.vpc.id}"
+  vpc_id            = "{{.VPCID}}"
   cidr_block        = "${var.bosh_subnet_cidr}"
 
   tags {
@@ -431,12 +431,12 @@ resource "aws_subnet" "bosh_subnet" {
 }
 
 resource "aws_route_table" "bosh_route_table" {
-  vpc_id = "${aws_vpc.vpc.id}"
+  vpc_id = "{{.VPCID}}"
 }
 
 resource "aws_route" "bosh_route_table" {
   destination_cidr_block = "0.0.0.0/0"
-  gateway_id = "${aws_internet_gateway.ig.id}"
+  gateway_id = "{{.InternetGatewayID}}"
   route_table_id = "${aws_route_table.bosh_route_table.id}"
 }
 
@@ -457,10 +457,14 @@ variable "availability_zones" {
   type = "list"
 }
 
+variable "internal_subnet_cidrs" {
+  type = "list"
+}
+
 resource "aws_subnet" "internal_subnets" {
   count             = "${length(var.availability_zones)}"
-  vpc_id            = "${aws_vpc.vpc.id}"
-  cidr_block        = "${cidrsubnet("10.0.0.0/16", 4, count.index+1)}"
+  vpc_id            = "{{.VPCID}}"
+  cidr_block        = "${element(var.internal_subnet_cidrs, count.index)}"
   availability_zone = "${element(var.availability_zones, count.index)}"
 
   tags {
@@ -473,7 +477,7 @@ resource "aws_subnet" "internal_subnets" {
 }
 
 resource "aws_route_table" "internal_route_table" {
-  vpc_id = "${aws_vpc.vpc.id}"
+  vpc_id = "{{.VPCID}}"
 }
 
 resource "aws_route" "internal_route_table" {
@@ -513,6 +517,22 @@ variable "vpc_cidr" {
   default = "10.0.0.0/16"
 }
 
+{{if .ExistingVPC -}}
+variable "existing_vpc_id" {
+  type = "string"
+}
+
+data "aws_vpc" "vpc" {
+  id = "${var.existing_vpc_id}"
+}
+
+data "aws_internet_gateway" "ig" {
+  filter {
+    name   = "attachment.vpc-id"
+    values = ["${var.existing_vpc_id}"]
+  }
+}
+{{- else -}}
 resource "aws_vpc" "vpc" {
   cidr_block           = "${var.vpc_cidr}"
   instance_tenancy     = "default"
@@ -524,17 +544,19 @@ resource "aws_vpc" "vpc" {
 }
 
 resource "aws_internet_gateway" "ig" {
-  vpc_id = "${aws_vpc.vpc.id}"
+  vpc_id = "{{.VPCID}}"
 }
+{{- end}}
 
 output "vpc_id" {
-  value = "${aws_vpc.vpc.id}"
+  value = "{{.VPCID}}"
 }
+`
 
-resource "aws_flow_log" "bbl" {
+const FlowLogsTemplate = `resource "aws_flow_log" "bbl" {
   log_group_name = "${aws_cloudwatch_log_group.bbl.name}"
   iam_role_arn   = "${aws_iam_role.flow_logs.arn}"
-  vpc_id         = "${aws_vpc.vpc.id}"
+  vpc_id         = "{{.VPCID}}"
   traffic_type   = "REJECT"
 }
 
@@ -585,8 +607,9 @@ resource "aws_iam_role_policy" "flow_logs" {
 }
 EOF
 }
+`
 
-resource "aws_kms_key" "kms_key" {
+const KMSTemplate = `resource "aws_kms_key" "kms_key" {
   enable_key_rotation = true
 }
 
@@ -595,10 +618,21 @@ output "kms_key_arn" {
 }
 `
 
-const LBSubnetTemplate = `resource "aws_subnet" "lb_subnets" {
+const S3EndpointTemplate = `resource "aws_vpc_endpoint" "s3" {
+  vpc_id          = "{{.VPCID}}"
+  service_name    = "com.amazonaws.${var.region}.s3"
+  route_table_ids = ["${aws_route_table.internal_route_table.id}"]
+}
+`
+
+const LBSubnetTemplate = `variable "lb_subnet_cidrs" {
+  type = "list"
+}
+
+resource "aws_subnet" "lb_subnets" {
   count             = "${length(var.availability_zones)}"
-  vpc_id            = "${aws_vpc.vpc.id}"
-  cidr_block        = "${cidrsubnet("10.0.0.0/20", 4, count.index+2)}"
+  vpc_id            = "{{.VPCID}}"
+  cidr_block        = "${element(var.lb_subnet_cidrs, count.index)}"
   availability_zone = "${element(var.availability_zones, count.index)}"
 
   tags {
@@ -611,12 +645,12 @@ const LBSubnetTemplate = `resource "aws_subnet" "lb_subnets" {
 }
 
 resource "aws_route_table" "lb_route_table" {
-  vpc_id = "${aws_vpc.vpc.id}"
+  vpc_id = "{{.VPCID}}"
 }
 
 resource "aws_route" "lb_route_table" {
   destination_cidr_block = "0.0.0.0/0"
-  gateway_id = "${aws_internet_gateway.ig.id}"
+  gateway_id = "{{.InternetGatewayID}}"
   route_table_id = "${aws_route_table.lb_route_table.id}"
 }
 
@@ -675,7 +709,7 @@ resource "aws_iam_server_certificate" "lb_cert" {
 
 const ConcourseLBTemplate = `resource "aws_security_group" "concourse_lb_security_group" {
   description = "{{.ConcourseDescription}}"
-  vpc_id      = "${aws_vpc.vpc.id}"
+  vpc_id      = "{{.VPCID}}"
 
   ingress {
     cidr_blocks = ["0.0.0.0/0"]
@@ -712,7 +746,7 @@ const ConcourseLBTemplate = `resource "aws_security_group" "concourse_lb_securit
 
 resource "aws_security_group" "concourse_lb_internal_security_group" {
   description = "{{.ConcourseInternalDescription}}"
-  vpc_id      = "${aws_vpc.vpc.id}"
+  vpc_id      = "{{.VPCID}}"
 
   ingress {
     security_groups = ["${aws_security_group.concourse_lb_security_group.id}"]
@@ -793,7 +827,7 @@ output "concourse_lb_url" {
 
 const CFLBTemplate = `resource "aws_security_group" "cf_ssh_lb_security_group" {
   description = "{{.SSHLBDescription}}"
-  vpc_id      = "${aws_vpc.vpc.id}"
+  vpc_id      = "{{.VPCID}}"
 
   ingress {
     cidr_blocks = ["0.0.0.0/0"]
@@ -820,7 +854,7 @@ output "cf_ssh_lb_security_group" {
 
 resource "aws_security_group" "cf_ssh_lb_internal_security_group" {
   description = "{{.SSHLBInternalDescription}}"
-  vpc_id      = "${aws_vpc.vpc.id}"
+  vpc_id      = "{{.VPCID}}"
 
   ingress {
     security_groups = ["${aws_security_group.cf_ssh_lb_security_group.id}"]
@@ -878,7 +912,7 @@ output "cf_ssh_lb_url" {
 
 resource "aws_security_group" "cf_router_lb_security_group" {
   description = "{{.RouterDescription}}"
-  vpc_id      = "${aws_vpc.vpc.id}"
+  vpc_id      = "{{.VPCID}}"
 
   ingress {
     cidr_blocks = ["0.0.0.0/0"]
@@ -919,7 +953,7 @@ output "cf_router_lb_security_group" {
 
 resource "aws_security_group" "cf_router_lb_internal_security_group" {
   description = "{{.RouterInternalDescription}}"
-  vpc_id      = "${aws_vpc.vpc.id}"
+  vpc_id      = "{{.VPCID}}"
 
   ingress {
     security_groups = ["${aws_security_group.cf_router_lb_security_group.id}"]
@@ -993,7 +1027,7 @@ output "cf_router_lb_url" {
 
 resource "aws_security_group" "cf_tcp_lb_security_group" {
   description = "{{.TCPLBDescription}}"
-  vpc_id      = "${aws_vpc.vpc.id}"
+  vpc_id      = "{{.VPCID}}"
 
   ingress {
     cidr_blocks = ["0.0.0.0/0"]
@@ -1020,7 +1054,7 @@ output "cf_tcp_lb_security_group" {
 
 resource "aws_security_group" "cf_tcp_lb_internal_security_group" {
   description = "{{.TCPLBInternalDescription}}"
-  vpc_id      = "${aws_vpc.vpc.id}"
+  vpc_id      = "{{.VPCID}}"
 
   ingress {
     security_groups = ["${aws_security_group.cf_tcp_lb_security_group.id}"]
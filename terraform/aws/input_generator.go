@@ -34,6 +34,16 @@ func (i InputGenerator) Generate(state storage.State) (map[string]string, error)
 		return map[string]string{}, err
 	}
 
+	network, err := state.NetworkLayout()
+	if err != nil {
+		return map[string]string{}, err
+	}
+
+	internalSubnetCIDRs, err := jsonMarshal(network.InternalSubnetCIDRs(len(azs)))
+	if err != nil {
+		return map[string]string{}, err
+	}
+
 	shortEnvID := state.EnvID
 	if len(shortEnvID) > terraformNameCharLimit {
 		sha1 := fmt.Sprintf("%x", sha1.Sum([]byte(state.EnvID)))
@@ -48,9 +58,22 @@ func (i InputGenerator) Generate(state storage.State) (map[string]string, error)
 		"region":                 state.AWS.Region,
 		"bosh_availability_zone": state.Stack.BOSHAZ,
 		"availability_zones":     string(azsString),
+		"vpc_cidr":               network.CIDR(),
+		"bosh_subnet_cidr":       network.DirectorSubnetCIDR(),
+		"internal_subnet_cidrs":  string(internalSubnetCIDRs),
+	}
+
+	if state.AWS.ExistingVPCID != "" {
+		inputs["existing_vpc_id"] = state.AWS.ExistingVPCID
 	}
 
 	if state.LB.Type == "cf" || state.LB.Type == "concourse" {
+		lbSubnetCIDRs, err := jsonMarshal(network.LBSubnetCIDRs(len(azs)))
+		if err != nil {
+			return map[string]string{}, err
+		}
+		inputs["lb_subnet_cidrs"] = string(lbSubnetCIDRs)
+
 		inputs["ssl_certificate_name_prefix"] = ""
 		inputs["ssl_certificate_name"] = state.Stack.CertificateName
 		if state.Stack.CertificateName == "" {
@@ -84,10 +84,60 @@ var _ = Describe("InputGenerator", func() {
 				"region":                 "some-region",
 				"bosh_availability_zone": "some-zone",
 				"availability_zones":     `["z1","z2","z3"]`,
+				"vpc_cidr":               "10.0.0.0/16",
+				"bosh_subnet_cidr":       "10.0.0.0/24",
+				"internal_subnet_cidrs":  `["10.0.16.0/20","10.0.32.0/20","10.0.48.0/20"]`,
 			}))
 		})
 	})
 
+	Context("when a network cidr is provided", func() {
+		It("returns the ranges carved out of the network", func() {
+			inputs, err := inputGenerator.Generate(storage.State{
+				IAAS:  "aws",
+				EnvID: "some-env-id",
+				AWS: storage.AWS{
+					Region: "some-region",
+				},
+				Network: &storage.Network{
+					CIDR:                "172.16.0.0/16",
+					InternalSubnetCIDRs: []string{"172.16.128.0/20"},
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(inputs["vpc_cidr"]).To(Equal("172.16.0.0/16"))
+			Expect(inputs["bosh_subnet_cidr"]).To(Equal("172.16.0.0/24"))
+			Expect(inputs["internal_subnet_cidrs"]).To(Equal(`["172.16.128.0/20","172.16.32.0/20","172.16.48.0/20"]`))
+		})
+
+		It("returns an error when the network is invalid", func() {
+			_, err := inputGenerator.Generate(storage.State{
+				IAAS: "aws",
+				Network: &storage.Network{
+					CIDR: "172.16.0.0/24",
+				},
+			})
+			Expect(err).To(MatchError("network cidr 172.16.0.0/24 is too small, it must be a /20 or larger"))
+		})
+	})
+
+	Context("when an existing vpc is provided", func() {
+		It("returns a map with the existing vpc id", func() {
+			inputs, err := inputGenerator.Generate(storage.State{
+				IAAS:  "aws",
+				EnvID: "some-env-id",
+				AWS: storage.AWS{
+					Region:        "some-region",
+					ExistingVPCID: "some-vpc-id",
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(inputs["existing_vpc_id"]).To(Equal("some-vpc-id"))
+		})
+	})
+
 	Context("when a cf lb exists", func() {
 		var (
 			state storage.State
@@ -130,6 +180,10 @@ var _ = Describe("InputGenerator", func() {
 				"region":                      "some-region",
 				"bosh_availability_zone":      "some-zone",
 				"availability_zones":          `["z1","z2","z3"]`,
+				"vpc_cidr":                    "10.0.0.0/16",
+				"bosh_subnet_cidr":            "10.0.0.0/24",
+				"internal_subnet_cidrs":       `["10.0.16.0/20","10.0.32.0/20","10.0.48.0/20"]`,
+				"lb_subnet_cidrs":             `["10.0.2.0/24","10.0.3.0/24","10.0.4.0/24"]`,
 				"ssl_certificate_name_prefix": "",
 				"ssl_certificate_name":        "some-certificate-name",
 			}))
@@ -154,6 +208,10 @@ var _ = Describe("InputGenerator", func() {
 					"region":                      "some-region",
 					"bosh_availability_zone":      "some-zone",
 					"availability_zones":          `["z1","z2","z3"]`,
+					"vpc_cidr":                    "10.0.0.0/16",
+					"bosh_subnet_cidr":            "10.0.0.0/24",
+					"internal_subnet_cidrs":       `["10.0.16.0/20","10.0.32.0/20","10.0.48.0/20"]`,
+					"lb_subnet_cidrs":             `["10.0.2.0/24","10.0.3.0/24","10.0.4.0/24"]`,
 					"ssl_certificate_name":        "some-certificate-name",
 					"ssl_certificate_name_prefix": "",
 					"system_domain":               "some-domain",
@@ -203,6 +261,10 @@ var _ = Describe("InputGenerator", func() {
 				"region":                      "some-region",
 				"bosh_availability_zone":      "some-zone",
 				"availability_zones":          `["z1","z2","z3"]`,
+				"vpc_cidr":                    "10.0.0.0/16",
+				"bosh_subnet_cidr":            "10.0.0.0/24",
+				"internal_subnet_cidrs":       `["10.0.16.0/20","10.0.32.0/20","10.0.48.0/20"]`,
+				"lb_subnet_cidrs":             `["10.0.2.0/24","10.0.3.0/24","10.0.4.0/24"]`,
 				"ssl_certificate":             "some-cert",
 				"ssl_certificate_chain":       "some-chain",
 				"ssl_certificate_private_key": "some-key",
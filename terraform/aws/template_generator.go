@@ -41,15 +41,32 @@ type TemplateData struct {
 	SSLCertificateNameProperty     string
 	IgnoreSSLCertificateProperties string
 	AWSNATAMIs                     map[string]string
+	ExistingVPC                    bool
+	VPCID                          string
+	InternetGateway                string
+	InternetGatewayID              string
 }
 
 func NewTemplateGenerator() TemplateGenerator {
 	return TemplateGenerator{}
 }
 
+// componentTemplates are the templates of the optional components.
+var componentTemplates = map[string]string{
+	storage.FlowLogsComponent:   FlowLogsTemplate,
+	storage.KMSComponent:        KMSTemplate,
+	storage.S3EndpointComponent: S3EndpointTemplate,
+}
+
 func (tg TemplateGenerator) Generate(state storage.State) string {
 	t := BaseTemplate
 
+	for _, component := range storage.ComponentsFor("aws") {
+		if state.ComponentEnabled(component.Name) {
+			t = strings.Join([]string{t, componentTemplates[component.Name]}, "\n")
+		}
+	}
+
 	switch state.LB.Type {
 	case "concourse":
 		t = strings.Join([]string{t, LBSubnetTemplate, ConcourseLBTemplate, SSLCertificateTemplate}, "\n")
@@ -107,6 +124,17 @@ func (tg TemplateGenerator) Generate(state storage.State) string {
 		}
 	}
 
+	if state.AWS.ExistingVPCID != "" {
+		templateData.ExistingVPC = true
+		templateData.VPCID = "${data.aws_vpc.vpc.id}"
+		templateData.InternetGateway = "data.aws_internet_gateway.ig"
+		templateData.InternetGatewayID = "${data.aws_internet_gateway.ig.id}"
+	} else {
+		templateData.VPCID = "${aws_vpc.vpc.id}"
+		templateData.InternetGateway = "aws_internet_gateway.ig"
+		templateData.InternetGatewayID = "${aws_internet_gateway.ig.id}"
+	}
+
 	if state.LB.Cert == "" || state.LB.Key == "" {
 		templateData.IgnoreSSLCertificateProperties = `ignore_changes = ["certificate_body", "certificate_chain", "private_key"]`
 	}
@@ -57,5 +57,48 @@ var _ = Describe("TemplateGenerator", func() {
 				Expect(template).To(ContainSubstring("BOSH"))
 			})
 		})
+
+		Context("when components are enabled or disabled", func() {
+			It("assembles the template from the enabled components", func() {
+				template := templateGenerator.Generate(storage.State{
+					Components: storage.Components{
+						"flow-logs":   false,
+						"kms":         false,
+						"s3-endpoint": true,
+					},
+				})
+				Expect(template).NotTo(ContainSubstring(`resource "aws_flow_log" "bbl"`))
+				Expect(template).NotTo(ContainSubstring(`resource "aws_iam_role" "flow_logs"`))
+				Expect(template).NotTo(ContainSubstring(`resource "aws_kms_key" "kms_key"`))
+				Expect(template).NotTo(ContainSubstring(`output "kms_key_arn"`))
+
+				Expect(template).To(ContainSubstring(`resource "aws_vpc_endpoint" "s3"`))
+				Expect(template).To(ContainSubstring(`vpc_id          = "${aws_vpc.vpc.id}"`))
+			})
+		})
+
+		Context("when an existing vpc is provided", func() {
+			It("references the vpc and internet gateway instead of creating them", func() {
+				template := templateGenerator.Generate(storage.State{
+					AWS: storage.AWS{
+						ExistingVPCID: "some-vpc-id",
+					},
+					LB: storage.LB{
+						Type: "cf",
+					},
+				})
+				Expect(template).To(ContainSubstring(`variable "existing_vpc_id"`))
+				Expect(template).To(ContainSubstring(`data "aws_vpc" "vpc"`))
+				Expect(template).To(ContainSubstring(`data "aws_internet_gateway" "ig"`))
+				Expect(template).To(ContainSubstring(`vpc_id      = "${data.aws_vpc.vpc.id}"`))
+				Expect(template).To(ContainSubstring(`depends_on = ["data.aws_internet_gateway.ig"]`))
+				Expect(template).To(ContainSubstring(`gateway_id = "${data.aws_internet_gateway.ig.id}"`))
+
+				Expect(template).NotTo(ContainSubstring(`resource "aws_vpc"`))
+				Expect(template).NotTo(ContainSubstring(`resource "aws_internet_gateway"`))
+				Expect(template).NotTo(ContainSubstring("${aws_vpc.vpc.id}"))
+				Expect(template).NotTo(ContainSubstring("${aws_internet_gateway.ig.id}"))
+			})
+		})
 	})
 })
@@ -28,6 +28,11 @@ variable "client_secret" {
 	type = "string"
 }
 
+variable "network_cidr" {
+	type = "string"
+	default = "10.0.0.0/16"
+}
+
 provider "azurerm" {
   subscription_id  = "${var.subscription_id}"
   tenant_id        = "${var.tenant_id}"
@@ -44,8 +49,9 @@ const ResourceGroupTemplate = `resource "azurerm_resource_group" "bosh" {
     environment = "${var.env_id}"
   }
 }
+` + "\n" + PublicIPTemplate
 
-resource "azurerm_public_ip" "bosh" {
+const PublicIPTemplate = `resource "azurerm_public_ip" "bosh" {
   name                         = "${var.env_id}-bosh"
   location                     = "${var.location}"
   resource_group_name          = "${azurerm_resource_group.bosh.name}"
@@ -59,14 +65,15 @@ resource "azurerm_public_ip" "bosh" {
 
 const NetworkTemplate = `resource "azurerm_virtual_network" "bosh" {
   name                = "${var.env_id}-bosh-vn"
-  address_space       = ["10.0.0.0/16"]
+  address_space       = ["${var.network_cidr}"]
   location            = "${var.location}"
   resource_group_name = "${azurerm_resource_group.bosh.name}"
 }
+` + "\n" + SubnetTemplate
 
-resource "azurerm_subnet" "bosh" {
+const SubnetTemplate = `resource "azurerm_subnet" "bosh" {
   name                 = "${var.env_id}-bosh-sn"
-  address_prefix       = "10.0.0.0/16"
+  address_prefix       = "${var.network_cidr}"
   resource_group_name  = "${azurerm_resource_group.bosh.name}"
   virtual_network_name = "${azurerm_virtual_network.bosh.name}"
 }
@@ -14,6 +14,11 @@ func NewInputGenerator() InputGenerator {
 }
 
 func (i InputGenerator) Generate(state storage.State) (map[string]string, error) {
+	network, err := state.NetworkLayout()
+	if err != nil {
+		return map[string]string{}, err
+	}
+
 	simpleEnvId := strings.Replace(state.EnvID, "-", "", -1)
 	if len(simpleEnvId) > 20 {
 		simpleEnvId = simpleEnvId[:20]
@@ -26,6 +31,12 @@ func (i InputGenerator) Generate(state storage.State) (map[string]string, error)
 		"tenant_id":       state.Azure.TenantID,
 		"client_id":       state.Azure.ClientID,
 		"client_secret":   state.Azure.ClientSecret,
+		"network_cidr":    network.CIDR(),
+	}
+
+	if state.Azure.ExistingVNet != "" {
+		input["existing_vnet"] = state.Azure.ExistingVNet
+		input["existing_vnet_resource_group"] = state.Azure.ExistingVNetResourceGroup
 	}
 
 	return input, nil
@@ -42,9 +42,23 @@ var _ = Describe("InputGenerator", func() {
 			"tenant_id":       state.Azure.TenantID,
 			"client_id":       state.Azure.ClientID,
 			"client_secret":   state.Azure.ClientSecret,
+			"network_cidr":    "10.0.0.0/16",
 		}))
 	})
 
+	Context("given an existing vnet", func() {
+		It("returns the vnet and its resource group", func() {
+			state.Azure.ExistingVNet = "some-vnet"
+			state.Azure.ExistingVNetResourceGroup = "some-resource-group"
+
+			inputs, err := inputGenerator.Generate(state)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(inputs["existing_vnet"]).To(Equal("some-vnet"))
+			Expect(inputs["existing_vnet_resource_group"]).To(Equal("some-resource-group"))
+		})
+	})
+
 	Context("given a long environment id", func() {
 		It("shortens the id for simple_env_id", func() {
 			state.EnvID = "super-long-environment-id-with-999"
@@ -59,6 +73,7 @@ var _ = Describe("InputGenerator", func() {
 				"tenant_id":       state.Azure.TenantID,
 				"client_id":       state.Azure.ClientID,
 				"client_secret":   state.Azure.ClientSecret,
+				"network_cidr":    "10.0.0.0/16",
 			}))
 		})
 	})
@@ -8,10 +8,38 @@ import (
 
 type TemplateGenerator struct{}
 
+const existingVNetTemplate = `variable "existing_vnet" {
+  type = "string"
+}
+
+variable "existing_vnet_resource_group" {
+  type = "string"
+}
+
+data "azurerm_resource_group" "bosh" {
+  name = "${var.existing_vnet_resource_group}"
+}
+
+data "azurerm_virtual_network" "bosh" {
+  name                = "${var.existing_vnet}"
+  resource_group_name = "${var.existing_vnet_resource_group}"
+}
+`
+
 func NewTemplateGenerator() TemplateGenerator {
 	return TemplateGenerator{}
 }
 
 func (t TemplateGenerator) Generate(state storage.State) string {
-	return strings.Join([]string{VarsTemplate, ResourceGroupTemplate, NetworkTemplate, StorageTemplate, NetworkSecurityGroupTemplate, OutputTemplate}, "\n")
+	if state.Azure.ExistingVNet == "" {
+		return strings.Join([]string{VarsTemplate, ResourceGroupTemplate, NetworkTemplate, StorageTemplate, NetworkSecurityGroupTemplate, OutputTemplate}, "\n")
+	}
+
+	// The Azure CPI looks the virtual network up in the director's resource
+	// group, so the environment lives in the resource group of the VNet.
+	template := strings.Join([]string{VarsTemplate, existingVNetTemplate, PublicIPTemplate, SubnetTemplate, StorageTemplate, NetworkSecurityGroupTemplate, OutputTemplate}, "\n")
+	template = strings.Replace(template, "${azurerm_resource_group.bosh.", "${data.azurerm_resource_group.bosh.", -1)
+	template = strings.Replace(template, "${azurerm_virtual_network.bosh.", "${data.azurerm_virtual_network.bosh.", -1)
+
+	return template
 }
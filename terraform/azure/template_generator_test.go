@@ -34,5 +34,27 @@ var _ = Describe("TemplateGenerator", func() {
 			})
 			Expect(template).To(Equal(string(expectedTemplate)))
 		})
+
+		Context("when an existing vnet is provided", func() {
+			It("creates the environment in the vnet and its resource group", func() {
+				template := templateGenerator.Generate(storage.State{
+					EnvID: "azure-environment",
+					Azure: storage.Azure{
+						ExistingVNet:              "some-vnet",
+						ExistingVNetResourceGroup: "some-resource-group",
+					},
+				})
+				Expect(template).To(ContainSubstring(`data "azurerm_resource_group" "bosh"`))
+				Expect(template).To(ContainSubstring(`data "azurerm_virtual_network" "bosh"`))
+				Expect(template).To(ContainSubstring(`resource "azurerm_subnet" "bosh"`))
+				Expect(template).To(ContainSubstring(`virtual_network_name = "${data.azurerm_virtual_network.bosh.name}"`))
+				Expect(template).To(ContainSubstring(`resource_group_name = "${data.azurerm_resource_group.bosh.name}"`))
+
+				Expect(template).NotTo(ContainSubstring(`resource "azurerm_resource_group"`))
+				Expect(template).NotTo(ContainSubstring(`resource "azurerm_virtual_network"`))
+				Expect(template).NotTo(ContainSubstring("${azurerm_resource_group.bosh"))
+				Expect(template).NotTo(ContainSubstring("${azurerm_virtual_network.bosh"))
+			})
+		})
 	})
 })
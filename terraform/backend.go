@@ -0,0 +1,108 @@
+package terraform
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+)
+
+// backendRequiredConfig lists the supported backend types and the settings
+// each one needs that bbl cannot provide itself.
+var backendRequiredConfig = map[string][]string{
+	"s3":      {"bucket"},
+	"gcs":     {"bucket"},
+	"azurerm": {"storage_account_name", "container_name"},
+	"local":   {"path"},
+}
+
+// NewBackend builds a terraform backend from its type and key=value
+// settings. A relative local path is made absolute since terraform runs in a
+// temporary directory.
+func NewBackend(backendType string, config []string) (storage.TFBackend, error) {
+	required, ok := backendRequiredConfig[backendType]
+	if !ok {
+		return storage.TFBackend{}, fmt.Errorf("unsupported terraform backend %q, valid options are: azurerm, gcs, local, s3", backendType)
+	}
+
+	backend := storage.TFBackend{
+		Type:   backendType,
+		Config: map[string]string{},
+	}
+
+	for _, setting := range config {
+		parts := strings.SplitN(setting, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return storage.TFBackend{}, fmt.Errorf("invalid terraform backend config %q, expected key=value", setting)
+		}
+		backend.Config[parts[0]] = parts[1]
+	}
+
+	for _, key := range required {
+		if backend.Config[key] == "" {
+			return storage.TFBackend{}, fmt.Errorf("the %s terraform backend requires --terraform-backend-config %s=<value>", backendType, key)
+		}
+	}
+
+	if backendType == "local" {
+		path, err := filepath.Abs(backend.Config["path"])
+		if err != nil {
+			return storage.TFBackend{}, err
+		}
+		backend.Config["path"] = path
+	}
+
+	return backend, nil
+}
+
+// backendTemplate renders the backend block for the environment. bbl fills in
+// the state key and, for the backend of the environment's own IAAS, the
+// credentials.
+func backendTemplate(state storage.State) string {
+	backend := state.TFBackend
+	config := map[string]string{}
+
+	switch backend.Type {
+	case "s3":
+		config["key"] = fmt.Sprintf("%s/terraform.tfstate", state.EnvID)
+		if state.IAAS == "aws" {
+			config["region"] = state.AWS.Region
+			config["access_key"] = state.AWS.AccessKeyID
+			config["secret_key"] = state.AWS.SecretAccessKey
+		}
+	case "gcs":
+		config["prefix"] = state.EnvID
+		if state.IAAS == "gcp" {
+			config["project"] = state.GCP.ProjectID
+			config["credentials"] = state.GCP.ServiceAccountKey
+		}
+	case "azurerm":
+		config["key"] = fmt.Sprintf("%s.terraform.tfstate", state.EnvID)
+		if state.IAAS == "azure" {
+			config["arm_subscription_id"] = state.Azure.SubscriptionID
+			config["arm_tenant_id"] = state.Azure.TenantID
+			config["arm_client_id"] = state.Azure.ClientID
+			config["arm_client_secret"] = state.Azure.ClientSecret
+		}
+	}
+
+	for key, value := range backend.Config {
+		config[key] = value
+	}
+
+	var keys []string
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	lines := []string{"terraform {", fmt.Sprintf("  backend %q {", backend.Type)}
+	for _, key := range keys {
+		lines = append(lines, fmt.Sprintf("    %s = %q", key, config[key]))
+	}
+	lines = append(lines, "  }", "}", "")
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,52 @@
+package terraform_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/bosh-bootloader/storage"
+	"github.com/cloudfoundry/bosh-bootloader/terraform"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewBackend", func() {
+	It("builds the backend from key=value settings", func() {
+		backend, err := terraform.NewBackend("s3", []string{"bucket=some-bucket", "dynamodb_table=some=table"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(backend).To(Equal(storage.TFBackend{
+			Type: "s3",
+			Config: map[string]string{
+				"bucket":         "some-bucket",
+				"dynamodb_table": "some=table",
+			},
+		}))
+	})
+
+	It("makes a relative local path absolute", func() {
+		wd, err := os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+
+		backend, err := terraform.NewBackend("local", []string{"path=some-dir/terraform.tfstate"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(backend.Config["path"]).To(Equal(filepath.Join(wd, "some-dir/terraform.tfstate")))
+	})
+
+	Context("failure cases", func() {
+		It("rejects unsupported backends", func() {
+			_, err := terraform.NewBackend("consul", nil)
+			Expect(err).To(MatchError(`unsupported terraform backend "consul", valid options are: azurerm, gcs, local, s3`))
+		})
+
+		It("rejects settings that are not key=value", func() {
+			_, err := terraform.NewBackend("gcs", []string{"some-bucket"})
+			Expect(err).To(MatchError(`invalid terraform backend config "some-bucket", expected key=value`))
+		})
+
+		It("requires the settings bbl cannot provide", func() {
+			_, err := terraform.NewBackend("azurerm", []string{"storage_account_name=some-account"})
+			Expect(err).To(MatchError("the azurerm terraform backend requires --terraform-backend-config container_name=<value>"))
+		})
+	})
+})
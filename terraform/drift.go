@@ -0,0 +1,150 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	DriftChanged = "changed"
+	DriftDeleted = "deleted"
+)
+
+type DriftedAttribute struct {
+	Name   string `json:"name"`
+	Stored string `json:"stored"`
+	Actual string `json:"actual"`
+}
+
+type DriftedResource struct {
+	Address    string             `json:"address"`
+	Status     string             `json:"status"`
+	Attributes []DriftedAttribute `json:"attributes,omitempty"`
+}
+
+// Drift is how the infrastructure differs from the terraform state bbl
+// stored for it.
+type Drift struct {
+	Resources []DriftedResource `json:"resources"`
+}
+
+type tfStateResource struct {
+	Primary *struct {
+		ID         string            `json:"id"`
+		Attributes map[string]string `json:"attributes"`
+	} `json:"primary"`
+}
+
+type tfStateFile struct {
+	Modules []struct {
+		Path      []string                   `json:"path"`
+		Resources map[string]tfStateResource `json:"resources"`
+	} `json:"modules"`
+}
+
+func (d Drift) HasDrift() bool {
+	return len(d.Resources) > 0
+}
+
+// DetectDrift compares the stored terraform state with the state terraform
+// refresh read from the infrastructure.
+func DetectDrift(storedTFState, refreshedTFState string) (Drift, error) {
+	stored, err := tfStateResources(storedTFState)
+	if err != nil {
+		return Drift{}, fmt.Errorf("parse stored terraform state: %s", err)
+	}
+
+	refreshed, err := tfStateResources(refreshedTFState)
+	if err != nil {
+		return Drift{}, fmt.Errorf("parse refreshed terraform state: %s", err)
+	}
+
+	var addresses []string
+	for address := range stored {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	drift := Drift{Resources: []DriftedResource{}}
+	for _, address := range addresses {
+		storedResource := stored[address]
+		if storedResource.Primary == nil {
+			continue
+		}
+
+		refreshedResource, ok := refreshed[address]
+		if !ok || refreshedResource.Primary == nil {
+			drift.Resources = append(drift.Resources, DriftedResource{
+				Address: address,
+				Status:  DriftDeleted,
+			})
+			continue
+		}
+
+		attributes := driftedAttributes(storedResource.Primary.Attributes, refreshedResource.Primary.Attributes)
+		if len(attributes) > 0 {
+			drift.Resources = append(drift.Resources, DriftedResource{
+				Address:    address,
+				Status:     DriftChanged,
+				Attributes: attributes,
+			})
+		}
+	}
+
+	return drift, nil
+}
+
+func driftedAttributes(stored, refreshed map[string]string) []DriftedAttribute {
+	names := map[string]struct{}{}
+	for name := range stored {
+		names[name] = struct{}{}
+	}
+	for name := range refreshed {
+		names[name] = struct{}{}
+	}
+
+	var sortedNames []string
+	for name := range names {
+		sortedNames = append(sortedNames, name)
+	}
+	sort.Strings(sortedNames)
+
+	var attributes []DriftedAttribute
+	for _, name := range sortedNames {
+		if stored[name] != refreshed[name] {
+			attributes = append(attributes, DriftedAttribute{
+				Name:   name,
+				Stored: stored[name],
+				Actual: refreshed[name],
+			})
+		}
+	}
+
+	return attributes
+}
+
+// tfStateResources returns the resources of all modules by address, e.g.
+// aws_vpc.vpc or module.some-module.aws_vpc.vpc.
+func tfStateResources(tfState string) (map[string]tfStateResource, error) {
+	var state tfStateFile
+	err := json.Unmarshal([]byte(tfState), &state)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := map[string]tfStateResource{}
+	for _, module := range state.Modules {
+		prefix := ""
+		if len(module.Path) > 1 {
+			prefix = "module." + strings.Join(module.Path[1:], ".module.") + "."
+		}
+
+		for name, resource := range module.Resources {
+			resources[prefix+name] = resource
+		}
+	}
+
+	return resources, nil
+}
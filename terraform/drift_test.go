@@ -0,0 +1,109 @@
+package terraform_test
+
+import (
+	"github.com/cloudfoundry/bosh-bootloader/terraform"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DetectDrift", func() {
+	var storedTFState string
+
+	BeforeEach(func() {
+		storedTFState = `{
+			"version": 3,
+			"modules": [
+				{
+					"path": ["root"],
+					"resources": {
+						"aws_vpc.vpc": {
+							"type": "aws_vpc",
+							"primary": {"id": "vpc-1", "attributes": {"id": "vpc-1", "cidr_block": "10.0.0.0/16"}}
+						},
+						"aws_security_group.internal_security_group": {
+							"type": "aws_security_group",
+							"primary": {"id": "sg-1", "attributes": {"id": "sg-1", "ingress.#": "2", "tags.Name": "internal"}}
+						},
+						"aws_instance.nat": {
+							"type": "aws_instance",
+							"primary": {"id": "i-1", "attributes": {"id": "i-1"}}
+						}
+					}
+				},
+				{
+					"path": ["root", "some-module"],
+					"resources": {
+						"aws_eip.ip": {
+							"type": "aws_eip",
+							"primary": {"id": "eip-1", "attributes": {"id": "eip-1"}}
+						}
+					}
+				}
+			]
+		}`
+	})
+
+	It("finds no drift when the refreshed state matches", func() {
+		drift, err := terraform.DetectDrift(storedTFState, storedTFState)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(drift.HasDrift()).To(BeFalse())
+	})
+
+	It("reports changed and deleted resources", func() {
+		refreshedTFState := `{
+			"version": 3,
+			"modules": [
+				{
+					"path": ["root"],
+					"resources": {
+						"aws_vpc.vpc": {
+							"type": "aws_vpc",
+							"primary": {"id": "vpc-1", "attributes": {"id": "vpc-1", "cidr_block": "10.0.0.0/16"}}
+						},
+						"aws_security_group.internal_security_group": {
+							"type": "aws_security_group",
+							"primary": {"id": "sg-1", "attributes": {"id": "sg-1", "ingress.#": "3", "ingress.1.from_port": "22"}}
+						}
+					}
+				},
+				{
+					"path": ["root", "some-module"],
+					"resources": {}
+				}
+			]
+		}`
+
+		drift, err := terraform.DetectDrift(storedTFState, refreshedTFState)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(drift.HasDrift()).To(BeTrue())
+		Expect(drift.Resources).To(Equal([]terraform.DriftedResource{
+			{
+				Address: "aws_instance.nat",
+				Status:  terraform.DriftDeleted,
+			},
+			{
+				Address: "aws_security_group.internal_security_group",
+				Status:  terraform.DriftChanged,
+				Attributes: []terraform.DriftedAttribute{
+					{Name: "ingress.#", Stored: "2", Actual: "3"},
+					{Name: "ingress.1.from_port", Stored: "", Actual: "22"},
+					{Name: "tags.Name", Stored: "internal", Actual: ""},
+				},
+			},
+			{
+				Address: "module.some-module.aws_eip.ip",
+				Status:  terraform.DriftDeleted,
+			},
+		}))
+	})
+
+	It("returns an error when a state cannot be parsed", func() {
+		_, err := terraform.DetectDrift("%%%", storedTFState)
+		Expect(err).To(MatchError(ContainSubstring("parse stored terraform state: invalid character")))
+
+		_, err = terraform.DetectDrift(storedTFState, "%%%")
+		Expect(err).To(MatchError(ContainSubstring("parse refreshed terraform state: invalid character")))
+	})
+})
@@ -2,6 +2,7 @@ package terraform
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,23 +13,40 @@ import (
 	"regexp"
 	"strings"
 
-	"github.com/cloudfoundry/bosh-bootloader/storage"
+	"github.com/cloudfoundry/bosh-bootloader/helpers"
 )
 
-var tempDir func(dir, prefix string) (string, error) = ioutil.TempDir
+var tempDir func(dir, prefix string) (string, error) = helpers.TempDir
 var writeFile func(file string, data []byte, perm os.FileMode) error = ioutil.WriteFile
 var readFile func(filename string) ([]byte, error) = ioutil.ReadFile
 
-type Executor struct {
-	cmd   terraformCmd
-	debug bool
-}
+// backendInitArgs let terraform init move an existing terraform.tfstate into
+// a newly configured backend without prompting.
+var backendInitArgs = []string{"init", "-input=false", "-force-copy"}
+
+// initSHA256FileName records the configuration terraform was last
+// initialized with in a working directory. It is kept with the provider
+// plugins, the only files left in a working directory between runs.
+const initSHA256FileName = "bbl-init-sha256"
+
+// pluginsDir is the directory terraform init installs provider plugins in.
+const pluginsDir = "plugins"
 
-type ImportInput struct {
-	TerraformAddr string
-	AWSResourceID string
-	TFState       string
-	Creds         storage.AWS
+// applyDir is the directory in the working directory that apply, destroy,
+// plan and the state commands run in. Outputs, refresh and pulling the state
+// from a backend each run in a directory of their own, so that cleaning up
+// after one run does not remove the plugins another one initialized.
+const applyDir = "apply"
+
+// inputsFileName is the var file the inputs are passed to terraform in,
+// which keeps credentials out of the terraform command line.
+const inputsFileName = "terraform.tfvars.json"
+
+type Executor struct {
+	cmd        terraformCmd
+	overrides  Overrides
+	workingDir string
+	debug      bool
 }
 
 type tfOutput struct {
@@ -41,80 +59,206 @@ type terraformCmd interface {
 	Run(stdout io.Writer, workingDirectory string, args []string, debug bool) error
 }
 
-func NewExecutor(cmd terraformCmd, debug bool) Executor {
-	return Executor{cmd: cmd, debug: debug}
+// NewExecutor returns an executor that runs terraform in workingDir, so that
+// init and the provider plugins it downloads are reused between runs. With
+// an empty workingDir every run gets a new temp dir.
+func NewExecutor(cmd terraformCmd, overridesDir, workingDir string, debug bool) Executor {
+	return Executor{
+		cmd:        cmd,
+		overrides:  NewOverrides(overridesDir),
+		workingDir: workingDir,
+		debug:      debug,
+	}
 }
 
 func (e Executor) Apply(input map[string]string, template, prevTFState string) (string, error) {
-	tempDir, err := tempDir("", "")
+	tempDir, err := e.dir(applyDir)
 	if err != nil {
 		return "", err
 	}
+	defer e.clean(tempDir)
 
-	err = writeFile(filepath.Join(tempDir, "template.tf"), []byte(template), os.ModePerm)
+	err = writeFile(filepath.Join(tempDir, "template.tf"), []byte(template), 0600)
 	if err != nil {
 		return "", err
 	}
 
-	if prevTFState != "" {
-		err = writeFile(filepath.Join(tempDir, "terraform.tfstate"), []byte(prevTFState), os.ModePerm)
-		if err != nil {
-			return "", err
-		}
+	varFiles, err := e.overrides.Write(tempDir, template)
+	if err != nil {
+		return "", err
+	}
+
+	err = e.writeTFState(tempDir, prevTFState)
+	if err != nil {
+		return "", err
+	}
+
+	err = e.init(tempDir, backendInitArgs, e.debug)
+	if err != nil {
+		return "", err
 	}
 
-	err = e.cmd.Run(os.Stdout, tempDir, []string{"init"}, e.debug)
+	err = writeInputs(tempDir, input)
 	if err != nil {
 		return "", err
 	}
+	defer os.Remove(filepath.Join(tempDir, inputsFileName))
 
 	args := []string{"apply"}
-	for k, v := range input {
-		args = append(args, makeVar(k, v)...)
+	for _, varFile := range varFiles {
+		args = append(args, "-var-file", varFile)
 	}
+	args = append(args, "-var-file", inputsFileName)
 	err = e.cmd.Run(os.Stdout, tempDir, args, e.debug)
 	if err != nil {
-		return "", NewExecutorError(filepath.Join(tempDir, "terraform.tfstate"), err, e.debug)
+		return "", NewExecutorError(e.keepTFState(tempDir), err, e.debug)
 	}
 
-	tfState, err := readFile(filepath.Join(tempDir, "terraform.tfstate"))
+	return e.readTFState(tempDir)
+}
+
+func (e Executor) Destroy(input map[string]string, template, prevTFState string) (string, error) {
+	tempDir, err := e.dir(applyDir)
 	if err != nil {
 		return "", err
 	}
+	defer e.clean(tempDir)
 
-	return string(tfState), nil
-}
+	err = writeFile(filepath.Join(tempDir, "template.tf"), []byte(template), 0600)
+	if err != nil {
+		return "", err
+	}
 
-func (e Executor) Destroy(input map[string]string, template, prevTFState string) (string, error) {
-	tempDir, err := tempDir("", "")
+	varFiles, err := e.overrides.Write(tempDir, template)
 	if err != nil {
 		return "", err
 	}
 
-	err = writeFile(filepath.Join(tempDir, "template.tf"), []byte(template), os.ModePerm)
+	err = e.writeTFState(tempDir, prevTFState)
 	if err != nil {
 		return "", err
 	}
 
-	if prevTFState != "" {
-		err = writeFile(filepath.Join(tempDir, "terraform.tfstate"), []byte(prevTFState), os.ModePerm)
-		if err != nil {
-			return "", err
-		}
+	err = e.init(tempDir, backendInitArgs, e.debug)
+	if err != nil {
+		return "", err
 	}
 
-	err = e.cmd.Run(os.Stdout, tempDir, []string{"init"}, e.debug)
+	err = writeInputs(tempDir, input)
 	if err != nil {
 		return "", err
 	}
+	defer os.Remove(filepath.Join(tempDir, inputsFileName))
 
 	args := []string{"destroy", "-force"}
-	for k, v := range input {
-		args = append(args, makeVar(k, v)...)
+	for _, varFile := range varFiles {
+		args = append(args, "-var-file", varFile)
 	}
+	args = append(args, "-var-file", inputsFileName)
 	err = e.cmd.Run(os.Stdout, tempDir, args, e.debug)
 	if err != nil {
-		return "", NewExecutorError(filepath.Join(tempDir, "terraform.tfstate"), err, e.debug)
+		return "", NewExecutorError(e.keepTFState(tempDir), err, e.debug)
+	}
+
+	return e.readTFState(tempDir)
+}
+
+// Plan runs `terraform plan` against the template and previous state and
+// returns its output. Nothing is written back to the state.
+func (e Executor) Plan(input map[string]string, template, prevTFState string) (string, error) {
+	tempDir, err := e.dir(applyDir)
+	if err != nil {
+		return "", err
+	}
+	defer e.clean(tempDir)
+
+	err = writeFile(filepath.Join(tempDir, "template.tf"), []byte(template), 0600)
+	if err != nil {
+		return "", err
+	}
+
+	varFiles, err := e.overrides.Write(tempDir, template)
+	if err != nil {
+		return "", err
+	}
+
+	err = e.writeTFState(tempDir, prevTFState)
+	if err != nil {
+		return "", err
+	}
+
+	err = e.init(tempDir, backendInitArgs, e.debug)
+	if err != nil {
+		return "", err
+	}
+
+	err = writeInputs(tempDir, input)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(filepath.Join(tempDir, inputsFileName))
+
+	args := []string{"plan", "-no-color", "-input=false"}
+	for _, varFile := range varFiles {
+		args = append(args, "-var-file", varFile)
+	}
+	args = append(args, "-var-file", inputsFileName)
+
+	buffer := bytes.NewBuffer([]byte{})
+	err = e.cmd.Run(buffer, tempDir, args, true)
+	if err != nil {
+		return "", fmt.Errorf("terraform plan: %s\n%s", err, buffer)
+	}
+
+	return buffer.String(), nil
+}
+
+// Refresh runs `terraform refresh` against the template and previous state
+// and returns the refreshed state. It runs apart from the other commands so
+// the refreshed state never replaces the stored one.
+func (e Executor) Refresh(input map[string]string, template, prevTFState string) (string, error) {
+	tempDir, err := e.dir("drift")
+	if err != nil {
+		return "", err
+	}
+	defer e.clean(tempDir)
+
+	err = writeFile(filepath.Join(tempDir, "template.tf"), []byte(template), 0600)
+	if err != nil {
+		return "", err
+	}
+
+	varFiles, err := e.overrides.Write(tempDir, template)
+	if err != nil {
+		return "", err
+	}
+
+	err = e.writeTFState(tempDir, prevTFState)
+	if err != nil {
+		return "", err
+	}
+
+	err = e.init(tempDir, []string{"init", "-input=false"}, e.debug)
+	if err != nil {
+		return "", err
+	}
+
+	err = writeInputs(tempDir, input)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(filepath.Join(tempDir, inputsFileName))
+
+	args := []string{"refresh", "-no-color", "-input=false"}
+	for _, varFile := range varFiles {
+		args = append(args, "-var-file", varFile)
+	}
+	args = append(args, "-var-file", inputsFileName)
+
+	buffer := bytes.NewBuffer([]byte{})
+	err = e.cmd.Run(buffer, tempDir, args, e.debug)
+	if err != nil {
+		return "", fmt.Errorf("terraform refresh: %s\n%s", err, buffer)
 	}
 
 	tfState, err := readFile(filepath.Join(tempDir, "terraform.tfstate"))
@@ -125,52 +269,89 @@ func (e Executor) Destroy(input map[string]string, template, prevTFState string)
 	return string(tfState), nil
 }
 
-func (e Executor) Import(input ImportInput) (string, error) {
-	tempDir, err := tempDir("", "")
+// PullState returns the terraform state held by the backend configured in
+// backendTemplate.
+func (e Executor) PullState(backendTemplate string) (string, error) {
+	tempDir, err := e.dir("backend")
 	if err != nil {
 		return "", err
 	}
+	defer e.clean(tempDir)
 
-	resourceType := strings.Split(input.TerraformAddr, ".")[0]
-	resourceName := strings.Split(input.TerraformAddr, ".")[1]
-	resourceName = strings.Split(resourceName, "[")[0]
+	err = writeFile(filepath.Join(tempDir, "backend.tf"), []byte(backendTemplate), 0600)
+	if err != nil {
+		return "", err
+	}
 
-	template := fmt.Sprintf(`
-provider "aws" {
-	region     = %q
-	access_key = %q
-	secret_key = %q
-}
+	err = e.init(tempDir, []string{"init", "-input=false"}, e.debug)
+	if err != nil {
+		return "", err
+	}
 
-resource %q %q {
-}`, input.Creds.Region, input.Creds.AccessKeyID, input.Creds.SecretAccessKey, resourceType, resourceName)
+	buffer := bytes.NewBuffer([]byte{})
+	err = e.cmd.Run(buffer, tempDir, []string{"state", "pull"}, true)
+	if err != nil {
+		return "", err
+	}
 
-	err = writeFile(filepath.Join(tempDir, "template.tf"), []byte(template), os.ModePerm)
+	return buffer.String(), nil
+}
+
+// StateCommand runs `terraform state <args>`, or `terraform import` when
+// args start with "import", against the template and previous state and
+// returns the resulting state. The command output is written to stdout.
+func (e Executor) StateCommand(input map[string]string, template, prevTFState string, args []string, stdout io.Writer) (string, error) {
+	tempDir, err := e.dir(applyDir)
 	if err != nil {
 		return "", err
 	}
+	defer e.clean(tempDir)
 
-	err = writeFile(filepath.Join(tempDir, "terraform.tfstate"), []byte(input.TFState), os.ModePerm)
+	err = writeFile(filepath.Join(tempDir, "template.tf"), []byte(template), 0600)
 	if err != nil {
 		return "", err
 	}
 
-	err = e.cmd.Run(os.Stdout, tempDir, []string{"init"}, e.debug)
+	varFiles, err := e.overrides.Write(tempDir, template)
 	if err != nil {
 		return "", err
 	}
 
-	err = e.cmd.Run(os.Stdout, tempDir, []string{"import", input.TerraformAddr, input.AWSResourceID}, e.debug)
+	err = e.writeTFState(tempDir, prevTFState)
 	if err != nil {
-		return "", fmt.Errorf("failed to import: %s", err)
+		return "", err
 	}
 
-	tfStateContents, err := readFile(filepath.Join(tempDir, "terraform.tfstate"))
+	err = e.init(tempDir, backendInitArgs, e.debug)
 	if err != nil {
 		return "", err
 	}
 
-	return string(tfStateContents), nil
+	tfArgs := append([]string{"state"}, args...)
+	command := strings.Join(tfArgs[:2], " ")
+	if args[0] == "import" {
+		command = "import"
+
+		err = writeInputs(tempDir, input)
+		if err != nil {
+			return "", err
+		}
+		defer os.Remove(filepath.Join(tempDir, inputsFileName))
+
+		tfArgs = []string{"import", "-input=false"}
+		for _, varFile := range varFiles {
+			tfArgs = append(tfArgs, "-var-file", varFile)
+		}
+		tfArgs = append(tfArgs, "-var-file", inputsFileName)
+		tfArgs = append(tfArgs, args[1:]...)
+	}
+
+	err = e.cmd.Run(stdout, tempDir, tfArgs, true)
+	if err != nil {
+		return "", fmt.Errorf("terraform %s: %s", command, err)
+	}
+
+	return e.readTFState(tempDir)
 }
 
 func (e Executor) Version() (string, error) {
@@ -191,17 +372,18 @@ func (e Executor) Version() (string, error) {
 }
 
 func (e Executor) Output(tfState, outputName string) (string, error) {
-	templateDir, err := tempDir("", "")
+	templateDir, err := e.dir("outputs")
 	if err != nil {
 		return "", err
 	}
+	defer e.clean(templateDir)
 
-	err = writeFile(filepath.Join(templateDir, "terraform.tfstate"), []byte(tfState), os.ModePerm)
+	err = writeFile(filepath.Join(templateDir, "terraform.tfstate"), []byte(tfState), 0600)
 	if err != nil {
 		return "", err
 	}
 
-	err = e.cmd.Run(os.Stdout, templateDir, []string{"init"}, e.debug)
+	err = e.init(templateDir, []string{"init"}, e.debug)
 	if err != nil {
 		return "", err
 	}
@@ -217,17 +399,18 @@ func (e Executor) Output(tfState, outputName string) (string, error) {
 }
 
 func (e Executor) Outputs(tfState string) (map[string]interface{}, error) {
-	templateDir, err := tempDir("", "")
+	templateDir, err := e.dir("outputs")
 	if err != nil {
 		return map[string]interface{}{}, err
 	}
+	defer e.clean(templateDir)
 
-	err = writeFile(filepath.Join(templateDir, "terraform.tfstate"), []byte(tfState), os.ModePerm)
+	err = writeFile(filepath.Join(templateDir, "terraform.tfstate"), []byte(tfState), 0600)
 	if err != nil {
 		return map[string]interface{}{}, err
 	}
 
-	err = e.cmd.Run(os.Stdout, templateDir, []string{"init"}, false)
+	err = e.init(templateDir, []string{"init"}, false)
 	if err != nil {
 		return map[string]interface{}{}, err
 	}
@@ -254,6 +437,217 @@ func (e Executor) Outputs(tfState string) (map[string]interface{}, error) {
 	return outputs, nil
 }
 
-func makeVar(name string, value string) []string {
-	return []string{"-var", fmt.Sprintf("%s=%s", name, value)}
+// dir returns the directory to run terraform in, subdir of the working
+// directory or a new temp dir.
+func (e Executor) dir(subdir string) (string, error) {
+	if e.workingDir == "" {
+		return tempDir("", "")
+	}
+
+	dir := filepath.Join(e.workingDir, subdir)
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return "", err
+	}
+
+	return dir, nil
+}
+
+// init runs terraform init in dir unless it was already initialized with the
+// same configuration.
+func (e Executor) init(dir string, args []string, debug bool) error {
+	if e.workingDir == "" {
+		return e.cmd.Run(os.Stdout, dir, args, debug)
+	}
+
+	sha, err := configSHA256(dir)
+	if err != nil {
+		return err
+	}
+
+	initSHAPath := filepath.Join(dir, ".terraform", pluginsDir, initSHA256FileName)
+	previousSHA, err := readFile(initSHAPath)
+	if err == nil && string(previousSHA) == sha {
+		return nil
+	}
+
+	err = e.cmd.Run(os.Stdout, dir, args, debug)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(initSHAPath), 0700)
+	if err != nil {
+		return err
+	}
+
+	return writeFile(initSHAPath, []byte(sha), 0600)
+}
+
+// clean removes everything but the provider plugins from a directory in the
+// working directory once a run is over, so that the terraform state, the
+// inputs and the templates, which hold credentials, are not left on disk.
+// The backend configuration terraform init keeps is removed too, so the next
+// run initializes the directory again when there is a backend.
+func (e Executor) clean(dir string) error {
+	if e.workingDir == "" {
+		return nil
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.Name() == ".terraform" {
+			continue
+		}
+
+		err = os.RemoveAll(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+	}
+
+	initDir := filepath.Join(dir, ".terraform")
+	entries, err = ioutil.ReadDir(initDir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.Name() == pluginsDir {
+			continue
+		}
+
+		if entry.Name() == "terraform.tfstate" {
+			err = os.Remove(filepath.Join(initDir, pluginsDir, initSHA256FileName))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+
+		err = os.RemoveAll(filepath.Join(initDir, entry.Name()))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// keepTFState returns the path of the terraform state a failed run left in
+// dir. In the working directory the state is removed once the run is over,
+// so it is copied to a temp dir for the error to read it from.
+func (e Executor) keepTFState(dir string) string {
+	path := filepath.Join(dir, "terraform.tfstate")
+	if e.workingDir == "" {
+		return path
+	}
+
+	tfState, err := readFile(path)
+	if err != nil {
+		return path
+	}
+
+	keepDir, err := tempDir("", "")
+	if err != nil {
+		return path
+	}
+
+	keepPath := filepath.Join(keepDir, "terraform.tfstate")
+	err = writeFile(keepPath, tfState, 0600)
+	if err != nil {
+		return path
+	}
+
+	return keepPath
+}
+
+// readTFState reads the terraform state left in dir by a run. When the state
+// is kept in a backend there is no local copy and it is pulled instead.
+func (e Executor) readTFState(dir string) (string, error) {
+	tfState, err := readFile(filepath.Join(dir, "terraform.tfstate"))
+	if err == nil {
+		return string(tfState), nil
+	}
+
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	buffer := bytes.NewBuffer([]byte{})
+	err = e.cmd.Run(buffer, dir, []string{"state", "pull"}, true)
+	if err != nil {
+		return "", err
+	}
+
+	return buffer.String(), nil
+}
+
+// writeTFState replaces the local terraform state in dir. An empty state
+// removes the copy a previous run left in the working directory.
+func (e Executor) writeTFState(dir, tfState string) error {
+	path := filepath.Join(dir, "terraform.tfstate")
+	if tfState == "" {
+		if e.workingDir == "" {
+			return nil
+		}
+
+		err := os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	return writeFile(path, []byte(tfState), 0600)
+}
+
+// configSHA256 identifies what terraform init depends on: the templates in
+// dir, which declare the providers and backend, and whether there is a local
+// state to move into a backend.
+func configSHA256(dir string) (string, error) {
+	templates, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	for _, template := range templates {
+		contents, err := readFile(template)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "%s\n%d\n", filepath.Base(template), len(contents))
+		hash.Write(contents)
+	}
+
+	_, err = os.Stat(filepath.Join(dir, "terraform.tfstate"))
+	fmt.Fprintf(hash, "tfstate=%t", err == nil)
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// writeInputs writes the inputs to a var file only the user can read. Inputs
+// holding a JSON list or map, such as availability_zones, are written as
+// such so that terraform reads them as list and map variables.
+func writeInputs(dir string, input map[string]string) error {
+	vars := map[string]interface{}{}
+	for name, value := range input {
+		vars[name] = value
+		if strings.HasPrefix(value, "[") || strings.HasPrefix(value, "{") {
+			if json.Valid([]byte(value)) {
+				vars[name] = json.RawMessage(value)
+			}
+		}
+	}
+
+	contents, err := json.Marshal(vars)
+	if err != nil {
+		return err
+	}
+
+	return writeFile(filepath.Join(dir, inputsFileName), contents, 0600)
 }
@@ -1,6 +1,7 @@
 package terraform_test
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
@@ -10,7 +11,6 @@ import (
 	"strings"
 
 	"github.com/cloudfoundry/bosh-bootloader/fakes"
-	"github.com/cloudfoundry/bosh-bootloader/storage"
 	"github.com/cloudfoundry/bosh-bootloader/terraform"
 
 	. "github.com/onsi/ginkgo"
@@ -29,7 +29,7 @@ var _ = Describe("Executor", func() {
 	BeforeEach(func() {
 		cmd = &fakes.TerraformCmd{}
 
-		executor = terraform.NewExecutor(cmd, true)
+		executor = terraform.NewExecutor(cmd, "", "", true)
 
 		var err error
 		tempDir, err = ioutil.TempDir("", "")
@@ -70,6 +70,10 @@ var _ = Describe("Executor", func() {
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(string(fileContents)).To(Equal("some-template"))
+
+			info, err := os.Stat(filepath.Join(tempDir, "template.tf"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode()).To(Equal(os.FileMode(0600)))
 		})
 
 		It("passes the correct args and dir to run command", func() {
@@ -77,20 +81,90 @@ var _ = Describe("Executor", func() {
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(cmd.RunCall.Receives.WorkingDirectory).To(Equal(tempDir))
-			Expect(cmd.RunCall.Receives.Args).To(ConsistOf([]string{
+			Expect(cmd.RunCall.Receives.Args).To(Equal([]string{
 				"apply",
-				"-var", "project_id=some-project-id",
-				"-var", "env_id=some-env-id",
-				"-var", "region=some-region",
-				"-var", "zone=some-zone",
-				"-var", "ssl_certificate=some/certificate/path",
-				"-var", "ssl_certificate_private_key=some/key/path",
-				"-var", "credentials=some/credentials/path",
-				"-var", "system_domain=some-domain",
+				"-var-file", "terraform.tfvars.json",
 			}))
 			Expect(cmd.RunCall.Receives.Debug).To(BeTrue())
 		})
 
+		It("passes the inputs in a var file only the user can read", func() {
+			var (
+				inputsContents string
+				inputsMode     os.FileMode
+			)
+			cmd.RunCall.Stub = func(stdout io.Writer) {
+				inputsPath := filepath.Join(tempDir, "terraform.tfvars.json")
+				if info, err := os.Stat(inputsPath); err == nil {
+					inputsMode = info.Mode()
+					contents, err := ioutil.ReadFile(inputsPath)
+					Expect(err).NotTo(HaveOccurred())
+					inputsContents = string(contents)
+				}
+			}
+
+			input := map[string]string{
+				"env_id":             "some-env-id",
+				"access_key":         "some-access-key",
+				"secret_key":         "some-secret-key",
+				"availability_zones": `["z1","z2"]`,
+			}
+			_, err := executor.Apply(input, "some-template", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(inputsContents).To(MatchJSON(`{
+				"env_id": "some-env-id",
+				"access_key": "some-access-key",
+				"secret_key": "some-secret-key",
+				"availability_zones": ["z1", "z2"]
+			}`))
+			Expect(inputsMode).To(Equal(os.FileMode(0600)))
+			Expect(filepath.Join(tempDir, "terraform.tfvars.json")).NotTo(BeAnExistingFile())
+
+			for _, value := range input {
+				for _, arg := range cmd.RunCall.Receives.Args {
+					Expect(arg).NotTo(ContainSubstring(value))
+				}
+			}
+		})
+
+		It("applies the terraform overrides with the template", func() {
+			overridesDir, err := ioutil.TempDir("", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			err = ioutil.WriteFile(filepath.Join(overridesDir, "bucket.tf"), []byte(`resource "aws_s3_bucket" "bucket" {}`), os.ModePerm)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = ioutil.WriteFile(filepath.Join(overridesDir, "bucket.tfvars"), []byte(`bucket_name = "some-bucket"`), os.ModePerm)
+			Expect(err).NotTo(HaveOccurred())
+
+			executor = terraform.NewExecutor(cmd, overridesDir, "", true)
+
+			_, err = executor.Apply(map[string]string{"env_id": "some-env-id"}, "some-template", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(tempDir, "user-bucket.tf")).To(BeARegularFile())
+			Expect(cmd.RunCall.Receives.Args).To(Equal([]string{
+				"apply",
+				"-var-file", "user-bucket.tfvars",
+				"-var-file", "terraform.tfvars.json",
+			}))
+		})
+
+		It("returns an error when the overrides redefine bbl resources", func() {
+			overridesDir, err := ioutil.TempDir("", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			err = ioutil.WriteFile(filepath.Join(overridesDir, "network.tf"), []byte(`resource "aws_vpc" "vpc" {}`), os.ModePerm)
+			Expect(err).NotTo(HaveOccurred())
+
+			executor = terraform.NewExecutor(cmd, overridesDir, "", true)
+
+			_, err = executor.Apply(input, `resource "aws_vpc" "vpc" {}`, "")
+			Expect(err).To(MatchError("terraform-overrides/network.tf redefines aws_vpc.vpc, which is managed by bbl"))
+			Expect(cmd.RunCall.CallCount).To(Equal(0))
+		})
+
 		It("reads and returns the terraform state written by the command", func() {
 			var actualFilename string
 
@@ -217,7 +291,7 @@ var _ = Describe("Executor", func() {
 
 			Context("when --debug is false", func() {
 				BeforeEach(func() {
-					executor = terraform.NewExecutor(cmd, false)
+					executor = terraform.NewExecutor(cmd, "", "", false)
 				})
 
 				It("returns an error and the current tf state when it fails to call terraform command run", func() {
@@ -256,17 +330,10 @@ var _ = Describe("Executor", func() {
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(cmd.RunCall.Receives.WorkingDirectory).To(Equal(tempDir))
-			Expect(cmd.RunCall.Receives.Args).To(ConsistOf([]string{
+			Expect(cmd.RunCall.Receives.Args).To(Equal([]string{
 				"destroy",
 				"-force",
-				"-var", "project_id=some-project-id",
-				"-var", "env_id=some-env-id",
-				"-var", "region=some-region",
-				"-var", "zone=some-zone",
-				"-var", "ssl_certificate=some/certificate/path",
-				"-var", "ssl_certificate_private_key=some/key/path",
-				"-var", "credentials=some/credentials/path",
-				"-var", "system_domain=some-domain",
+				"-var-file", "terraform.tfvars.json",
 			}))
 			Expect(cmd.RunCall.Receives.Debug).To(BeTrue())
 		})
@@ -350,7 +417,7 @@ var _ = Describe("Executor", func() {
 
 			Context("when --debug is false", func() {
 				BeforeEach(func() {
-					executor = terraform.NewExecutor(cmd, false)
+					executor = terraform.NewExecutor(cmd, "", "", false)
 				})
 
 				It("returns an error and the current tf state when it fails to call terraform command run", func() {
@@ -369,162 +436,156 @@ var _ = Describe("Executor", func() {
 		})
 	})
 
-	Describe("Import", func() {
-		var (
-			receivedTFState    string
-			receivedTFTemplate string
-		)
-
-		BeforeEach(func() {
+	Describe("Plan", func() {
+		It("writes the template and tf state and returns the plan output", func() {
 			cmd.RunCall.Stub = func(stdout io.Writer) {
-				fileContents, err := ioutil.ReadFile(filepath.Join(tempDir, "terraform.tfstate"))
-				Expect(err).NotTo(HaveOccurred())
-				receivedTFState = string(fileContents)
+				fmt.Fprintf(stdout, "Plan: 1 to add, 0 to change, 0 to destroy.")
+			}
 
-				fileContents, err = ioutil.ReadFile(filepath.Join(tempDir, "template.tf"))
-				Expect(err).NotTo(HaveOccurred())
-				receivedTFTemplate = string(fileContents)
+			output, err := executor.Plan(input, "some-template", "some-tf-state")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output).To(Equal("Plan: 1 to add, 0 to change, 0 to destroy."))
 
-				err = ioutil.WriteFile(filepath.Join(tempDir, "terraform.tfstate"), []byte("some-other-tfstate"), os.ModePerm)
-				Expect(err).NotTo(HaveOccurred())
-			}
+			template, err := ioutil.ReadFile(filepath.Join(tempDir, "template.tf"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(template)).To(Equal("some-template"))
 
-			terraform.ResetReadFile()
+			tfState, err := ioutil.ReadFile(filepath.Join(tempDir, "terraform.tfstate"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(tfState)).To(Equal("some-tf-state"))
 		})
 
-		It("writes the tfState to a file", func() {
-			_, err := executor.Import(terraform.ImportInput{
-				TerraformAddr: "some-resource-type.some-addr",
-				AWSResourceID: "some-id",
-				TFState:       "some-tf-state",
-				Creds:         storage.AWS{},
-			})
+		It("passes the correct args and dir to run command", func() {
+			_, err := executor.Plan(input, "some-template", "")
 			Expect(err).NotTo(HaveOccurred())
 
-			Expect(receivedTFState).To(Equal("some-tf-state"))
+			Expect(cmd.RunCall.Receives.WorkingDirectory).To(Equal(tempDir))
+			Expect(cmd.RunCall.Receives.Args).To(Equal([]string{
+				"plan", "-no-color", "-input=false",
+				"-var-file", "terraform.tfvars.json",
+			}))
 		})
 
-		It("writes a terraform template to a file", func() {
-			_, err := executor.Import(terraform.ImportInput{
-				TerraformAddr: "some-resource-type.some-addr[i]",
-				AWSResourceID: "some-id",
-				TFState:       "some-tf-state",
-				Creds: storage.AWS{
-					Region:          "some-region",
-					AccessKeyID:     "some-access-key",
-					SecretAccessKey: "some-secret",
-				},
+		Context("failure cases", func() {
+			It("returns an error when terraform init fails", func() {
+				cmd.RunCall.Returns.Errors = []error{errors.New("failed to initialize terraform")}
+
+				_, err := executor.Plan(input, "some-template", "")
+				Expect(err).To(MatchError("failed to initialize terraform"))
 			})
-			Expect(err).NotTo(HaveOccurred())
 
-			Expect(receivedTFTemplate).To(Equal(`
-provider "aws" {
-	region     = "some-region"
-	access_key = "some-access-key"
-	secret_key = "some-secret"
-}
+			It("returns an error with the plan output when terraform plan fails", func() {
+				cmd.RunCall.Stub = func(stdout io.Writer) {
+					fmt.Fprintf(stdout, "some plan error")
+				}
+				cmd.RunCall.Returns.Errors = []error{nil, errors.New("exit status 1")}
 
-resource "some-resource-type" "some-addr" {
-}`))
+				_, err := executor.Plan(input, "some-template", "")
+				Expect(err).To(MatchError("terraform plan: exit status 1\nsome plan error"))
+			})
 		})
+	})
 
-		It("shells out to terraform import and returns the tfState", func() {
-			tfState, err := executor.Import(terraform.ImportInput{
-				TerraformAddr: "some-resource-type.some-addr",
-				AWSResourceID: "some-id",
-				TFState:       "some-tf-state",
-				Creds:         storage.AWS{},
+	Describe("Refresh", func() {
+		It("refreshes the tf state and returns the refreshed state", func() {
+			terraform.SetReadFile(func(filename string) ([]byte, error) {
+				Expect(filename).To(Equal(filepath.Join(tempDir, "terraform.tfstate")))
+				return []byte("some-refreshed-tf-state"), nil
 			})
+
+			tfState, err := executor.Refresh(input, "some-template", "some-tf-state")
 			Expect(err).NotTo(HaveOccurred())
+			Expect(tfState).To(Equal("some-refreshed-tf-state"))
 
-			Expect(cmd.RunCall.Receives.Args).To(Equal([]string{"import", "some-resource-type.some-addr", "some-id"}))
-			Expect(cmd.RunCall.Receives.Debug).To(BeTrue())
-			Expect(tfState).To(Equal("some-other-tfstate"))
+			template, err := ioutil.ReadFile(filepath.Join(tempDir, "template.tf"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(template)).To(Equal("some-template"))
+
+			Expect(cmd.RunCall.Receives.WorkingDirectory).To(Equal(tempDir))
+			Expect(cmd.RunCall.Receives.Args).To(Equal([]string{
+				"refresh", "-no-color", "-input=false",
+				"-var-file", "terraform.tfvars.json",
+			}))
 		})
 
-		Context("when an error occurs", func() {
-			Context("when it fails to create a temp dir", func() {
-				It("returns an error", func() {
-					terraform.SetTempDir(func(dir, prefix string) (string, error) {
-						return "", errors.New("failed to make temp dir")
-					})
-					_, err := executor.Import(terraform.ImportInput{
-						TerraformAddr: "some-resource-type.some-addr",
-						AWSResourceID: "some-id",
-						TFState:       "some-tf-state",
-						Creds:         storage.AWS{},
-					})
-					Expect(err).To(MatchError("failed to make temp dir"))
-				})
-			})
+		It("returns the terraform output when refresh fails", func() {
+			cmd.RunCall.Stub = func(stdout io.Writer) {
+				fmt.Fprintf(stdout, "some-refresh-output")
+			}
+			cmd.RunCall.Returns.Errors = []error{nil, errors.New("exit status 1")}
 
-			Context("when the file cannot be written", func() {
-				It("returns an error", func() {
-					terraform.SetWriteFile(func(file string, data []byte, perm os.FileMode) error {
-						if strings.Contains(file, "terraform.tfstate") {
-							return errors.New("failed to write tfstate")
-						}
+			_, err := executor.Refresh(input, "some-template", "some-tf-state")
+			Expect(err).To(MatchError("terraform refresh: exit status 1\nsome-refresh-output"))
+		})
+	})
 
-						return nil
-					})
+	Describe("PullState", func() {
+		It("initializes the backend and pulls the tf state", func() {
+			cmd.RunCall.Stub = func(stdout io.Writer) {
+				fmt.Fprintf(stdout, "some-tf-state")
+			}
 
-					_, err := executor.Import(terraform.ImportInput{
-						TerraformAddr: "some-resource-type.some-addr",
-						AWSResourceID: "some-id",
-						TFState:       "some-tf-state",
-						Creds:         storage.AWS{},
-					})
-					Expect(err).To(MatchError("failed to write tfstate"))
-				})
-			})
+			tfState, err := executor.PullState("some-backend-template")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tfState).To(Equal("some-tf-state"))
 
-			Context("when terraform init fails", func() {
-				It("returns an error", func() {
-					cmd.RunCall.Returns.Errors = []error{errors.New("failed to initialize terraform")}
+			backend, err := ioutil.ReadFile(filepath.Join(tempDir, "backend.tf"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(backend)).To(Equal("some-backend-template"))
 
-					_, err := executor.Import(terraform.ImportInput{
-						TerraformAddr: "some-resource-type.some-addr",
-						AWSResourceID: "some-id",
-						TFState:       "some-tf-state",
-						Creds:         storage.AWS{},
-					})
-					Expect(err).To(MatchError("failed to initialize terraform"))
-				})
-			})
+			Expect(cmd.RunCall.Receives.Args).To(Equal([]string{"state", "pull"}))
+		})
 
-			Context("when the command fails to run", func() {
-				It("returns an error", func() {
-					cmd.RunCall.Returns.Errors = []error{nil, errors.New("bad import")}
+		It("returns an error when terraform init fails", func() {
+			cmd.RunCall.Returns.Errors = []error{errors.New("failed to initialize terraform")}
 
-					_, err := executor.Import(terraform.ImportInput{
-						TerraformAddr: "some-resource-type.some-addr",
-						AWSResourceID: "some-id",
-						TFState:       "some-tf-state",
-						Creds:         storage.AWS{},
-					})
-					Expect(err).To(MatchError("failed to import: bad import"))
-				})
+			_, err := executor.PullState("some-backend-template")
+			Expect(err).To(MatchError("failed to initialize terraform"))
+		})
+	})
+
+	Describe("StateCommand", func() {
+		It("runs the terraform state subcommand against the tf state", func() {
+			terraform.SetReadFile(func(filename string) ([]byte, error) {
+				Expect(filename).To(Equal(filepath.Join(tempDir, "terraform.tfstate")))
+				return []byte("some-updated-tf-state"), nil
 			})
+			stdout := bytes.NewBuffer([]byte{})
+
+			tfState, err := executor.StateCommand(input, "some-template", "some-tf-state", []string{"rm", "some.address"}, stdout)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tfState).To(Equal("some-updated-tf-state"))
 
-			Context("when the state cannot be read", func() {
-				It("returns an error", func() {
-					terraform.SetReadFile(func(filePath string) ([]byte, error) {
-						if strings.Contains(filePath, "terraform.tfstate") {
-							return []byte{}, errors.New("failed to read tf state file")
-						}
+			template, err := ioutil.ReadFile(filepath.Join(tempDir, "template.tf"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(template)).To(Equal("some-template"))
 
-						return []byte{}, nil
-					})
+			prevTFState, err := ioutil.ReadFile(filepath.Join(tempDir, "terraform.tfstate"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(prevTFState)).To(Equal("some-tf-state"))
 
-					_, err := executor.Import(terraform.ImportInput{
-						TerraformAddr: "some-resource-type.some-addr",
-						AWSResourceID: "some-id",
-						TFState:       "some-tf-state",
-						Creds:         storage.AWS{},
-					})
-					Expect(err).To(MatchError("failed to read tf state file"))
-				})
-			})
+			Expect(cmd.RunCall.Receives.Stdout).To(Equal(stdout))
+			Expect(cmd.RunCall.Receives.WorkingDirectory).To(Equal(tempDir))
+			Expect(cmd.RunCall.Receives.Args).To(Equal([]string{"state", "rm", "some.address"}))
+		})
+
+		It("runs terraform import with the inputs", func() {
+			_, err := executor.StateCommand(input, "some-template", "some-tf-state", []string{"import", "some.address", "some-id"}, ioutil.Discard)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(cmd.RunCall.Receives.Args).To(Equal([]string{
+				"import", "-input=false",
+				"-var-file", "terraform.tfvars.json",
+				"some.address", "some-id",
+			}))
+			Expect(filepath.Join(tempDir, "terraform.tfvars.json")).NotTo(BeAnExistingFile())
+		})
+
+		It("returns an error when the terraform command fails", func() {
+			cmd.RunCall.Returns.Errors = []error{nil, errors.New("exit status 1")}
+
+			_, err := executor.StateCommand(input, "some-template", "some-tf-state", []string{"mv", "a", "b"}, ioutil.Discard)
+			Expect(err).To(MatchError("terraform state mv: exit status 1"))
 		})
 	})
 
@@ -692,4 +753,173 @@ resource "some-resource-type" "some-addr" {
 			})
 		})
 	})
+
+	Describe("with a working directory", func() {
+		var (
+			workingDir string
+			applyDir   string
+		)
+
+		BeforeEach(func() {
+			var err error
+			workingDir, err = ioutil.TempDir("", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			applyDir = filepath.Join(workingDir, "apply")
+			err = os.MkdirAll(applyDir, os.ModePerm)
+			Expect(err).NotTo(HaveOccurred())
+
+			terraform.ResetReadFile()
+			terraform.SetTempDir(func(dir, prefix string) (string, error) {
+				return "", errors.New("should not create a temp dir")
+			})
+
+			executor = terraform.NewExecutor(cmd, "", workingDir, true)
+		})
+
+		It("runs terraform init only when the configuration changed", func() {
+			_, err := executor.Apply(input, "some-template", "some-tf-state")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cmd.RunCall.CallCount).To(Equal(2))
+			Expect(cmd.RunCall.Receives.WorkingDirectory).To(Equal(applyDir))
+
+			_, err = executor.Apply(input, "some-template", "some-tf-state")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cmd.RunCall.CallCount).To(Equal(3))
+
+			_, err = executor.Apply(input, "some-other-template", "some-tf-state")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cmd.RunCall.CallCount).To(Equal(5))
+		})
+
+		It("removes the tf state left by an earlier run and pulls the state from the backend", func() {
+			err := ioutil.WriteFile(filepath.Join(applyDir, "terraform.tfstate"), []byte("some-stale-tf-state"), os.ModePerm)
+			Expect(err).NotTo(HaveOccurred())
+
+			cmd.RunCall.Stub = func(stdout io.Writer) {
+				fmt.Fprintf(stdout, "some-backend-tf-state")
+			}
+
+			tfState, err := executor.Apply(input, "some-template", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tfState).To(Equal("some-backend-tf-state"))
+
+			Expect(filepath.Join(applyDir, "terraform.tfstate")).NotTo(BeAnExistingFile())
+			Expect(cmd.RunCall.Receives.Args).To(Equal([]string{"state", "pull"}))
+		})
+
+		It("replaces the override files of an earlier run", func() {
+			err := ioutil.WriteFile(filepath.Join(applyDir, "user-removed.tf"), []byte(`resource "aws_s3_bucket" "removed" {}`), os.ModePerm)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = executor.Apply(input, "some-template", "some-tf-state")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(applyDir, "user-removed.tf")).NotTo(BeAnExistingFile())
+		})
+
+		It("leaves only the provider plugins in the working directory after a run", func() {
+			cmd.RunCall.Stub = func(stdout io.Writer) {
+				for _, path := range []string{
+					filepath.Join(".terraform", "plugins", "terraform-provider-aws"),
+					filepath.Join(".terraform", "modules", "some-module"),
+					"terraform.tfstate.backup",
+				} {
+					err := os.MkdirAll(filepath.Dir(filepath.Join(applyDir, path)), os.ModePerm)
+					Expect(err).NotTo(HaveOccurred())
+					err = ioutil.WriteFile(filepath.Join(applyDir, path), []byte("some-contents"), os.ModePerm)
+					Expect(err).NotTo(HaveOccurred())
+				}
+			}
+
+			tfState, err := executor.Apply(input, "some-template", "some-tf-state")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tfState).To(Equal("some-tf-state"))
+
+			entries, err := ioutil.ReadDir(applyDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Name()).To(Equal(".terraform"))
+
+			entries, err = ioutil.ReadDir(filepath.Join(applyDir, ".terraform"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Name()).To(Equal("plugins"))
+			Expect(filepath.Join(applyDir, ".terraform", "plugins", "terraform-provider-aws")).To(BeAnExistingFile())
+		})
+
+		It("runs terraform init again when the backend configuration was removed", func() {
+			cmd.RunCall.Stub = func(stdout io.Writer) {
+				err := os.MkdirAll(filepath.Join(applyDir, ".terraform"), os.ModePerm)
+				Expect(err).NotTo(HaveOccurred())
+				err = ioutil.WriteFile(filepath.Join(applyDir, ".terraform", "terraform.tfstate"), []byte("some-backend-config"), os.ModePerm)
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			_, err := executor.Apply(input, "some-template", "some-tf-state")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cmd.RunCall.CallCount).To(Equal(2))
+			Expect(filepath.Join(applyDir, ".terraform", "terraform.tfstate")).NotTo(BeAnExistingFile())
+
+			_, err = executor.Apply(input, "some-template", "some-tf-state")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cmd.RunCall.CallCount).To(Equal(4))
+		})
+
+		It("keeps the terraform state of a failed run readable from the error", func() {
+			keepDir, err := ioutil.TempDir("", "")
+			Expect(err).NotTo(HaveOccurred())
+			terraform.SetTempDir(func(dir, prefix string) (string, error) {
+				return keepDir, nil
+			})
+
+			cmd.RunCall.Stub = func(stdout io.Writer) {
+				if cmd.RunCall.CallCount == 2 {
+					err := ioutil.WriteFile(filepath.Join(applyDir, "terraform.tfstate"), []byte("some-partial-tf-state"), os.ModePerm)
+					Expect(err).NotTo(HaveOccurred())
+				}
+			}
+			cmd.RunCall.Returns.Errors = []error{nil, errors.New("failed to apply")}
+
+			_, err = executor.Apply(input, "some-template", "some-tf-state")
+			Expect(err).To(BeAssignableToTypeOf(terraform.ExecutorError{}))
+			Expect(filepath.Join(applyDir, "terraform.tfstate")).NotTo(BeAnExistingFile())
+
+			tfState, err := err.(terraform.ExecutorError).TFState()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tfState).To(Equal("some-partial-tf-state"))
+		})
+
+		It("reads outputs in a directory of their own", func() {
+			cmd.RunCall.Stub = func(stdout io.Writer) {
+				fmt.Fprintf(stdout, "{}")
+			}
+
+			_, err := executor.Outputs("some-tf-state")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(cmd.RunCall.Receives.WorkingDirectory).To(Equal(filepath.Join(workingDir, "outputs")))
+		})
+
+		It("does not run terraform init for outputs again after an apply", func() {
+			cmd.RunCall.Stub = func(stdout io.Writer) {
+				if cmd.RunCall.Receives.Args[0] == "output" {
+					fmt.Fprintf(stdout, "{}")
+				}
+			}
+
+			_, err := executor.Outputs("some-tf-state")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cmd.RunCall.CallCount).To(Equal(2))
+
+			_, err = executor.Apply(input, "some-template", "some-tf-state")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cmd.RunCall.CallCount).To(Equal(4))
+
+			_, err = executor.Outputs("some-tf-state")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cmd.RunCall.CallCount).To(Equal(5))
+			Expect(cmd.RunCall.Receives.Args).To(Equal([]string{"output", "--json"}))
+		})
+	})
 })
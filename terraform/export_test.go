@@ -3,6 +3,9 @@ package terraform
 import (
 	"io/ioutil"
 	"os"
+	"time"
+
+	"github.com/cloudfoundry/bosh-bootloader/helpers"
 )
 
 func SetTempDir(f func(dir, prefix string) (string, error)) {
@@ -10,7 +13,7 @@ func SetTempDir(f func(dir, prefix string) (string, error)) {
 }
 
 func ResetTempDir() {
-	tempDir = ioutil.TempDir
+	tempDir = helpers.TempDir
 }
 
 func SetWriteFile(f func(file string, data []byte, perm os.FileMode) error) {
@@ -28,3 +31,19 @@ func SetReadFile(f func(filename string) ([]byte, error)) {
 func ResetReadFile() {
 	readFile = ioutil.ReadFile
 }
+
+func SetUserCacheDir(f func() (string, error)) {
+	userCacheDir = f
+}
+
+func ResetUserCacheDir() {
+	userCacheDir = os.UserCacheDir
+}
+
+func SetSleep(f func(time.Duration)) {
+	sleep = f
+}
+
+func ResetSleep() {
+	sleep = time.Sleep
+}
@@ -0,0 +1,66 @@
+package terraform
+
+import (
+	"regexp"
+	"time"
+)
+
+// FailureClass is the category ClassifyFailure puts a failed terraform run
+// into. Only the transient classes are retried.
+type FailureClass string
+
+const (
+	FailureFatal       FailureClass = "fatal"
+	FailureRateLimited FailureClass = "rate limited"
+	FailureNotReady    FailureClass = "resource not ready"
+)
+
+var sleep = time.Sleep
+
+// fatalFailures cannot succeed on a retry, even when the output also
+// mentions a transient error.
+var fatalFailures = regexp.MustCompile(`AuthFailure|UnauthorizedOperation|InvalidClientTokenId|SignatureDoesNotMatch|AuthorizationFailed|quotaExceeded|QUOTA_EXCEEDED|Error loading|Error parsing`)
+
+var transientFailures = []struct {
+	class   FailureClass
+	pattern *regexp.Regexp
+}{
+	{
+		class:   FailureRateLimited,
+		pattern: regexp.MustCompile(`RequestLimitExceeded|Throttling|Rate exceeded|TooManyRequests|rateLimitExceeded|Error 429`),
+	},
+	{
+		// IAM roles and instance profiles take a while to be usable on AWS,
+		// and GCP and Azure reject changes to resources that are still busy.
+		class:   FailureNotReady,
+		pattern: regexp.MustCompile(`Invalid IAM Instance Profile|iamInstanceProfile\.name is invalid|InvalidInstanceID\.NotFound|DependencyViolation|resourceNotReady|is not ready|AnotherOperationInProgress|RetryableError`),
+	},
+}
+
+// RetryPolicy is how often and how long apart failed terraform runs are
+// retried when their failure is transient.
+type RetryPolicy struct {
+	Retries int
+	Backoff time.Duration
+}
+
+// ClassifyFailure returns the class of the failure in the output of a
+// terraform run. Failures it does not recognize are fatal.
+func ClassifyFailure(output string) FailureClass {
+	if fatalFailures.MatchString(output) {
+		return FailureFatal
+	}
+
+	for _, failure := range transientFailures {
+		if failure.pattern.MatchString(output) {
+			return failure.class
+		}
+	}
+
+	return FailureFatal
+}
+
+// backoff is the wait before the given retry, doubling with every retry.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	return p.Backoff * time.Duration(1<<uint(retry-1))
+}
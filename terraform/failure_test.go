@@ -0,0 +1,25 @@
+package terraform_test
+
+import (
+	"github.com/cloudfoundry/bosh-bootloader/terraform"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ClassifyFailure", func() {
+	DescribeTable("classifies the failure in the terraform output",
+		func(output string, expectedClass terraform.FailureClass) {
+			Expect(terraform.ClassifyFailure(output)).To(Equal(expectedClass))
+		},
+		Entry("aws rate limiting", "* aws_instance.nat: Error launching source instance: RequestLimitExceeded: Request limit exceeded.", terraform.FailureRateLimited),
+		Entry("gcp rate limiting", "googleapi: Error 403: Rate Limit Exceeded, rateLimitExceeded", terraform.FailureRateLimited),
+		Entry("aws iam eventual consistency", "Error launching source instance: InvalidParameterValue: Value (bosh) for parameter iamInstanceProfile.name is invalid. Invalid IAM Instance Profile name", terraform.FailureNotReady),
+		Entry("gcp resource not ready", "googleapi: Error 400: The resource 'projects/p/global/networks/n' is not ready, resourceNotReady", terraform.FailureNotReady),
+		Entry("azure operation in progress", "Code=\"AnotherOperationInProgress\" Message=\"Another operation on this or dependent resource is in progress.\"", terraform.FailureNotReady),
+		Entry("invalid credentials", "Error: AuthFailure: AWS was not able to validate the provided access credentials", terraform.FailureFatal),
+		Entry("a quota mentioned next to a transient error", "googleapi: Error 403: Quota 'CPUS' exceeded, quotaExceeded; resourceNotReady", terraform.FailureFatal),
+		Entry("unknown errors", "Error: something went wrong", terraform.FailureFatal),
+	)
+})
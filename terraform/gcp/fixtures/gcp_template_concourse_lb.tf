@@ -56,9 +56,14 @@ resource "google_compute_network" "bbl-network" {
   name		 = "${var.env_id}-network"
 }
 
+variable "network_cidr" {
+  type = "string"
+  default = "10.0.0.0/16"
+}
+
 resource "google_compute_subnetwork" "bbl-subnet" {
   name			= "${var.env_id}-subnet"
-  ip_cidr_range = "10.0.0.0/16"
+  ip_cidr_range = "${var.network_cidr}"
   network		= "${google_compute_network.bbl-network.self_link}"
 }
 
@@ -5,10 +5,11 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/cloudfoundry/bosh-bootloader/helpers"
 	"github.com/cloudfoundry/bosh-bootloader/storage"
 )
 
-var tempDir func(dir, prefix string) (string, error) = ioutil.TempDir
+var tempDir func(dir, prefix string) (string, error) = helpers.TempDir
 var writeFile func(file string, data []byte, perm os.FileMode) error = ioutil.WriteFile
 
 type InputGenerator struct {
@@ -19,13 +20,18 @@ func NewInputGenerator() InputGenerator {
 }
 
 func (i InputGenerator) Generate(state storage.State) (map[string]string, error) {
+	network, err := state.NetworkLayout()
+	if err != nil {
+		return map[string]string{}, err
+	}
+
 	dir, err := tempDir("", "")
 	if err != nil {
 		return map[string]string{}, err
 	}
 
 	credentialsPath := filepath.Join(dir, "credentials.json")
-	err = writeFile(credentialsPath, []byte(state.GCP.ServiceAccountKey), os.ModePerm)
+	err = writeFile(credentialsPath, []byte(state.GCP.ServiceAccountKey), 0600)
 	if err != nil {
 		return map[string]string{}, err
 	}
@@ -37,18 +43,23 @@ func (i InputGenerator) Generate(state storage.State) (map[string]string, error)
 		"zone":          state.GCP.Zone,
 		"credentials":   credentialsPath,
 		"system_domain": state.LB.Domain,
+		"network_cidr":  network.CIDR(),
+	}
+
+	if state.GCP.ExistingNetwork != "" {
+		input["existing_network"] = state.GCP.ExistingNetwork
 	}
 
 	if state.LB.Cert != "" && state.LB.Key != "" {
 		certPath := filepath.Join(dir, "cert")
-		err = writeFile(certPath, []byte(state.LB.Cert), os.ModePerm)
+		err = writeFile(certPath, []byte(state.LB.Cert), 0600)
 		if err != nil {
 			return map[string]string{}, err
 		}
 		input["ssl_certificate"] = certPath
 
 		keyPath := filepath.Join(dir, "key")
-		err = writeFile(keyPath, []byte(state.LB.Key), os.ModePerm)
+		err = writeFile(keyPath, []byte(state.LB.Key), 0600)
 		if err != nil {
 			return map[string]string{}, err
 		}
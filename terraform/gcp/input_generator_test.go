@@ -65,6 +65,7 @@ var _ = Describe("InputGenerator", func() {
 			"zone":          state.GCP.Zone,
 			"credentials":   filepath.Join(tempDir, "credentials.json"),
 			"system_domain": state.LB.Domain,
+			"network_cidr":  "10.0.0.0/16",
 		}))
 
 		credentials, err := ioutil.ReadFile(inputs["credentials"])
@@ -88,6 +89,7 @@ var _ = Describe("InputGenerator", func() {
 			"ssl_certificate":             filepath.Join(tempDir, "cert"),
 			"ssl_certificate_private_key": filepath.Join(tempDir, "key"),
 			"system_domain":               state.LB.Domain,
+			"network_cidr":                "10.0.0.0/16",
 		}))
 
 		sslCertificate, err := ioutil.ReadFile(inputs["ssl_certificate"])
@@ -99,6 +101,38 @@ var _ = Describe("InputGenerator", func() {
 		Expect(string(sslCertificatePrivateKey)).To(Equal("some-key"))
 	})
 
+	It("returns the network cidr when one is provided", func() {
+		state.Network = &storage.Network{CIDR: "172.16.0.0/16"}
+
+		inputs, err := inputGenerator.Generate(state)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(inputs["network_cidr"]).To(Equal("172.16.0.0/16"))
+	})
+
+	It("returns the existing network when one is provided", func() {
+		state.GCP.ExistingNetwork = "some-network"
+
+		inputs, err := inputGenerator.Generate(state)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(inputs["existing_network"]).To(Equal("some-network"))
+	})
+
+	It("writes the credentials and key so only the user can read them", func() {
+		state.LB.Cert = "some-cert"
+		state.LB.Key = "some-key"
+
+		inputs, err := inputGenerator.Generate(state)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, name := range []string{"credentials", "ssl_certificate", "ssl_certificate_private_key"} {
+			info, err := os.Stat(inputs[name])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode()).To(Equal(os.FileMode(0600)))
+		}
+	})
+
 	Context("failure cases", func() {
 		It("returns an error if temp dir cannot be created", func() {
 			gcp.SetTempDir(func(dir, prefix string) (string, error) {
@@ -20,6 +20,17 @@ const backendBase = `resource "google_compute_backend_service" "router-lb-backen
 }
 `
 
+const networkResource = "resource \"google_compute_network\" \"bbl-network\" {\n  name\t\t = \"${var.env_id}-network\"\n}\n"
+
+const existingNetworkData = `variable "existing_network" {
+  type = "string"
+}
+
+data "google_compute_network" "bbl-network" {
+  name = "${var.existing_network}"
+}
+`
+
 func NewTemplateGenerator() TemplateGenerator {
 	return TemplateGenerator{}
 }
@@ -40,6 +51,12 @@ func (t TemplateGenerator) Generate(state storage.State) string {
 			template = strings.Join([]string{template, CFDNSTemplate}, "\n")
 		}
 	}
+
+	if state.GCP.ExistingNetwork != "" {
+		template = strings.Replace(template, "google_compute_network.bbl-network", "data.google_compute_network.bbl-network", -1)
+		template = strings.Replace(template, networkResource, existingNetworkData, 1)
+	}
+
 	return template
 }
 
@@ -45,6 +45,28 @@ var _ = Describe("TemplateGenerator", func() {
 			Entry("when a cf lb type is provided", "fixtures/gcp_template_cf_lb.tf", "some-region", "cf", ""),
 			Entry("when a cf lb type is provided with a domain", "fixtures/gcp_template_cf_lb_dns.tf", "some-region", "cf", "some-domain"),
 		)
+
+		Context("when an existing network is provided", func() {
+			It("references the network instead of creating it", func() {
+				template := templateGenerator.Generate(storage.State{
+					GCP: storage.GCP{
+						Region:          "some-region",
+						Zones:           zones,
+						ExistingNetwork: "some-network",
+					},
+					LB: storage.LB{
+						Type: "cf",
+					},
+				})
+				Expect(template).To(ContainSubstring(`variable "existing_network"`))
+				Expect(template).To(ContainSubstring(`data "google_compute_network" "bbl-network"`))
+				Expect(template).To(ContainSubstring(`network		= "${data.google_compute_network.bbl-network.self_link}"`))
+				Expect(template).To(ContainSubstring(`depends_on = ["data.google_compute_network.bbl-network"]`))
+
+				Expect(template).NotTo(ContainSubstring(`resource "google_compute_network"`))
+				Expect(template).NotTo(ContainSubstring("${google_compute_network.bbl-network"))
+			})
+		})
 	})
 
 	Describe("GenerateBackendService", func() {
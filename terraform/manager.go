@@ -2,7 +2,11 @@ package terraform
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"errors"
+	"fmt"
+	"io"
+	"strings"
 
 	"github.com/cloudfoundry/bosh-bootloader/storage"
 	"github.com/coreos/go-semver/semver"
@@ -16,12 +20,17 @@ type Manager struct {
 	terraformOutputBuffer *bytes.Buffer
 	logger                logger
 	stackMigrator         stackMigrator
+	retryPolicy           RetryPolicy
 }
 
 type executor interface {
 	Version() (string, error)
 	Destroy(inputs map[string]string, terraformTemplate, tfState string) (string, error)
 	Apply(inputs map[string]string, terraformTemplate, tfState string) (string, error)
+	Plan(inputs map[string]string, terraformTemplate, tfState string) (string, error)
+	Refresh(inputs map[string]string, terraformTemplate, tfState string) (string, error)
+	PullState(backendTemplate string) (string, error)
+	StateCommand(inputs map[string]string, terraformTemplate, tfState string, args []string, stdout io.Writer) (string, error)
 }
 
 //go:generate counterfeiter -o ./fakes/stack_migrator.go --fake-name StackMigrator . stackMigrator
@@ -53,6 +62,7 @@ type NewManagerArgs struct {
 	TerraformOutputBuffer *bytes.Buffer
 	Logger                logger
 	StackMigrator         stackMigrator
+	RetryPolicy           RetryPolicy
 }
 
 func NewManager(args NewManagerArgs) Manager {
@@ -64,6 +74,7 @@ func NewManager(args NewManagerArgs) Manager {
 		terraformOutputBuffer: args.TerraformOutputBuffer,
 		logger:                args.Logger,
 		stackMigrator:         args.StackMigrator,
+		retryPolicy:           args.RetryPolicy,
 	}
 }
 
@@ -121,7 +132,7 @@ func (m Manager) Apply(bblState storage.State) (storage.State, error) {
 	}
 
 	m.logger.Step("generating terraform template")
-	template := m.templateGenerator.Generate(bblState)
+	template := m.template(bblState)
 
 	m.logger.Step("generating terraform variables")
 	input, err := m.inputGenerator.Generate(bblState)
@@ -129,14 +140,16 @@ func (m Manager) Apply(bblState storage.State) (storage.State, error) {
 		return storage.State{}, err
 	}
 
-	m.logger.Step("applying terraform template")
-	tfState, err := m.executor.Apply(
-		input,
-		template,
-		bblState.TFState,
-	)
+	if bblState.TFBackend != nil && bblState.TFState != "" {
+		m.logger.Step("moving terraform state into the %s backend", bblState.TFBackend.Type)
+	}
 
-	bblState.LatestTFOutput = readAndReset(m.terraformOutputBuffer)
+	bblState.TFOutputs = nil
+
+	m.logger.Step("applying terraform template")
+	tfState, err := m.runWithRetries("apply", &bblState, func(tfState string) (string, error) {
+		return m.executor.Apply(input, template, tfState)
+	})
 
 	switch err.(type) {
 	case executorError:
@@ -145,29 +158,59 @@ func (m Manager) Apply(bblState storage.State) (storage.State, error) {
 		return storage.State{}, err
 	}
 
-	bblState.TFState = tfState
+	bblState.TFState = embeddedTFState(bblState, tfState)
+	bblState.TFOutputs = m.cacheOutputs(tfState)
 	return bblState, nil
 }
 
+func (m Manager) Plan(bblState storage.State) (Plan, error) {
+	// Planning with the backend while the state is still embedded would
+	// move the state into the backend, so the move is left to apply.
+	if bblState.TFState != "" {
+		bblState.TFBackend = nil
+	}
+
+	m.logger.Step("generating terraform template")
+	template := m.template(bblState)
+
+	m.logger.Step("generating terraform variables")
+	input, err := m.inputGenerator.Generate(bblState)
+	if err != nil {
+		return Plan{}, err
+	}
+
+	m.logger.Step("planning terraform changes")
+	output, err := m.executor.Plan(input, template, bblState.TFState)
+
+	// The plan output is not the result of an apply, so it is not kept as
+	// the latest terraform output.
+	readAndReset(m.terraformOutputBuffer)
+
+	if err != nil {
+		return Plan{}, err
+	}
+
+	return ParsePlan(output)
+}
+
 func (m Manager) Destroy(bblState storage.State) (storage.State, error) {
 	m.logger.Step("destroying infrastructure")
-	if bblState.TFState == "" {
+	if !bblState.HasTerraformState() {
 		return bblState, nil
 	}
 
-	template := m.templateGenerator.Generate(bblState)
+	template := m.template(bblState)
 
 	input, err := m.inputGenerator.Generate(bblState)
 	if err != nil {
 		return storage.State{}, err
 	}
 
-	tfState, err := m.executor.Destroy(
-		input,
-		template,
-		bblState.TFState)
+	bblState.TFOutputs = nil
 
-	bblState.LatestTFOutput = readAndReset(m.terraformOutputBuffer)
+	tfState, err := m.runWithRetries("destroy", &bblState, func(tfState string) (string, error) {
+		return m.executor.Destroy(input, template, tfState)
+	})
 
 	switch err.(type) {
 	case executorError:
@@ -177,12 +220,213 @@ func (m Manager) Destroy(bblState storage.State) (storage.State, error) {
 	}
 	m.logger.Step("finished destroying infrastructure")
 
-	bblState.TFState = tfState
+	bblState.TFState = embeddedTFState(bblState, tfState)
+	return bblState, nil
+}
+
+func (m Manager) GetTemplate(bblState storage.State) string {
+	return m.templateGenerator.Generate(bblState)
+}
+
+// Drift refreshes a copy of the terraform state against the infrastructure
+// and returns how the two differ. It logs no steps since its output may be
+// read by other programs.
+func (m Manager) Drift(bblState storage.State) (Drift, error) {
+	tfState, err := m.tfState(bblState)
+	if err != nil {
+		return Drift{}, err
+	}
+
+	template := m.templateGenerator.Generate(bblState)
+
+	input, err := m.inputGenerator.Generate(bblState)
+	if err != nil {
+		return Drift{}, err
+	}
+
+	refreshedTFState, err := m.executor.Refresh(input, template, tfState)
+
+	// Refreshing is not an apply, so its output is not kept as the latest
+	// terraform output.
+	readAndReset(m.terraformOutputBuffer)
+
+	if err != nil {
+		return Drift{}, err
+	}
+
+	return DetectDrift(tfState, refreshedTFState)
+}
+
+// StateCommand runs a terraform state subcommand, or import, against the
+// terraform state of the environment and returns the state with its result.
+// Like Drift it logs no steps since its output may be read by other programs.
+func (m Manager) StateCommand(bblState storage.State, args []string, stdout io.Writer) (storage.State, error) {
+	runState := bblState
+	if runState.TFState != "" {
+		runState.TFBackend = nil
+	}
+
+	template := m.template(runState)
+
+	input, err := m.inputGenerator.Generate(runState)
+	if err != nil {
+		return storage.State{}, err
+	}
+
+	tfState, err := m.executor.StateCommand(input, template, runState.TFState, args, stdout)
+
+	// The command does not apply the template, so its output is not kept as
+	// the latest terraform output.
+	readAndReset(m.terraformOutputBuffer)
+
+	if err != nil {
+		return storage.State{}, err
+	}
+
+	bblState.TFState = embeddedTFState(runState, tfState)
+	if runState.TFBackend != nil {
+		bblState.TFOutputs = m.cacheOutputs(tfState)
+	}
 	return bblState, nil
 }
 
+// GetOutputs returns the terraform outputs of the environment. The outputs
+// cached for an embedded tf state are used while its SHA matches. A tf state
+// kept in a backend cannot be checked without pulling it, so the outputs
+// cached by the last bbl command that changed it are trusted instead.
 func (m Manager) GetOutputs(state storage.State) (map[string]interface{}, error) {
-	return m.outputGenerator.Generate(state.TFState)
+	if state.TFBackend != nil && state.TFState == "" && state.TFOutputs != nil {
+		return cachedOutputs(state.TFOutputs.Values), nil
+	}
+
+	tfState, err := m.tfState(state)
+	if err != nil {
+		return map[string]interface{}{}, err
+	}
+
+	if state.TFOutputs != nil && state.TFOutputs.TFStateSHA256 == tfStateSHA256(tfState) {
+		return cachedOutputs(state.TFOutputs.Values), nil
+	}
+
+	return m.outputGenerator.Generate(tfState)
+}
+
+// tfState returns the terraform state of the environment, pulling it from
+// the backend when it is not embedded.
+func (m Manager) tfState(state storage.State) (string, error) {
+	if state.TFBackend == nil || state.TFState != "" {
+		return state.TFState, nil
+	}
+
+	tfState, err := m.executor.PullState(backendTemplate(state))
+	if err != nil {
+		return "", fmt.Errorf("pull terraform state from the %s backend: %s", state.TFBackend.Type, err)
+	}
+
+	return tfState, nil
+}
+
+// cacheOutputs returns the outputs of tfState to keep in the state. The
+// infrastructure has already changed at this point, so outputs that cannot
+// be read are left for GetOutputs to retry instead of failing the run.
+func (m Manager) cacheOutputs(tfState string) *storage.TFOutputs {
+	outputs, err := m.outputGenerator.Generate(tfState)
+	if err != nil {
+		return nil
+	}
+
+	return &storage.TFOutputs{
+		TFStateSHA256: tfStateSHA256(tfState),
+		Values:        outputs,
+	}
+}
+
+// runWithRetries runs a terraform command and retries its transient
+// failures as the retry policy allows. Every retry starts from the state the
+// failed attempt left behind. The output of all attempts is kept as the
+// latest terraform output, so that latest-error reports each of them.
+func (m Manager) runWithRetries(command string, bblState *storage.State, run func(tfState string) (string, error)) (string, error) {
+	var outputs []string
+	tfState := bblState.TFState
+
+	for retry := 1; ; retry++ {
+		result, err := run(tfState)
+
+		output := readAndReset(m.terraformOutputBuffer)
+		outputs = append(outputs, output)
+
+		execErr, ok := err.(executorError)
+		class := ClassifyFailure(output)
+		if !ok || class == FailureFatal || retry > m.retryPolicy.Retries {
+			bblState.LatestTFOutput = strings.Join(outputs, "\n")
+			return result, err
+		}
+
+		if bblState.TFBackend != nil {
+			tfState = ""
+		} else if partialTFState, err := execErr.TFState(); err == nil {
+			tfState = partialTFState
+		}
+
+		backoff := m.retryPolicy.backoff(retry)
+		message := fmt.Sprintf("terraform %s failed (%s), retry %d of %d in %s", command, class, retry, m.retryPolicy.Retries, backoff)
+		m.logger.Step(message)
+		outputs = append(outputs, message)
+
+		sleep(backoff)
+	}
+}
+
+// template is the generated template plus, when the environment uses a
+// terraform backend, the backend block.
+func (m Manager) template(bblState storage.State) string {
+	template := m.templateGenerator.Generate(bblState)
+	if bblState.TFBackend == nil {
+		return template
+	}
+
+	return strings.Join([]string{template, backendTemplate(bblState)}, "\n")
+}
+
+// embeddedTFState is the terraform state to keep in bbl-state.json after a
+// run. Environments with a backend keep none.
+func embeddedTFState(bblState storage.State, tfState string) string {
+	if bblState.TFBackend != nil {
+		return ""
+	}
+	return tfState
+}
+
+func tfStateSHA256(tfState string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(tfState)))
+}
+
+// cachedOutputs restores the lists of strings the output generators return,
+// which come back from bbl-state.json as []interface{}.
+func cachedOutputs(values map[string]interface{}) map[string]interface{} {
+	outputs := map[string]interface{}{}
+	for name, value := range values {
+		outputs[name] = value
+
+		list, ok := value.([]interface{})
+		if !ok {
+			continue
+		}
+
+		strs := []string{}
+		for _, element := range list {
+			str, ok := element.(string)
+			if !ok {
+				break
+			}
+			strs = append(strs, str)
+		}
+		if len(strs) == len(list) {
+			outputs[name] = strs
+		}
+	}
+
+	return outputs
 }
 
 func readAndReset(buf *bytes.Buffer) string {
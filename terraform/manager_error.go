@@ -20,6 +20,13 @@ func NewManagerError(bblState storage.State, executorError executorError) Manage
 }
 
 func (m ManagerError) BBLState() (storage.State, error) {
+	if m.bblState.TFBackend != nil {
+		// terraform init has already moved any embedded state into the
+		// backend, which now holds the state of the failed run.
+		m.bblState.TFState = ""
+		return m.bblState, nil
+	}
+
 	tfState, err := m.executorError.TFState()
 	if err != nil {
 		return storage.State{}, err
@@ -44,6 +44,22 @@ var _ = Describe("ManagerError", func() {
 			}))
 		})
 
+		It("drops the embedded tf state when the environment uses a terraform backend", func() {
+			executorError.TFStateCall.Returns.TFState = "some-local-tf-state"
+			managerError := terraform.NewManagerError(storage.State{
+				IAAS:      "gcp",
+				TFState:   "some-migrated-tf-state",
+				TFBackend: &storage.TFBackend{Type: "gcs"},
+			}, executorError)
+
+			actualBBLState, err := managerError.BBLState()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actualBBLState).To(Equal(storage.State{
+				IAAS:      "gcp",
+				TFBackend: &storage.TFBackend{Type: "gcs"},
+			}))
+		})
+
 		Context("failure cases", func() {
 			It("returns an error when ExecutorError.TFState returns an error", func() {
 				executorError.TFStateCall.Returns.Error = errors.New("failed to get tf state")
@@ -6,6 +6,8 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/cloudfoundry/bosh-bootloader/fakes"
 	"github.com/cloudfoundry/bosh-bootloader/storage"
@@ -77,10 +79,15 @@ var _ = Describe("Manager", func() {
 			}
 
 			executor.ApplyCall.Returns.TFState = expectedTFState
+			outputGenerator.GenerateCall.Returns.Outputs = map[string]interface{}{"external_ip": "some-external-ip"}
 
 			expectedState = incomingState
 			expectedState.TFState = expectedTFState
 			expectedState.LatestTFOutput = expectedTFOutput
+			expectedState.TFOutputs = &storage.TFOutputs{
+				TFStateSHA256: "ed82973d06b5dee8932957a70ef7caa3f85eba05bd57f095624aa3b589806730",
+				Values:        map[string]interface{}{"external_ip": "some-external-ip"},
+			}
 
 			templateGenerator.GenerateCall.Returns.Template = "some-gcp-terraform-template"
 			inputGenerator.GenerateCall.Returns.Inputs = map[string]string{
@@ -139,6 +146,7 @@ var _ = Describe("Manager", func() {
 				expectedAWSState := awsState
 				expectedAWSState.TFState = "some-updated-tf-state"
 				expectedAWSState.LatestTFOutput = "some-updated-tf-state"
+				expectedAWSState.TFOutputs = expectedState.TFOutputs
 				state, err := manager.Apply(awsState)
 				Expect(err).NotTo(HaveOccurred())
 
@@ -185,9 +193,21 @@ var _ = Describe("Manager", func() {
 			}))
 			Expect(executor.ApplyCall.Receives.TFState).To(Equal("some-tf-state"))
 			Expect(executor.ApplyCall.Receives.Template).To(Equal(string("some-gcp-terraform-template")))
+			Expect(outputGenerator.GenerateCall.Receives.TFState).To(Equal(expectedTFState))
 			Expect(state).To(Equal(expectedState))
 		})
 
+		It("drops outputs cached for an earlier tf state", func() {
+			incomingState.TFOutputs = &storage.TFOutputs{TFStateSHA256: "some-old-sha"}
+			outputGenerator.GenerateCall.Returns.Error = errors.New("failed to get outputs")
+
+			state, err := manager.Apply(incomingState)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(state.TFState).To(Equal(expectedTFState))
+			Expect(state.TFOutputs).To(BeNil())
+		})
+
 		Context("when an error occurs", func() {
 			Context("when InputGenerator.Generate returns an error", func() {
 				BeforeEach(func() {
@@ -235,6 +255,89 @@ var _ = Describe("Manager", func() {
 				})
 			})
 		})
+		Context("when terraform fails with a transient error", func() {
+			var (
+				executorError *fakes.TerraformExecutorError
+				sleeps        []time.Duration
+			)
+
+			BeforeEach(func() {
+				manager = terraform.NewManager(terraform.NewManagerArgs{
+					Executor:              executor,
+					TemplateGenerator:     templateGenerator,
+					InputGenerator:        inputGenerator,
+					OutputGenerator:       outputGenerator,
+					TerraformOutputBuffer: &terraformOutputBuffer,
+					Logger:                logger,
+					StackMigrator:         migrator,
+					RetryPolicy:           terraform.RetryPolicy{Retries: 2, Backoff: time.Second},
+				})
+
+				executorError = &fakes.TerraformExecutorError{}
+				executorError.TFStateCall.Returns.TFState = "some-partial-tf-state"
+
+				sleeps = []time.Duration{}
+				terraform.SetSleep(func(d time.Duration) {
+					sleeps = append(sleeps, d)
+				})
+			})
+
+			AfterEach(func() {
+				terraform.ResetSleep()
+			})
+
+			It("retries from the partial state and reports every attempt", func() {
+				executor.ApplyCall.Stub = func(tfState string) (string, error) {
+					if executor.ApplyCall.CallCount == 1 {
+						terraformOutputBuffer.WriteString("Error: RequestLimitExceeded: Request limit exceeded.")
+						return "", executorError
+					}
+					terraformOutputBuffer.WriteString("Apply complete!")
+					return expectedTFState, nil
+				}
+
+				state, err := manager.Apply(incomingState)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(executor.ApplyCall.CallCount).To(Equal(2))
+				Expect(executor.ApplyCall.Receives.TFState).To(Equal("some-partial-tf-state"))
+				Expect(sleeps).To(Equal([]time.Duration{time.Second}))
+				Expect(logger.StepCall.Messages).To(ContainElement("terraform apply failed (rate limited), retry 1 of 2 in 1s"))
+
+				Expect(state.TFState).To(Equal(expectedTFState))
+				Expect(state.LatestTFOutput).To(Equal(strings.Join([]string{
+					"Error: RequestLimitExceeded: Request limit exceeded.",
+					"terraform apply failed (rate limited), retry 1 of 2 in 1s",
+					"Apply complete!",
+				}, "\n")))
+			})
+
+			It("backs off between retries and gives up when they run out", func() {
+				executor.ApplyCall.Stub = func(tfState string) (string, error) {
+					terraformOutputBuffer.WriteString("Error: Invalid IAM Instance Profile name")
+					return "", executorError
+				}
+
+				_, err := manager.Apply(incomingState)
+				Expect(err).To(BeAssignableToTypeOf(terraform.ManagerError{}))
+
+				Expect(executor.ApplyCall.CallCount).To(Equal(3))
+				Expect(sleeps).To(Equal([]time.Duration{time.Second, 2 * time.Second}))
+			})
+
+			It("does not retry fatal errors", func() {
+				executor.ApplyCall.Stub = func(tfState string) (string, error) {
+					terraformOutputBuffer.WriteString("Error: UnauthorizedOperation: You are not authorized to perform this operation.")
+					return "", executorError
+				}
+
+				_, err := manager.Apply(incomingState)
+				Expect(err).To(BeAssignableToTypeOf(terraform.ManagerError{}))
+
+				Expect(executor.ApplyCall.CallCount).To(Equal(1))
+				Expect(sleeps).To(BeEmpty())
+			})
+		})
 	})
 
 	Describe("Destroy", func() {
@@ -390,6 +493,282 @@ var _ = Describe("Manager", func() {
 		})
 	})
 
+	Context("when the environment uses a terraform backend", func() {
+		var bblState storage.State
+
+		BeforeEach(func() {
+			bblState = storage.State{
+				IAAS:    "gcp",
+				EnvID:   "some-env-id",
+				TFState: "some-tf-state",
+				GCP: storage.GCP{
+					ProjectID:         "some-project-id",
+					ServiceAccountKey: "some-service-account-key",
+				},
+				TFBackend: &storage.TFBackend{
+					Type:   "gcs",
+					Config: map[string]string{"bucket": "some-bucket"},
+				},
+			}
+
+			templateGenerator.GenerateCall.Returns.Template = "some-template"
+			executor.ApplyCall.Returns.TFState = "some-local-tf-state"
+			executor.DestroyCall.Returns.TFState = "some-local-tf-state"
+		})
+
+		It("applies the template with the backend block and moves the tf state into the backend", func() {
+			state, err := manager.Apply(bblState)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(executor.ApplyCall.Receives.Template).To(Equal(`some-template
+terraform {
+  backend "gcs" {
+    bucket = "some-bucket"
+    credentials = "some-service-account-key"
+    prefix = "some-env-id"
+    project = "some-project-id"
+  }
+}
+`))
+			Expect(executor.ApplyCall.Receives.TFState).To(Equal("some-tf-state"))
+			Expect(logger.StepCall.Messages).To(ContainElement("moving terraform state into the gcs backend"))
+
+			Expect(state.TFState).To(BeEmpty())
+			Expect(state.TFBackend).To(Equal(bblState.TFBackend))
+		})
+
+		It("plans without the backend while the tf state is still embedded", func() {
+			executor.PlanCall.Returns.Output = "No changes."
+
+			_, err := manager.Plan(bblState)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(executor.PlanCall.Receives.Template).To(Equal("some-template"))
+		})
+
+		It("destroys the infrastructure in the backend", func() {
+			bblState.TFState = ""
+
+			state, err := manager.Destroy(bblState)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(executor.DestroyCall.CallCount).To(Equal(1))
+			Expect(executor.DestroyCall.Receives.Template).To(ContainSubstring(`backend "gcs"`))
+			Expect(state.TFState).To(BeEmpty())
+		})
+
+		Describe("GetOutputs", func() {
+			BeforeEach(func() {
+				bblState.TFState = ""
+				executor.PullStateCall.Returns.TFState = "some-pulled-tf-state"
+				outputGenerator.GenerateCall.Returns.Outputs = map[string]interface{}{"external_ip": "some-ip"}
+			})
+
+			It("reads the outputs of the tf state pulled from the backend", func() {
+				outputs, err := manager.GetOutputs(bblState)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(outputs).To(Equal(map[string]interface{}{"external_ip": "some-ip"}))
+
+				Expect(executor.PullStateCall.Receives.BackendTemplate).To(ContainSubstring(`bucket = "some-bucket"`))
+				Expect(outputGenerator.GenerateCall.Receives.TFState).To(Equal("some-pulled-tf-state"))
+			})
+
+			It("returns an error when the tf state cannot be pulled", func() {
+				executor.PullStateCall.Returns.Error = errors.New("access denied")
+
+				_, err := manager.GetOutputs(bblState)
+				Expect(err).To(MatchError("pull terraform state from the gcs backend: access denied"))
+			})
+
+			It("returns the cached outputs without pulling the tf state", func() {
+				bblState.TFOutputs = &storage.TFOutputs{
+					TFStateSHA256: "some-sha",
+					Values:        map[string]interface{}{"external_ip": "some-cached-ip"},
+				}
+
+				outputs, err := manager.GetOutputs(bblState)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(outputs).To(Equal(map[string]interface{}{"external_ip": "some-cached-ip"}))
+
+				Expect(executor.PullStateCall.CallCount).To(Equal(0))
+				Expect(outputGenerator.GenerateCall.CallCount).To(Equal(0))
+			})
+		})
+
+		It("caches the outputs of the tf state a state command leaves in the backend", func() {
+			bblState.TFState = ""
+			bblState.TFOutputs = &storage.TFOutputs{TFStateSHA256: "some-old-sha"}
+			executor.StateCommandCall.Returns.TFState = "some-updated-tf-state"
+			outputGenerator.GenerateCall.Returns.Outputs = map[string]interface{}{"external_ip": "some-ip"}
+
+			state, err := manager.StateCommand(bblState, []string{"rm", "some.address"}, ioutil.Discard)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(outputGenerator.GenerateCall.Receives.TFState).To(Equal("some-updated-tf-state"))
+			Expect(state.TFOutputs.Values).To(Equal(map[string]interface{}{"external_ip": "some-ip"}))
+		})
+	})
+
+	Describe("Drift", func() {
+		var bblState storage.State
+
+		BeforeEach(func() {
+			bblState = storage.State{IAAS: "gcp", EnvID: "some-env-id", TFState: `{"modules": [{"path": ["root"], "resources": {"google_compute_network.bbl-network": {"primary": {"id": "some-network"}}}}]}`}
+
+			templateGenerator.GenerateCall.Returns.Template = "some-template"
+			inputGenerator.GenerateCall.Returns.Inputs = map[string]string{"env_id": "some-env-id"}
+			executor.RefreshCall.Returns.TFState = `{"modules": [{"path": ["root"], "resources": {}}]}`
+		})
+
+		It("refreshes a copy of the tf state and compares it with the stored one", func() {
+			terraformOutputBuffer.Write([]byte("some-refresh-output"))
+
+			drift, err := manager.Drift(bblState)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(executor.RefreshCall.Receives.Inputs).To(Equal(map[string]string{"env_id": "some-env-id"}))
+			Expect(executor.RefreshCall.Receives.Template).To(Equal("some-template"))
+			Expect(executor.RefreshCall.Receives.TFState).To(Equal(bblState.TFState))
+			Expect(terraformOutputBuffer.Len()).To(Equal(0))
+
+			Expect(drift.Resources).To(Equal([]terraform.DriftedResource{
+				{Address: "google_compute_network.bbl-network", Status: terraform.DriftDeleted},
+			}))
+		})
+
+		It("refreshes the tf state pulled from the backend without the backend block", func() {
+			bblState.TFBackend = &storage.TFBackend{Type: "gcs", Config: map[string]string{"bucket": "some-bucket"}}
+			executor.PullStateCall.Returns.TFState = bblState.TFState
+			bblState.TFState = ""
+
+			_, err := manager.Drift(bblState)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(executor.PullStateCall.CallCount).To(Equal(1))
+			Expect(executor.RefreshCall.Receives.Template).To(Equal("some-template"))
+			Expect(executor.RefreshCall.Receives.TFState).To(Equal(executor.PullStateCall.Returns.TFState))
+		})
+
+		It("returns an error when terraform refresh fails", func() {
+			executor.RefreshCall.Returns.Error = errors.New("failed to refresh")
+
+			_, err := manager.Drift(bblState)
+			Expect(err).To(MatchError("failed to refresh"))
+		})
+	})
+
+	Describe("Plan", func() {
+		var bblState storage.State
+
+		BeforeEach(func() {
+			bblState = storage.State{IAAS: "gcp", EnvID: "some-env-id", TFState: "some-tf-state"}
+
+			templateGenerator.GenerateCall.Returns.Template = "some-template"
+			inputGenerator.GenerateCall.Returns.Inputs = map[string]string{"env_id": "some-env-id"}
+			executor.PlanCall.Returns.Output = "  + google_compute_network.bbl-network\n\nPlan: 1 to add, 0 to change, 0 to destroy."
+		})
+
+		It("plans the generated template against the current tf state", func() {
+			plan, err := manager.Plan(bblState)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(executor.PlanCall.Receives.Inputs).To(Equal(map[string]string{"env_id": "some-env-id"}))
+			Expect(executor.PlanCall.Receives.Template).To(Equal("some-template"))
+			Expect(executor.PlanCall.Receives.TFState).To(Equal("some-tf-state"))
+			Expect(executor.ApplyCall.CallCount).To(Equal(0))
+
+			Expect(plan).To(Equal(terraform.Plan{
+				Add:       1,
+				Resources: []terraform.PlannedResource{{Action: terraform.PlanCreate, Address: "google_compute_network.bbl-network"}},
+			}))
+		})
+
+		It("does not keep the plan output as the latest terraform output", func() {
+			terraformOutputBuffer.WriteString("some plan output")
+
+			_, err := manager.Plan(bblState)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(terraformOutputBuffer.Len()).To(Equal(0))
+		})
+
+		It("returns an error when the inputs cannot be generated", func() {
+			inputGenerator.GenerateCall.Returns.Error = errors.New("failed to generate inputs")
+
+			_, err := manager.Plan(bblState)
+			Expect(err).To(MatchError("failed to generate inputs"))
+		})
+
+		It("returns an error when terraform plan fails", func() {
+			executor.PlanCall.Returns.Error = errors.New("failed to plan")
+
+			_, err := manager.Plan(bblState)
+			Expect(err).To(MatchError("failed to plan"))
+		})
+	})
+
+	Describe("StateCommand", func() {
+		var bblState storage.State
+
+		BeforeEach(func() {
+			bblState = storage.State{IAAS: "gcp", EnvID: "some-env-id", TFState: "some-tf-state"}
+
+			templateGenerator.GenerateCall.Returns.Template = "some-template"
+			inputGenerator.GenerateCall.Returns.Inputs = map[string]string{"env_id": "some-env-id"}
+			executor.StateCommandCall.Returns.TFState = "some-updated-tf-state"
+		})
+
+		It("runs the command against the tf state and returns the updated state", func() {
+			stdout := bytes.NewBuffer([]byte{})
+
+			state, err := manager.StateCommand(bblState, []string{"rm", "some.address"}, stdout)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(executor.StateCommandCall.Receives.Inputs).To(Equal(map[string]string{"env_id": "some-env-id"}))
+			Expect(executor.StateCommandCall.Receives.Template).To(Equal("some-template"))
+			Expect(executor.StateCommandCall.Receives.TFState).To(Equal("some-tf-state"))
+			Expect(executor.StateCommandCall.Receives.Args).To(Equal([]string{"rm", "some.address"}))
+			Expect(executor.StateCommandCall.Receives.Stdout).To(Equal(stdout))
+
+			Expect(state).To(Equal(storage.State{IAAS: "gcp", EnvID: "some-env-id", TFState: "some-updated-tf-state"}))
+		})
+
+		It("leaves moving an embedded tf state into the backend to apply", func() {
+			bblState.TFBackend = &storage.TFBackend{Type: "gcs", Config: map[string]string{"bucket": "some-bucket"}}
+
+			state, err := manager.StateCommand(bblState, []string{"rm", "some.address"}, ioutil.Discard)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(executor.StateCommandCall.Receives.Template).To(Equal("some-template"))
+			Expect(state.TFBackend).To(Equal(bblState.TFBackend))
+			Expect(state.TFState).To(Equal("some-updated-tf-state"))
+		})
+
+		It("does not keep the command output as the latest terraform output", func() {
+			terraformOutputBuffer.WriteString("some state output")
+
+			_, err := manager.StateCommand(bblState, []string{"list"}, ioutil.Discard)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(terraformOutputBuffer.Len()).To(Equal(0))
+		})
+
+		It("returns an error when the terraform command fails", func() {
+			executor.StateCommandCall.Returns.Error = errors.New("failed to run state command")
+
+			_, err := manager.StateCommand(bblState, []string{"list"}, ioutil.Discard)
+			Expect(err).To(MatchError("failed to run state command"))
+		})
+	})
+
+	Describe("GetTemplate", func() {
+		It("returns the generated terraform template", func() {
+			templateGenerator.GenerateCall.Returns.Template = "some-template"
+
+			template := manager.GetTemplate(storage.State{IAAS: "gcp", EnvID: "some-env-id"})
+			Expect(template).To(Equal("some-template"))
+			Expect(templateGenerator.GenerateCall.Receives.State).To(Equal(storage.State{IAAS: "gcp", EnvID: "some-env-id"}))
+		})
+	})
+
 	Describe("GetOutputs", func() {
 		BeforeEach(func() {
 			outputGenerator.GenerateCall.Returns.Outputs = map[string]interface{}{
@@ -412,6 +791,50 @@ var _ = Describe("Manager", func() {
 			}))
 		})
 
+		Context("when the state has outputs cached for its tf state", func() {
+			It("returns the cached outputs without running terraform", func() {
+				terraformOutputs, err := manager.GetOutputs(storage.State{
+					IAAS:    "aws",
+					TFState: "some-tf-state",
+					TFOutputs: &storage.TFOutputs{
+						TFStateSHA256: "c12feefe15b26c5f9048e186ae21bd8317551f53ce974f81571d25c7ee1de8ee",
+						Values: map[string]interface{}{
+							"external_ip":               "some-cached-ip",
+							"env_dns_zone_name_servers": []interface{}{"some-name-server", "some-other-name-server"},
+							"internal_az_subnet_id_mapping": map[string]interface{}{
+								"us-east-1a": "some-subnet-id",
+							},
+						},
+					},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(outputGenerator.GenerateCall.CallCount).To(Equal(0))
+				Expect(terraformOutputs).To(Equal(map[string]interface{}{
+					"external_ip":               "some-cached-ip",
+					"env_dns_zone_name_servers": []string{"some-name-server", "some-other-name-server"},
+					"internal_az_subnet_id_mapping": map[string]interface{}{
+						"us-east-1a": "some-subnet-id",
+					},
+				}))
+			})
+
+			It("ignores outputs cached for a different tf state", func() {
+				terraformOutputs, err := manager.GetOutputs(storage.State{
+					IAAS:    "gcp",
+					TFState: "some-tf-state",
+					TFOutputs: &storage.TFOutputs{
+						TFStateSHA256: "some-old-sha",
+						Values:        map[string]interface{}{"external_ip": "some-stale-ip"},
+					},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(outputGenerator.GenerateCall.CallCount).To(Equal(1))
+				Expect(terraformOutputs).To(Equal(map[string]interface{}{"external_ip": "some-external-ip"}))
+			})
+		})
+
 		Context("when the output generator fails", func() {
 			It("returns the error to the caller", func() {
 				outputGenerator.GenerateCall.Returns.Error = errors.New("fail")
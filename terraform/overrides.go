@@ -0,0 +1,169 @@
+package terraform
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	OverridesDirName = "terraform-overrides"
+
+	overrideFilePrefix = "user-"
+)
+
+var declarationRegex = regexp.MustCompile(`(?m)^\s*(resource|data)\s+"([^"]+)"\s+"([^"]+)"|^\s*(variable|output)\s+"([^"]+)"`)
+
+// Overrides are the user supplied *.tf and *.tfvars files that are applied
+// together with the generated template.
+type Overrides struct {
+	dir string
+}
+
+type overrideFiles struct {
+	templates map[string]string
+	varFiles  map[string]string
+}
+
+func NewOverrides(dir string) Overrides {
+	return Overrides{dir: dir}
+}
+
+// Write validates the overrides against the generated template and copies
+// them into the terraform working directory, replacing the copies of an
+// earlier run. It returns the names of the var files to pass to terraform.
+func (o Overrides) Write(workingDir, template string) ([]string, error) {
+	files, err := o.load()
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateOverrides(files.templates, template)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := filepath.Glob(filepath.Join(workingDir, overrideFilePrefix+"*"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range previous {
+		err = os.Remove(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for name, contents := range files.templates {
+		err = ioutil.WriteFile(filepath.Join(workingDir, overrideFilePrefix+name), []byte(contents), 0600)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var varFiles []string
+	for name, contents := range files.varFiles {
+		err = ioutil.WriteFile(filepath.Join(workingDir, overrideFilePrefix+name), []byte(contents), 0600)
+		if err != nil {
+			return nil, err
+		}
+		varFiles = append(varFiles, overrideFilePrefix+name)
+	}
+	sort.Strings(varFiles)
+
+	return varFiles, nil
+}
+
+func (o Overrides) load() (overrideFiles, error) {
+	files := overrideFiles{
+		templates: map[string]string{},
+		varFiles:  map[string]string{},
+	}
+
+	if o.dir == "" {
+		return files, nil
+	}
+
+	entries, err := ioutil.ReadDir(o.dir)
+	if os.IsNotExist(err) {
+		return files, nil
+	}
+	if err != nil {
+		return overrideFiles{}, fmt.Errorf("read terraform overrides: %s", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		name := entry.Name()
+		if !strings.HasSuffix(name, ".tf") && !strings.HasSuffix(name, ".tfvars") {
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(filepath.Join(o.dir, name))
+		if err != nil {
+			return overrideFiles{}, fmt.Errorf("read terraform overrides: %s", err)
+		}
+
+		if strings.HasSuffix(name, ".tfvars") {
+			files.varFiles[name] = string(contents)
+		} else {
+			files.templates[name] = string(contents)
+		}
+	}
+
+	return files, nil
+}
+
+func validateOverrides(templates map[string]string, template string) error {
+	owned := map[string]bool{}
+	for _, declaration := range declarations(template) {
+		owned[declaration] = true
+	}
+
+	var names []string
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if name == "override.tf" || strings.HasSuffix(name, "_override.tf") {
+			return fmt.Errorf("%s/%s is a terraform override file, which would change resources managed by bbl", OverridesDirName, name)
+		}
+
+		for _, declaration := range declarations(templates[name]) {
+			if owned[declaration] {
+				return fmt.Errorf("%s/%s redefines %s, which is managed by bbl", OverridesDirName, name, declaration)
+			}
+		}
+	}
+
+	return nil
+}
+
+// declarations returns the addresses of the resources, data sources,
+// variables and outputs declared in a template.
+func declarations(template string) []string {
+	var addresses []string
+	for _, match := range declarationRegex.FindAllStringSubmatch(template, -1) {
+		switch {
+		case match[1] == "resource":
+			addresses = append(addresses, fmt.Sprintf("%s.%s", match[2], match[3]))
+		case match[1] == "data":
+			addresses = append(addresses, fmt.Sprintf("data.%s.%s", match[2], match[3]))
+		case match[4] == "variable":
+			addresses = append(addresses, fmt.Sprintf("var.%s", match[5]))
+		default:
+			addresses = append(addresses, fmt.Sprintf("output.%s", match[5]))
+		}
+	}
+	return addresses
+}
@@ -0,0 +1,109 @@
+package terraform_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/bosh-bootloader/terraform"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Overrides", func() {
+	const template = `variable "env_id" {}
+
+resource "google_compute_network" "bbl-network" {
+  name = "${var.env_id}-network"
+}
+
+output "network_name" {
+  value = "${google_compute_network.bbl-network.name}"
+}
+`
+
+	var (
+		overridesDir string
+		workingDir   string
+		overrides    terraform.Overrides
+	)
+
+	BeforeEach(func() {
+		var err error
+		overridesDir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		workingDir, err = ioutil.TempDir("", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		overrides = terraform.NewOverrides(overridesDir)
+	})
+
+	writeOverride := func(name, contents string) {
+		err := ioutil.WriteFile(filepath.Join(overridesDir, name), []byte(contents), os.ModePerm)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	Describe("Write", func() {
+		It("copies the templates and var files into the working dir", func() {
+			writeOverride("peering.tf", `resource "google_compute_network_peering" "peering" {}`)
+			writeOverride("peering.tfvars", `peer_network = "some-network"`)
+			writeOverride("README.md", "some notes")
+
+			varFiles, err := overrides.Write(workingDir, template)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(varFiles).To(Equal([]string{"user-peering.tfvars"}))
+
+			contents, err := ioutil.ReadFile(filepath.Join(workingDir, "user-peering.tf"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal(`resource "google_compute_network_peering" "peering" {}`))
+
+			contents, err = ioutil.ReadFile(filepath.Join(workingDir, "user-peering.tfvars"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal(`peer_network = "some-network"`))
+
+			_, err = os.Stat(filepath.Join(workingDir, "user-README.md"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		It("does nothing when the overrides dir does not exist", func() {
+			overrides = terraform.NewOverrides(filepath.Join(overridesDir, "missing"))
+
+			varFiles, err := overrides.Write(workingDir, template)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(varFiles).To(BeEmpty())
+		})
+
+		Context("when an override redefines something bbl manages", func() {
+			expectRejected := func(contents, expectedError string) {
+				writeOverride("extra.tf", contents)
+
+				_, err := overrides.Write(workingDir, template)
+				Expect(err).To(MatchError(expectedError))
+			}
+
+			It("rejects resources", func() {
+				expectRejected(`
+resource "google_compute_network" "bbl-network" {
+  name = "other"
+}`, "terraform-overrides/extra.tf redefines google_compute_network.bbl-network, which is managed by bbl")
+			})
+
+			It("rejects variables", func() {
+				expectRejected(`variable "env_id" {}`, "terraform-overrides/extra.tf redefines var.env_id, which is managed by bbl")
+			})
+
+			It("rejects outputs", func() {
+				expectRejected(`output "network_name" { value = "x" }`, "terraform-overrides/extra.tf redefines output.network_name, which is managed by bbl")
+			})
+		})
+
+		It("rejects terraform override files", func() {
+			writeOverride("network_override.tf", `resource "google_compute_network" "bbl-network" {}`)
+
+			_, err := overrides.Write(workingDir, template)
+			Expect(err).To(MatchError("terraform-overrides/network_override.tf is a terraform override file, which would change resources managed by bbl"))
+		})
+	})
+})
@@ -0,0 +1,75 @@
+package terraform
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	PlanCreate  = "create"
+	PlanUpdate  = "update"
+	PlanReplace = "replace"
+	PlanDestroy = "destroy"
+)
+
+var (
+	planResourceRegex = regexp.MustCompile(`^\s*(-/\+|\+|~|-)\s+([A-Za-z0-9_-]+\.[^\s]+)`)
+	planSummaryRegex  = regexp.MustCompile(`Plan: (\d+) to add, (\d+) to change, (\d+) to destroy`)
+
+	planActions = map[string]string{
+		"+":   PlanCreate,
+		"~":   PlanUpdate,
+		"-/+": PlanReplace,
+		"-":   PlanDestroy,
+	}
+)
+
+type PlannedResource struct {
+	Action  string
+	Address string
+}
+
+type Plan struct {
+	Add       int
+	Change    int
+	Destroy   int
+	Resources []PlannedResource
+}
+
+func (p Plan) HasChanges() bool {
+	return p.Add+p.Change+p.Destroy > 0
+}
+
+// ParsePlan reads the resources and totals out of the human readable output
+// of `terraform plan -no-color`.
+func ParsePlan(output string) (Plan, error) {
+	if strings.Contains(output, "No changes.") {
+		return Plan{}, nil
+	}
+
+	summary := planSummaryRegex.FindStringSubmatch(output)
+	if summary == nil {
+		return Plan{}, errors.New("could not find the summary in the terraform plan output")
+	}
+
+	var plan Plan
+	plan.Add, _ = strconv.Atoi(summary[1])
+	plan.Change, _ = strconv.Atoi(summary[2])
+	plan.Destroy, _ = strconv.Atoi(summary[3])
+
+	for _, line := range strings.Split(output, "\n") {
+		matches := planResourceRegex.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+
+		plan.Resources = append(plan.Resources, PlannedResource{
+			Action:  planActions[matches[1]],
+			Address: matches[2],
+		})
+	}
+
+	return plan, nil
+}
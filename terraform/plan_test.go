@@ -0,0 +1,53 @@
+package terraform_test
+
+import (
+	"github.com/cloudfoundry/bosh-bootloader/terraform"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParsePlan", func() {
+	It("returns the planned resources and totals", func() {
+		plan, err := terraform.ParsePlan(`Refreshing Terraform state in-memory prior to plan...
+
+  + google_compute_firewall.bosh-open
+      name:                    "some-env-id-bosh-open"
+
+  ~ google_compute_instance_group.router-lb-0
+      instances.#:             "1" => "2"
+
+-/+ google_compute_address.bosh-external-ip (new resource required)
+      name:                    "some-env-id-bosh-external-ip" => "some-env-id-bosh-ip" (forces new resource)
+
+  - google_compute_firewall.internal
+
+
+Plan: 2 to add, 1 to change, 2 to destroy.
+`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan).To(Equal(terraform.Plan{
+			Add:     2,
+			Change:  1,
+			Destroy: 2,
+			Resources: []terraform.PlannedResource{
+				{Action: terraform.PlanCreate, Address: "google_compute_firewall.bosh-open"},
+				{Action: terraform.PlanUpdate, Address: "google_compute_instance_group.router-lb-0"},
+				{Action: terraform.PlanReplace, Address: "google_compute_address.bosh-external-ip"},
+				{Action: terraform.PlanDestroy, Address: "google_compute_firewall.internal"},
+			},
+		}))
+		Expect(plan.HasChanges()).To(BeTrue())
+	})
+
+	It("returns an empty plan when there are no changes", func() {
+		plan, err := terraform.ParsePlan("No changes. Infrastructure is up-to-date.")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan.HasChanges()).To(BeFalse())
+	})
+
+	It("returns an error when the output has no summary", func() {
+		_, err := terraform.ParsePlan("something unexpected")
+		Expect(err).To(MatchError("could not find the summary in the terraform plan output"))
+	})
+})
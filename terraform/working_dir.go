@@ -0,0 +1,27 @@
+package terraform
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var userCacheDir func() (string, error) = os.UserCacheDir
+
+// WorkingDir returns the directory terraform runs in for the environment
+// whose state is in stateDir. It is empty when there is no cache directory
+// for the user, in which case terraform runs in temp dirs.
+func WorkingDir(stateDir string) string {
+	cacheDir, err := userCacheDir()
+	if err != nil {
+		return ""
+	}
+
+	absStateDir, err := filepath.Abs(stateDir)
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(cacheDir, "bbl", "terraform", fmt.Sprintf("%x", sha256.Sum256([]byte(absStateDir))))
+}
@@ -0,0 +1,40 @@
+package terraform_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/bosh-bootloader/terraform"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WorkingDir", func() {
+	AfterEach(func() {
+		terraform.ResetUserCacheDir()
+	})
+
+	It("returns a directory per state dir in the user cache directory", func() {
+		terraform.SetUserCacheDir(func() (string, error) {
+			return "/some/cache", nil
+		})
+
+		wd, err := os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+
+		dir := terraform.WorkingDir("some-state-dir")
+		Expect(dir).To(HavePrefix("/some/cache/bbl/terraform/"))
+		Expect(dir).To(Equal(terraform.WorkingDir(filepath.Join(wd, "some-state-dir"))))
+		Expect(dir).NotTo(Equal(terraform.WorkingDir("some-other-state-dir")))
+	})
+
+	It("returns an empty dir when the user has no cache directory", func() {
+		terraform.SetUserCacheDir(func() (string, error) {
+			return "", errors.New("$HOME is not defined")
+		})
+
+		Expect(terraform.WorkingDir("some-state-dir")).To(BeEmpty())
+	})
+})